@@ -0,0 +1,82 @@
+package restconf
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Address is a complete RESTCONF address broken into the pieces you would
+// use in the appropriate API calls.
+// Example:
+//
+//	http://server[:port]/restconf[=device]/module:path/here?depth=1#top
+//
+//	Base     : http://server[:port]/restconf[=device]/
+//	Module   : module
+//	Path     : path/here
+//	RawQuery : depth=1
+//	Query    : {"depth":["1"]}
+//	Fragment : top
+type Address struct {
+	Base     string
+	Module   string
+	Path     string
+	RawQuery string
+	Query    url.Values
+	Fragment string
+}
+
+// ParseAddress takes a complete address and breaks it into pieces according
+// to RESTCONF standards.
+func ParseAddress(fullurl string) (Address, error) {
+	a, err := splitAddress(fullurl)
+	if err != nil {
+		return a, err
+	}
+	if a.Query, err = url.ParseQuery(a.RawQuery); err != nil {
+		return a, fmt.Errorf("%w. %s", ErrBadAddress, err)
+	}
+	return a, nil
+}
+
+// splitAddress is address w/o query parsed
+func splitAddress(fullurl string) (Address, error) {
+	var a Address
+	eoSlashSlash := strings.Index(fullurl, "//") + 2
+	if eoSlashSlash < 2 {
+		return a, ErrBadAddress
+	}
+	eoSlash := eoSlashSlash + strings.IndexRune(fullurl[eoSlashSlash:], '/') + 1
+	if eoSlash <= eoSlashSlash {
+		return a, ErrBadAddress
+	}
+	colon := eoSlash + strings.IndexRune(fullurl[eoSlash:], ':')
+	if colon <= eoSlash {
+		return a, ErrBadAddress
+	}
+	moduleBegin := strings.LastIndex(fullurl[:colon], "/")
+	a.Base = fullurl[:moduleBegin+1]
+	a.Module = fullurl[moduleBegin+1 : colon]
+	a.Path = fullurl[colon+1:]
+	if hash := strings.IndexRune(a.Path, '#'); hash >= 0 {
+		a.Path, a.Fragment = a.Path[:hash], a.Path[hash+1:]
+	}
+	if q := strings.IndexRune(a.Path, '?'); q >= 0 {
+		a.Path, a.RawQuery = a.Path[:q], a.Path[q+1:]
+	}
+	return a, nil
+}
+
+// legacyPath is path with query and fragment as SplitAddress has always
+// returned it
+func (a Address) legacyPath() string {
+	path := a.Path
+	if a.RawQuery != "" {
+		path += "?" + a.RawQuery
+	}
+	if a.Fragment != "" {
+		path += "#" + a.Fragment
+	}
+	return path
+}
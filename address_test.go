@@ -1,6 +1,7 @@
 package restconf
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/freeconf/yang/fc"
@@ -12,3 +13,40 @@ func TestFindDeviceIdInUrl(t *testing.T) {
 	dev = FindDeviceIdInUrl("http://server:port/restconf/")
 	fc.AssertEqual(t, "", dev)
 }
+
+func TestParseAddress(t *testing.T) {
+	a, err := ParseAddress("http://server:port/restconf=100/data/module:path/some=x")
+	fc.RequireEqual(t, nil, err)
+	fc.AssertEqual(t, "http://server:port/restconf=100/data/", a.Base)
+	fc.AssertEqual(t, "module", a.Module)
+	fc.AssertEqual(t, "path/some=x", a.Path)
+
+	_, err = ParseAddress("foo://server/mount/no-module")
+	fc.AssertEqual(t, ErrBadAddress, err)
+}
+
+func TestParseAddressQuery(t *testing.T) {
+	a, err := ParseAddress("http://server/restconf/data/module:path/some=x?depth=1&fields=a%3Bb&with-defaults=trim#top")
+	fc.RequireEqual(t, nil, err)
+	fc.AssertEqual(t, "module", a.Module)
+	fc.AssertEqual(t, "path/some=x", a.Path)
+	fc.AssertEqual(t, "depth=1&fields=a%3Bb&with-defaults=trim", a.RawQuery)
+	fc.AssertEqual(t, "1", a.Query.Get("depth"))
+	fc.AssertEqual(t, "a;b", a.Query.Get("fields"))
+	fc.AssertEqual(t, "trim", a.Query.Get("with-defaults"))
+	fc.AssertEqual(t, "top", a.Fragment)
+
+	a, err = ParseAddress("http://server/restconf/data/module:path#top")
+	fc.RequireEqual(t, nil, err)
+	fc.AssertEqual(t, "path", a.Path)
+	fc.AssertEqual(t, 0, len(a.Query))
+	fc.AssertEqual(t, "top", a.Fragment)
+
+	_, err = ParseAddress("http://server/restconf/data/module:path?depth=%zz")
+	fc.AssertEqual(t, true, errors.Is(err, ErrBadAddress))
+
+	// legacy keeps query on path
+	_, _, path, err := SplitAddress("http://server/restconf/data/module:path?depth=%zz#top")
+	fc.RequireEqual(t, nil, err)
+	fc.AssertEqual(t, "path?depth=%zz#top", path)
+}
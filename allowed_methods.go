@@ -0,0 +1,48 @@
+package restconf
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// methodAllowed is true unless Server.AllowedMethods leaves method out
+func (srv *Server) methodAllowed(method string) bool {
+	if srv.AllowedMethods == nil {
+		return true
+	}
+	return hasMethod(srv.AllowedMethods, method)
+}
+
+// checkMethodAllowed rejects methods that are not in Server.AllowedMethods
+// with 405 and what is allowed in Allow header
+func (srv *Server) checkMethodAllowed(w http.ResponseWriter, method string) error {
+	if srv.methodAllowed(method) {
+		return nil
+	}
+	w.Header().Set("Allow", strings.Join(restrictMethods(allMethods, srv.AllowedMethods), ", "))
+	return fmt.Errorf("%w. %s is not allowed on this server", ErrOperationNotSupported, method)
+}
+
+// restrictMethods are methods that are also in allowed, nil allowed is all
+func restrictMethods(methods []string, allowed []string) []string {
+	if allowed == nil {
+		return methods
+	}
+	var restricted []string
+	for _, m := range methods {
+		if hasMethod(allowed, m) {
+			restricted = append(restricted, m)
+		}
+	}
+	return restricted
+}
+
+func hasMethod(methods []string, method string) bool {
+	for _, candidate := range methods {
+		if candidate == method {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,39 @@
+package restconf
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+)
+
+func TestAllowedMethods(t *testing.T) {
+	s, ts := newTestServer(t, nestedYang, nestedData)
+	defer ts.Close()
+	s.AllowedMethods = []string{"GET", "HEAD", "OPTIONS"}
+	url := ts.URL + "/restconf/data/x:a"
+	json := string(YangDataJsonMimeType)
+
+	for _, method := range []string{"POST", "PUT", "PATCH", "DELETE"} {
+		resp, actual := testRequest(t, method, url+"/b", `{"x:b":"B2"}`, "Content-Type", json, "Accept", json)
+		fc.AssertEqual(t, 405, resp.StatusCode, method)
+		fc.AssertEqual(t, "GET, HEAD, OPTIONS", resp.Header.Get("Allow"), method)
+		fc.AssertEqual(t, true, strings.Contains(actual, `"error-tag":"operation-not-supported"`), actual)
+	}
+	resp, actual := testRequest(t, "GET", url+"/b", "", "Accept", json)
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	fc.AssertEqual(t, `{"b":"B"}`, actual)
+	resp, _ = testRequest(t, "HEAD", url+"/b", "", "Accept", json)
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	resp, _ = testRequest(t, "OPTIONS", url+"/b", "")
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	fc.AssertEqual(t, "GET, HEAD, OPTIONS", resp.Header.Get("Allow"))
+	fc.AssertEqual(t, "GET, HEAD, OPTIONS", resp.Header.Get("Access-Control-Allow-Methods"))
+
+	// default is all methods
+	s.AllowedMethods = nil
+	resp, _ = testRequest(t, "OPTIONS", url+"/b", "")
+	fc.AssertEqual(t, "GET, HEAD, POST, PUT, PATCH, DELETE, OPTIONS", resp.Header.Get("Allow"))
+	resp, _ = testRequest(t, "PUT", url+"/b", `{"x:b":"B2"}`, "Content-Type", json)
+	fc.AssertEqual(t, 204, resp.StatusCode)
+}
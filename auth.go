@@ -0,0 +1,77 @@
+package restconf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Auth verifies credentials from HTTP Basic authentication. The principal is
+// whatever application wants to identify the user with and is put in request
+// context under PrincipalContextKey so access control can find it.
+type Auth interface {
+	Authenticate(username, password string) (principal any, err error)
+}
+
+// ErrUnauthenticated is when request has no credentials or they are not
+// valid. Reported with error-tag "access-denied" and status 401
+var ErrUnauthenticated = errors.New("unauthenticated")
+
+type PrincipalContextKeyType string
+
+// PrincipalContextKey is the principal returned by Auth for the request
+var PrincipalContextKey = PrincipalContextKeyType("RESTCONF_PRINCIPAL")
+
+// PrincipalOf is the principal returned by Auth for the request or nil if
+// request is not authenticated
+func PrincipalOf(ctx context.Context) any {
+	return ctx.Value(PrincipalContextKey)
+}
+
+// TokenValidator verifies the token from "Authorization: Bearer <token>",
+// for example a JWT. Principal is handled same as Auth. RFC6750
+type TokenValidator func(token string) (principal any, err error)
+
+// authRealm is sent in WWW-Authenticate challenges
+const authRealm = "restconf"
+
+const bearerPrefix = "Bearer "
+
+// authenticate checks the client certificate, Basic or Bearer credentials of
+// request, whichever server accepts. RFC7617 and RFC6750
+func (srv *Server) authenticate(ctx context.Context, r *http.Request, w http.ResponseWriter) (context.Context, error) {
+	if srv.ClientCertAuth && r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+		principal := certPrincipal(r.TLS.VerifiedChains[0][0])
+		return context.WithValue(ctx, PrincipalContextKey, principal), nil
+	}
+	var principal any
+	var err error
+	if token, hasToken := bearerToken(r); srv.TokenValidator != nil && hasToken {
+		principal, err = srv.TokenValidator(token)
+	} else if username, password, hasCreds := r.BasicAuth(); srv.BasicAuth != nil && hasCreds {
+		principal, err = srv.BasicAuth.Authenticate(username, password)
+	} else {
+		err = errors.New("missing credentials")
+	}
+	if err != nil {
+		if srv.BasicAuth != nil {
+			w.Header().Add("WWW-Authenticate", fmt.Sprintf(`Basic realm="%s"`, authRealm))
+		}
+		if srv.TokenValidator != nil {
+			w.Header().Add("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s"`, authRealm))
+		}
+		return ctx, fmt.Errorf("%w. %s", ErrUnauthenticated, err)
+	}
+	return context.WithValue(ctx, PrincipalContextKey, principal), nil
+}
+
+// bearerToken is token from Authorization header, scheme is case insensitive
+func bearerToken(r *http.Request) (string, bool) {
+	hdr := r.Header.Get("Authorization")
+	if len(hdr) <= len(bearerPrefix) || !strings.EqualFold(hdr[:len(bearerPrefix)], bearerPrefix) {
+		return "", false
+	}
+	return strings.TrimSpace(hdr[len(bearerPrefix):]), true
+}
@@ -0,0 +1,100 @@
+package restconf
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+	"github.com/freeconf/yang/val"
+)
+
+type testAuth map[string]string
+
+func (a testAuth) Authenticate(username, password string) (any, error) {
+	if expected, found := a[username]; found && expected == password {
+		return "user:" + username, nil
+	}
+	return nil, errors.New("bad username or password")
+}
+
+func TestAuth(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module x {namespace ""; prefix ""; revision 0;
+		leaf whoami {
+			type string;
+		}
+	}`)
+	fc.RequireEqual(t, nil, err)
+	s, ts := newTestServerWithNode(t, m, &nodeutil.Basic{
+		OnField: func(r node.FieldRequest, hnd *node.ValueHandle) error {
+			principal, _ := PrincipalOf(r.Selection.Context).(string)
+			hnd.Val = val.String(principal)
+			return nil
+		},
+	})
+	defer ts.Close()
+	s.BasicAuth = testAuth{"joe": "secret"}
+	get := func(username string, password string) (*http.Response, string) {
+		req, err := http.NewRequest("GET", ts.URL+"/restconf/data/x:whoami", nil)
+		fc.RequireEqual(t, nil, err)
+		req.Header.Set("Accept", string(YangDataJsonMimeType1))
+		if username == "token" {
+			req.Header.Set("Authorization", "Bearer "+password)
+		} else if username != "" {
+			req.SetBasicAuth(username, password)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		fc.RequireEqual(t, nil, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		fc.RequireEqual(t, nil, err)
+		return resp, string(body)
+	}
+
+	resp, actual := get("joe", "secret")
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	fc.AssertEqual(t, `{"whoami":"user:joe"}`, actual)
+	fc.AssertEqual(t, "", resp.Header.Get("WWW-Authenticate"))
+
+	resp, actual = get("joe", "guess")
+	fc.AssertEqual(t, 401, resp.StatusCode)
+	fc.AssertEqual(t, `Basic realm="restconf"`, resp.Header.Get("WWW-Authenticate"))
+	fc.AssertEqual(t, true, strings.Contains(actual, `"error-tag":"access-denied"`), actual)
+
+	resp, _ = get("", "")
+	fc.AssertEqual(t, 401, resp.StatusCode)
+	fc.AssertEqual(t, `Basic realm="restconf"`, resp.Header.Get("WWW-Authenticate"))
+
+	// either basic or bearer once there is a token validator
+	s.TokenValidator = func(token string) (any, error) {
+		if token == "expired" {
+			return nil, errors.New("token expired")
+		}
+		return "token:" + token, nil
+	}
+	resp, actual = get("token", "abc")
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	fc.AssertEqual(t, `{"whoami":"token:abc"}`, actual)
+
+	resp, actual = get("joe", "secret")
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	fc.AssertEqual(t, `{"whoami":"user:joe"}`, actual)
+
+	resp, actual = get("token", "expired")
+	fc.AssertEqual(t, 401, resp.StatusCode)
+	fc.AssertEqual(t, true, strings.Contains(actual, "token expired"), actual)
+
+	resp, _ = get("", "")
+	fc.AssertEqual(t, 401, resp.StatusCode)
+	fc.AssertEqual(t, `Basic realm="restconf",Bearer realm="restconf"`, strings.Join(resp.Header.Values("WWW-Authenticate"), ","))
+
+	s.BasicAuth = nil
+	resp, _ = get("joe", "secret")
+	fc.AssertEqual(t, 401, resp.StatusCode)
+	fc.AssertEqual(t, `Bearer realm="restconf"`, resp.Header.Get("WWW-Authenticate"))
+}
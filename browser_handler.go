@@ -1,47 +1,67 @@
 package restconf
 
 import (
+	"bufio"
 	"bytes"
 	"fmt"
 	"io"
-	"mime"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"context"
+	"errors"
 
 	"github.com/freeconf/yang/fc"
 	"github.com/freeconf/yang/meta"
 	"github.com/freeconf/yang/node"
 	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/val"
 )
 
 type browserHandler struct {
-	browser *node.Browser
-}
+	browser      *node.Browser
+	withDefaults string
+	pretty       bool
+	streamLists  bool
+	heartbeat    time.Duration
+	authorizer   Authorizer
+	readOnly     readOnlyPaths
+	observer     Observer
+	modified     *modTracker
+	now          func() time.Time
+	codecs       *codecs
+	fieldsCache  *fieldsCache
+	activity     *activity
 
-var subscribeCount int
+	// edits of browser's data are one at a time, nil is no locking
+	locks       *dataLocks
+	lockTimeout time.Duration
 
-const EventTimeFormat = "2006-01-02T15:04:05-07:00"
+	// edits go to a copy of data, see serveDryRun
+	dryRun bool
 
-type ProxyContextKey string
+	// NMDA datastore when served under {+restconf}/ds, see ServeDatastore
+	datastore string
 
-var RemoteIpAddressKey = ProxyContextKey("FC_REMOTE_IP")
+	// told about edits that succeed, nil when no one is interested
+	changed func(ConfigChange)
 
-type MimeType string
+	// Server.AllowedMethods reported in Allow header, nil is all
+	allowedMethods []string
+}
 
-const (
-	// TODO: Clarify this: RFC8572 uses application/yang.data+xml and RFC8040 uses application/yang-data+json
-	YangDataJsonMimeType1 = MimeType("application/yang-data+json")
-	YangDataJsonMimeType2 = MimeType("application/yang.data+json")
+// event streams open, requests add and remove theirs concurrently
+var subscribeCount atomic.Int32
 
-	YangDataXmlMimeType1 = MimeType("application/yang-data+xml")
-	YangDataXmlMimeType2 = MimeType("application/yang.data+xml")
+const EventTimeFormat = "2006-01-02T15:04:05-07:00"
 
-	PlainJsonMimeType = MimeType("application/json")
+type ProxyContextKey string
 
-	TextStreamMimeType = MimeType("text/event-stream")
-)
+var RemoteIpAddressKey = ProxyContextKey("FC_REMOTE_IP")
 
 const SimplifiedComplianceParam = "simplified"
 
@@ -50,37 +70,192 @@ type ComplianceContextKeyType string
 var ComplianceContextKey = ComplianceContextKeyType("RESTCONF_COMPLIANCE")
 
 func (hndlr *browserHandler) ServeHTTP(compliance ComplianceOptions, ctx context.Context, w http.ResponseWriter, r *http.Request, endpointId int) {
+	if accept := r.Header.Get("Accept"); !hndlr.codecs.acceptable(hndlr.codecs.accepted(accept)) {
+		// RFC8040 Sec. 5.2
+		err := fmt.Errorf("%w. no format in '%s' can be written", ErrNotAcceptable, accept)
+		handleErr(compliance, err, r, w, YangDataJsonMimeType)
+		return
+	}
+	if !hndlr.dryRun && dryRunOf(r.URL) {
+		hndlr.serveDryRun(compliance, ctx, w, r, endpointId)
+		return
+	}
 	var err error
 	var payload node.Node
 	var cancel context.CancelFunc
 	ctx, cancel = context.WithCancel(ctx)
 	defer cancel()
+	if r.Method == "HEAD" {
+		// same as GET w/o the body. RFC8040 Sec. 4.3
+		head := &headWriter{ResponseWriter: w}
+		defer head.finish()
+		w = head
+	}
 	if r.RemoteAddr != "" {
 		host, _ := ipAddrSplitHostPort(r.RemoteAddr)
 		ctx = context.WithValue(ctx, RemoteIpAddressKey, host)
 	}
 	sel := hndlr.browser.RootWithContext(ctx)
+	addCancelConstraint(sel)
+	addReadOnlyConstraint(sel, hndlr.readOnly)
+	addAccessConstraint(sel, hndlr.authorizer, PrincipalOf(ctx))
 	var target *node.Selection
+	var isDataResource bool
+	var patchStatus *yangPatchStatus
+	var location string
+	// PUT to a resource that does not exist yet, target is then the parent
+	var creating bool
+	var createBase string
 	defer sel.Release()
-	acceptType := MimeType(r.Header.Get("Accept"))
-	contentType := MimeType(r.Header.Get("Content-Type"))
-	if target, err = sel.Find(r.URL.EscapedPath()); err == nil {
-		if err = node.BuildConstraints(target, r.URL.Query()); err != nil {
-			if handleErr(compliance, err, r, w, acceptType) {
+	acceptType := hndlr.codecs.accepted(r.Header.Get("Accept"))
+	contentType := mediaType(r.Header.Get("Content-Type"))
+	if err = checkListKeys(hndlr.browser.Meta, r.URL.EscapedPath()); err != nil {
+		handleErr(compliance, err, r, w, acceptType)
+		return
+	}
+	findPath := r.URL.EscapedPath()
+	// freeconf finds whole leaf-lists so target of an entry is its leaf-list
+	llPath, llValue, isLeafListEntry := leafListEntry(hndlr.browser.Meta, findPath)
+	if isLeafListEntry {
+		if r.Method != "DELETE" {
+			err = fmt.Errorf("%w. %s of a leaf-list entry, edit the whole leaf-list instead", ErrOperationNotSupported, r.Method)
+			handleErr(compliance, err, r, w, acceptType)
+			return
+		}
+		findPath = llPath
+	}
+	// held until response is sent so preconditions are checked against the
+	// data that is edited and reads never see an edit half done
+	var unlock func()
+	defer func() {
+		if unlock != nil {
+			unlock()
+		}
+	}()
+	if endpointId == endpointData && hndlr.locks != nil {
+		edit := r.Method == "POST" || r.Method == "PUT" || r.Method == "PATCH" || r.Method == "DELETE"
+		timeout := hndlr.lockTimeout
+		if !edit {
+			timeout = 0
+		}
+		if unlock, err = hndlr.locks.lock(ctx, hndlr.browser, edit, timeout); err != nil {
+			handleErr(compliance, err, r, w, acceptType)
+			return
+		}
+	}
+	if target, err = sel.Find(findPath); err == nil {
+		// POST w/o content names the resource it creates like PUT does
+		if target == nil && (r.Method == "PUT" || (r.Method == "POST" && !hasBody(r))) && endpointId == endpointData {
+			if target, createBase, err = putCreateParent(sel, r); err != nil {
+				handleErr(compliance, err, r, w, acceptType)
 				return
 			}
+			creating = target != nil
 		}
-		wireFmt := getWireFormatter(acceptType)
-		hdr := w.Header()
 		if target == nil {
-			http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+			err = fmt.Errorf("%w. %s", fc.NotFoundError, DecodeErrorPath(r.RequestURI))
+			handleErr(compliance, err, r, w, acceptType)
 			return
 		}
 		defer target.Release()
+		if hndlr.authorizer != nil {
+			method := r.Method
+			if creating {
+				method = "POST"
+			}
+			if err = authorize(ctx, hndlr.authorizer, method, target); err != nil {
+				handleErr(compliance, err, r, w, acceptType)
+				return
+			}
+		}
+		if err = checkReadOnly(hndlr.readOnly, w, r.Method, target, r.Method == "PUT" && !creating); err != nil {
+			handleErr(compliance, err, r, w, acceptType)
+			return
+		}
+		var params QueryParams
+		if params, err = parseQueryParams(r.URL, hndlr.fieldsCache); err == nil {
+			if err = params.CheckMethod(r.Method); err == nil {
+				err = params.CheckUnknown(errorModeOf(ctx))
+			}
+			if err == nil {
+				err = checkWithOrigin(hndlr.datastore, params)
+			}
+		}
+		if err != nil {
+			handleErr(compliance, err, r, w, acceptType)
+			return
+		}
+		if params.WithDefaults == "" {
+			params.WithDefaults = hndlr.withDefaults
+		}
+		if (hndlr.pretty || params.pretty()) && !meta.IsNotification(target.Meta()) {
+			pretty := &prettyWriter{ResponseWriter: w}
+			defer func() {
+				if err := pretty.flush(); err != nil {
+					fc.Err.Printf("error writing response. %s", err)
+				}
+			}()
+			w = pretty
+		}
+		isDataResource = endpointId == endpointData && !meta.IsAction(target.Meta()) && !meta.IsNotification(target.Meta())
+		if !isDataResource && unlock != nil {
+			// actions and event streams do not edit data, only finding them
+			// reads it
+			unlock()
+			unlock = nil
+		}
+		if hndlr.dryRun && !isDataResource {
+			err = fmt.Errorf("%w. parameter '%s' is only allowed on data resources", fc.BadRequestError, dryRunParam)
+			handleErr(compliance, err, r, w, acceptType)
+			return
+		}
+		if isDataResource && creating {
+			// there is no resource to match
+			if r.Header.Get("If-Match") != "" {
+				http.Error(w, http.StatusText(http.StatusPreconditionFailed), http.StatusPreconditionFailed)
+				return
+			}
+		} else if isDataResource {
+			var etag string
+			if etag, err = resourceEtag(target); err != nil {
+				handleErr(compliance, err, r, w, acceptType)
+				return
+			}
+			modified := hndlr.modified.lastModified(hndlr.browser, target.Path.String(), hndlr.now())
+			if r.Method == "GET" || r.Method == "HEAD" {
+				w.Header().Set("ETag", etag)
+				w.Header().Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+			}
+			status := checkPreconditions(r, etag)
+			if status == 0 {
+				status = checkModifiedPreconditions(r, modified)
+			}
+			if status == http.StatusNotModified {
+				w.WriteHeader(status)
+				return
+			} else if status != 0 {
+				http.Error(w, http.StatusText(status), status)
+				return
+			}
+		}
+		tagDefaults := params.WithDefaults == WithDefaultsTagged
+		if err = buildConstraints(target, endpointId, params); err != nil {
+			handleErr(compliance, err, r, w, acceptType)
+			return
+		}
+		var page *listPage
+		if isDataResource && (r.Method == "GET" || r.Method == "HEAD") {
+			if page, err = newListPage(target, params); err != nil {
+				handleErr(compliance, err, r, w, acceptType)
+				return
+			}
+		}
+		wireFmt := getWireFormatter(acceptType)
+		hdr := w.Header()
 		if handleErr(compliance, err, r, w, acceptType) {
 			return
 		}
-		isRpcOrAction := r.Method == "POST" && meta.IsAction(target.Meta())
+		isRpcOrAction := (r.Method == "POST" || r.Method == "OPTIONS") && meta.IsAction(target.Meta())
 		if !isRpcOrAction && endpointId == endpointOperations {
 			http.Error(w, "{+restconf}/operations is only intended for rpcs", http.StatusBadRequest)
 		} else if isRpcOrAction && !compliance.AllowRpcUnderData && endpointId == endpointData {
@@ -90,37 +265,62 @@ func (hndlr *browserHandler) ServeHTTP(compliance ComplianceOptions, ctx context
 				return
 			}
 		}
+		if (r.Method == "POST" || r.Method == "PUT" || r.Method == "PATCH") && hasBody(r) {
+			supported := hndlr.codecs.contentTypes(r.Method)
+			if err = checkContentType(r, contentType, supported); err != nil {
+				hdr.Set("Accept", joinMimeTypes(supported))
+				handleErr(compliance, err, r, w, acceptType)
+				return
+			}
+		}
+		insert := params.insert
+		var tx Transaction
+		if isDataResource && (r.Method == "POST" || r.Method == "PUT" || r.Method == "PATCH" || r.Method == "DELETE") {
+			if tx, err = beginTransaction(sel); err != nil {
+				handleErr(compliance, err, r, w, acceptType)
+				return
+			}
+			// requests that fail before edits are done never get to commit
+			defer func() {
+				abortTransaction(tx)
+			}()
+		}
 		switch r.Method {
 		case "DELETE":
 			// CRUD - Delete
-			err = target.Delete()
-		case "GET":
+			if isLeafListEntry {
+				err = deleteLeafListEntry(target, llValue)
+			} else if leaf, isLeaf := target.Meta().(meta.Leafable); isLeaf {
+				err = target.ClearField(leaf)
+			} else {
+				err = target.Delete()
+			}
+		case "GET", "HEAD":
 			if meta.IsNotification(target.Meta()) {
-				hdr.Set("Content-Type", string(TextStreamMimeType)+"; charset=utf-8")
-				hdr.Set("Cache-Control", "no-cache")
-				hdr.Set("Connection", "keep-alive")
-				hdr.Set("X-Accel-Buffering", "no")
-
-				// TODO: Make CORS configurable
-				hdr.Set("Access-Control-Allow-Origin", "*")
-
-				// default is chunked and web browsers don't know to read after each flush
-				hdr.Set("Transfer-Encoding", "identity")
+				// event streams are not limited by Server.Timeout
+				target.Context = untimed(target.Context)
+				setEventStreamHeaders(r, hdr)
+				if r.Method == "HEAD" {
+					return
+				}
 
 				var sub node.NotifyCloser
 				flusher, hasFlusher := w.(http.Flusher)
 				if !hasFlusher {
 					panic("invalid response writer")
 				}
+				origMod := meta.OriginalModule(target.Meta())
+				stream := fmt.Sprint(origMod.Ident(), ":", target.Path.StringNoModule())
+				hndlr.observer.StreamOpened(stream)
+				defer hndlr.observer.StreamClosed(stream)
 				flusher.Flush()
+				sse := newSseWriter(w, flusher, hndlr.heartbeat)
+				defer sse.stop()
 
-				subscribeCount++
-				defer func() {
-					subscribeCount--
-				}()
+				subscribeCount.Add(1)
+				defer subscribeCount.Add(-1)
 
 				errOnSend := make(chan error, 20)
-				origMod := meta.OriginalModule(target.Meta())
 				sub, err = target.Notifications(func(n node.Notification) {
 					defer func() {
 						if r := recover(); r != nil {
@@ -128,78 +328,145 @@ func (hndlr *browserHandler) ServeHTTP(compliance ComplianceOptions, ctx context
 							errOnSend <- err
 						}
 					}()
-
-					// write into a buffer so we write data all at once to handle concurrent messages and
-					// ensure messages are not corrupted.  We could use a lock, but might cause deadlocks
-					var buf bytes.Buffer
-
-					// According to SSE Spec, each event needs following format:
-					// data: {payload}\n\n
-					fmt.Fprint(&buf, "data: ")
-					if !compliance.DisableNotificationWrapper {
-						etime := n.EventTime.Format(EventTimeFormat)
-						wireFmt.writeNotificationStart(&buf, origMod, etime)
-					}
-					err := n.Event.InsertInto(nodeWtr(acceptType, compliance, &buf))
-					if err != nil {
-						errOnSend <- err
-						return
-					}
-					if !compliance.DisableNotificationWrapper {
-						wireFmt.writeNotificationEnd(&buf)
-					}
-					fmt.Fprint(&buf, "\n\n")
-					_, err = w.Write(buf.Bytes())
-					if err != nil {
+					if err := sse.event(compliance, acceptType, origMod, n); err != nil {
 						errOnSend <- fmt.Errorf("error writing notif. %s", err)
 					}
-					flusher.Flush()
-					fc.Debug.Printf("sent %d bytes in notif", buf.Len())
 				})
 				if err != nil {
 					fc.Err.Print(err)
 					return
 				}
 				defer sub()
-				select {
-				case <-r.Context().Done():
-					// normal client closing subscription
-				case err = <-errOnSend:
-					fc.Err.Print(err)
+				closing, closed := hndlr.activity.openStream()
+				defer closed()
+				for {
+					select {
+					case <-r.Context().Done():
+						// normal client closing subscription
+						return
+					case <-closing:
+						return
+					case err = <-errOnSend:
+						fc.Err.Print(err)
+						return
+					case <-sse.heartbeat():
+						if err = sse.beat(); err != nil {
+							return
+						}
+					}
 				}
-				return
 			} else {
 				// CRUD - Read
-				setContentType(compliance, w.Header(), acceptType)
-				err = target.InsertInto(nodeWtr(acceptType, compliance, w))
+				hndlr.codecs.setContentType(compliance, w.Header(), acceptType)
+				var out io.Writer = w
+				var pageBuf bytes.Buffer
+				if page != nil {
+					// next link is only known once window is read
+					out = &pageBuf
+				}
+				if tagDefaults || params.WithOrigin {
+					err = writeTagged(target, acceptType, compliance, tagDefaults, params.WithOrigin, out)
+				} else if hndlr.streamLists && r.Method == "GET" && page == nil && streamsList(target, hndlr.codecs, acceptType, w) {
+					err = streamList(target, hndlr.codecs.encoder(acceptType), compliance, w, w.(http.Flusher))
+				} else {
+					err = target.UpsertIntoSetDefaults(hndlr.codecs.encoder(acceptType)(out, compliance))
+				}
+				if err == nil && page != nil {
+					var link string
+					if link, err = page.nextLink(r); err == nil {
+						if link != "" {
+							hdr.Add("Link", link)
+						}
+						_, err = w.Write(pageBuf.Bytes())
+					}
+				}
+				if err != nil {
+					encodeFailed(r, err)
+				}
 			}
 		case "PATCH":
+			if contentType == JsonPatchMimeType {
+				var ops []*jsonPatchOp
+				if ops, err = readJsonPatch(r.Body); err != nil {
+					decodeFailed(r, err)
+					handleErr(compliance, err, r, w, acceptType)
+					return
+				}
+				editable, _ := target.Constrain("content=config")
+				err = applyJsonPatch(editable, ops)
+				break
+			}
+			if contentType.IsYangPatch() {
+				var patch *yangPatch
+				if patch, err = readYangPatch(contentType, r.Body); err != nil {
+					decodeFailed(r, err)
+					handleErr(compliance, err, r, w, acceptType)
+					return
+				}
+				editable, _ := target.Constrain("content=config")
+				patchStatus = patch.apply(editable)
+				break
+			}
 			// CRUD - Upsert
 			var input node.Node
-			input, err = requestNode(r, contentType)
+			input, err = patchNode(r, contentType, hndlr.codecs.decoder(contentType), target)
 			if err != nil {
+				decodeFailed(r, err)
 				handleErr(compliance, err, r, w, acceptType)
 				return
 			}
 			editable, _ := target.Constrain("content=config")
-			err = editable.UpsertFrom(input)
+			err = editable.UpsertFrom(mergeLeafLists(target, input))
 		case "PUT":
 			// CRUD - Remove and replace
 			var input node.Node
-			input, err = requestNode(r, contentType)
+			if hasBody(r) {
+				input, err = requestNode(r, hndlr.codecs.decoder(contentType))
+			} else if input, err = emptyContent(target, creating, r.URL.EscapedPath()); err != nil {
+				handleErr(compliance, err, r, w, acceptType)
+				return
+			}
 			if err != nil {
+				decodeFailed(r, err)
 				handleErr(compliance, err, r, w, acceptType)
 				return
 			}
 			editable, _ := target.Constrain("content=config")
-			err = editable.ReplaceFrom(input)
+			if creating {
+				// RFC8040 Sec. 4.5 - content has to be the resource in the URL
+				reqPath := strings.SplitN(r.RequestURI, "?", 2)[0]
+				if location, err = createdLocation(createBase, target, input); err == nil && !samePath(location, reqPath) {
+					err = fmt.Errorf("%w. content does not identify %s", fc.BadRequestError, reqPath)
+				}
+				if err == nil {
+					err = editable.InsertFrom(input)
+				}
+			} else if meta.IsLeaf(target.Meta()) {
+				// writing a leaf or leaf-list replaces all its values
+				err = editable.UpsertFrom(input)
+			} else {
+				// target is deleted before content is read
+				if insert != nil && isOrderedByUser(target.Meta()) {
+					err = insert.checkMove(editable)
+				}
+				if err == nil {
+					err = checkIdentities(editable.Parent(), input)
+				}
+				if err == nil {
+					err = editable.ReplaceFrom(input)
+				}
+				if err == nil && insert != nil && isOrderedByUser(target.Meta()) {
+					err = insert.moveEntry(editable)
+				}
+			}
 		case "POST":
 			if meta.IsAction(target.Meta()) {
 				// RPC
 				a := target.Meta().(*meta.Rpc)
 				var input node.Node
-				if a.Input() != nil && r.ContentLength > 0 {
-					if input, err = readInput(compliance, contentType, r, a); err != nil {
+				if a.Input() != nil && hasBody(r) {
+					if input, err = readInput(compliance, hndlr.codecs.decoder(contentType), r, a); err != nil {
+						decodeFailed(r, err)
 						handleErr(compliance, err, r, w, acceptType)
 						return
 					}
@@ -210,8 +477,9 @@ func (hndlr *browserHandler) ServeHTTP(compliance ComplianceOptions, ctx context
 					return
 				}
 				if outputSel != nil && a.Output() != nil {
-					setContentType(compliance, w.Header(), acceptType)
-					if err = sendActionOutput(acceptType, compliance, wireFmt, w, outputSel, a); err != nil {
+					hndlr.codecs.setContentType(compliance, w.Header(), acceptType)
+					if err = sendActionOutput(hndlr.codecs.encoder(acceptType), compliance, wireFmt, w, outputSel, a); err != nil {
+						encodeFailed(r, err)
 						handleErr(compliance, err, r, w, acceptType)
 						return
 					}
@@ -221,33 +489,128 @@ func (hndlr *browserHandler) ServeHTTP(compliance ComplianceOptions, ctx context
 				}
 			} else {
 				// CRUD - Insert
-				payload, err = nodeutil.ReadJSONIO(r.Body)
+				base := strings.SplitN(r.RequestURI, "?", 2)[0]
+				if !hasBody(r) {
+					payload, err = emptyContent(target, creating, r.URL.EscapedPath())
+					if creating {
+						base = createBase
+					} else if err == nil {
+						err = fmt.Errorf("%w. %s", ErrDataExists, target.Path)
+					}
+				} else if payload, err = hndlr.codecs.decoder(contentType)(r.Body); err != nil {
+					decodeFailed(r, err)
+				}
 				if err == nil {
 					editable, _ := target.Constrain("content=config")
-					err = editable.InsertFrom(payload)
+					var before map[string]*orderedEntries
+					if insert != nil {
+						if before, err = readOrderedEntries(orderedParent(editable)); err == nil {
+							var list meta.Definition
+							if meta.IsList(target.Meta()) && !target.InsideList {
+								list = target.Meta()
+							}
+							err = insert.checkPoint(orderedParent(editable), list, before)
+						}
+						if err != nil {
+							handleErr(compliance, err, r, w, acceptType)
+							return
+						}
+					}
+					if err = editable.InsertFrom(payload); errors.Is(err, fc.ConflictError) {
+						if createOnly(r) {
+							http.Error(w, http.StatusText(http.StatusPreconditionFailed), http.StatusPreconditionFailed)
+							return
+						}
+						err = fmt.Errorf("%w. %w", ErrDataExists, err)
+					} else if err == nil && insert != nil {
+						err = insert.place(orderedParent(editable), before)
+					}
+					if err == nil {
+						location, err = createdLocation(base, target, payload)
+					}
 				}
 			}
 		case "OPTIONS":
-			// NOP
+			hdr.Set("Allow", strings.Join(restrictMethods(allowedMethods(target.Meta()), hndlr.allowedMethods), ", "))
+			if isConfig(target.Meta()) {
+				hdr.Set("Accept-Patch", joinMimeTypes(hndlr.codecs.acceptPatch()))
+			}
 		default:
-			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			err = fmt.Errorf("%w. %s", ErrOperationNotSupported, r.Method)
+		}
+		if tx != nil {
+			editErr := err
+			if editErr == nil && patchStatus != nil {
+				editErr = patchStatus.failed()
+			}
+			if cerr := endTransaction(tx, editErr); editErr == nil && cerr != nil {
+				if patchStatus != nil {
+					patchStatus.globalErrs = append(patchStatus.globalErrs, cerr)
+				} else {
+					err = cerr
+				}
+			}
+			tx = nil
 		}
 	}
 
 	if err != nil {
 		handleErr(compliance, err, r, w, acceptType)
+		return
 	}
-}
-
-func setContentType(compliance ComplianceOptions, h http.Header, contentType MimeType) {
-	if compliance.QualifyNamespaceDisabled {
-		h.Set("Content-Type", mime.TypeByExtension(".json"))
-	} else {
-		h.Set("Content-Type", string(contentType))
+	if isDataResource && hndlr.changed != nil && (patchStatus == nil || patchStatus.failed() == nil) {
+		hndlr.configChanged(ctx, r, target, creating)
+	}
+	if isDataResource {
+		if r.Method == "DELETE" {
+			hndlr.modified.edited(hndlr.browser, target.Path.String(), hndlr.now())
+		} else if r.Method == "PUT" || r.Method == "PATCH" || r.Method == "POST" {
+			now := hndlr.now()
+			hndlr.modified.edited(hndlr.browser, target.Path.String(), now)
+			// report etag of resource after edit
+			w.Header().Set("Last-Modified", now.UTC().Format(http.TimeFormat))
+			if err = updateEtag(sel, r.URL.EscapedPath(), w.Header()); err != nil {
+				handleErr(compliance, err, r, w, acceptType)
+				return
+			}
+			if location != "" {
+				w.Header().Set("Location", location)
+			}
+			if r.Method != "PATCH" && returnRepresentation(r) {
+				if path, valid := representationPath(r, location); valid {
+					if err = hndlr.writeRepresentation(compliance, sel, path, w, acceptType); err != nil {
+						encodeFailed(r, err)
+						handleErr(compliance, err, r, w, acceptType)
+					}
+					return
+				}
+			}
+			if location != "" {
+				w.WriteHeader(http.StatusCreated)
+			} else if r.Method == "PUT" {
+				// RFC8040 Sec. 4.5
+				w.WriteHeader(http.StatusNoContent)
+			}
+		}
+	}
+	if patchStatus != nil {
+		// RFC8072 Sec. 2.3
+		statusType := acceptType
+		if !statusType.IsXml() && !statusType.IsJson() {
+			statusType = YangDataJsonMimeType
+			if contentType.IsXml() {
+				statusType = YangDataXmlMimeType
+			}
+		}
+		w.Header().Set("Content-Type", string(statusType))
+		w.WriteHeader(patchStatus.httpStatus())
+		if err = patchStatus.write(statusType, w); err != nil {
+			fc.Err.Printf("error writing yang-patch-status. %s", err)
+		}
 	}
 }
 
-func sendActionOutput(acceptType MimeType, compliance ComplianceOptions, wireFormat wireFormat, out io.Writer, output *node.Selection, a *meta.Rpc) error {
+func sendActionOutput(enc Encoder, compliance ComplianceOptions, wireFormat wireFormat, out io.Writer, output *node.Selection, a *meta.Rpc) error {
 	if !compliance.DisableActionWrapper {
 		// IETF formated output
 		// https://datatracker.ietf.org/doc/html/rfc8040#section-3.6.2
@@ -256,7 +619,7 @@ func sendActionOutput(acceptType MimeType, compliance ComplianceOptions, wireFor
 			return err
 		}
 	}
-	err := output.InsertInto(nodeWtr(acceptType, compliance, out))
+	err := output.InsertInto(enc(out, compliance))
 
 	if !compliance.DisableActionWrapper {
 		if _, err := wireFormat.writeRpcOutputEnd(out); err != nil {
@@ -266,33 +629,21 @@ func sendActionOutput(acceptType MimeType, compliance ComplianceOptions, wireFor
 	return err
 }
 
+// nodeWtr is the built-in JSON or XML writer, for responses that are edited
+// after they are written
 func nodeWtr(mime MimeType, compliance ComplianceOptions, out io.Writer) node.Node {
 	if mime.IsXml() {
-		wtr := &nodeutil.XMLWtr{
-			Out: out,
-		}
-		return wtr.Node()
+		return xmlEncoder(out, compliance)
 	}
-	wtr := &nodeutil.JSONWtr{
-		Out:              out,
-		QualifyNamespace: !compliance.QualifyNamespaceDisabled,
-	}
-	return wtr.Node()
+	return jsonEncoder(out, compliance)
 }
 
-func nodeRdr(mime MimeType, in io.Reader) (node.Node, error) {
-	if mime.IsXml() {
-		return nodeutil.ReadXMLBlock(in)
-	}
-	return nodeutil.ReadJSONIO(in)
-}
-
-func readInput(compliance ComplianceOptions, contentType MimeType, r *http.Request, a *meta.Rpc) (node.Node, error) {
+func readInput(compliance ComplianceOptions, dec Decoder, r *http.Request, a *meta.Rpc) (node.Node, error) {
 	// not part of spec, custom feature to allow for form uploads
 	if isMultiPartForm(r.Header) {
 		return formNode(r)
 	}
-	n, err := nodeRdr(contentType, r.Body)
+	n, err := dec(r.Body)
 	if err != nil {
 		return nil, err
 	}
@@ -312,24 +663,393 @@ func readInput(compliance ComplianceOptions, contentType MimeType, r *http.Reque
 	return n, nil
 }
 
-func requestNode(r *http.Request, contentType MimeType) (node.Node, error) {
+// createdLocation is the URL of the resource a POST to target created. base is
+// the URL of target. RFC8040 Sec. 4.4.1
+func createdLocation(base string, target *node.Selection, payload node.Node) (string, error) {
+	from := target.Split(payload)
+	if meta.IsList(target.Meta()) && !target.InsideList {
+		// entry posted directly to list
+		key, err := firstEntryKey(from)
+		if err != nil || len(key) == 0 {
+			return "", err
+		}
+		slash := strings.LastIndexByte(base, '/')
+		return buildResourceURL(base[:slash+1], "", base[slash+1:], key...), nil
+	}
+	parent, valid := target.Meta().(meta.HasDataDefinitions)
+	if !valid {
+		return "", nil
+	}
+	module := ""
+	if _, isModule := target.Meta().(*meta.Module); isModule {
+		// top-level resources are qualified with module name
+		module = target.Meta().Ident()
+		base = base[:strings.LastIndexByte(strings.TrimSuffix(base, "/"), '/')]
+	}
+	for _, m := range parent.DataDefinitions() {
+		if meta.IsLeaf(m) {
+			continue
+		}
+		child, err := from.Find(m.Ident())
+		if err != nil {
+			return "", err
+		}
+		if child == nil {
+			continue
+		}
+		var key []string
+		if meta.IsList(m) {
+			key, err = firstEntryKey(child)
+			child.Release()
+			if err != nil || len(key) == 0 {
+				return "", err
+			}
+		} else {
+			child.Release()
+		}
+		return buildResourceURL(base, module, m.Ident(), key...), nil
+	}
+	return "", nil
+}
+
+// putCreateParent is the resource a PUT to a resource that does not exist
+// yet creates the resource in and the URL of that resource. For list entries
+// that is the list unless list is empty.
+//
+//	x:a/c/e=three => x:a/c/e
+//	x:a/c         => x:a
+func putCreateParent(sel *node.Selection, r *http.Request) (*node.Selection, string, error) {
+	p := r.URL.EscapedPath()
+	reqPath := strings.SplitN(r.RequestURI, "?", 2)[0]
+	if !strings.HasSuffix(reqPath, p) {
+		return nil, "", nil
+	}
+	base := func(parentPath string) string {
+		return reqPath[:len(reqPath)-len(p)+len(parentPath)]
+	}
+	slash := strings.LastIndexByte(p, '/')
+	if eq := strings.LastIndexByte(p, '='); eq > slash {
+		list, err := sel.Find(p[:eq])
+		if err != nil || list != nil {
+			return list, base(p[:eq]), err
+		}
+	}
+	if slash < 0 {
+		// createdLocation expects URL of a top-level resource
+		return sel, reqPath, nil
+	}
+	parent, err := sel.Find(p[:slash])
+	return parent, base(p[:slash]), err
+}
+
+// emptyContent is the content of a POST or PUT w/o any for the resource at
+// path p. Only a presence container means something w/o content, it either
+// exists or it does not. RFC7950 Sec. 7.5.1
+func emptyContent(target *node.Selection, creating bool, p string) (node.Node, error) {
+	var m meta.Meta = target.Meta()
+	if creating {
+		ident := p[strings.LastIndexByte(p, '/')+1:]
+		if colon := strings.IndexByte(ident, ':'); colon >= 0 {
+			ident = ident[colon+1:]
+		}
+		// list entries are never presence containers
+		if strings.IndexByte(ident, '=') < 0 {
+			m = meta.Find(m, ident)
+		}
+	}
+	if c, valid := m.(*meta.Container); !valid || c.Presence() == "" {
+		return nil, fmt.Errorf("%w. content is required, only a presence container is created w/o content", fc.BadRequestError)
+	}
+	return readJsonNode(nil, map[string]interface{}{
+		m.(meta.Definition).Ident(): map[string]interface{}{},
+	})
+}
+
+// samePath compares URL paths regardless of how they are escaped
+func samePath(a string, b string) bool {
+	ua, aerr := url.PathUnescape(a)
+	ub, berr := url.PathUnescape(b)
+	return aerr == nil && berr == nil && ua == ub
+}
+
+// firstEntryKey is the key of the first entry in list
+func firstEntryKey(list *node.Selection) ([]string, error) {
+	item, err := list.First()
+	if err != nil || item.Selection == nil {
+		return nil, err
+	}
+	defer item.Selection.Release()
+	key := make([]string, len(item.Key))
+	for i, k := range item.Key {
+		key[i] = k.String()
+	}
+	return key, nil
+}
+
+// contentTypes are formats of data server can read in request content, PATCH
+// can also be in a patch format, see codecs.contentTypes
+func contentTypes() []MimeType {
+	return []MimeType{
+		YangDataJsonMimeType,
+		YangDataXmlMimeType,
+		YangDataJsonMimeType2,
+		YangDataXmlMimeType2,
+		PlainJsonMimeType,
+		PlainXmlMimeType,
+	}
+}
+
+func joinMimeTypes(types []MimeType) string {
+	strs := make([]string, len(types))
+	for i, t := range types {
+		strs[i] = string(t)
+	}
+	return strings.Join(strs, ", ")
+}
+
+// checkContentType verifies request content is in a format server can read.
+// Content w/o a type is assumed to be JSON.
+func checkContentType(r *http.Request, contentType MimeType, supported []MimeType) error {
+	if contentType == "" || isMultiPartForm(r.Header) {
+		return nil
+	}
+	for _, candidate := range supported {
+		if contentType == candidate {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w. '%s' with %s, expected one of %s", ErrUnsupportedMediaType, contentType, r.Method, joinMimeTypes(supported))
+}
+
+// hasBody is true when request has content even if content length is not
+// known like with chunked requests
+func hasBody(r *http.Request) bool {
+	if r.ContentLength >= 0 || r.Body == nil {
+		return r.ContentLength > 0
+	}
+	buf := bufio.NewReader(r.Body)
+	_, err := buf.Peek(1)
+	// http server closes original body
+	r.Body = io.NopCloser(buf)
+	return err == nil
+}
+
+func requestNode(r *http.Request, dec Decoder) (node.Node, error) {
 	// not part of spec, custom feature to allow for form uploads
 	if isMultiPartForm(r.Header) {
 		return formNode(r)
 	}
-	return nodeRdr(contentType, r.Body)
+	return dec(r.Body)
 }
 
-func (m MimeType) IsXml() bool {
-	return strings.HasSuffix(string(m), "xml")
+// patchNode is the content of a plain PATCH. RFC8040 Sec. 4.6.1 has JSON
+// content wrapped in the target's identifier like in a GET response but the
+// content of the target w/o the wrapper is accepted too
+//
+//	{"x:a":{"b":"B"}}  or  {"b":"B"}
+func patchNode(r *http.Request, contentType MimeType, dec Decoder, target *node.Selection) (node.Node, error) {
+	if isMultiPartForm(r.Header) || (contentType != "" && !contentType.IsJson()) {
+		return requestNode(r, dec)
+	}
+	c, values, err := readJsonContent(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	if values, err = unwrapTarget(c, target, values); err != nil {
+		return nil, err
+	}
+	return readJsonNode(c, values)
+}
+
+// mergeLeafLists has leaf-lists in plain PATCH content add to the values that
+// are already there instead of replacing them. RFC8040 Sec. 4.6.1
+func mergeLeafLists(target *node.Selection, content node.Node) node.Node {
+	return &nodeutil.Extend{
+		Base: content,
+		OnChild: func(parent node.Node, r node.ChildRequest) (node.Node, error) {
+			child, err := parent.Child(r)
+			if err != nil || child == nil {
+				return child, err
+			}
+			existing, err := target.Find(r.Meta.Ident())
+			if err != nil || existing == nil {
+				return child, err
+			}
+			return mergeLeafLists(existing, child), nil
+		},
+		OnNext: func(parent node.Node, r node.ListRequest) (node.Node, []val.Value, error) {
+			child, key, err := parent.Next(r)
+			if err != nil || child == nil || target.Parent() == nil {
+				return child, key, err
+			}
+			keys := make([]string, len(key))
+			for i, k := range key {
+				keys[i] = k.String()
+			}
+			existing, err := target.Parent().Find(r.Meta.Ident() + "=" + joinListKeys(keys))
+			if err != nil || existing == nil {
+				return child, key, err
+			}
+			return mergeLeafLists(existing, child), key, nil
+		},
+		OnField: func(parent node.Node, r node.FieldRequest, hnd *node.ValueHandle) error {
+			if err := parent.Field(r, hnd); err != nil {
+				return err
+			}
+			ll, isLeafList := r.Meta.(*meta.LeafList)
+			if !isLeafList || r.Write || hnd.Val == nil {
+				return nil
+			}
+			var existing val.Value
+			var err error
+			if target.Meta() == r.Meta {
+				existing, err = target.Get()
+			} else {
+				existing, err = target.GetValue(ll.Ident())
+			}
+			if err != nil || existing == nil {
+				return err
+			}
+			merged := listValueStrings(existing)
+			for _, v := range listValueStrings(hnd.Val) {
+				if indexOf(merged, v) < 0 {
+					merged = append(merged, v)
+				}
+			}
+			hnd.Val, err = node.NewValue(ll.Type(), merged)
+			return err
+		},
+	}
 }
 
-func (m MimeType) IsJson() bool {
-	return strings.HasSuffix(string(m), "json")
+// deleteLeafListEntry removes value from leaf-list sel. Leaf-list is cleared
+// when it was the last value.
+func deleteLeafListEntry(sel *node.Selection, value string) error {
+	ll := sel.Meta().(*meta.LeafList)
+	existing, err := sel.Get()
+	if err != nil {
+		return err
+	}
+	var remaining []string
+	if existing != nil {
+		remaining = listValueStrings(existing)
+	}
+	i := indexOf(remaining, value)
+	if i < 0 {
+		return fmt.Errorf("%w. %s=%s", fc.NotFoundError, ll.Ident(), value)
+	}
+	remaining = append(remaining[:i], remaining[i+1:]...)
+	if len(remaining) == 0 {
+		return sel.ClearField(ll)
+	}
+	v, err := node.NewValue(ll.Type(), remaining)
+	if err != nil {
+		return err
+	}
+	return sel.Set(v)
 }
 
-func (m MimeType) IsRfc() bool {
-	return m == YangDataJsonMimeType1 || m == YangDataJsonMimeType2 || m == YangDataXmlMimeType1 || m == YangDataXmlMimeType2
+func indexOf(values []string, v string) int {
+	for i, candidate := range values {
+		if candidate == v {
+			return i
+		}
+	}
+	return -1
+}
+
+// unwrapTarget removes the target's identifier around values if it is there
+func unwrapTarget(c *requestContent, target *node.Selection, values map[string]interface{}) (map[string]interface{}, error) {
+	if len(values) != 1 {
+		return values, nil
+	}
+	for name, content := range values {
+		ident := name
+		if colon := strings.IndexByte(name, ':'); colon >= 0 {
+			if name[:colon] != meta.OriginalModule(target.Meta()).Ident() {
+				return values, nil
+			}
+			ident = name[colon+1:]
+		} else if parent, valid := target.Meta().(meta.HasDataDefinitions); valid && meta.Find(parent, ident) != nil {
+			// a child with the same name as target
+			return values, nil
+		}
+		if ident != target.Meta().Ident() {
+			return values, nil
+		}
+		if target.InsideList {
+			entries, valid := c.jsonEntries(content)
+			if !valid {
+				return values, nil
+			}
+			entry, _, err := entries(0)
+			if err != nil {
+				return nil, err
+			}
+			if _, more, err := entries(1); err != nil || more {
+				return values, err
+			}
+			content = entry
+		}
+		obj, valid, err := c.jsonObject(content)
+		if err != nil || !valid {
+			return values, err
+		}
+		return obj, nil
+	}
+	return values, nil
+}
+
+func isConfig(m meta.Definition) bool {
+	if meta.IsAction(m) || meta.IsNotification(m) {
+		return false
+	}
+	if c, hasConfig := m.(meta.HasConfig); hasConfig {
+		return c.Config()
+	}
+	return true
+}
+
+// allowedMethods are methods reported in Allow header for resource. RFC8040
+// Sec. 4.1
+func allowedMethods(m meta.Definition) []string {
+	if meta.IsAction(m) {
+		return []string{"POST", "OPTIONS"}
+	}
+	if !isConfig(m) {
+		return []string{"GET", "HEAD", "OPTIONS"}
+	}
+	return allMethods
+}
+
+var allMethods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+
+// headWriter discards the body of a response to a HEAD request but reports
+// the Content-Length the GET would have had
+type headWriter struct {
+	http.ResponseWriter
+	status int
+	length int
+}
+
+func (w *headWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *headWriter) Write(data []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	w.length += len(data)
+	return len(data), nil
+}
+
+func (w *headWriter) finish() {
+	w.WriteHeader(http.StatusOK)
+	if w.length > 0 {
+		w.Header().Set("Content-Length", strconv.Itoa(w.length))
+	}
+	w.ResponseWriter.WriteHeader(w.status)
 }
 
 func findNodeOutsideSchema(m *meta.Module, container string, n node.Node) (node.Node, error) {
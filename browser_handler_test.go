@@ -0,0 +1,1299 @@
+package restconf
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/freeconf/restconf/device"
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+	"github.com/freeconf/yang/source"
+	"github.com/freeconf/yang/val"
+)
+
+const nestedYang = `module x {
+	namespace "x";
+	prefix "x";
+	revision 0;
+	container a {
+		leaf b {
+			type string;
+		}
+		container c {
+			leaf d {
+				type string;
+			}
+			list e {
+				key "f";
+				leaf f {
+					type string;
+				}
+				container g {
+					leaf h {
+						type int32;
+					}
+				}
+			}
+		}
+	}
+}`
+
+const nestedData = `{
+	"a" : {
+		"b" : "B",
+		"c" : {
+			"d" : "D",
+			"e" : [{
+				"f" : "one",
+				"g" : { "h" : 1 }
+			},{
+				"f" : "two",
+				"g" : { "h" : 2 }
+			}]
+		}
+	}
+}`
+
+// newTestServer serves a single module backed by a map loaded from json data
+func newTestServer(t *testing.T, mstr string, data string) (*Server, *httptest.Server) {
+	t.Helper()
+	m, err := parser.LoadModuleFromString(nil, mstr)
+	fc.RequireEqual(t, nil, err)
+	var vals map[string]interface{}
+	fc.RequireEqual(t, nil, json.Unmarshal([]byte(data), &vals))
+	return newTestServerWithNode(t, m, nodeutil.ReflectChild(vals))
+}
+
+// newTestServerWithNode serves a single module backed by given node
+func newTestServerWithNode(t *testing.T, m *meta.Module, n node.Node) (*Server, *httptest.Server) {
+	t.Helper()
+	d := device.New(source.Dir("./yang"))
+	d.AddBrowser(node.NewBrowser(m, n))
+	s := NewHttpServe(d)
+	return s, httptest.NewServer(s)
+}
+
+// testRequest sends request and returns response with body already read. Headers are
+// given in name, value pairs
+func testRequest(t *testing.T, method string, url string, body string, hdrs ...string) (*http.Response, string) {
+	t.Helper()
+	var payload io.Reader
+	if body != "" {
+		payload = strings.NewReader(body)
+	}
+	req, err := http.NewRequest(method, url, payload)
+	fc.RequireEqual(t, nil, err)
+	for i := 0; i+1 < len(hdrs); i += 2 {
+		req.Header.Set(hdrs[i], hdrs[i+1])
+	}
+	resp, err := http.DefaultClient.Do(req)
+	fc.RequireEqual(t, nil, err)
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	fc.RequireEqual(t, nil, err)
+	return resp, string(data)
+}
+
+func TestDepthParam(t *testing.T) {
+	_, ts := newTestServer(t, nestedYang, nestedData)
+	defer ts.Close()
+	tests := []struct {
+		depth string
+		gold  string
+	}{
+		{depth: "1", gold: "testdata/gold/depth/1.json"},
+		{depth: "3", gold: "testdata/gold/depth/3.json"},
+		{depth: "unbounded", gold: "testdata/gold/depth/unbounded.json"},
+	}
+	for _, test := range tests {
+		resp, actual := testRequest(t, "GET", ts.URL+"/restconf/data/x:a?depth="+test.depth, "",
+			"Accept", string(YangDataJsonMimeType1))
+		fc.AssertEqual(t, 200, resp.StatusCode, test.depth)
+		fc.Gold(t, *updateFlag, []byte(actual), test.gold)
+	}
+
+	for _, bad := range []string{"0", "65536", "-1", "deep"} {
+		resp, actual := testRequest(t, "GET", ts.URL+"/restconf/data/x:a?depth="+bad, "",
+			"Accept", string(YangDataJsonMimeType1))
+		fc.AssertEqual(t, 400, resp.StatusCode, bad)
+		fc.AssertEqual(t, true, strings.Contains(actual, `"error-tag":"invalid-value"`), actual)
+	}
+}
+
+func TestFieldsParam(t *testing.T) {
+	_, ts := newTestServer(t, nestedYang, nestedData)
+	defer ts.Close()
+	tests := []struct {
+		fields string
+		accept MimeType
+		gold   string
+	}{
+		{fields: "b", accept: YangDataJsonMimeType1, gold: "testdata/gold/fields/b.json"},
+		{fields: "b;c/d", accept: YangDataJsonMimeType1, gold: "testdata/gold/fields/b-c-d.json"},
+		{fields: "c(d;e(g/h))", accept: YangDataJsonMimeType1, gold: "testdata/gold/fields/nested.json"},
+		{fields: "c(d;e(g/h))", accept: YangDataXmlMimeType1, gold: "testdata/gold/fields/nested.xml"},
+	}
+	for _, test := range tests {
+		resp, actual := testRequest(t, "GET", ts.URL+"/restconf/data/x:a?fields="+url.QueryEscape(test.fields), "",
+			"Accept", string(test.accept))
+		fc.AssertEqual(t, 200, resp.StatusCode, test.fields)
+		fc.Gold(t, *updateFlag, []byte(actual), test.gold)
+	}
+
+	resp, actual := testRequest(t, "GET", ts.URL+"/restconf/data/x:a?fields="+url.QueryEscape("c(d"), "",
+		"Accept", string(YangDataJsonMimeType1))
+	fc.AssertEqual(t, 400, resp.StatusCode)
+	fc.AssertEqual(t, true, strings.Contains(actual, `"error-tag":"invalid-value"`), actual)
+}
+
+func TestContentParam(t *testing.T) {
+	mstr := `module x {
+		namespace "x";
+		prefix "x";
+		revision 0;
+		container a {
+			leaf b {
+				type string;
+			}
+			leaf c {
+				type string;
+				config false;
+			}
+			container d {
+				config false;
+				leaf e {
+					type string;
+				}
+			}
+		}
+		rpc r {}
+	}`
+	_, ts := newTestServer(t, mstr, `{"a":{"b":"B","c":"C","d":{"e":"E"}}}`)
+	defer ts.Close()
+	tests := []struct {
+		content  string
+		expected string
+	}{
+		{content: "all", expected: `{"b":"B","c":"C","d":{"e":"E"}}`},
+		{content: "config", expected: `{"b":"B"}`},
+		{content: "nonconfig", expected: `{"c":"C","d":{"e":"E"}}`},
+	}
+	for _, test := range tests {
+		resp, actual := testRequest(t, "GET", ts.URL+"/restconf/data/x:a?content="+test.content, "",
+			"Accept", string(YangDataJsonMimeType1))
+		fc.AssertEqual(t, 200, resp.StatusCode, test.content)
+		fc.AssertEqual(t, test.expected, actual, test.content)
+	}
+
+	resp, actual := testRequest(t, "GET", ts.URL+"/restconf/data/x:a?content=bogus", "",
+		"Accept", string(YangDataJsonMimeType1))
+	fc.AssertEqual(t, 400, resp.StatusCode)
+	fc.AssertEqual(t, true, strings.Contains(actual, `"error-tag":"invalid-value"`), actual)
+
+	resp, _ = testRequest(t, "POST", ts.URL+"/restconf/operations/x:r?content=config", "",
+		"Accept", string(YangDataJsonMimeType1))
+	fc.AssertEqual(t, 400, resp.StatusCode)
+}
+
+const defaultsYang = `module x {
+	namespace "x";
+	prefix "x";
+	revision 0;
+	container a {
+		leaf b {
+			type int32;
+			default 10;
+		}
+		leaf c {
+			type string;
+			default "C";
+		}
+		leaf d {
+			type string;
+		}
+		leaf-list e {
+			type string;
+		}
+		leaf-list i {
+			type string;
+			default "I1";
+			default "I2";
+		}
+		list f {
+			key "g";
+			leaf g {
+				type string;
+			}
+			leaf h {
+				type boolean;
+				default true;
+			}
+		}
+	}
+}`
+
+// b is explicitly set to its default value, c and i are not set at all
+const defaultsData = `{
+	"a" : {
+		"b" : 10,
+		"d" : "D",
+		"e" : ["E1", "E2"],
+		"f" : [{
+			"g" : "one"
+		}]
+	}
+}`
+
+func TestWithDefaultsParam(t *testing.T) {
+	_, ts := newTestServer(t, defaultsYang, defaultsData)
+	defer ts.Close()
+	for _, mode := range []string{"report-all", "trim", "explicit", "report-all-tagged"} {
+		for _, accept := range []MimeType{YangDataJsonMimeType1, YangDataXmlMimeType1} {
+			ext := ".json"
+			if accept.IsXml() {
+				ext = ".xml"
+			}
+			resp, actual := testRequest(t, "GET", ts.URL+"/restconf/data/x:a?with-defaults="+mode, "",
+				"Accept", string(accept))
+			fc.AssertEqual(t, 200, resp.StatusCode, mode)
+			fc.Gold(t, *updateFlag, []byte(actual), "testdata/gold/with-defaults/"+mode+ext)
+		}
+	}
+
+	resp, actual := testRequest(t, "GET", ts.URL+"/restconf/data/x:a?with-defaults=bogus", "",
+		"Accept", string(YangDataJsonMimeType1))
+	fc.AssertEqual(t, 400, resp.StatusCode)
+	fc.AssertEqual(t, true, strings.Contains(actual, `"error-tag":"invalid-value"`), actual)
+}
+
+func TestWithDefaultsBasicMode(t *testing.T) {
+	s, ts := newTestServer(t, defaultsYang, defaultsData)
+	defer ts.Close()
+	s.WithDefaultsBasicMode = WithDefaultsTrim
+
+	resp, actual := testRequest(t, "GET", ts.URL+"/restconf/data/x:a/f=one", "",
+		"Accept", string(YangDataJsonMimeType1))
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	fc.AssertEqual(t, `{"g":"one"}`, actual)
+
+	resp, actual = testRequest(t, "GET", ts.URL+"/restconf/data/x:a/f=one?with-defaults=report-all", "",
+		"Accept", string(YangDataJsonMimeType1))
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	fc.AssertEqual(t, `{"g":"one","h":true}`, actual)
+
+	resp, actual = testRequest(t, "GET", ts.URL+"/restconf/data/ietf-restconf-monitoring:restconf-state/capabilities", "",
+		"Accept", string(YangDataJsonMimeType1))
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	expected := `"urn:ietf:params:restconf:capability:defaults:1.0?basic-mode=trim"`
+	fc.AssertEqual(t, true, strings.Contains(actual, expected), actual)
+}
+
+func TestEtag(t *testing.T) {
+	_, ts := newTestServer(t, nestedYang, nestedData)
+	defer ts.Close()
+	addr := ts.URL + "/restconf/data/x:a"
+	accept := string(YangDataJsonMimeType1)
+
+	resp, _ := testRequest(t, "GET", addr, "", "Accept", accept)
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	orig := resp.Header.Get("ETag")
+	fc.AssertEqual(t, true, orig != "")
+
+	resp, _ = testRequest(t, "GET", addr, "", "Accept", accept, "If-None-Match", orig)
+	fc.AssertEqual(t, 304, resp.StatusCode)
+
+	edit := `{"b":"B2"}`
+	resp, _ = testRequest(t, "PATCH", addr, edit, "Content-Type", accept, "If-Match", `"bogus"`)
+	fc.AssertEqual(t, 412, resp.StatusCode)
+
+	resp, _ = testRequest(t, "PATCH", addr, edit, "Content-Type", accept, "If-Match", orig)
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	edited := resp.Header.Get("ETag")
+	fc.AssertEqual(t, true, edited != "" && edited != orig, edited)
+
+	resp, actual := testRequest(t, "GET", addr, "", "Accept", accept, "If-None-Match", orig)
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	fc.AssertEqual(t, edited, resp.Header.Get("ETag"))
+	fc.AssertEqual(t, true, strings.Contains(actual, `"B2"`), actual)
+
+	resp, _ = testRequest(t, "DELETE", addr+"/c/e=one", "", "If-Match", orig)
+	fc.AssertEqual(t, 412, resp.StatusCode)
+
+	resp, _ = testRequest(t, "PUT", addr, edit, "Content-Type", accept, "If-None-Match", "*")
+	fc.AssertEqual(t, 412, resp.StatusCode)
+}
+
+func TestCreateOnly(t *testing.T) {
+	_, ts := newTestServer(t, nestedYang, nestedData)
+	defer ts.Close()
+	addr := ts.URL + "/restconf/data/x:a/c"
+	rfc := string(YangDataJsonMimeType1)
+
+	put := `{"x:e":[{"f":"three"}]}`
+	resp, _ := testRequest(t, "PUT", addr+"/e=three", put, "Content-Type", rfc, "If-None-Match", "*")
+	fc.AssertEqual(t, 201, resp.StatusCode)
+	resp, _ = testRequest(t, "PUT", addr+"/e=three", put, "Content-Type", rfc, "If-None-Match", "*")
+	fc.AssertEqual(t, 412, resp.StatusCode)
+
+	// POST target exists, only new entry is checked
+	post := `{"x:e":[{"f":"four"}]}`
+	resp, _ = testRequest(t, "POST", addr+"/e", post, "Content-Type", rfc, "If-None-Match", "*")
+	fc.AssertEqual(t, 201, resp.StatusCode)
+	resp, _ = testRequest(t, "POST", addr+"/e", post, "Content-Type", rfc, "If-None-Match", "*")
+	fc.AssertEqual(t, 412, resp.StatusCode)
+	resp, _ = testRequest(t, "POST", addr+"/e", post, "Content-Type", rfc)
+	fc.AssertEqual(t, 409, resp.StatusCode)
+
+	_, actual := testRequest(t, "GET", addr+"/e=four", "", "Accept", rfc)
+	fc.AssertEqual(t, `{"f":"four"}`, actual)
+}
+
+func TestHead(t *testing.T) {
+	_, ts := newTestServer(t, nestedYang, nestedData)
+	defer ts.Close()
+	addr := ts.URL + "/restconf/data/x:a"
+	accept := string(YangDataJsonMimeType1)
+
+	get, body := testRequest(t, "GET", addr, "", "Accept", accept)
+	fc.AssertEqual(t, 200, get.StatusCode)
+	resp, actual := testRequest(t, "HEAD", addr, "", "Accept", accept)
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	fc.AssertEqual(t, "", actual)
+	fc.AssertEqual(t, get.Header.Get("ETag"), resp.Header.Get("ETag"))
+	fc.AssertEqual(t, get.Header.Get("Last-Modified"), resp.Header.Get("Last-Modified"))
+	fc.AssertEqual(t, get.Header.Get("Content-Type"), resp.Header.Get("Content-Type"))
+	fc.AssertEqual(t, int64(len(body)), resp.ContentLength)
+
+	resp, _ = testRequest(t, "HEAD", addr, "", "Accept", accept, "If-None-Match", get.Header.Get("ETag"))
+	fc.AssertEqual(t, 304, resp.StatusCode)
+
+	resp, actual = testRequest(t, "HEAD", addr+"/c/e=nope", "", "Accept", accept)
+	fc.AssertEqual(t, 404, resp.StatusCode)
+	fc.AssertEqual(t, string(YangDataJsonMimeType1), resp.Header.Get("Content-Type"))
+	fc.AssertEqual(t, "", actual)
+
+	resp, actual = testRequest(t, "GET", addr+"/c/e=nope", "", "Accept", accept)
+	fc.AssertEqual(t, 404, resp.StatusCode)
+	fc.AssertEqual(t, string(YangDataJsonMimeType1), resp.Header.Get("Content-Type"))
+	fc.AssertEqual(t, true, strings.Contains(actual, `"error-tag":"invalid-value"`), actual)
+}
+
+func TestOptions(t *testing.T) {
+	mstr := `module x {
+		namespace "x";
+		prefix "x";
+		revision 0;
+		container a {
+			leaf b {
+				type string;
+			}
+		}
+		container s {
+			config false;
+			leaf t {
+				type string;
+			}
+		}
+		rpc r {}
+	}`
+	m, err := parser.LoadModuleFromString(nil, mstr)
+	fc.RequireEqual(t, nil, err)
+	data, err := nodeutil.ReadJSON(`{"a":{"b":"B"},"s":{"t":"T"}}`)
+	fc.RequireEqual(t, nil, err)
+	_, ts := newTestServerWithNode(t, m, data)
+	defer ts.Close()
+	tests := []struct {
+		url         string
+		allow       string
+		acceptPatch bool
+	}{
+		{url: "/restconf/data/x:a", allow: "GET, HEAD, POST, PUT, PATCH, DELETE, OPTIONS", acceptPatch: true},
+		{url: "/restconf/data/x:a/b", allow: "GET, HEAD, POST, PUT, PATCH, DELETE, OPTIONS", acceptPatch: true},
+		{url: "/restconf/data/x:s", allow: "GET, HEAD, OPTIONS"},
+		{url: "/restconf/data/x:s/t", allow: "GET, HEAD, OPTIONS"},
+		{url: "/restconf/operations/x:r", allow: "POST, OPTIONS"},
+	}
+	for _, test := range tests {
+		resp, _ := testRequest(t, "OPTIONS", ts.URL+test.url, "")
+		fc.AssertEqual(t, 200, resp.StatusCode, test.url)
+		fc.AssertEqual(t, test.allow, resp.Header.Get("Allow"), test.url)
+		fc.AssertEqual(t, test.acceptPatch, strings.Contains(resp.Header.Get("Accept-Patch"), string(YangPatchJsonMimeType)), test.url)
+	}
+}
+
+func TestContentType(t *testing.T) {
+	mstr := `module x {
+		namespace "x";
+		prefix "x";
+		revision 0;
+		container a {
+			leaf b {
+				type string;
+			}
+		}
+	}`
+	_, ts := newTestServer(t, mstr, `{"a":{"b":"B"}}`)
+	defer ts.Close()
+	addr := ts.URL + "/restconf/data/x:a"
+	tests := []struct {
+		method      string
+		contentType string
+		body        string
+		status      int
+	}{
+		{method: "PUT", contentType: "text/plain", body: "b=C", status: 415},
+		{method: "POST", contentType: "text/plain", body: "b=C", status: 415},
+		{method: "PATCH", contentType: "text/plain", body: "b=C", status: 415},
+		{method: "PUT", contentType: string(YangPatchJsonMimeType), body: `{"b":"C"}`, status: 415},
+		{method: "PATCH", contentType: string(YangDataJsonMimeType1), body: `{"b":"C"}`, status: 200},
+		{method: "PATCH", contentType: string(YangDataJsonMimeType1) + "; charset=utf-8", body: `{"b":"C"}`, status: 200},
+		{method: "PATCH", contentType: string(YangDataXmlMimeType1), body: `<a xmlns="x"><b>D</b></a>`, status: 200},
+		{method: "PATCH", body: `{"b":"E"}`, status: 200},
+	}
+	for _, test := range tests {
+		var hdrs []string
+		if test.contentType != "" {
+			hdrs = append(hdrs, "Content-Type", test.contentType)
+		}
+		resp, _ := testRequest(t, test.method, addr, test.body, hdrs...)
+		fc.AssertEqual(t, test.status, resp.StatusCode, test.method+" "+test.contentType)
+		if test.status == 415 {
+			fc.AssertEqual(t, true, strings.Contains(resp.Header.Get("Accept"), string(YangDataXmlMimeType1)))
+			fc.AssertEqual(t, test.method == "PATCH", strings.Contains(resp.Header.Get("Accept"), string(YangPatchJsonMimeType)))
+		}
+	}
+	_, actual := testRequest(t, "GET", addr, "", "Accept", string(YangDataJsonMimeType1))
+	fc.AssertEqual(t, `{"b":"E"}`, actual)
+}
+
+func TestPostLocation(t *testing.T) {
+	mstr := `module x {
+		namespace "x";
+		prefix "x";
+		revision 0;
+		container a {
+			list e {
+				key "f";
+				leaf f {
+					type string;
+				}
+			}
+			list p {
+				key "q r";
+				leaf q {
+					type string;
+				}
+				leaf r {
+					type int32;
+				}
+			}
+			container s {
+				leaf t {
+					type string;
+				}
+			}
+		}
+	}`
+	type entry struct {
+		F string
+	}
+	type pair struct {
+		Q string
+		R int
+	}
+	type sub struct {
+		T string
+	}
+	data := struct {
+		A struct {
+			E []*entry
+			P []*pair
+			S *sub
+		}
+	}{}
+	m, err := parser.LoadModuleFromString(nil, mstr)
+	fc.RequireEqual(t, nil, err)
+	_, ts := newTestServerWithNode(t, m, &nodeutil.Node{Object: &data})
+	defer ts.Close()
+	ctype := string(YangDataJsonMimeType1)
+	tests := []struct {
+		url      string
+		body     string
+		location string
+	}{
+		{url: "/restconf/data/x:a", body: `{"e":[{"f":"one"}]}`, location: "/restconf/data/x:a/e=one"},
+		{url: "/restconf/data/x:a/e", body: `{"e":[{"f":"a b"}]}`, location: "/restconf/data/x:a/e=a%20b"},
+		{url: "/restconf/data/x:a", body: `{"p":[{"q":"x,y","r":2}]}`, location: "/restconf/data/x:a/p=x%2Cy,2"},
+		{url: "/restconf/data/x:a", body: `{"s":{"t":"T"}}`, location: "/restconf/data/x:a/s"},
+		{url: "/restconf/data/x:a/e", body: `{"e":[{"f":"a/b"}]}`, location: "/restconf/data/x:a/e=a%2Fb"},
+		{url: "/restconf/data/x:a/e", body: `{"e":[{"f":"a=b+c"}]}`, location: "/restconf/data/x:a/e=a%3Db%2Bc"},
+		{url: "/restconf/data/x:a/p", body: `{"p":[{"q":"a,b/c=d","r":3}]}`, location: "/restconf/data/x:a/p=a%2Cb%2Fc%3Dd,3"},
+		{url: "/restconf/data/x:a/e", body: `{"e":[{"f":"grüße"}]}`, location: "/restconf/data/x:a/e=gr%C3%BC%C3%9Fe"},
+	}
+	for _, test := range tests {
+		resp, actual := testRequest(t, "POST", ts.URL+test.url, test.body, "Content-Type", ctype)
+		fc.AssertEqual(t, 201, resp.StatusCode, test.body, actual)
+		fc.AssertEqual(t, test.location, resp.Header.Get("Location"), test.body)
+
+		// location has to find the entry that was created
+		resp, actual = testRequest(t, "GET", ts.URL+test.location, "", "Accept", ctype)
+		fc.AssertEqual(t, 200, resp.StatusCode, test.location, actual)
+	}
+
+	resp, actual := testRequest(t, "POST", ts.URL+"/restconf/data/x:a", `{"e":[{"f":"one"}]}`,
+		"Content-Type", ctype, "Accept", ctype)
+	fc.AssertEqual(t, 409, resp.StatusCode)
+	fc.AssertEqual(t, true, strings.Contains(actual, `"error-tag":"data-exists"`), actual)
+}
+
+func TestOperations(t *testing.T) {
+	mstr := `module x {
+		namespace "x";
+		prefix "x";
+		revision 0;
+		rpc add {
+			input {
+				leaf a {
+					type int32;
+				}
+				leaf b {
+					type int32;
+				}
+			}
+			output {
+				leaf sum {
+					type int32;
+				}
+			}
+		}
+		rpc reset {}
+		list l {
+			key "k";
+			leaf k {
+				type string;
+			}
+			action greet {
+				output {
+					leaf msg {
+						type string;
+					}
+				}
+			}
+		}
+	}`
+	m, err := parser.LoadModuleFromString(nil, mstr)
+	fc.RequireEqual(t, nil, err)
+	resets := 0
+	var n node.Node
+	n = &nodeutil.Basic{
+		OnAction: func(r node.ActionRequest) (node.Node, error) {
+			switch r.Meta.Ident() {
+			case "add":
+				var in struct {
+					A int
+					B int
+				}
+				if err := r.Input.UpsertInto(&nodeutil.Node{Object: &in}); err != nil {
+					return nil, err
+				}
+				return &nodeutil.Node{Object: map[string]interface{}{"sum": in.A + in.B}}, nil
+			case "reset":
+				resets++
+			}
+			return nil, nil
+		},
+		OnNext: func(r node.ListRequest) (node.Node, []val.Value, error) {
+			if r.Key == nil || r.Key[0].String() != "joe" {
+				return nil, nil, nil
+			}
+			return &nodeutil.Basic{
+				OnAction: func(ar node.ActionRequest) (node.Node, error) {
+					return &nodeutil.Node{Object: map[string]interface{}{"msg": "hi " + r.Key[0].String()}}, nil
+				},
+			}, r.Key, nil
+		},
+	}
+	n.(*nodeutil.Basic).OnChild = func(r node.ChildRequest) (node.Node, error) {
+		return n, nil
+	}
+	_, ts := newTestServerWithNode(t, m, n)
+	defer ts.Close()
+	rfc := string(YangDataJsonMimeType1)
+
+	resp, actual := testRequest(t, "POST", ts.URL+"/restconf/operations/x:add", `{"x:input":{"a":1,"b":2}}`,
+		"Content-Type", rfc, "Accept", rfc)
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	fc.AssertEqual(t, `{"x:output":{"sum":3}}`, actual)
+
+	resp, actual = testRequest(t, "POST", ts.URL+"/restconf/operations/x:add",
+		`<input xmlns="x"><a>3</a><b>4</b></input>`,
+		"Content-Type", string(YangDataXmlMimeType1), "Accept", string(YangDataXmlMimeType1))
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	fc.AssertEqual(t, true, strings.Contains(actual, "<sum>7</sum>"), actual)
+
+	resp, actual = testRequest(t, "POST", ts.URL+"/restconf/operations/x:reset", "", "Accept", rfc)
+	fc.AssertEqual(t, 204, resp.StatusCode)
+	fc.AssertEqual(t, "", actual)
+	fc.AssertEqual(t, 1, resets)
+
+	resp, actual = testRequest(t, "POST", ts.URL+"/restconf/data/x:l=joe/greet", "", "Accept", rfc)
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	fc.AssertEqual(t, `{"x:output":{"msg":"hi joe"}}`, actual)
+
+	// input w/o content length
+	req, err := http.NewRequest("POST", ts.URL+"/restconf/operations/x:add", io.NopCloser(strings.NewReader(`{"x:input":{"a":5,"b":6}}`)))
+	fc.RequireEqual(t, nil, err)
+	req.Header.Set("Content-Type", rfc)
+	req.Header.Set("Accept", rfc)
+	chunked, err := http.DefaultClient.Do(req)
+	fc.RequireEqual(t, nil, err)
+	defer chunked.Body.Close()
+	body, _ := io.ReadAll(chunked.Body)
+	fc.AssertEqual(t, `{"x:output":{"sum":11}}`, string(body))
+}
+
+func TestLastModified(t *testing.T) {
+	s, ts := newTestServer(t, nestedYang, nestedData)
+	defer ts.Close()
+	clock := time.Date(2020, time.March, 4, 5, 6, 7, 0, time.UTC)
+	s.Now = func() time.Time {
+		return clock
+	}
+	addr := ts.URL + "/restconf/data/x:a"
+	accept := string(YangDataJsonMimeType1)
+
+	resp, _ := testRequest(t, "GET", addr, "", "Accept", accept)
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	orig := resp.Header.Get("Last-Modified")
+	fc.AssertEqual(t, "Wed, 04 Mar 2020 05:06:07 GMT", orig)
+
+	resp, _ = testRequest(t, "GET", addr, "", "Accept", accept, "If-Modified-Since", orig)
+	fc.AssertEqual(t, 304, resp.StatusCode)
+
+	clock = clock.Add(time.Minute)
+	resp, _ = testRequest(t, "PATCH", addr+"/c", `{"d":"D2"}`, "Content-Type", accept)
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	fc.AssertEqual(t, "Wed, 04 Mar 2020 05:07:07 GMT", resp.Header.Get("Last-Modified"))
+
+	// ancestor is modified by edit to descendant, sibling is not
+	resp, _ = testRequest(t, "GET", addr, "", "Accept", accept, "If-Modified-Since", orig)
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	fc.AssertEqual(t, "Wed, 04 Mar 2020 05:07:07 GMT", resp.Header.Get("Last-Modified"))
+	resp, _ = testRequest(t, "GET", addr+"/b", "", "Accept", accept)
+	fc.AssertEqual(t, orig, resp.Header.Get("Last-Modified"))
+
+	resp, _ = testRequest(t, "PUT", addr+"/c", `{"d":"D3"}`, "Content-Type", accept, "If-Unmodified-Since", orig)
+	fc.AssertEqual(t, 412, resp.StatusCode)
+}
+
+func TestInsertParam(t *testing.T) {
+	mstr := `module x {
+		namespace "x";
+		prefix "x";
+		revision 0;
+		container a {
+			list e {
+				key "f";
+				ordered-by user;
+				leaf f {
+					type string;
+				}
+				leaf g {
+					type int32;
+				}
+			}
+			leaf-list l {
+				type string;
+				ordered-by user;
+			}
+		}
+	}`
+	// reflecting on maps sorts list entries, slices keep their order
+	type entry struct {
+		F string
+		G int
+	}
+	data := struct {
+		A struct {
+			E []*entry
+			L []string
+		}
+	}{}
+	data.A.E = []*entry{{F: "one"}, {F: "two"}}
+	data.A.L = []string{"L1", "L2"}
+	m, err := parser.LoadModuleFromString(nil, mstr)
+	fc.RequireEqual(t, nil, err)
+	_, ts := newTestServerWithNode(t, m, &nodeutil.Node{Object: &data})
+	defer ts.Close()
+	addr := ts.URL + "/restconf/data/x:a"
+	ctype := string(YangDataJsonMimeType1)
+	tests := []struct {
+		params   string
+		body     string
+		expected string
+	}{
+		{params: "insert=first", body: `{"e":[{"f":"zero"}]}`, expected: `zero,one,two`},
+		{params: "insert=last", body: `{"e":[{"f":"three"}]}`, expected: `zero,one,two,three`},
+		{params: "insert=before&point=" + url.QueryEscape("/x:a/e=two"), body: `{"e":[{"f":"1.5","g":15}]}`, expected: `zero,one,1.5,two,three`},
+		{params: "insert=after&point=" + url.QueryEscape("/x:a/e=zero"), body: `{"e":[{"f":"0.5"}]}`, expected: `zero,0.5,one,1.5,two,three`},
+	}
+	for _, test := range tests {
+		resp, actual := testRequest(t, "POST", addr+"/e?"+test.params, test.body, "Content-Type", ctype)
+		fc.AssertEqual(t, 201, resp.StatusCode, test.params, actual)
+		_, actual = testRequest(t, "GET", addr+"?fields=e/f", "", "Accept", ctype)
+		var resp2 struct {
+			E []struct {
+				F string
+			}
+		}
+		fc.RequireEqual(t, nil, json.Unmarshal([]byte(actual), &resp2))
+		var keys []string
+		for _, e := range resp2.E {
+			keys = append(keys, e.F)
+		}
+		fc.AssertEqual(t, test.expected, strings.Join(keys, ","), test.params)
+	}
+
+	// existing entries keep their data
+	_, actual := testRequest(t, "GET", addr+"/e=1.5", "", "Accept", ctype)
+	fc.AssertEqual(t, `{"f":"1.5","g":15}`, actual)
+
+	// replacing an entry can move it
+	resp, actual := testRequest(t, "PUT", addr+"/e=two?insert=first", `{"e":[{"f":"two","g":2}]}`, "Content-Type", ctype)
+	fc.AssertEqual(t, 204, resp.StatusCode, actual)
+	_, actual = testRequest(t, "GET", addr+"?fields=e/f", "", "Accept", ctype)
+	fc.AssertEqual(t, `{"e":[{"f":"two"},{"f":"zero"},{"f":"0.5"},{"f":"one"},{"f":"1.5"},{"f":"three"}]}`, actual)
+
+	resp, _ = testRequest(t, "POST", addr+"?insert=after&point="+url.QueryEscape("/x:a/l=L1"), `{"l":["L1.5"]}`, "Content-Type", ctype)
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	_, actual = testRequest(t, "GET", addr+"?fields=l", "", "Accept", ctype)
+	fc.AssertEqual(t, `{"l":["L1","L1.5","L2"]}`, actual)
+
+	resp, actual = testRequest(t, "POST", addr+"/e?insert=before", `{"e":[{"f":"four"}]}`, "Content-Type", ctype)
+	fc.AssertEqual(t, 400, resp.StatusCode)
+	fc.AssertEqual(t, true, strings.Contains(actual, `"error-tag":"missing-element"`), actual)
+
+	resp, _ = testRequest(t, "POST", addr+"/e?insert=sideways", `{"e":[{"f":"four"}]}`, "Content-Type", ctype)
+	fc.AssertEqual(t, 400, resp.StatusCode)
+
+	// point has to be an existing entry of the list entry is added to
+	unchanged := `{"e":[{"f":"two"},{"f":"zero"},{"f":"0.5"},{"f":"one"},{"f":"1.5"},{"f":"three"}]}`
+	for _, point := range []string{"/x:a/e=bogus", "/x:a/l=L1", "/x:a/e/x=one", "/x:b/e=one"} {
+		resp, _ = testRequest(t, "POST", addr+"/e?insert=after&point="+url.QueryEscape(point), `{"e":[{"f":"four"}]}`, "Content-Type", ctype)
+		fc.AssertEqual(t, 400, resp.StatusCode, point)
+		_, actual = testRequest(t, "GET", addr+"?fields=e/f", "", "Accept", ctype)
+		fc.AssertEqual(t, unchanged, actual, point)
+	}
+	resp, _ = testRequest(t, "PUT", addr+"/e=one?insert=after&point="+url.QueryEscape("/x:a/e=bogus"), `{"e":[{"f":"one","g":1}]}`, "Content-Type", ctype)
+	fc.AssertEqual(t, 400, resp.StatusCode)
+	_, actual = testRequest(t, "GET", addr+"?fields=e/f", "", "Accept", ctype)
+	fc.AssertEqual(t, unchanged, actual)
+
+	resp, _ = testRequest(t, "POST", addr+"/e?insert=after&point="+url.QueryEscape("/x:a/e=0.5"), `{"e":[{"f":"0.7"}]}`, "Content-Type", ctype)
+	fc.AssertEqual(t, 201, resp.StatusCode)
+	_, actual = testRequest(t, "GET", addr+"?fields=e/f", "", "Accept", ctype)
+	fc.AssertEqual(t, `{"e":[{"f":"two"},{"f":"zero"},{"f":"0.5"},{"f":"0.7"},{"f":"one"},{"f":"1.5"},{"f":"three"}]}`, actual)
+}
+
+func TestPatchMergesPutReplaces(t *testing.T) {
+	_, ts := newTestServer(t, nestedYang, nestedData)
+	defer ts.Close()
+	rfc := string(YangDataJsonMimeType1)
+	addr := ts.URL + "/restconf/data/x:a"
+
+	// RFC8040 Sec. 4.6.1 - siblings of given leaves are left alone
+	resp, _ := testRequest(t, "PATCH", addr, `{"x:a":{"b":"B2"}}`, "Content-Type", rfc)
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	_, actual := testRequest(t, "GET", addr+"/c", "", "Accept", rfc)
+	fc.AssertEqual(t, `{"d":"D","e":[{"f":"one","g":{"h":1}},{"f":"two","g":{"h":2}}]}`, actual)
+	resp, _ = testRequest(t, "PATCH", addr+"/c", `{"x:c":{"d":"D2"}}`, "Content-Type", rfc)
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	resp, _ = testRequest(t, "PATCH", addr+"/c/e=two", `{"x:e":[{"f":"two","g":{"h":22}}]}`, "Content-Type", rfc)
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	_, actual = testRequest(t, "GET", addr, "", "Accept", rfc)
+	fc.AssertEqual(t, `{"b":"B2","c":{"d":"D2","e":[{"f":"one","g":{"h":1}},{"f":"two","g":{"h":22}}]}}`, actual)
+
+	// RFC8040 Sec. 4.5 - whatever is not given is removed
+	resp, _ = testRequest(t, "PUT", addr+"/c", `{"x:c":{"d":"D3"}}`, "Content-Type", rfc)
+	fc.AssertEqual(t, 204, resp.StatusCode)
+	_, actual = testRequest(t, "GET", addr, "", "Accept", rfc)
+	fc.AssertEqual(t, `{"b":"B2","c":{"d":"D3"}}`, actual)
+	resp, _ = testRequest(t, "PUT", addr, `{"x:a":{"c":{"d":"D4"}}}`, "Content-Type", rfc)
+	fc.AssertEqual(t, 204, resp.StatusCode)
+	_, actual = testRequest(t, "GET", addr, "", "Accept", rfc)
+	fc.AssertEqual(t, `{"c":{"d":"D4"}}`, actual)
+}
+
+func TestPutCreate(t *testing.T) {
+	_, ts := newTestServer(t, nestedYang, nestedData)
+	defer ts.Close()
+	rfc := string(YangDataJsonMimeType1)
+	addr := ts.URL + "/restconf/data/x:a"
+
+	// RFC8040 Sec. 4.5
+	resp, _ := testRequest(t, "PUT", addr+"/c/e=three", `{"x:e":[{"f":"three","g":{"h":3}}]}`, "Content-Type", rfc)
+	fc.AssertEqual(t, 201, resp.StatusCode)
+	fc.AssertEqual(t, "/restconf/data/x:a/c/e=three", resp.Header.Get("Location"))
+	fc.AssertEqual(t, true, resp.Header.Get("ETag") != "")
+	_, actual := testRequest(t, "GET", addr+"/c/e=three", "", "Accept", rfc)
+	fc.AssertEqual(t, `{"f":"three","g":{"h":3}}`, actual)
+
+	resp, _ = testRequest(t, "PUT", addr+"/c/e=three", `{"x:e":[{"f":"three","g":{"h":33}}]}`, "Content-Type", rfc)
+	fc.AssertEqual(t, 204, resp.StatusCode)
+	fc.AssertEqual(t, "", resp.Header.Get("Location"))
+	_, actual = testRequest(t, "GET", addr+"/c/e=three", "", "Accept", rfc)
+	fc.AssertEqual(t, `{"f":"three","g":{"h":33}}`, actual)
+
+	resp, _ = testRequest(t, "DELETE", addr+"/c", "")
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	resp, _ = testRequest(t, "PUT", addr+"/c", `{"x:c":{"d":"D2"}}`, "Content-Type", rfc)
+	fc.AssertEqual(t, 201, resp.StatusCode)
+	fc.AssertEqual(t, "/restconf/data/x:a/c", resp.Header.Get("Location"))
+
+	// content has to match URL
+	resp, _ = testRequest(t, "PUT", addr+"/c/e=four", `{"x:e":[{"f":"five"}]}`, "Content-Type", rfc)
+	fc.AssertEqual(t, 400, resp.StatusCode)
+	resp, _ = testRequest(t, "GET", addr+"/c/e=five", "", "Accept", rfc)
+	fc.AssertEqual(t, 404, resp.StatusCode)
+
+	// nothing to match
+	resp, _ = testRequest(t, "PUT", addr+"/c/e=four", `{"x:e":[{"f":"four"}]}`, "Content-Type", rfc, "If-Match", "*")
+	fc.AssertEqual(t, 412, resp.StatusCode)
+	resp, _ = testRequest(t, "PUT", addr+"/c/e=four", `{"x:e":[{"f":"four"}]}`, "Content-Type", rfc, "If-None-Match", "*")
+	fc.AssertEqual(t, 201, resp.StatusCode)
+}
+
+func TestYangPatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType MimeType
+		patch       string
+		status      int
+	}{
+		{
+			name:        "multi-edit",
+			contentType: YangPatchJsonMimeType,
+			status:      200,
+			patch: `{"ietf-yang-patch:yang-patch":{
+				"patch-id": "multi",
+				"edit": [{
+					"edit-id": "1",
+					"operation": "create",
+					"target": "/c/e=three",
+					"value": {"x:e":[{"f":"three","g":{"h":3}}]}
+				},{
+					"edit-id": "2",
+					"operation": "merge",
+					"target": "/b",
+					"value": {"x:b":"B2"}
+				},{
+					"edit-id": "3",
+					"operation": "replace",
+					"target": "/c/e=one",
+					"value": {"x:e":[{"f":"one","g":{"h":11}}]}
+				},{
+					"edit-id": "4",
+					"operation": "delete",
+					"target": "/c/e=two"
+				},{
+					"edit-id": "5",
+					"operation": "remove",
+					"target": "/c/d"
+				}]
+			}}`,
+		},
+		{
+			name:        "multi-edit-xml",
+			contentType: YangPatchXmlMimeType,
+			status:      200,
+			patch: `<yang-patch xmlns="urn:ietf:params:xml:ns:yang:ietf-yang-patch">
+				<patch-id>multi</patch-id>
+				<edit>
+					<edit-id>1</edit-id>
+					<operation>create</operation>
+					<target>/c/e=three</target>
+					<value><e xmlns="x"><f>three</f><g><h>3</h></g></e></value>
+				</edit>
+				<edit>
+					<edit-id>2</edit-id>
+					<operation>merge</operation>
+					<target>/b</target>
+					<value><b xmlns="x">B2</b></value>
+				</edit>
+			</yang-patch>`,
+		},
+		{
+			name:        "partial-failure",
+			contentType: YangPatchJsonMimeType,
+			status:      409,
+			patch: `{"ietf-yang-patch:yang-patch":{
+				"patch-id": "partial",
+				"edit": [{
+					"edit-id": "1",
+					"operation": "merge",
+					"target": "/b",
+					"value": {"x:b":"B2"}
+				},{
+					"edit-id": "2",
+					"operation": "delete",
+					"target": "/c/e=two"
+				},{
+					"edit-id": "3",
+					"operation": "create",
+					"target": "/c/e=one",
+					"value": {"x:e":[{"f":"one"}]}
+				}]
+			}}`,
+		},
+		{
+			name:        "partial-failure-xml",
+			contentType: YangPatchXmlMimeType,
+			status:      409,
+			patch: `<yang-patch xmlns="urn:ietf:params:xml:ns:yang:ietf-yang-patch">
+				<patch-id>partial</patch-id>
+				<edit>
+					<edit-id>1</edit-id>
+					<operation>merge</operation>
+					<target>/b</target>
+					<value><b xmlns="x">B2</b></value>
+				</edit>
+				<edit>
+					<edit-id>2</edit-id>
+					<operation>delete</operation>
+					<target>/c/e=two</target>
+				</edit>
+				<edit>
+					<edit-id>3</edit-id>
+					<operation>create</operation>
+					<target>/c/e=one</target>
+					<value><e xmlns="x"><f>one</f></e></value>
+				</edit>
+				<edit>
+					<edit-id>4</edit-id>
+					<operation>remove</operation>
+					<target>/c/d</target>
+				</edit>
+			</yang-patch>`,
+		},
+	}
+	for _, test := range tests {
+		_, ts := newTestServer(t, nestedYang, nestedData)
+		accept := YangDataJsonMimeType1
+		if test.contentType.IsXml() {
+			accept = YangDataXmlMimeType1
+		}
+		resp, actual := testRequest(t, "PATCH", ts.URL+"/restconf/data/x:a", test.patch,
+			"Content-Type", string(test.contentType), "Accept", string(accept))
+		fc.AssertEqual(t, test.status, resp.StatusCode, test.name, actual)
+		ext := ".json"
+		if accept.IsXml() {
+			ext = ".xml"
+		}
+		fc.Gold(t, *updateFlag, []byte(actual), "testdata/gold/yang-patch/"+test.name+"-status"+ext)
+
+		// failed patches leave data unchanged
+		_, actual = testRequest(t, "GET", ts.URL+"/restconf/data/x:a", "", "Accept", string(YangDataJsonMimeType1))
+		fc.Gold(t, *updateFlag, []byte(actual), "testdata/gold/yang-patch/"+test.name+"-data.json")
+		ts.Close()
+	}
+}
+
+func TestAcceptedMimeType(t *testing.T) {
+	tests := []struct {
+		accept   string
+		expected MimeType
+	}{
+		{accept: "application/yang-data+json", expected: YangDataJsonMimeType1},
+		{accept: "application/yang-data+json, application/yang-data+xml;q=0.9", expected: YangDataJsonMimeType1},
+		{accept: "application/yang-data+json;q=0.5, application/yang-data+xml", expected: YangDataXmlMimeType1},
+		{accept: "text/event-stream; charset=utf-8", expected: TextStreamMimeType},
+		{accept: "application/json, application/yang-data+xml", expected: PlainJsonMimeType},
+		{accept: "application/json, application/yang-data+json", expected: YangDataJsonMimeType1},
+		{accept: "application/xml, application/yang-data+xml", expected: YangDataXmlMimeType1},
+		{accept: "application/json;q=0.9, application/yang-data+json;q=0.5", expected: PlainJsonMimeType},
+		{accept: "application/yang-data+xml;q=0, application/yang-data+json;q=0.1", expected: YangDataJsonMimeType1},
+		{accept: "application/yang-data+xml;q=0, text/html", expected: "text/html"},
+		{accept: "application/yang-data+xml;q=0", expected: unacceptableMimeType},
+		{accept: "", expected: ""},
+	}
+	for _, test := range tests {
+		fc.AssertEqual(t, test.expected, acceptedMimeType(test.accept), test.accept)
+	}
+}
+
+func TestNotAcceptable(t *testing.T) {
+	_, ts := newTestServer(t, nestedYang, nestedData)
+	defer ts.Close()
+	url := ts.URL + "/restconf/data/x:a/b"
+	tests := []struct {
+		accept string
+		status int
+	}{
+		{accept: "application/yang-data+xml;q=0, text/html", status: 406},
+		{accept: "application/yang-data+xml;q=0", status: 406},
+		{accept: "text/html", status: 406},
+		{accept: "application/yang-data+xml;q=0, */*", status: 200},
+		{accept: "application/*", status: 200},
+	}
+	for _, test := range tests {
+		resp, actual := testRequest(t, "GET", url, "", "Accept", test.accept)
+		fc.AssertEqual(t, test.status, resp.StatusCode, test.accept)
+		if test.status == 406 {
+			fc.AssertEqual(t, true, strings.Contains(actual, "not acceptable"), actual)
+		}
+	}
+}
+
+func TestGenericMimeTypes(t *testing.T) {
+	_, ts := newTestServer(t, nestedYang, nestedData)
+	defer ts.Close()
+	url := ts.URL + "/restconf/data/x:a/c/e=one/g"
+
+	resp, actual := testRequest(t, "GET", url, "", "Accept", string(PlainJsonMimeType))
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	fc.AssertEqual(t, string(PlainJsonMimeType), resp.Header.Get("Content-Type"))
+	var data map[string]interface{}
+	fc.AssertEqual(t, nil, json.Unmarshal([]byte(actual), &data))
+	fc.AssertEqual(t, `{"h":1}`, actual)
+
+	resp, actual = testRequest(t, "GET", url, "", "Accept", string(PlainXmlMimeType))
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	fc.AssertEqual(t, string(PlainXmlMimeType), resp.Header.Get("Content-Type"))
+	fc.AssertEqual(t, `<g xmlns="x"><h>1</h></g>`, actual)
+
+	resp, _ = testRequest(t, "PATCH", url, `<h>10</h>`, "Content-Type", string(PlainXmlMimeType))
+	fc.AssertEqual(t, 200, resp.StatusCode)
+
+	// yang-data type is still preferred
+	resp, actual = testRequest(t, "GET", url, "", "Accept", "application/json, application/yang-data+json")
+	fc.AssertEqual(t, string(YangDataJsonMimeType1), resp.Header.Get("Content-Type"))
+	fc.AssertEqual(t, `{"h":10}`, actual)
+}
+
+func TestListKeyTypes(t *testing.T) {
+	mstr := `module x {
+		namespace "x";
+		prefix "x";
+		revision 0;
+		list l {
+			key "id";
+			leaf id {
+				type int32;
+			}
+			list e {
+				key "color";
+				leaf color {
+					type enumeration {
+						enum red;
+						enum blue;
+					}
+				}
+			}
+		}
+	}`
+	_, ts := newTestServer(t, mstr, `{"l":[{"id":1,"e":[{"color":"red"}]}]}`)
+	defer ts.Close()
+	url := ts.URL + "/restconf/data/x:l="
+	tests := []struct {
+		path     string
+		errPath  string
+		expected string
+	}{
+		{path: "1/e=red", expected: `{"color":"red"}`},
+		{path: "abc", errPath: "x:l=abc"},
+		{path: "99999999999/e=red", errPath: "x:l=99999999999"},
+		{path: "1/e=green", errPath: "x:l=1/e=green"},
+		{path: "1/e=gr%20een/color", errPath: "x:l=1/e=gr een"},
+	}
+	for _, test := range tests {
+		resp, actual := testRequest(t, "GET", url+test.path, "", "Accept", string(YangDataJsonMimeType1))
+		if test.errPath == "" {
+			fc.AssertEqual(t, 200, resp.StatusCode, test.path)
+			fc.AssertEqual(t, test.expected, actual)
+			continue
+		}
+		fc.AssertEqual(t, 400, resp.StatusCode, test.path)
+		var errs struct {
+			Errors struct {
+				Error []Error `json:"error"`
+			} `json:"ietf-restconf:errors"`
+		}
+		fc.RequireEqual(t, nil, json.Unmarshal([]byte(actual), &errs))
+		fc.RequireEqual(t, 1, len(errs.Errors.Error))
+		fc.AssertEqual(t, "invalid-value", errs.Errors.Error[0].Tag)
+		fc.AssertEqual(t, test.errPath, errs.Errors.Error[0].Path)
+	}
+}
+
+func TestLeafListEdit(t *testing.T) {
+	mstr := `module x {
+		namespace "x";
+		prefix "x";
+		revision 0;
+		container c {
+			leaf-list tags {
+				type string;
+			}
+			leaf-list ports {
+				type uint16;
+			}
+			leaf other {
+				type string;
+			}
+		}
+	}`
+	_, ts := newTestServer(t, mstr, `{"c":{"tags":["a","b c","d/e"],"other":"o"}}`)
+	defer ts.Close()
+	url := ts.URL + "/restconf/data/x:c"
+	get := func() string {
+		_, actual := testRequest(t, "GET", url, "")
+		return actual
+	}
+
+	t.Run("patch adds", func(t *testing.T) {
+		resp, _ := testRequest(t, "PATCH", url, `{"x:c":{"tags":["b c","f"]}}`)
+		fc.AssertEqual(t, 200, resp.StatusCode)
+		fc.AssertEqual(t, `{"tags":["a","b c","d/e","f"],"other":"o"}`, get())
+
+		resp, _ = testRequest(t, "PATCH", url+"/tags", `<tags xmlns="x">g</tags>`, "Content-Type", string(YangDataXmlMimeType1))
+		fc.AssertEqual(t, 200, resp.StatusCode)
+		fc.AssertEqual(t, `{"tags":["a","b c","d/e","f","g"],"other":"o"}`, get())
+	})
+
+	t.Run("delete entry", func(t *testing.T) {
+		resp, _ := testRequest(t, "DELETE", url+"/tags=b%20c", "")
+		fc.AssertEqual(t, 200, resp.StatusCode)
+		resp, _ = testRequest(t, "DELETE", url+"/tags=d%2Fe", "")
+		fc.AssertEqual(t, 200, resp.StatusCode)
+		fc.AssertEqual(t, `{"tags":["a","f","g"],"other":"o"}`, get())
+
+		resp, _ = testRequest(t, "DELETE", url+"/tags=b%20c", "")
+		fc.AssertEqual(t, 404, resp.StatusCode)
+		resp, _ = testRequest(t, "DELETE", url+"/ports=http", "")
+		fc.AssertEqual(t, 400, resp.StatusCode)
+		resp, _ = testRequest(t, "GET", url+"/tags=a", "")
+		fc.AssertEqual(t, 405, resp.StatusCode)
+	})
+
+	t.Run("put replaces", func(t *testing.T) {
+		resp, _ := testRequest(t, "PUT", url+"/tags", `{"x:tags":["q"]}`)
+		fc.AssertEqual(t, 204, resp.StatusCode)
+		fc.AssertEqual(t, `{"tags":["q"],"other":"o"}`, get())
+
+		resp, _ = testRequest(t, "PUT", url, `{"x:c":{"tags":["r","s"]}}`)
+		fc.AssertEqual(t, 204, resp.StatusCode)
+		fc.AssertEqual(t, `{"tags":["r","s"]}`, get())
+	})
+
+	t.Run("delete last entry", func(t *testing.T) {
+		testRequest(t, "DELETE", url+"/tags=r", "")
+		resp, _ := testRequest(t, "DELETE", url+"/tags=s", "")
+		fc.AssertEqual(t, 200, resp.StatusCode)
+		fc.AssertEqual(t, `{}`, get())
+	})
+}
+
+func TestEmptyContent(t *testing.T) {
+	mstr := `module x {
+		namespace "x";
+		prefix "x";
+		revision 0;
+		container a {
+			leaf b {
+				type string;
+			}
+			container p {
+				presence "enabled";
+				leaf q {
+					type string;
+				}
+			}
+			container n {
+				leaf q {
+					type string;
+				}
+			}
+		}
+	}`
+	_, ts := newTestServer(t, mstr, `{"a":{"b":"B"}}`)
+	defer ts.Close()
+	url := ts.URL + "/restconf/data/x:a"
+	get := func() string {
+		_, actual := testRequest(t, "GET", url, "", "Accept", string(YangDataJsonMimeType))
+		return actual
+	}
+
+	resp, _ := testRequest(t, "POST", url+"/p", "")
+	fc.AssertEqual(t, 201, resp.StatusCode)
+	fc.AssertEqual(t, "/restconf/data/x:a/p", resp.Header.Get("Location"))
+	fc.AssertEqual(t, `{"b":"B","p":{}}`, get())
+	resp, _ = testRequest(t, "POST", url+"/p", "")
+	fc.AssertEqual(t, 409, resp.StatusCode)
+
+	resp, _ = testRequest(t, "DELETE", url+"/p", "")
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	resp, _ = testRequest(t, "PUT", url+"/p", "")
+	fc.AssertEqual(t, 201, resp.StatusCode)
+	fc.AssertEqual(t, `{"b":"B","p":{}}`, get())
+	resp, _ = testRequest(t, "PUT", url+"/p", "")
+	fc.AssertEqual(t, 204, resp.StatusCode)
+
+	for _, method := range []string{"POST", "PUT"} {
+		for _, path := range []string{"/n", "/b", ""} {
+			resp, actual := testRequest(t, method, url+path, "")
+			fc.AssertEqual(t, 400, resp.StatusCode, method, path)
+			fc.AssertEqual(t, true, strings.Contains(actual, "content is required"), actual)
+		}
+	}
+	fc.AssertEqual(t, `{"b":"B","p":{}}`, get())
+}
+
+func TestKeylessList(t *testing.T) {
+	_, ts := newTestServer(t, nestedYang, `{"a":{"c":{"e":[{"f":"one"},{"f":"two"},{"f":"three"}]}}}`)
+	defer ts.Close()
+	json := string(YangDataJsonMimeType)
+	url := ts.URL + "/restconf/data/x:a/c/e"
+
+	resp, actual := testRequest(t, "GET", url, "", "Accept", json)
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	fc.AssertEqual(t, `{"e":[{"f":"one"},{"f":"two"},{"f":"three"}]}`, actual)
+	_, actual = testRequest(t, "GET", url+"=two", "", "Accept", json)
+	fc.AssertEqual(t, `{"f":"two"}`, actual)
+
+	// content of whole list replaces every entry
+	resp, _ = testRequest(t, "PUT", url, `{"x:e":[{"f":"x"},{"f":"y"}]}`, "Content-Type", json)
+	fc.AssertEqual(t, 204, resp.StatusCode)
+	_, actual = testRequest(t, "GET", url, "", "Accept", json)
+	fc.AssertEqual(t, `{"e":[{"f":"x"},{"f":"y"}]}`, actual)
+	resp, actual = testRequest(t, "PUT", url, `{"x:e":{"f":"z"}}`, "Content-Type", json)
+	fc.AssertEqual(t, 400, resp.StatusCode, actual)
+	_, actual = testRequest(t, "GET", url, "", "Accept", json)
+	fc.AssertEqual(t, `{"e":[{"f":"x"},{"f":"y"}]}`, actual)
+
+	resp, _ = testRequest(t, "DELETE", url, "")
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	resp, _ = testRequest(t, "GET", url, "", "Accept", json)
+	fc.AssertEqual(t, 404, resp.StatusCode)
+	resp, _ = testRequest(t, "GET", url+"=x", "", "Accept", json)
+	fc.AssertEqual(t, 404, resp.StatusCode)
+}
+
+func TestWithDefaultsTaggedEmptyList(t *testing.T) {
+	// empty list is not a leaf-list even though nothing in it says so
+	_, ts := newTestServer(t, defaultsYang, `{"a":{"e":["E1"],"f":[]}}`)
+	defer ts.Close()
+	resp, actual := testRequest(t, "GET", ts.URL+"/restconf/data/x:a?with-defaults=report-all-tagged", "",
+		"Accept", string(YangDataJsonMimeType1))
+	fc.AssertEqual(t, 200, resp.StatusCode, actual)
+	tag := `{"ietf-netconf-with-defaults:default":true}`
+	fc.AssertEqual(t, `{"b":10,"@b":`+tag+`,"c":"C","@c":`+tag+`,"e":["E1"],"i":["I1","I2"],"@i":[`+tag+`,`+tag+`],"f":[]}`, actual)
+}
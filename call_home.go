@@ -0,0 +1,149 @@
+package restconf
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/freeconf/yang/fc"
+)
+
+// CallHome is the controller server connects to. Unlike the registration in
+// package callhome, server opens the connection and then serves RESTCONF to
+// the controller over it. RFC8071
+type CallHome struct {
+	// host:port of controller, RFC8071 assigns port 4336
+	Address string
+
+	// Optional: server side of TLS, controller is the TLS client. Plain TCP
+	// when nil which is only useful for testing
+	TLS *tls.Config
+
+	// Wait before reconnecting after connection fails or is closed, doubles
+	// on each failed attempt up to RetryMax. Defaults are 1s and 1m
+	RetryMin time.Duration
+	RetryMax time.Duration
+
+	// Optional: TCP keepalive period, negative disables. Default is 15s
+	Keepalive time.Duration
+}
+
+func (c CallHome) retryMin() time.Duration {
+	if c.RetryMin == 0 {
+		return time.Second
+	}
+	return c.RetryMin
+}
+
+func (c CallHome) retryMax() time.Duration {
+	if c.RetryMax == 0 {
+		return time.Minute
+	}
+	return c.RetryMax
+}
+
+// CallHome keeps a connection to controller open and serves requests over it
+// until ctx is done.
+func (srv *Server) CallHome(ctx context.Context, c CallHome) error {
+	dialer := &net.Dialer{KeepAlive: c.Keepalive}
+	wait := c.retryMin()
+	for {
+		conn, err := dialer.DialContext(ctx, "tcp", c.Address)
+		if err == nil {
+			fc.Debug.Printf("call home connected to %s", c.Address)
+			wait = c.retryMin()
+			err = srv.serveCallHome(ctx, conn, c.TLS)
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			fc.Err.Printf("call home to %s. %s", c.Address, err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(wait):
+		}
+		if wait *= 2; wait > c.retryMax() {
+			wait = c.retryMax()
+		}
+	}
+}
+
+// serveCallHome serves RESTCONF on conn until controller or ctx closes it
+func (srv *Server) serveCallHome(ctx context.Context, conn net.Conn, config *tls.Config) error {
+	l := newConnListener(conn, config)
+	web := &http.Server{Handler: srv}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			web.Close()
+		case <-done:
+		}
+	}()
+	if err := web.Serve(l); !errors.Is(err, net.ErrClosed) && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// connListener accepts a single connection. Once that connection is closed,
+// Accept fails so http.Server stops serving. TLS is on top of the connection
+// so http.Server still sees a *tls.Conn and fills in Request.TLS
+type connListener struct {
+	conn   net.Conn
+	once   sync.Once
+	accept chan net.Conn
+	closed chan struct{}
+}
+
+func newConnListener(conn net.Conn, config *tls.Config) *connListener {
+	l := &connListener{
+		conn:   conn,
+		accept: make(chan net.Conn, 1),
+		closed: make(chan struct{}),
+	}
+	var accepted net.Conn = &listenerConn{Conn: conn, l: l}
+	if config != nil {
+		accepted = tls.Server(accepted, config)
+	}
+	l.accept <- accepted
+	return l
+}
+
+func (l *connListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.accept:
+		return conn, nil
+	case <-l.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *connListener) Close() error {
+	l.once.Do(func() {
+		close(l.closed)
+	})
+	return nil
+}
+
+func (l *connListener) Addr() net.Addr {
+	return l.conn.LocalAddr()
+}
+
+type listenerConn struct {
+	net.Conn
+	l *connListener
+}
+
+func (c *listenerConn) Close() error {
+	c.l.Close()
+	return c.Conn.Close()
+}
@@ -0,0 +1,98 @@
+package restconf
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/freeconf/yang/fc"
+)
+
+// controllerGet issues a GET to device over connection device opened
+func controllerGet(t *testing.T, conn net.Conn, path string) (int, string) {
+	t.Helper()
+	c := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return conn, nil
+			},
+		},
+	}
+	req, err := http.NewRequest("GET", "http://device"+path, nil)
+	fc.RequireEqual(t, nil, err)
+	req.Header.Set("Accept", string(YangDataJsonMimeType1))
+	resp, err := c.Do(req)
+	fc.RequireEqual(t, nil, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	fc.RequireEqual(t, nil, err)
+	return resp.StatusCode, string(body)
+}
+
+func TestCallHome(t *testing.T) {
+	s, ts := newTestServer(t, nestedYang, nestedData)
+	defer ts.Close()
+	controller, err := net.Listen("tcp", "127.0.0.1:0")
+	fc.RequireEqual(t, nil, err)
+	defer controller.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- s.CallHome(ctx, CallHome{
+			Address:  controller.Addr().String(),
+			RetryMin: 10 * time.Millisecond,
+		})
+	}()
+
+	conn, err := controller.Accept()
+	fc.RequireEqual(t, nil, err)
+	status, actual := controllerGet(t, conn, "/restconf/data/x:a/b")
+	fc.AssertEqual(t, 200, status)
+	fc.AssertEqual(t, `{"b":"B"}`, actual)
+
+	// device calls back after controller drops connection
+	conn.Close()
+	conn, err = controller.Accept()
+	fc.RequireEqual(t, nil, err)
+	status, _ = controllerGet(t, conn, "/restconf/data/x:a/b")
+	fc.AssertEqual(t, 200, status)
+
+	cancel()
+	fc.AssertEqual(t, nil, <-done)
+	conn.Close()
+}
+
+func TestCallHomeTls(t *testing.T) {
+	s, ts := newTestServer(t, nestedYang, nestedData)
+	defer ts.Close()
+	s.ClientCertAuth = true
+	controller, err := net.Listen("tcp", "127.0.0.1:0")
+	fc.RequireEqual(t, nil, err)
+	defer controller.Close()
+
+	ca := testCert(t, "ca", nil)
+	pool := x509CertPool(ca)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.CallHome(ctx, CallHome{
+		Address: controller.Addr().String(),
+		TLS:     MutualTLS(&tls.Config{Certificates: []tls.Certificate{testCert(t, "device", &ca)}}, pool),
+	})
+
+	conn, err := controller.Accept()
+	fc.RequireEqual(t, nil, err)
+	// controller is TLS client. RFC8071 Sec. 4.1
+	tlsConn := tls.Client(conn, &tls.Config{
+		Certificates:       []tls.Certificate{testCert(t, "controller", &ca)},
+		InsecureSkipVerify: true,
+	})
+	defer tlsConn.Close()
+	status, actual := controllerGet(t, tlsConn, "/restconf/data/x:a/b")
+	fc.AssertEqual(t, 200, status)
+	fc.AssertEqual(t, `{"b":"B"}`, actual)
+}
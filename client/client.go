@@ -30,8 +30,24 @@ import (
 type Client struct {
 	YangPath  source.Opener
 	Complance restconf.ComplianceOptions
+
+	// Optional: EncodingJson (default) or EncodingXml. Responses are decoded
+	// using the content type server sends regardless of this preference.
+	PreferredEncoding string
+
+	// Optional: Ask server to compress responses and compress large request
+	// content with gzip
+	Compression bool
+
+	// Optional: Retry idempotent requests and reconnect event streams
+	Retry *RetryPolicy
 }
 
+const (
+	EncodingJson = "json"
+	EncodingXml  = "xml"
+)
+
 func ProtocolHandler(ypath source.Opener) device.ProtocolHandler {
 	c := Client{YangPath: ypath}
 	return c.NewDevice
@@ -76,6 +92,21 @@ func (factory Client) NewDevice(url string) (device.Device, error) {
 	if err != nil {
 		return nil, err
 	}
+	c, remoteSchemaPath := factory.newClient(address)
+	d := &clientNode{support: c, device: address.DeviceId, compliance: c.compliance}
+	m := parser.RequireModule(factory.YangPath, "ietf-yang-library")
+	b := node.NewBrowser(m, d.node())
+	modules, err := device.LoadModules(b, remoteSchemaPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not load modules. %s", err)
+	}
+	fc.Debug.Printf("loaded modules %v", modules)
+	c.modules = modules
+	return c, nil
+}
+
+// newClient is client w/o any modules loaded, modules are loaded as needed
+func (factory Client) newClient(address Address) (*client, httpStream) {
 	httpClient := &http.Client{
 		Transport: &http.Transport{
 			TLSClientConfig: &tls.Config{
@@ -89,31 +120,29 @@ func (factory Client) NewDevice(url string) (device.Device, error) {
 		url:    address.Schema,
 	}
 	c := &client{
-		address:    address,
-		yangPath:   factory.YangPath,
-		schemaPath: source.Any(factory.YangPath, remoteSchemaPath.OpenStream),
-		client:     httpClient,
-		compliance: factory.Complance,
-	}
-	d := &clientNode{support: c, device: address.DeviceId, compliance: c.compliance}
-	m := parser.RequireModule(factory.YangPath, "ietf-yang-library")
-	b := node.NewBrowser(m, d.node())
-	modules, err := device.LoadModules(b, remoteSchemaPath)
-	if err != nil {
-		return nil, fmt.Errorf("could not load modules. %s", err)
+		address:     address,
+		yangPath:    factory.YangPath,
+		schemaPath:  source.Any(factory.YangPath, remoteSchemaPath.OpenStream),
+		client:      httpClient,
+		compliance:  factory.Complance,
+		encoding:    factory.PreferredEncoding,
+		compression: factory.Compression,
+		retry:       factory.Retry,
+		modules:     make(map[string]*meta.Module),
 	}
-	fc.Debug.Printf("loaded modules %v", modules)
-	c.modules = modules
-	return c, nil
+	return c, remoteSchemaPath
 }
 
 type client struct {
-	address    Address
-	yangPath   source.Opener
-	schemaPath source.Opener
-	client     *http.Client
-	modules    map[string]*meta.Module
-	compliance restconf.ComplianceOptions
+	address     Address
+	yangPath    source.Opener
+	schemaPath  source.Opener
+	client      *http.Client
+	modules     map[string]*meta.Module
+	compliance  restconf.ComplianceOptions
+	encoding    string
+	compression bool
+	retry       *RetryPolicy
 }
 
 func (c *client) SchemaSource() source.Opener {
@@ -165,7 +194,40 @@ type streamEvent struct {
 func (c *client) clientStream(params string, p *node.Path, ctx context.Context) (<-chan streamEvent, error) {
 	mod := meta.RootModule(p.Meta)
 	fullUrl := fmt.Sprint(c.address.Data, mod.Ident(), ":", p.StringNoModule())
-	req, err := http.NewRequest("GET", fullUrl, nil)
+	if params != "" {
+		fullUrl = fmt.Sprint(fullUrl, "?", params)
+	}
+	if _, err := c.streamRequest(ctx, fullUrl); err != nil {
+		return nil, err
+	}
+	fc.Debug.Printf("<=> SSE %s", fullUrl)
+	stream := make(chan streamEvent)
+	go func() {
+		defer close(stream)
+		for attempt := 1; ; attempt++ {
+			connected, status, after, err := c.receiveStream(ctx, fullUrl, stream)
+			if ctx.Err() != nil {
+				return
+			}
+			if connected {
+				attempt = 1
+			}
+			if !c.retry.allows(attempt, status, err) {
+				if err != errStreamEnded {
+					sendEvent(ctx, stream, errorEvent(err))
+				}
+				return
+			}
+			fc.Debug.Printf("reconnecting SSE %s after attempt %d. %s", fullUrl, attempt, err)
+			c.retry.wait(ctx, attempt, after)
+		}
+	}()
+
+	return stream, nil
+}
+
+func (c *client) streamRequest(ctx context.Context, fullUrl string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", fullUrl, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -175,83 +237,151 @@ func (c *client) clientStream(params string, p *node.Path, ctx context.Context)
 		req.URL.RawQuery = q.Encode()
 	}
 	req.Header.Set("Accept", string(restconf.TextStreamMimeType))
-	fc.Debug.Printf("<=> SSE %s", fullUrl)
-	stream := make(chan streamEvent)
-	go func() {
-		resp, err := c.client.Do(req)
-		if err != nil {
-			stream <- streamEvent{
-				Timestamp: time.Now(),
-				Node:      node.ErrorNode{Err: err},
+	return req, nil
+}
+
+// receiveStream sends events to stream until server ends the stream, the
+// connection fails or ctx is done. connected is true if server accepted the
+// request, otherwise after is how long server asked to wait before trying
+// again.
+func (c *client) receiveStream(ctx context.Context, fullUrl string, stream chan<- streamEvent) (connected bool, status int, after time.Duration, err error) {
+	req, err := c.streamRequest(ctx, fullUrl)
+	if err != nil {
+		return false, 0, 0, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return false, 0, 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		err = fmt.Errorf("stream request failed. status %d", resp.StatusCode)
+		return false, resp.StatusCode, c.retry.retryAfter(resp), err
+	}
+	events := decodeSse(resp.Body)
+	for {
+		select {
+		case event, open := <-events:
+			if !open {
+				return true, 0, 0, errStreamEnded
 			}
-			return
+			if !sendEvent(ctx, stream, c.decodeEvent(event)) {
+				return true, 0, 0, ctx.Err()
+			}
+		case <-ctx.Done():
+			return true, 0, 0, ctx.Err()
 		}
-		events := decodeSse(resp.Body)
-		defer resp.Body.Close()
-		for {
-			select {
-			case event := <-events:
-				var e streamEvent
-				var vals map[string]interface{}
-				err := json.Unmarshal(event, &vals)
-				if err == nil {
-					if !c.compliance.DisableNotificationWrapper {
-						payload, found := vals["ietf-restconf:notification"].(map[string]interface{})
-						if !found {
-							err = errors.New("SSE message missing ietf-restconf:notification wrapper")
-						} else {
-							body, found := payload["event"].(map[string]interface{})
-							if !found {
-								err = errors.New("SSE message missing event payload")
-							} else {
-								tstr, found := payload["eventTime"].(string)
-								if !found {
-									err = errors.New("SSE message missing eventTime")
-								} else {
-									var t time.Time
-									t, err = time.Parse(restconf.EventTimeFormat, tstr)
-									if err != nil {
-										err = fmt.Errorf("eventTime in wrong format '%s'", tstr)
-									} else {
-										n, err := nodeutil.ReadJSONValues(body)
-										if err != nil {
-											err = fmt.Errorf("could not parse event payload. %s", err)
-										} else {
-											e = streamEvent{
-												Timestamp: t,
-												Node:      n,
-											}
-										}
-									}
-								}
-							}
-						}
+	}
+}
+
+// sendEvent is false if ctx is done before event could be sent
+func sendEvent(ctx context.Context, stream chan<- streamEvent, e streamEvent) bool {
+	select {
+	case stream <- e:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func errorEvent(err error) streamEvent {
+	return streamEvent{
+		Node:      node.ErrorNode{Err: err},
+		Timestamp: time.Now(),
+	}
+}
+
+func (c *client) decodeEvent(event []byte) streamEvent {
+	if bytes.HasPrefix(bytes.TrimSpace(event), []byte("<")) {
+		return c.decodeXmlEvent(event)
+	}
+	var e streamEvent
+	var vals map[string]interface{}
+	err := json.Unmarshal(event, &vals)
+	if err == nil {
+		if !c.compliance.DisableNotificationWrapper {
+			payload, found := vals["ietf-restconf:notification"].(map[string]interface{})
+			if !found {
+				err = errors.New("SSE message missing ietf-restconf:notification wrapper")
+			} else {
+				body, found := payload["event"].(map[string]interface{})
+				if !found {
+					err = errors.New("SSE message missing event payload")
+				} else {
+					tstr, found := payload["eventTime"].(string)
+					if !found {
+						err = errors.New("SSE message missing eventTime")
 					} else {
-						n, err := nodeutil.ReadJSONIO(bytes.NewReader(event))
+						var t time.Time
+						t, err = time.Parse(restconf.EventTimeFormat, tstr)
 						if err != nil {
-							err = fmt.Errorf("could not parse event payload. %s", err)
+							err = fmt.Errorf("eventTime in wrong format '%s'", tstr)
 						} else {
-							e = streamEvent{
-								Node:      n,
-								Timestamp: time.Now(),
+							n, err := nodeutil.ReadJSONValues(body)
+							if err != nil {
+								err = fmt.Errorf("could not parse event payload. %s", err)
+							} else {
+								e = streamEvent{
+									Timestamp: t,
+									Node:      n,
+								}
 							}
 						}
 					}
 				}
-				if err != nil {
-					e = streamEvent{
-						Node:      node.ErrorNode{Err: err},
-						Timestamp: time.Now(),
-					}
+			}
+		} else {
+			n, err := nodeutil.ReadJSONIO(bytes.NewReader(event))
+			if err != nil {
+				err = fmt.Errorf("could not parse event payload. %s", err)
+			} else {
+				e = streamEvent{
+					Node:      n,
+					Timestamp: time.Now(),
 				}
-				stream <- e
-			case <-ctx.Done():
-				return
 			}
 		}
-	}()
+	}
+	if err != nil {
+		e = errorEvent(err)
+	}
+	return e
+}
 
-	return stream, nil
+// decodeXmlEvent reads events from streams server sends in XML. RFC8040
+// Sec. 6.4
+func (c *client) decodeXmlEvent(event []byte) streamEvent {
+	doc, err := nodeutil.ReadXMLDoc(bytes.NewReader(event))
+	if err != nil {
+		return errorEvent(fmt.Errorf("could not parse event payload. %s", err))
+	}
+	if c.compliance.DisableNotificationWrapper {
+		return streamEvent{
+			Node:      doc,
+			Timestamp: time.Now(),
+		}
+	}
+	var e streamEvent
+	for _, child := range doc.Nodes {
+		switch child.XMLName.Local {
+		case "eventTime":
+			tstr := string(child.Content)
+			if e.Timestamp, err = time.Parse(restconf.EventTimeFormat, tstr); err != nil {
+				return errorEvent(fmt.Errorf("eventTime in wrong format '%s'", tstr))
+			}
+		case "event":
+			if len(child.Nodes) > 0 {
+				e.Node = child.Nodes[0]
+			}
+		}
+	}
+	if e.Node == nil {
+		return errorEvent(errors.New("SSE message missing event payload"))
+	}
+	if e.Timestamp.IsZero() {
+		return errorEvent(errors.New("SSE message missing eventTime"))
+	}
+	return e
 }
 
 // ClientSchema downloads schema and implements yang.StreamSource so it can transparently
@@ -282,7 +412,6 @@ func (s httpStream) OpenStream(name string, ext string) (io.Reader, error) {
 }
 
 func (c *client) clientDo(method string, params string, p *node.Path, payload io.Reader) (io.ReadCloser, error) {
-	var req *http.Request
 	var err error
 	mod := meta.RootModule(p.Meta)
 	fullUrl := fmt.Sprint(c.address.Data, mod.Ident(), ":", p.StringNoModule())
@@ -296,27 +425,95 @@ func (c *client) clientDo(method string, params string, p *node.Path, payload io
 	if params != "" {
 		fullUrl = fmt.Sprint(fullUrl, "?", params)
 	}
-	if req, err = http.NewRequest(method, fullUrl, payload); err != nil {
-		return nil, err
+	compressed := false
+	if c.compression && payload != nil {
+		if payload, compressed, err = compressRequest(payload); err != nil {
+			return nil, err
+		}
 	}
-	if c.compliance == restconf.Simplified {
-		req.Header.Set("Content-Type", string(restconf.PlainJsonMimeType))
-		req.Header.Set("Accept", string(restconf.PlainJsonMimeType))
-	} else {
-		req.Header.Set("Content-Type", string(restconf.YangDataJsonMimeType1))
-		req.Header.Set("Accept", string(restconf.YangDataJsonMimeType1))
+	// content is kept so request can be sent again
+	var content []byte
+	if payload != nil {
+		if content, err = io.ReadAll(payload); err != nil {
+			return nil, err
+		}
 	}
-	fc.Debug.Printf("=> %s %s", method, fullUrl)
-	resp, err := c.client.Do(req)
+	var resp *http.Response
+	for attempt := 1; ; attempt++ {
+		var body io.Reader
+		if payload != nil {
+			body = bytes.NewReader(content)
+		}
+		req, err := http.NewRequest(method, fullUrl, body)
+		if err != nil {
+			return nil, err
+		}
+		if c.compression {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		if compressed {
+			req.Header.Set("Content-Encoding", "gzip")
+		}
+		if c.compliance == restconf.Simplified {
+			req.Header.Set("Content-Type", string(restconf.PlainJsonMimeType))
+		} else {
+			req.Header.Set("Content-Type", string(restconf.YangDataJsonMimeType))
+		}
+		req.Header.Set("Accept", c.accept())
+		fc.Debug.Printf("=> %s %s", method, fullUrl)
+		resp, err = c.client.Do(req)
+		status := 0
+		if resp != nil {
+			status = resp.StatusCode
+		}
+		if !isIdempotent(method) || !c.retry.allows(attempt, status, err) {
+			if err != nil {
+				return nil, err
+			}
+			break
+		}
+		fc.Debug.Printf("retrying %s %s after attempt %d. status=%d, err=%v", method, fullUrl, attempt, status, err)
+		after := c.retry.retryAfter(resp)
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		c.retry.wait(context.Background(), attempt, after)
+	}
+	body, err := decompressResponse(resp)
 	if err != nil {
+		resp.Body.Close()
 		return nil, err
 	}
 	if resp.StatusCode >= 300 {
-		msg, _ := ioutil.ReadAll(resp.Body)
+		msg, _ := ioutil.ReadAll(body)
 		return nil, fmt.Errorf("(%d) %s", resp.StatusCode, string(msg))
 	}
 	if resp.Body == nil || resp.ContentLength == 0 {
 		return nil, nil
 	}
-	return resp.Body, nil
+	return &response{
+		ReadCloser:  body,
+		contentType: restconf.MimeType(resp.Header.Get("Content-Type")),
+	}, nil
+}
+
+// accept lists both encodings so servers that only support one still answer
+// but with the preferred encoding ranked first.
+func (c *client) accept() string {
+	jsonType := restconf.YangDataJsonMimeType
+	if c.compliance == restconf.Simplified {
+		jsonType = restconf.PlainJsonMimeType
+	}
+	if c.encoding == EncodingXml {
+		return fmt.Sprintf("%s, %s;q=0.9", restconf.YangDataXmlMimeType, jsonType)
+	}
+	return fmt.Sprintf("%s, %s;q=0.9", jsonType, restconf.YangDataXmlMimeType)
+}
+
+// response is the body of a response along with the content type so it can
+// be decoded with the right reader
+type response struct {
+	io.ReadCloser
+	contentType restconf.MimeType
 }
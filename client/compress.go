@@ -0,0 +1,65 @@
+package client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// compressMinSize is the smallest request body worth compressing
+const compressMinSize = 1024
+
+// acceptEncoding is sent when compression is enabled. Setting it ourselves
+// means responses are decompressed here and not by http.Transport
+const acceptEncoding = "gzip, deflate"
+
+// compressRequest gzips payload when it is large enough to be worth it
+func compressRequest(payload io.Reader) (io.Reader, bool, error) {
+	data, err := io.ReadAll(payload)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(data) < compressMinSize {
+		return bytes.NewReader(data), false, nil
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err = zw.Write(data); err != nil {
+		return nil, false, err
+	}
+	if err = zw.Close(); err != nil {
+		return nil, false, err
+	}
+	return &buf, true, nil
+}
+
+type decompressed struct {
+	io.Reader
+	io.Closer
+}
+
+// decompressResponse undoes the Content-Encoding of response so readers get
+// plain JSON or XML
+func decompressResponse(resp *http.Response) (io.ReadCloser, error) {
+	switch encoding := resp.Header.Get("Content-Encoding"); encoding {
+	case "", "identity":
+		return resp.Body, nil
+	case "gzip":
+		zr, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		return decompressed{Reader: zr, Closer: resp.Body}, nil
+	case "deflate":
+		zr, err := zlib.NewReader(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		return decompressed{Reader: zr, Closer: resp.Body}, nil
+	default:
+		return nil, fmt.Errorf("unsupported content encoding '%s'", encoding)
+	}
+}
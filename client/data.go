@@ -0,0 +1,197 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/freeconf/restconf"
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/val"
+)
+
+// Get reads data at url, a complete address to a data resource such as
+//
+//	http://server/restconf/data/car:engine?depth=1
+//
+// Data is read once so selection does not contact server again.
+func (factory Client) Get(url string) (*node.Selection, error) {
+	cn, target, err := factory.target(url)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := cn.support.clientDo("GET", cn.params, target.Path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil {
+		return nil, fmt.Errorf("%w. no data at %s", fc.NotFoundError, url)
+	}
+	data, err := responseNode(resp)
+	if err != nil {
+		return nil, err
+	}
+	return target.Split(data), nil
+}
+
+// Put replaces data at url, creating data if it does not exist.
+func (factory Client) Put(url string, data node.Node) error {
+	return factory.edit("PUT", url, data)
+}
+
+// Post creates data under url.  Data has the new resources, for example to
+// add the first entry to list "tire" post to ".../car:" with {"tire":[{...}]}
+func (factory Client) Post(url string, data node.Node) error {
+	return factory.edit("POST", url, data)
+}
+
+// Delete removes data at url
+func (factory Client) Delete(url string) error {
+	cn, target, err := factory.target(url)
+	if err != nil {
+		return err
+	}
+	_, err = cn.support.clientDo("DELETE", cn.params, target.Path, nil)
+	return err
+}
+
+// SubscribeOption changes what Subscribe asks server for
+type SubscribeOption func(*subscribeRequest)
+
+type subscribeRequest struct {
+	startTime time.Time
+}
+
+// StartTime asks server to first send events since t. Only servers that keep
+// a replay log of events will send past events. RFC8040 Sec. 4.8.7
+func StartTime(t time.Time) SubscribeOption {
+	return func(r *subscribeRequest) {
+		r.startTime = t
+	}
+}
+
+func (r subscribeRequest) params(params string) (string, error) {
+	if r.startTime.IsZero() {
+		return params, nil
+	}
+	q, err := url.ParseQuery(params)
+	if err != nil {
+		return "", err
+	}
+	q.Set("start-time", r.startTime.Format(restconf.EventTimeFormat))
+	return q.Encode(), nil
+}
+
+// Subscribe receives events of notification at url, a complete address to a
+// notification such as
+//
+//	http://server/restconf/data/car:update
+//
+// Each event is a selection of the notification with data read once. A stream
+// that fails is reconnected when Retry is set, otherwise the last event is a
+// selection that fails to read with the reason. Channel is closed when stream
+// ends or cancel is called.
+func (factory Client) Subscribe(url string, opts ...SubscribeOption) (<-chan *node.Selection, func(), error) {
+	cn, target, err := factory.target(url)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !meta.IsNotification(target.Meta()) {
+		return nil, nil, fmt.Errorf("%w. %s is not a notification", fc.BadRequestError, url)
+	}
+	var r subscribeRequest
+	for _, opt := range opts {
+		opt(&r)
+	}
+	params, err := r.params(cn.params)
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err := cn.support.clientStream(params, target.Path, ctx)
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+	selections := make(chan *node.Selection)
+	go func() {
+		defer close(selections)
+		for e := range events {
+			select {
+			case selections <- target.Split(e.Node):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return selections, cancel, nil
+}
+
+func (factory Client) edit(method string, url string, data node.Node) error {
+	cn, target, err := factory.target(url)
+	if err != nil {
+		return err
+	}
+	var payload bytes.Buffer
+	in := target.Split(data)
+	if target.InsideList {
+		// list entries are sent as a list of one RFC8040 Sec. 4.5
+		fmt.Fprintf(&payload, `{"%s":[`, target.Meta().Ident())
+	}
+	if err = in.InsertInto(jsonWtr(cn.compliance, &payload)); err != nil {
+		return err
+	}
+	if target.InsideList {
+		payload.WriteString("]}")
+	}
+	_, err = cn.support.clientDo(method, cn.params, target.Path, &payload)
+	return err
+}
+
+// target is selection at url with schema but no data so it can be used to
+// build requests for data that does not exist yet
+func (factory Client) target(url string) (*clientNode, *node.Selection, error) {
+	a, err := restconf.ParseAddress(url)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !strings.HasSuffix(a.Base, "/data/") {
+		return nil, nil, fmt.Errorf("%w. %s is not a data resource", fc.BadRequestError, url)
+	}
+	address, err := NewAddress(strings.TrimSuffix(a.Base, "data/"))
+	if err != nil {
+		return nil, nil, err
+	}
+	c, _ := factory.newClient(address)
+	m, err := c.module(a.Module)
+	if err != nil {
+		return nil, nil, err
+	}
+	target, err := node.NewBrowser(m, schemaNavigator()).Root().Find(a.Path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if target == nil {
+		return nil, nil, fmt.Errorf("%w. %s not in %s", fc.NotFoundError, a.Path, a.Module)
+	}
+	cn := &clientNode{support: c, params: a.RawQuery, device: address.DeviceId, compliance: c.compliance}
+	return cn, target, nil
+}
+
+// schemaNavigator finds any path in the schema w/o checking if there is data
+func schemaNavigator() node.Node {
+	n := &nodeutil.Basic{}
+	n.OnChild = func(r node.ChildRequest) (node.Node, error) {
+		return n, nil
+	}
+	n.OnNext = func(r node.ListRequest) (node.Node, []val.Value, error) {
+		return n, r.Key, nil
+	}
+	return n
+}
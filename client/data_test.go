@@ -0,0 +1,209 @@
+package client
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/freeconf/restconf"
+	"github.com/freeconf/restconf/device"
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+	"github.com/freeconf/yang/source"
+)
+
+type dataTestCar struct {
+	Name  string
+	Speed int
+}
+
+type dataTestData struct {
+	Garage struct {
+		Car []*dataTestCar
+	}
+}
+
+const dataTestYang = `module x {
+	namespace "x";
+	prefix "x";
+	revision 0;
+	container garage {
+		list car {
+			key "name";
+			leaf name {
+				type string;
+			}
+			leaf speed {
+				type int32;
+			}
+		}
+	}
+}`
+
+// newDataTestDevice is a local device w/module x along with yang path that
+// has module x
+func newDataTestDevice(t *testing.T, data *dataTestData) (*device.Local, source.Opener) {
+	t.Helper()
+	ypath := source.Any(source.Path("../yang"), func(name string, ext string) (io.Reader, error) {
+		if name == "x" {
+			return strings.NewReader(dataTestYang), nil
+		}
+		return nil, nil
+	})
+	m, err := parser.LoadModule(ypath, "x")
+	fc.RequireEqual(t, nil, err)
+	local := device.New(ypath)
+	local.AddBrowser(node.NewBrowser(m, &nodeutil.Node{Object: data}))
+	return local, ypath
+}
+
+func TestClientData(t *testing.T) {
+	var data dataTestData
+	local, ypath := newDataTestDevice(t, &data)
+	ts := httptest.NewServer(restconf.NewHttpServe(local))
+	defer ts.Close()
+	garage := ts.URL + "/restconf/data/x:garage"
+
+	for _, compliance := range []restconf.ComplianceOptions{restconf.Strict, restconf.Simplified} {
+		t.Log(compliance)
+		data.Garage.Car = nil
+		c := Client{YangPath: ypath, Complance: compliance}
+
+		// create
+		fc.RequireEqual(t, nil, c.Post(garage, readJSON(t, `{"car":[{"name":"beetle","speed":10}]}`)))
+
+		// read
+		sel, err := c.Get(garage + "/car=beetle")
+		fc.RequireEqual(t, nil, err)
+		actual, err := nodeutil.WriteJSON(sel)
+		fc.RequireEqual(t, nil, err)
+		fc.AssertEqual(t, `{"name":"beetle","speed":10}`, actual)
+
+		// update
+		fc.RequireEqual(t, nil, c.Put(garage+"/car=beetle", readJSON(t, `{"name":"beetle","speed":20}`)))
+		sel, err = c.Get(garage + "?depth=3")
+		fc.RequireEqual(t, nil, err)
+		actual, err = nodeutil.WriteJSON(sel)
+		fc.RequireEqual(t, nil, err)
+		fc.AssertEqual(t, `{"car":[{"name":"beetle","speed":20}]}`, actual)
+
+		// delete
+		fc.RequireEqual(t, nil, c.Delete(garage+"/car=beetle"))
+		_, err = c.Get(garage + "/car=beetle")
+		fc.AssertEqual(t, true, err != nil)
+
+		// not in schema
+		_, err = c.Get(garage + "/bogus")
+		fc.AssertEqual(t, true, err != nil)
+	}
+}
+
+func readJSON(t *testing.T, data string) node.Node {
+	t.Helper()
+	n, err := nodeutil.ReadJSON(data)
+	fc.RequireEqual(t, nil, err)
+	return n
+}
+
+// contentTypeRecorder remembers the content type server responded with and
+// optionally removes it
+type contentTypeRecorder struct {
+	http.ResponseWriter
+	omit        bool
+	contentType *string
+}
+
+func (w contentTypeRecorder) WriteHeader(status int) {
+	*w.contentType = w.Header().Get("Content-Type")
+	if w.omit {
+		// nil keeps go from sniffing the content type
+		w.Header()["Content-Type"] = nil
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w contentTypeRecorder) Write(data []byte) (int, error) {
+	if *w.contentType == "" {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func TestClientEncoding(t *testing.T) {
+	var data dataTestData
+	data.Garage.Car = []*dataTestCar{{Name: "beetle", Speed: 10}}
+	local, ypath := newDataTestDevice(t, &data)
+	s := restconf.NewHttpServe(local)
+	tests := []struct {
+		desc        string
+		encoding    string
+		forceXml    bool
+		omit        bool
+		contentType restconf.MimeType
+	}{
+		{desc: "prefer json", contentType: restconf.YangDataJsonMimeType1},
+		{desc: "prefer xml", encoding: EncodingXml, contentType: restconf.YangDataXmlMimeType1},
+		{desc: "always xml", forceXml: true, contentType: restconf.YangDataXmlMimeType1},
+		{desc: "no content type", forceXml: true, omit: true, contentType: restconf.YangDataXmlMimeType1},
+	}
+	for _, test := range tests {
+		var contentType string
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if test.forceXml {
+				r.Header.Set("Accept", string(restconf.YangDataXmlMimeType1))
+			}
+			s.ServeHTTP(contentTypeRecorder{ResponseWriter: w, omit: test.omit, contentType: &contentType}, r)
+		}))
+		c := Client{YangPath: ypath, Complance: restconf.Strict, PreferredEncoding: test.encoding}
+		sel, err := c.Get(ts.URL + "/restconf/data/x:garage")
+		fc.RequireEqual(t, nil, err, test.desc)
+		actual, err := nodeutil.WriteJSON(sel)
+		fc.RequireEqual(t, nil, err, test.desc)
+		fc.AssertEqual(t, `{"car":[{"name":"beetle","speed":10}]}`, actual, test.desc)
+		fc.AssertEqual(t, string(test.contentType), contentType, test.desc)
+		ts.Close()
+	}
+}
+
+func TestClientCompression(t *testing.T) {
+	var data dataTestData
+	local, ypath := newDataTestDevice(t, &data)
+	s := restconf.NewHttpServe(local)
+	s.Compression = true
+	var requestEncodings, responseEncodings []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestEncodings = append(requestEncodings, r.Header.Get("Content-Encoding"))
+		s.ServeHTTP(w, r)
+		responseEncodings = append(responseEncodings, w.Header().Get("Content-Encoding"))
+	}))
+	defer ts.Close()
+	garage := ts.URL + "/restconf/data/x:garage"
+
+	var cars []string
+	for i := 0; i < 50; i++ {
+		cars = append(cars, fmt.Sprintf(`{"name":"car%d","speed":%d}`, i, i))
+	}
+	for _, encoding := range []string{EncodingJson, EncodingXml} {
+		data.Garage.Car = nil
+		requestEncodings, responseEncodings = nil, nil
+		c := Client{YangPath: ypath, Complance: restconf.Strict, PreferredEncoding: encoding, Compression: true}
+		fc.RequireEqual(t, nil, c.Post(garage, readJSON(t, `{"car":[`+strings.Join(cars, ",")+`]}`)), encoding)
+		fc.AssertEqual(t, "gzip", requestEncodings[0], encoding)
+
+		// small requests are not worth compressing
+		fc.RequireEqual(t, nil, c.Put(garage+"/car=car0", readJSON(t, `{"name":"car0","speed":0}`)), encoding)
+		fc.AssertEqual(t, "", requestEncodings[1], encoding)
+
+		sel, err := c.Get(garage + "?depth=3")
+		fc.RequireEqual(t, nil, err, encoding)
+		fc.AssertEqual(t, "gzip", responseEncodings[2], encoding)
+		actual, err := nodeutil.WriteJSON(sel)
+		fc.RequireEqual(t, nil, err, encoding)
+		fc.AssertEqual(t, 50, strings.Count(actual, `"name"`), encoding)
+	}
+}
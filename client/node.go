@@ -26,6 +26,7 @@ type clientNode struct {
 	edit       node.Node
 	method     string
 	changes    node.Node
+	editing    bool
 	device     string
 	compliance restconf.ComplianceOptions
 }
@@ -49,12 +50,17 @@ func (cn *clientNode) node() node.Node {
 		} else {
 			cn.method = "PATCH"
 		}
+		cn.editing = true
 		return cn.startEditMode(r.Selection.Path)
 	}
 	n.OnRelease = func(*node.Selection) {
 		cn.read = nil
-		cn.edit = nil
-		cn.changes = nil
+		// releasing selections under edit root releases edit root too so
+		// changes are kept until they are sent
+		if !cn.editing {
+			cn.edit = nil
+			cn.changes = nil
+		}
 	}
 	n.OnChild = func(r node.ChildRequest) (node.Node, error) {
 		if r.IsNavigation() {
@@ -135,6 +141,7 @@ func (cn *clientNode) node() node.Node {
 		if !r.EditRoot {
 			return nil
 		}
+		cn.editing = false
 		if r.Delete {
 			return nil
 		}
@@ -195,17 +202,33 @@ func (cn *clientNode) get(p *node.Path, params string) (node.Node, error) {
 	if err != nil {
 		return nil, err
 	}
-	return jsonNode(resp)
+	return responseNode(resp)
 }
 
-func jsonNode(in io.ReadCloser) (node.Node, error) {
+// responseNode decodes response with XML or JSON reader depending on the
+// content type
+func responseNode(in io.ReadCloser) (node.Node, error) {
 	defer in.Close()
 	data, err := ioutil.ReadAll(in)
 	if err != nil || len(data) == 0 {
 		return nil, err
 	}
+	if isXmlResponse(in, data) {
+		return nodeutil.ReadXMLDoc(bytes.NewBuffer(data))
+	}
 	return nodeutil.ReadJSONIO(bytes.NewBuffer(data))
+}
 
+func isXmlResponse(in io.ReadCloser, data []byte) bool {
+	if resp, valid := in.(*response); valid {
+		if t, _ := restconf.ParseMimeType(string(resp.contentType)); t.IsXml() {
+			return true
+		} else if t.IsJson() {
+			return false
+		}
+	}
+	// server didn't say or is using a type we don't recognize
+	return bytes.HasPrefix(bytes.TrimSpace(data), []byte("<"))
 }
 
 func (cn *clientNode) request(method string, p *node.Path, in *node.Selection) (node.Node, error) {
@@ -219,7 +242,7 @@ func (cn *clientNode) request(method string, p *node.Path, in *node.Selection) (
 	if err != nil || resp == nil {
 		return nil, err
 	}
-	return jsonNode(resp)
+	return responseNode(resp)
 }
 
 func jsonWtr(compliance restconf.ComplianceOptions, out io.Writer) node.Node {
@@ -248,12 +271,20 @@ func (cn *clientNode) requestAction(p *node.Path, in *node.Selection) (node.Node
 		return nil, err
 	}
 	if resp != nil {
+		defer resp.Close()
+		data, err := ioutil.ReadAll(resp)
+		if err != nil {
+			return nil, err
+		}
+		if isXmlResponse(resp, data) {
+			// output wrapper, if any, is the root element
+			return nodeutil.ReadXMLDoc(bytes.NewBuffer(data))
+		}
 		if !cn.compliance.DisableActionWrapper {
 			// IETF formated input
 			// https://datatracker.ietf.org/doc/html/rfc8040#section-3.6.2
 			var vals map[string]interface{}
-			d := json.NewDecoder(resp)
-			err := d.Decode(&vals)
+			err := json.Unmarshal(data, &vals)
 			if err != nil {
 				return nil, err
 			}
@@ -265,7 +296,7 @@ func (cn *clientNode) requestAction(p *node.Path, in *node.Selection) (node.Node
 			}
 			return nodeutil.ReadJSONValues(respVals)
 		}
-		return nodeutil.ReadJSONIO(resp)
+		return nodeutil.ReadJSONIO(bytes.NewBuffer(data))
 	}
 	return nil, nil
 }
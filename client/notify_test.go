@@ -2,6 +2,8 @@ package client
 
 import (
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
@@ -89,3 +91,79 @@ func TestClientNotif(t *testing.T) {
 	fc.AssertEqual(t, nil, testClient(restconf.Simplified))
 	fc.AssertEqual(t, nil, testClient(restconf.Strict))
 }
+
+func TestSubscribe(t *testing.T) {
+	ypath := source.Path("../testdata:../yang")
+	m := parser.RequireModule(ypath, "x")
+	t1 := time.Date(2020, 3, 4, 5, 6, 7, 0, time.UTC)
+	n := &nodeutil.Basic{
+		OnNotify: func(r node.NotifyRequest) (node.NotifyCloser, error) {
+			go func() {
+				for i, z := range []string{"one", "two"} {
+					r.SendWhen(nodeutil.ReflectChild(map[string]interface{}{
+						"z": z,
+					}), t1.Add(time.Duration(i)*time.Minute))
+				}
+			}()
+			return func() error {
+				return nil
+			}, nil
+		},
+	}
+	d := device.New(ypath)
+	d.AddBrowser(node.NewBrowser(m, n))
+	var query string
+	s := restconf.NewHttpServe(d)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") == string(restconf.TextStreamMimeType) {
+			query = r.URL.RawQuery
+		}
+		s.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	for _, compliance := range []restconf.ComplianceOptions{restconf.Strict, restconf.Simplified} {
+		c := Client{YangPath: ypath, Complance: compliance}
+		events, cancel, err := c.Subscribe(ts.URL+"/restconf/data/x:y", StartTime(t1))
+		fc.RequireEqual(t, nil, err)
+		for _, expected := range []string{`{"z":"one"}`, `{"z":"two"}`} {
+			actual, err := nodeutil.WriteJSON(<-events)
+			fc.RequireEqual(t, nil, err)
+			fc.AssertEqual(t, expected, actual)
+		}
+		cancel()
+		fc.AssertEqual(t, true, strings.Contains(query, "start-time=2020-03-04T05%3A06%3A07"), query)
+		for range events {
+		}
+	}
+
+	c := Client{YangPath: ypath}
+	_, _, err := c.Subscribe(ts.URL + "/restconf/data/x:nope")
+	fc.AssertEqual(t, true, err != nil)
+}
+
+func TestDecodeXmlEvent(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module x {
+		namespace "x";
+		prefix "x";
+		notification y {
+			leaf z {
+				type string;
+			}
+		}
+	}`)
+	fc.RequireEqual(t, nil, err)
+	y := sel(node.NewBrowser(m, &nodeutil.Basic{}).Root().Find("y"))
+	c := &client{compliance: restconf.Strict}
+	e := c.decodeEvent([]byte(`<notification xmlns="urn:ietf:params:xml:ns:netconf:notification:1.0">` +
+		`<eventTime>2020-03-04T05:06:07+00:00</eventTime>` +
+		`<event xmlns="x"><y xmlns="x"><z>hi</z></y></event></notification>`))
+	actual, err := nodeutil.WriteJSON(y.Split(e.Node))
+	fc.RequireEqual(t, nil, err)
+	fc.AssertEqual(t, `{"z":"hi"}`, actual)
+	fc.AssertEqual(t, time.Date(2020, 3, 4, 5, 6, 7, 0, time.UTC), e.Timestamp.UTC())
+
+	e = c.decodeEvent([]byte(`<notification><event/></notification>`))
+	_, isErr := e.Node.(node.ErrorNode)
+	fc.AssertEqual(t, true, isErr)
+}
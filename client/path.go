@@ -0,0 +1,79 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+)
+
+// PathFromSelection is the path of data in sel as it is in a RESTCONF URL,
+// qualified with module at top and wherever module changes and with list
+// keys encoded. Append it to the data resource of a server to read sel from
+// that server.
+//
+//	car:garage/car=beetle/tire=front%2Fleft,1
+//
+// RFC8040 Sec. 3.5.3
+func PathFromSelection(sel *node.Selection) (string, error) {
+	if sel == nil || sel.Path == nil {
+		return "", errors.New("no selection")
+	}
+	segs := sel.Path.Segments()
+	if len(segs) < 2 {
+		return "", fmt.Errorf("selection is module %s, not data in it", sel.Path.Meta.Ident())
+	}
+	var path strings.Builder
+	var module string
+	for i, seg := range segs[1:] {
+		if i > 0 {
+			path.WriteByte('/')
+		}
+		if m := meta.OriginalModule(seg.Meta).Ident(); m != module {
+			path.WriteString(m)
+			path.WriteByte(':')
+			module = m
+		}
+		path.WriteString(seg.Meta.Ident())
+		if len(seg.Key) == 0 {
+			continue
+		}
+		list, isList := seg.Meta.(*meta.List)
+		if !isList {
+			return "", fmt.Errorf("%s has keys but is not a list", seg.Meta.Ident())
+		}
+		if len(seg.Key) != len(list.KeyMeta()) {
+			return "", fmt.Errorf("%s has %d keys, expected %d", seg.Meta.Ident(), len(seg.Key), len(list.KeyMeta()))
+		}
+		path.WriteByte('=')
+		for j, k := range seg.Key {
+			if k == nil {
+				return "", fmt.Errorf("%s has no value for key %s", seg.Meta.Ident(), list.KeyMeta()[j].Ident())
+			}
+			if j > 0 {
+				path.WriteByte(',')
+			}
+			path.WriteString(escapeKey(k.String()))
+		}
+	}
+	return path.String(), nil
+}
+
+// escapeKey percent-encodes every byte of list key k that is not unreserved
+// so key can have "/", "=", "," or anything else in it
+func escapeKey(k string) string {
+	var escaped strings.Builder
+	for i := 0; i < len(k); i++ {
+		c := k[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9',
+			c == '-', c == '.', c == '_', c == '~':
+			escaped.WriteByte(c)
+		default:
+			fmt.Fprintf(&escaped, "%%%02X", c)
+		}
+	}
+	return escaped.String()
+}
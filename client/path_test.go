@@ -0,0 +1,102 @@
+package client
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/freeconf/restconf"
+	"github.com/freeconf/restconf/device"
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+	"github.com/freeconf/yang/source"
+)
+
+func TestPathFromSelection(t *testing.T) {
+	mstr := `module x {
+		namespace "x";
+		prefix "x";
+		revision 0;
+		container garage {
+			list car {
+				key "name";
+				leaf name {
+					type string;
+				}
+				list tire {
+					key "pos size";
+					leaf pos {
+						type string;
+					}
+					leaf size {
+						type int32;
+					}
+					container wear {
+						leaf level {
+							type int32;
+						}
+					}
+				}
+			}
+		}
+	}`
+	m, err := parser.LoadModuleFromString(nil, mstr)
+	fc.RequireEqual(t, nil, err)
+	data := map[string]interface{}{
+		"garage": map[string]interface{}{
+			"car": []interface{}{
+				map[string]interface{}{
+					"name": "beetle",
+					"tire": []interface{}{
+						map[string]interface{}{"pos": "front/left", "size": 15, "wear": map[string]interface{}{"level": 1}},
+					},
+				},
+				map[string]interface{}{
+					"name": "a=b,c ü",
+				},
+			},
+		},
+	}
+	b := node.NewBrowser(m, nodeutil.ReflectChild(data))
+	tests := []struct {
+		find string
+		path string
+	}{
+		{find: "garage", path: "x:garage"},
+		{find: "garage/car", path: "x:garage/car"},
+		{find: "garage/car=beetle", path: "x:garage/car=beetle"},
+		{find: "garage/car=beetle/tire=front%2Fleft,15", path: "x:garage/car=beetle/tire=front%2Fleft,15"},
+		{find: "garage/car=beetle/tire=front%2Fleft,15/wear/level", path: "x:garage/car=beetle/tire=front%2Fleft,15/wear/level"},
+		{find: "garage/car=a%3Db%2Cc%20%C3%BC", path: "x:garage/car=a%3Db%2Cc%20%C3%BC"},
+	}
+	for _, test := range tests {
+		sel, err := b.Root().Find(test.find)
+		fc.RequireEqual(t, nil, err, test.find)
+		fc.RequireEqual(t, true, sel != nil, test.find)
+		actual, err := PathFromSelection(sel)
+		fc.RequireEqual(t, nil, err, test.find)
+		fc.AssertEqual(t, test.path, actual, test.find)
+	}
+
+	_, err = PathFromSelection(b.Root())
+	fc.AssertEqual(t, true, err != nil)
+	_, err = PathFromSelection(nil)
+	fc.AssertEqual(t, true, err != nil)
+
+	t.Run("server", func(t *testing.T) {
+		d := device.New(source.Dir("../yang"))
+		d.AddBrowser(b)
+		ts := httptest.NewServer(restconf.NewHttpServe(d))
+		defer ts.Close()
+		for _, test := range tests {
+			resp, err := http.Get(ts.URL + "/restconf/data/" + test.path)
+			fc.RequireEqual(t, nil, err)
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			fc.AssertEqual(t, 200, resp.StatusCode, test.path)
+		}
+	})
+}
@@ -0,0 +1,142 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy retries requests that fail because of the network or a status
+// server expects to go away, such as 503. Only idempotent requests are retried
+// and event streams are reconnected when they fail or server ends them.
+// Retries wait at least as long as server asks for with Retry-After.
+type RetryPolicy struct {
+	// Attempts including the first one. 0 or 1 means no retries
+	MaxAttempts int
+
+	// Wait before first retry, doubles with each retry up to MaxDelay when
+	// MaxDelay is set
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+
+	// Optional: 0..1 fraction of delay randomly added or removed so clients
+	// do not all retry at once
+	Jitter float64
+
+	// Optional: Default is 429, 502, 503 and 504
+	RetryableStatus []int
+
+	// Optional: Default waits for delay or ctx to be done. Set in tests to
+	// record delays instead of sleeping
+	Sleep func(ctx context.Context, delay time.Duration)
+
+	// Optional: Source of time for Retry-After given as a date, default is
+	// time.Now
+	Now func() time.Time
+}
+
+var defaultRetryableStatus = []int{
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// errStreamEnded is when server ends an event stream
+var errStreamEnded = errors.New("stream ended")
+
+func isIdempotent(method string) bool {
+	switch method {
+	case "GET", "HEAD", "PUT", "DELETE", "OPTIONS":
+		return true
+	}
+	return false
+}
+
+// allows is true if there should be another attempt after attempt failed
+// with status or, when there was no response, with err
+func (p *RetryPolicy) allows(attempt int, status int, err error) bool {
+	if p == nil || attempt >= p.MaxAttempts {
+		return false
+	}
+	if status == 0 {
+		return err != nil
+	}
+	retryable := p.RetryableStatus
+	if retryable == nil {
+		retryable = defaultRetryableStatus
+	}
+	for _, candidate := range retryable {
+		if candidate == status {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *RetryPolicy) delay(attempt int) time.Duration {
+	d := p.BaseDelay
+	for i := 1; i < attempt && (p.MaxDelay == 0 || d < p.MaxDelay); i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if p.Jitter > 0 {
+		d += time.Duration((rand.Float64()*2 - 1) * p.Jitter * float64(d))
+	}
+	return d
+}
+
+// retryAfter is how long server asked to wait before trying again in
+// Retry-After header of resp, either seconds or a date. 0 when server did not
+// ask. RFC9110 Sec. 10.2.3
+func (p *RetryPolicy) retryAfter(resp *http.Response) time.Duration {
+	if p == nil || resp == nil {
+		return 0
+	}
+	after := resp.Header.Get("Retry-After")
+	if after == "" {
+		return 0
+	}
+	if secs, err := strconv.ParseInt(after, 10, 64); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	when, err := http.ParseTime(after)
+	if err != nil {
+		return 0
+	}
+	now := time.Now
+	if p.Now != nil {
+		now = p.Now
+	}
+	if d := when.Sub(now()); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// wait before the attempt after given attempt, at least as long as server
+// asked for with Retry-After
+func (p *RetryPolicy) wait(ctx context.Context, attempt int, after time.Duration) {
+	d := p.delay(attempt)
+	if after > d {
+		d = after
+	}
+	if p.Sleep != nil {
+		p.Sleep(ctx, d)
+		return
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+	case <-t.C:
+	}
+}
@@ -0,0 +1,192 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/freeconf/restconf"
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+	"github.com/freeconf/yang/source"
+)
+
+func TestRetry(t *testing.T) {
+	var data dataTestData
+	data.Garage.Car = []*dataTestCar{{Name: "car0"}}
+	local, ypath := newDataTestDevice(t, &data)
+	s := restconf.NewHttpServe(local)
+	var failures []int
+	var requests int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "x:garage") {
+			requests++
+			if len(failures) > 0 {
+				status := failures[0]
+				failures = failures[1:]
+				http.Error(w, http.StatusText(status), status)
+				return
+			}
+		}
+		s.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+	garage := ts.URL + "/restconf/data/x:garage"
+
+	var delays []time.Duration
+	c := Client{YangPath: ypath, Complance: restconf.Strict, Retry: &RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Second,
+		Sleep: func(ctx context.Context, delay time.Duration) {
+			delays = append(delays, delay)
+		},
+	}}
+	reset := func(statuses ...int) {
+		failures, requests, delays = statuses, 0, nil
+	}
+
+	reset(503, 503)
+	_, err := c.Get(garage)
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, 3, requests)
+	fc.AssertEqual(t, []time.Duration{time.Second, 2 * time.Second}, delays)
+
+	reset(503, 503, 503)
+	_, err = c.Get(garage)
+	fc.AssertEqual(t, true, err != nil)
+	fc.AssertEqual(t, 3, requests)
+
+	reset(400)
+	_, err = c.Get(garage)
+	fc.AssertEqual(t, true, err != nil)
+	fc.AssertEqual(t, 1, requests)
+	fc.AssertEqual(t, 0, len(delays))
+
+	// not idempotent
+	reset(503)
+	err = c.Post(garage, readJSON(t, `{"car":[{"name":"car1"}]}`))
+	fc.AssertEqual(t, true, err != nil)
+	fc.AssertEqual(t, 1, requests)
+
+	reset(503)
+	err = c.Put(garage+"/car=car0", readJSON(t, `{"name":"car0","speed":10}`))
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, 2, requests)
+	fc.AssertEqual(t, 10, data.Garage.Car[0].Speed)
+}
+
+func TestRetryStream(t *testing.T) {
+	ypath := source.Path("../testdata")
+	m := parser.RequireModule(ypath, "x")
+	connects := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		connects++
+		if connects == 2 {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		} else if connects > 3 {
+			http.Error(w, "bad", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", string(restconf.TextStreamMimeType))
+		fmt.Fprintf(w, "data: {\"z\":\"session %d\"}\n\n", connects)
+	}))
+	defer ts.Close()
+	address, err := NewAddress(ts.URL + "/restconf")
+	fc.RequireEqual(t, nil, err)
+	factory := Client{
+		YangPath:  ypath,
+		Complance: restconf.ComplianceOptions{DisableNotificationWrapper: true},
+		Retry: &RetryPolicy{
+			MaxAttempts: 3,
+			Sleep:       func(context.Context, time.Duration) {},
+		},
+	}
+	c, _ := factory.newClient(address)
+	b := node.NewBrowser(m, &nodeutil.Basic{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	y := sel(b.Root().Find("y"))
+	events, err := c.clientStream("", y.Path, ctx)
+	fc.RequireEqual(t, nil, err)
+	var actual []string
+	for e := range events {
+		if errNode, isErr := e.Node.(node.ErrorNode); isErr {
+			actual = append(actual, errNode.Err.Error())
+			continue
+		}
+		msg, err := nodeutil.WriteJSON(y.Split(e.Node))
+		fc.RequireEqual(t, nil, err)
+		actual = append(actual, msg)
+	}
+	// ended and reconnected, 503 is retried, ended and reconnected again but
+	// 400 is not retried
+	expected := []string{
+		`{"z":"session 1"}`,
+		`{"z":"session 3"}`,
+		"stream request failed. status 400",
+	}
+	fc.AssertEqual(t, expected, actual)
+	fc.AssertEqual(t, 4, connects)
+}
+
+func TestRetryDelay(t *testing.T) {
+	p := RetryPolicy{BaseDelay: time.Second, MaxDelay: 5 * time.Second}
+	fc.AssertEqual(t, time.Second, p.delay(1))
+	fc.AssertEqual(t, 2*time.Second, p.delay(2))
+	fc.AssertEqual(t, 4*time.Second, p.delay(3))
+	fc.AssertEqual(t, 5*time.Second, p.delay(4))
+	fc.AssertEqual(t, 5*time.Second, p.delay(40))
+
+	p.Jitter = 0.5
+	for i := 0; i < 10; i++ {
+		d := p.delay(2)
+		fc.AssertEqual(t, true, d >= time.Second && d <= 3*time.Second, d.String())
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	var data dataTestData
+	local, ypath := newDataTestDevice(t, &data)
+	s := restconf.NewHttpServe(local)
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	var retryAfter []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "x:garage") && len(retryAfter) > 0 {
+			w.Header().Set("Retry-After", retryAfter[0])
+			retryAfter = retryAfter[1:]
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		s.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+	var delays []time.Duration
+	c := Client{YangPath: ypath, Complance: restconf.Strict, Retry: &RetryPolicy{
+		MaxAttempts: 4,
+		BaseDelay:   time.Second,
+		Sleep: func(ctx context.Context, delay time.Duration) {
+			delays = append(delays, delay)
+		},
+		Now: func() time.Time { return now },
+	}}
+
+	// seconds, a date and one shorter than backoff which is then used
+	retryAfter = []string{"7", now.Add(30 * time.Second).Format(http.TimeFormat), "1"}
+	_, err := c.Get(ts.URL + "/restconf/data/x:garage")
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, []time.Duration{7 * time.Second, 30 * time.Second, 4 * time.Second}, delays)
+
+	// from a restconf server that is shutting down
+	fc.RequireEqual(t, nil, s.Shutdown(context.Background()))
+	delays = nil
+	_, err = c.Get(ts.URL + "/restconf/data/x:garage")
+	fc.AssertEqual(t, true, err != nil)
+	fc.AssertEqual(t, []time.Duration{restconf.DefaultRetryAfter, restconf.DefaultRetryAfter, restconf.DefaultRetryAfter}, delays)
+}
@@ -3,6 +3,7 @@ package client
 import (
 	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 func TestSseDecode(t *testing.T) {
@@ -40,3 +41,14 @@ data: foo
 		}
 	}
 }
+
+func TestSseDecodePartialReads(t *testing.T) {
+	payload := "data: {\"a\":\ndata: 1}\n\ndata: {\"b\":2}\n\n"
+	events := decodeSse(iotest.OneByteReader(strings.NewReader(payload)))
+	for _, expected := range []string{`{"a":1}`, `{"b":2}`} {
+		actual := <-events
+		if expected != string(actual) {
+			t.Errorf("expected '%s' got '%s'", expected, actual)
+		}
+	}
+}
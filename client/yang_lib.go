@@ -0,0 +1,189 @@
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/freeconf/restconf/device"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+	"github.com/freeconf/yang/source"
+	"github.com/freeconf/yang/val"
+)
+
+// ModuleSet are the modules server reports in ietf-yang-library. RFC7895
+type ModuleSet struct {
+	// sorted by name
+	Modules []*YangModule
+}
+
+// YangModule is a module in server's yang library
+type YangModule struct {
+	device.ModuleHnd
+
+	// YANG source of module when asked for with Sources
+	Source []byte
+}
+
+// Module by name, nil if server does not have it
+func (s *ModuleSet) Module(name string) *YangModule {
+	for _, m := range s.Modules {
+		if m.Name == name {
+			return m
+		}
+	}
+	return nil
+}
+
+// Opener reads the sources that were downloaded so modules can be parsed
+// w/o asking server again
+//
+//	m, err := parser.LoadModule(set.Opener(), "car")
+func (s *ModuleSet) Opener() source.Opener {
+	return func(name string, ext string) (io.Reader, error) {
+		if m := s.Module(name); m != nil && m.Source != nil && (ext == "" || ext == ".yang") {
+			return bytes.NewReader(m.Source), nil
+		}
+		return nil, nil
+	}
+}
+
+// YangLibraryOption changes what LoadYangLibrary reads from server
+type YangLibraryOption func(*yangLibraryRequest)
+
+type yangLibraryRequest struct {
+	sources  bool
+	cacheDir string
+}
+
+// Sources downloads YANG source of each module from schema resource server
+// reports. When dir is not empty sources are saved there named like
+// car@2023-01-01.yang and read from there on later calls instead of being
+// downloaded again. RFC6020 Sec. 5.2
+func Sources(dir string) YangLibraryOption {
+	return func(r *yangLibraryRequest) {
+		r.sources = true
+		r.cacheDir = dir
+	}
+}
+
+// LoadYangLibrary reads modules server at url has from its ietf-yang-library,
+// url being RESTCONF root like NewDevice is given
+//
+//	http://server/restconf
+func (factory Client) LoadYangLibrary(url string, opts ...YangLibraryOption) (*ModuleSet, error) {
+	var req yangLibraryRequest
+	for _, opt := range opts {
+		opt(&req)
+	}
+	address, err := NewAddress(url)
+	if err != nil {
+		return nil, err
+	}
+	c, _ := factory.newClient(address)
+	ylib, err := parser.LoadModule(factory.YangPath, "ietf-yang-library")
+	if err != nil {
+		return nil, err
+	}
+	cn := &clientNode{support: c, device: address.DeviceId, compliance: c.compliance}
+	sel, err := node.NewBrowser(ylib, cn.node()).Root().Find("modules-state/module")
+	if err != nil {
+		return nil, err
+	}
+	set := &ModuleSet{}
+	if sel != nil {
+		if err = sel.InsertInto(yangModulesNode(set)); err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(set.Modules, func(i, j int) bool {
+		return set.Modules[i].Name < set.Modules[j].Name
+	})
+	if req.sources {
+		for _, m := range set.Modules {
+			if m.Source, err = c.yangSource(m, req.cacheDir); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return set, nil
+}
+
+// yangModulesNode collects entries of modules-state/module into set
+func yangModulesNode(set *ModuleSet) node.Node {
+	return &nodeutil.Basic{
+		OnNext: func(r node.ListRequest) (node.Node, []val.Value, error) {
+			if !r.New {
+				return nil, nil, nil
+			}
+			m := &YangModule{}
+			set.Modules = append(set.Modules, m)
+			return nodeutil.ReflectChild(&m.ModuleHnd), r.Key, nil
+		},
+	}
+}
+
+// yangSource of module from cache or else from server, saved in cache when
+// there is one
+func (c *client) yangSource(m *YangModule, cacheDir string) ([]byte, error) {
+	fname := m.Name + ".yang"
+	if m.Revision != "" {
+		fname = m.Name + "@" + m.Revision + ".yang"
+	}
+	cached := filepath.Join(cacheDir, fname)
+	if cacheDir != "" {
+		if data, err := os.ReadFile(cached); err == nil {
+			return data, nil
+		}
+	}
+	data, err := c.download(c.schemaAddress(m))
+	if err != nil {
+		return nil, fmt.Errorf("could not download module %s. %w", m.Name, err)
+	}
+	if cacheDir != "" {
+		if err := os.MkdirAll(cacheDir, 0755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(cached, data, 0644); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// schemaAddress is where source of module is, schema in yang library may be
+// relative to RESTCONF root
+func (c *client) schemaAddress(m *YangModule) string {
+	if m.Schema == "" {
+		return c.address.Schema + m.Name + ".yang"
+	}
+	base, err := url.Parse(c.address.Base)
+	if err != nil {
+		return m.Schema
+	}
+	ref, err := url.Parse(m.Schema)
+	if err != nil {
+		return m.Schema
+	}
+	return base.ResolveReference(ref).String()
+}
+
+func (c *client) download(fullUrl string) ([]byte, error) {
+	resp, err := c.client.Get(fullUrl)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("(%d) %s", resp.StatusCode, string(msg))
+	}
+	return io.ReadAll(resp.Body)
+}
@@ -0,0 +1,85 @@
+package client
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/freeconf/restconf"
+	"github.com/freeconf/restconf/device"
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+	"github.com/freeconf/yang/source"
+)
+
+const yangLibTestYang = `module y {
+	namespace "y";
+	prefix "y";
+	revision 2024-01-01;
+	leaf z {
+		type string;
+	}
+}`
+
+func TestLoadYangLibrary(t *testing.T) {
+	srcs := map[string]string{"x": dataTestYang, "y": yangLibTestYang}
+	ypath := source.Any(source.Path("../yang"), func(name string, ext string) (io.Reader, error) {
+		// server reads schema resource schema/x.yang as name x.yang
+		if src, found := srcs[strings.TrimSuffix(name, ".yang")]; found {
+			return strings.NewReader(src), nil
+		}
+		return nil, nil
+	})
+	local := device.New(ypath)
+	for _, name := range []string{"x", "y"} {
+		m, err := parser.LoadModule(ypath, name)
+		fc.RequireEqual(t, nil, err)
+		local.AddBrowser(node.NewBrowser(m, &nodeutil.Basic{}))
+	}
+	s := restconf.NewHttpServe(local)
+	var downloads int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/restconf/schema/") {
+			atomic.AddInt32(&downloads, 1)
+		}
+		s.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+	c := Client{YangPath: source.Path("../yang")}
+
+	set, err := c.LoadYangLibrary(ts.URL + "/restconf")
+	fc.RequireEqual(t, nil, err)
+	fc.RequireEqual(t, true, set.Module("x") != nil)
+	fc.RequireEqual(t, true, set.Module("y") != nil)
+	fc.AssertEqual(t, "2024-01-01", set.Module("y").Revision)
+	fc.AssertEqual(t, "y", set.Module("y").Namespace)
+	fc.AssertEqual(t, 0, len(set.Module("x").Source))
+	fc.AssertEqual(t, int32(0), atomic.LoadInt32(&downloads))
+
+	dir := t.TempDir()
+	set, err = c.LoadYangLibrary(ts.URL+"/restconf", Sources(dir))
+	fc.RequireEqual(t, nil, err)
+	fc.AssertEqual(t, dataTestYang, string(set.Module("x").Source))
+	fc.AssertEqual(t, yangLibTestYang, string(set.Module("y").Source))
+	m, err := parser.LoadModule(set.Opener(), "y")
+	fc.RequireEqual(t, nil, err)
+	fc.AssertEqual(t, "z", m.DataDefinitions()[0].Ident())
+	cached, err := os.ReadFile(filepath.Join(dir, "y@2024-01-01.yang"))
+	fc.RequireEqual(t, nil, err)
+	fc.AssertEqual(t, yangLibTestYang, string(cached))
+	downloaded := atomic.LoadInt32(&downloads)
+	fc.AssertEqual(t, true, downloaded >= 2)
+
+	// from cache
+	set, err = c.LoadYangLibrary(ts.URL+"/restconf", Sources(dir))
+	fc.RequireEqual(t, nil, err)
+	fc.AssertEqual(t, yangLibTestYang, string(set.Module("y").Source))
+	fc.AssertEqual(t, downloaded, atomic.LoadInt32(&downloads))
+}
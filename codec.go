@@ -0,0 +1,249 @@
+package restconf
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+)
+
+// Encoder is the node response data is written into for a media type. Data
+// is complete once the selection is done writing into the node.
+type Encoder func(out io.Writer, compliance ComplianceOptions) node.Node
+
+// Decoder reads request content of a media type as a node
+type Decoder func(in io.Reader) (node.Node, error)
+
+// codecs are the media types server can write responses in and read request
+// content from keyed by MIME type. JSON and XML are registered like any other
+// type so they can be replaced
+type codecs struct {
+	encoders map[MimeType]Encoder
+	decoders map[MimeType]Decoder
+	// types still written by built-in JSON encoder, see streamList
+	jsonTypes map[MimeType]bool
+	// patchFormats that are enabled, see EnablePatchFormat
+	patches map[MimeType]bool
+}
+
+// patchFormats are formats of PATCH content other than data that is merged
+// into target
+var patchFormats = []MimeType{YangPatchJsonMimeType, YangPatchXmlMimeType, JsonPatchMimeType}
+
+var builtinCodecs = newCodecs()
+
+func newCodecs() *codecs {
+	c := &codecs{
+		encoders:  make(map[MimeType]Encoder),
+		decoders:  make(map[MimeType]Decoder),
+		jsonTypes: make(map[MimeType]bool),
+		patches:   make(map[MimeType]bool),
+	}
+	for _, m := range patchFormats {
+		c.patches[m] = true
+	}
+	for _, m := range []MimeType{YangDataJsonMimeType, YangDataJsonMimeType2, PlainJsonMimeType} {
+		c.encoders[m] = jsonEncoder
+		c.jsonTypes[m] = true
+		c.decoders[m] = jsonDecoder
+	}
+	for _, m := range []MimeType{YangDataXmlMimeType, YangDataXmlMimeType2, PlainXmlMimeType} {
+		c.encoders[m] = xmlEncoder
+		c.decoders[m] = xmlDecoder
+	}
+	return c
+}
+
+func jsonEncoder(out io.Writer, compliance ComplianceOptions) node.Node {
+	wtr := &nodeutil.JSONWtr{
+		Out:              out,
+		QualifyNamespace: !compliance.QualifyNamespaceDisabled,
+	}
+	return jsonStrings(wtr.Node(), !compliance.NumbersNotQuoted)
+}
+
+// snapshotCompliance is for data written as JSON only to be read back again
+var snapshotCompliance = ComplianceOptions{QualifyNamespaceDisabled: true}
+
+func xmlEncoder(out io.Writer, compliance ComplianceOptions) node.Node {
+	leaves := &xmlLeaves{out: out}
+	wtr := &nodeutil.XMLWtr{
+		Out: &leaves.buf,
+	}
+	return leaves.node(wtr.Node())
+}
+
+func xmlDecoder(in io.Reader) (node.Node, error) {
+	return readXmlNode(in)
+}
+
+// RegisterEncoder writes responses in media type m when a client asks for it
+// in Accept header. Replaces any encoder already registered for m including
+// the built-in JSON and XML encoders.
+func (srv *Server) RegisterEncoder(m MimeType, e Encoder) {
+	c := srv.registeredCodecs()
+	c.encoders[m] = e
+	delete(c.jsonTypes, m)
+}
+
+// RegisterDecoder reads request content with Content-Type m. Replaces any
+// decoder already registered for m including the built-in JSON and XML
+// decoders.
+func (srv *Server) RegisterDecoder(m MimeType, d Decoder) {
+	srv.registeredCodecs().decoders[m] = d
+}
+
+// EnablePatchFormat turns PATCH content in media type m, yang-patch in JSON
+// or XML or json-patch, on or off. All are on by default. Content in a format
+// that is off is rejected with 415 and format is left out of Accept-Patch.
+func (srv *Server) EnablePatchFormat(m MimeType, enabled bool) error {
+	c := srv.registeredCodecs()
+	if _, known := c.patches[m]; !known {
+		return fmt.Errorf("%s is not a patch format", m)
+	}
+	c.patches[m] = enabled
+	return nil
+}
+
+func (srv *Server) registeredCodecs() *codecs {
+	if srv.codecs == nil {
+		srv.codecs = newCodecs()
+	}
+	return srv.codecs
+}
+
+func (c *codecs) orBuiltin() *codecs {
+	if c == nil {
+		return builtinCodecs
+	}
+	return c
+}
+
+// encoder for media type m. Types w/o an encoder are written as JSON unless
+// they look like XML
+func (c *codecs) encoder(m MimeType) Encoder {
+	c = c.orBuiltin()
+	if e, found := c.encoders[m]; found {
+		return e
+	}
+	if m.IsXml() {
+		return c.encoders[YangDataXmlMimeType]
+	}
+	return c.encoders[YangDataJsonMimeType]
+}
+
+// decoder for media type m. Content w/o a known type is read as JSON unless
+// it looks like XML
+func (c *codecs) decoder(m MimeType) Decoder {
+	c = c.orBuiltin()
+	if d, found := c.decoders[m]; found {
+		return d
+	}
+	if m.IsXml() {
+		return c.decoders[YangDataXmlMimeType]
+	}
+	return c.decoders[YangDataJsonMimeType]
+}
+
+// streamsLists is true when m is written by built-in JSON encoder, including
+// types w/o an encoder that fall back to it
+func (c *codecs) streamsLists(m MimeType) bool {
+	c = c.orBuiltin()
+	if _, found := c.encoders[m]; found {
+		return c.jsonTypes[m]
+	}
+	return !m.IsXml() && c.jsonTypes[YangDataJsonMimeType]
+}
+
+// custom is true when m has a registered encoder that is neither JSON nor XML
+func (c *codecs) custom(m MimeType) bool {
+	_, found := c.orBuiltin().encoders[m]
+	return found && !m.IsJson() && !m.IsXml()
+}
+
+func (c *codecs) setContentType(compliance ComplianceOptions, h http.Header, contentType MimeType) {
+	if compliance.QualifyNamespaceDisabled && contentType.IsXml() {
+		h.Set("Content-Type", string(PlainXmlMimeType))
+	} else if compliance.QualifyNamespaceDisabled && !c.custom(contentType) {
+		h.Set("Content-Type", mime.TypeByExtension(".json"))
+	} else {
+		h.Set("Content-Type", string(contentType))
+	}
+}
+
+// accepted is the type in an Accept header with the highest quality value
+// that has an encoder. When none do the highest is still reported as that
+// might be for an event stream.
+func (c *codecs) accepted(accept string) MimeType {
+	c = c.orBuiltin()
+	return preferredMimeType(accept, func(m MimeType) bool {
+		_, found := c.encoders[m]
+		return found
+	})
+}
+
+// acceptable is true when response can be written in accepted type m. No
+// Accept header, ranges of types json or xml are in and event streams are
+// too.
+func (c *codecs) acceptable(m MimeType) bool {
+	switch m {
+	case "", "*/*", "application/*", TextStreamMimeType:
+		return true
+	}
+	_, found := c.orBuiltin().encoders[m]
+	return found
+}
+
+// contentTypes are built-in content types for method, enabled patch formats
+// for PATCH, and then registered ones
+func (c *codecs) contentTypes(method string) []MimeType {
+	types := contentTypes()
+	if method == "PATCH" {
+		types = append(types, c.enabledPatchFormats()...)
+	}
+	return append(types, c.registeredTypes()...)
+}
+
+// acceptPatch are formats accepted for PATCH. Types of built-in data only
+// older clients use, like application/json, are left out. RFC5789 Sec. 3.1
+func (c *codecs) acceptPatch() []MimeType {
+	types := append([]MimeType{YangDataJsonMimeType, YangDataXmlMimeType}, c.enabledPatchFormats()...)
+	return append(types, c.registeredTypes()...)
+}
+
+func (c *codecs) enabledPatchFormats() []MimeType {
+	var enabled []MimeType
+	for _, m := range patchFormats {
+		if c.orBuiltin().patches[m] {
+			enabled = append(enabled, m)
+		}
+	}
+	return enabled
+}
+
+// registeredTypes are types with a decoder that is not built-in sorted by
+// name
+func (c *codecs) registeredTypes() []MimeType {
+	builtin := contentTypes()
+	var extra []MimeType
+	for m := range c.orBuiltin().decoders {
+		if !containsMimeType(builtin, m) && !containsMimeType(patchFormats, m) {
+			extra = append(extra, m)
+		}
+	}
+	sort.Slice(extra, func(i, j int) bool { return extra[i] < extra[j] })
+	return extra
+}
+
+func containsMimeType(types []MimeType, m MimeType) bool {
+	for _, candidate := range types {
+		if candidate == m {
+			return true
+		}
+	}
+	return false
+}
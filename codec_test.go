@@ -0,0 +1,105 @@
+package restconf
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+)
+
+// one "leaf=value" line per leaf of a container
+const linesMimeType = MimeType("text/x-lines")
+
+func linesEncoder(out io.Writer, compliance ComplianceOptions) node.Node {
+	return &nodeutil.Basic{
+		OnField: func(r node.FieldRequest, hnd *node.ValueHandle) error {
+			_, err := fmt.Fprintf(out, "%s=%s\n", r.Meta.Ident(), hnd.Val.String())
+			return err
+		},
+	}
+}
+
+func linesDecoder(in io.Reader) (node.Node, error) {
+	vals := make(map[string]interface{})
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		if ident, v, valid := strings.Cut(scanner.Text(), "="); valid {
+			vals[ident] = v
+		}
+	}
+	return nodeutil.ReflectChild(vals), scanner.Err()
+}
+
+func TestCustomCodec(t *testing.T) {
+	mstr := `module x {
+		revision 0;
+		container c {
+			leaf a {
+				type string;
+			}
+			leaf n {
+				type int32;
+			}
+		}
+	}`
+	s, ts := newTestServer(t, mstr, `{"c":{"a":"hi","n":1}}`)
+	defer ts.Close()
+	s.RegisterEncoder(linesMimeType, linesEncoder)
+	s.RegisterDecoder(linesMimeType, linesDecoder)
+
+	resp, actual := testRequest(t, "GET", ts.URL+"/restconf/data/x:c", "", "Accept", string(linesMimeType))
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	fc.AssertEqual(t, string(linesMimeType), resp.Header.Get("Content-Type"))
+	fc.AssertEqual(t, "a=hi\nn=1\n", actual)
+
+	// registered types win over ones server cannot write
+	resp, actual = testRequest(t, "GET", ts.URL+"/restconf/data/x:c", "", "Accept", "text/html, text/x-lines;q=0.5")
+	fc.AssertEqual(t, string(linesMimeType), resp.Header.Get("Content-Type"))
+	fc.AssertEqual(t, "a=hi\nn=1\n", actual)
+
+	resp, _ = testRequest(t, "PATCH", ts.URL+"/restconf/data/x:c", "a=bye\n", "Content-Type", string(linesMimeType))
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	_, actual = testRequest(t, "GET", ts.URL+"/restconf/data/x:c", "")
+	fc.AssertEqual(t, `{"a":"bye","n":1}`, actual)
+
+	// built-ins are still there
+	_, actual = testRequest(t, "GET", ts.URL+"/restconf/data/x:c", "", "Accept", string(YangDataXmlMimeType1))
+	fc.AssertEqual(t, true, strings.Contains(actual, "<a>bye</a>"), actual)
+
+	resp, _ = testRequest(t, "PATCH", ts.URL+"/restconf/data/x:c", "a", "Content-Type", "application/cbor")
+	fc.AssertEqual(t, 415, resp.StatusCode)
+	fc.AssertEqual(t, true, strings.Contains(resp.Header.Get("Accept"), string(linesMimeType)), resp.Header.Get("Accept"))
+}
+
+func TestAcceptPatch(t *testing.T) {
+	s, ts := newTestServer(t, nestedYang, nestedData)
+	defer ts.Close()
+	url := ts.URL + "/restconf/data/x:a"
+	acceptPatch := func() string {
+		resp, _ := testRequest(t, "OPTIONS", url, "")
+		return resp.Header.Get("Accept-Patch")
+	}
+	fc.AssertEqual(t, "application/yang-data+json, application/yang-data+xml, "+
+		"application/yang-patch+json, application/yang-patch+xml, application/json-patch+json", acceptPatch())
+
+	fc.RequireEqual(t, nil, s.EnablePatchFormat(JsonPatchMimeType, false))
+	fc.RequireEqual(t, nil, s.EnablePatchFormat(YangPatchXmlMimeType, false))
+	s.RegisterDecoder(linesMimeType, linesDecoder)
+	fc.AssertEqual(t, "application/yang-data+json, application/yang-data+xml, "+
+		"application/yang-patch+json, text/x-lines", acceptPatch())
+	resp, _ := testRequest(t, "PATCH", url, `[{"op":"replace","path":"/b","value":"B2"}]`, "Content-Type", string(JsonPatchMimeType))
+	fc.AssertEqual(t, 415, resp.StatusCode)
+	fc.AssertEqual(t, false, strings.Contains(resp.Header.Get("Accept"), string(JsonPatchMimeType)), resp.Header.Get("Accept"))
+
+	fc.RequireEqual(t, nil, s.EnablePatchFormat(JsonPatchMimeType, true))
+	fc.AssertEqual(t, true, strings.HasSuffix(acceptPatch(), "application/json-patch+json, text/x-lines"), acceptPatch())
+	resp, _ = testRequest(t, "PATCH", url, `[{"op":"replace","path":"/b","value":"B2"}]`, "Content-Type", string(JsonPatchMimeType))
+	fc.AssertEqual(t, 200, resp.StatusCode)
+
+	fc.AssertEqual(t, true, s.EnablePatchFormat(YangDataJsonMimeType, false) != nil)
+}
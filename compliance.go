@@ -24,6 +24,7 @@ var Simplified = ComplianceOptions{
 	DisableActionWrapper:       true,
 	SimpleErrorResponse:        true,
 	QualifyNamespaceDisabled:   true,
+	NumbersNotQuoted:           true,
 }
 
 // ComplianceOptions hold all the compliance settings.  If you enable any of these
@@ -51,6 +52,10 @@ type ComplianceOptions struct {
 	// QualifyNamespaceDisabled when true then all JSON object keys will not
 	// include YANG module according to RFC7952.
 	QualifyNamespaceDisabled bool
+
+	// NumbersNotQuoted when true then int64, uint64 and decimal64 values are
+	// written as JSON numbers and not as strings according to RFC7951 Sec. 6.1
+	NumbersNotQuoted bool
 }
 
 func (compliance ComplianceOptions) String() string {
@@ -0,0 +1,138 @@
+package restconf
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/freeconf/yang/fc"
+)
+
+// HTTP content codings server can compress responses with, best first.
+// "deflate" is zlib format. RFC9110 Sec. 8.4.1
+var compressEncodings = []string{"gzip", "deflate"}
+
+// acceptedEncoding is the content coding in an Accept-Encoding header with
+// highest quality value that server supports or empty if there is none
+//
+//	deflate, gzip;q=0.8
+func acceptedEncoding(accept string) string {
+	var best string
+	bestQ := 0.0
+	for _, candidate := range strings.Split(accept, ",") {
+		coding, params, err := mime.ParseMediaType(candidate)
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if qstr, hasQ := params["q"]; hasQ {
+			if q, err = strconv.ParseFloat(qstr, 64); err != nil {
+				continue
+			}
+		}
+		if coding == "*" {
+			coding = compressEncodings[0]
+		}
+		supported := false
+		for _, e := range compressEncodings {
+			supported = supported || e == coding
+		}
+		if supported && q > bestQ {
+			best, bestQ = coding, q
+		}
+	}
+	return best
+}
+
+// compressWriter compresses response body. Compression starts with the
+// response status so responses that cannot have a body are left alone. Flush
+// sends everything written so far so event streams keep working.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding    string
+	wroteHeader bool
+	out         interface {
+		io.WriteCloser
+		Flush() error
+	}
+}
+
+func newCompressWriter(w http.ResponseWriter, encoding string) *compressWriter {
+	w.Header().Add("Vary", "Accept-Encoding")
+	return &compressWriter{ResponseWriter: w, encoding: encoding}
+}
+
+func (c *compressWriter) WriteHeader(status int) {
+	if c.wroteHeader {
+		return
+	}
+	c.wroteHeader = true
+	if status >= 200 && status != http.StatusNoContent && status != http.StatusNotModified {
+		h := c.Header()
+		h.Set("Content-Encoding", c.encoding)
+		h.Del("Content-Length")
+		if c.encoding == "deflate" {
+			c.out = zlib.NewWriter(c.ResponseWriter)
+		} else {
+			c.out = gzip.NewWriter(c.ResponseWriter)
+		}
+	}
+	c.ResponseWriter.WriteHeader(status)
+}
+
+func (c *compressWriter) Write(data []byte) (int, error) {
+	c.WriteHeader(http.StatusOK)
+	if c.out == nil {
+		return c.ResponseWriter.Write(data)
+	}
+	return c.out.Write(data)
+}
+
+func (c *compressWriter) Flush() {
+	c.WriteHeader(http.StatusOK)
+	if c.out != nil {
+		c.out.Flush()
+	}
+	if flusher, valid := c.ResponseWriter.(http.Flusher); valid {
+		flusher.Flush()
+	}
+}
+
+// Close writes the end of the compressed content
+func (c *compressWriter) Close() error {
+	if c.out == nil {
+		return nil
+	}
+	return c.out.Close()
+}
+
+// decompressRequest undoes Content-Encoding of request content so readers get
+// plain JSON or XML
+func decompressRequest(r *http.Request) error {
+	encoding := r.Header.Get("Content-Encoding")
+	if encoding == "" || encoding == "identity" || r.Body == nil || r.ContentLength == 0 {
+		return nil
+	}
+	var body io.ReadCloser
+	var err error
+	switch encoding {
+	case "gzip":
+		body, err = gzip.NewReader(r.Body)
+	case "deflate":
+		body, err = zlib.NewReader(r.Body)
+	default:
+		return fmt.Errorf("%w. content encoding '%s'", ErrUnsupportedMediaType, encoding)
+	}
+	if err != nil {
+		return fmt.Errorf("%w. %s content. %s", fc.BadRequestError, encoding, err)
+	}
+	r.Body = body
+	r.Header.Del("Content-Encoding")
+	r.ContentLength = -1
+	return nil
+}
@@ -0,0 +1,118 @@
+package restconf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/parser"
+)
+
+func TestAcceptedEncoding(t *testing.T) {
+	tests := []struct {
+		accept   string
+		expected string
+	}{
+		{accept: "", expected: ""},
+		{accept: "gzip", expected: "gzip"},
+		{accept: "deflate", expected: "deflate"},
+		{accept: "gzip, deflate, br", expected: "gzip"},
+		{accept: "br", expected: ""},
+		{accept: "gzip;q=0.5, deflate", expected: "deflate"},
+		{accept: "gzip;q=0", expected: ""},
+		{accept: "*", expected: "gzip"},
+	}
+	for _, test := range tests {
+		fc.AssertEqual(t, test.expected, acceptedEncoding(test.accept), test.accept)
+	}
+}
+
+// gunzip reads as much as can be decompressed so partial streams can be
+// checked
+func gunzip(t *testing.T, data []byte) string {
+	t.Helper()
+	rdr, err := gzip.NewReader(bytes.NewReader(data))
+	fc.RequireEqual(t, nil, err)
+	var out bytes.Buffer
+	io.Copy(&out, rdr)
+	return out.String()
+}
+
+func TestCompression(t *testing.T) {
+	s, ts := newTestServer(t, nestedYang, nestedData)
+	defer ts.Close()
+	get := func(encoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/restconf/data/x:a", nil)
+		req.Header.Set("Accept", string(YangDataJsonMimeType1))
+		req.Header.Set("Accept-Encoding", encoding)
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		return w
+	}
+	plain := get("gzip")
+	fc.AssertEqual(t, "", plain.Header().Get("Content-Encoding"))
+
+	s.Compression = true
+	zipped := get("gzip")
+	fc.AssertEqual(t, 200, zipped.Code)
+	fc.AssertEqual(t, "gzip", zipped.Header().Get("Content-Encoding"))
+	fc.AssertEqual(t, "Accept-Encoding", zipped.Header().Get("Vary"))
+	fc.AssertEqual(t, plain.Body.String(), gunzip(t, zipped.Body.Bytes()))
+
+	deflated := get("deflate")
+	fc.AssertEqual(t, "deflate", deflated.Header().Get("Content-Encoding"))
+	rdr, err := zlib.NewReader(deflated.Body)
+	fc.RequireEqual(t, nil, err)
+	actual, err := io.ReadAll(rdr)
+	fc.RequireEqual(t, nil, err)
+	fc.AssertEqual(t, plain.Body.String(), string(actual))
+
+	fc.AssertEqual(t, "", get("br").Header().Get("Content-Encoding"))
+
+	// nothing to compress
+	req := httptest.NewRequest("GET", "/restconf/data/x:a", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("If-None-Match", zipped.Header().Get("ETag"))
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	fc.AssertEqual(t, 304, w.Code)
+	fc.AssertEqual(t, "", w.Header().Get("Content-Encoding"))
+	fc.AssertEqual(t, 0, w.Body.Len())
+}
+
+func TestCompressionStream(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, streamYang)
+	fc.RequireEqual(t, nil, err)
+	tn := newStreamTestNode()
+	s, ts := newTestServerWithNode(t, m, tn.node())
+	defer ts.Close()
+	s.Compression = true
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest("GET", "/restconf/streams/NETCONF", nil).WithContext(ctx)
+	req.Header.Set("Accept", string(TextStreamMimeType))
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := newFlushRecorder()
+	done := make(chan bool)
+	go func() {
+		s.ServeHTTP(w, req)
+		done <- true
+	}()
+	tn.waitSubscribed(t, true)
+	<-w.flushed
+	for _, z := range []string{"one", "two"} {
+		tn.send("y", z, s.now())
+		body := gunzip(t, []byte(<-w.flushed))
+		fc.AssertEqual(t, true, strings.Contains(body, `"z":"`+z+`"`), body)
+	}
+	fc.AssertEqual(t, "gzip", w.Header().Get("Content-Encoding"))
+	cancel()
+	<-done
+	tn.waitSubscribed(t, false)
+}
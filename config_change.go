@@ -0,0 +1,285 @@
+package restconf
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/freeconf/restconf/device"
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+	"github.com/freeconf/yang/val"
+)
+
+// ConfigChange is an edit to config made through RESTCONF. Given to
+// Server.OnConfigChange and, with Server.ConfigChangeNotifications, sent to
+// event streams as netconf-config-change. RFC6470 Sec. 2.2
+type ConfigChange struct {
+	// module of data that was edited
+	Module string
+
+	// NMDA datastore, DatastoreRunning unless edit was to another datastore
+	// served with ServeDatastore
+	Datastore string
+
+	Edits []ConfigEdit
+
+	// nil when request is not authenticated, see PrincipalOf
+	Principal any
+
+	// ip address of client when known
+	RemoteHost string
+
+	Time time.Time
+}
+
+// ConfigEdit is the data an edit was made to and how
+type ConfigEdit struct {
+	// instance-identifier of resource in request like /car:engine/speed, or
+	// of its parent when resource was created. RFC7951 Sec. 6.11
+	Target string
+
+	// merge, replace, create, delete or remove. RFC6241 Sec. 7.2
+	Operation string
+}
+
+// edit operations of ietf-netconf
+const (
+	editMerge   = "merge"
+	editReplace = "replace"
+	editCreate  = "create"
+	editDelete  = "delete"
+)
+
+const (
+	configChangeModule       = "ietf-netconf-notifications"
+	configChangeNotification = "netconf-config-change"
+)
+
+// editOperation of a request that edited data
+func editOperation(method string, creating bool) string {
+	switch method {
+	case "POST":
+		return editCreate
+	case "PUT":
+		if creating {
+			return editCreate
+		}
+		return editReplace
+	case "DELETE":
+		return editDelete
+	}
+	return editMerge
+}
+
+// instanceIdentifierOf data at path, qualified at top and wherever module
+// changes
+//
+//	/car:tire[pos='1']/wear
+func instanceIdentifierOf(p *node.Path) string {
+	var id []byte
+	var module string
+	for _, seg := range p.Segments()[1:] {
+		id = append(id, '/')
+		if m := meta.OriginalModule(seg.Meta).Ident(); m != module {
+			id = append(id, m...)
+			id = append(id, ':')
+			module = m
+		}
+		id = append(id, seg.Meta.Ident()...)
+		list, isList := seg.Meta.(*meta.List)
+		if !isList {
+			continue
+		}
+		for i, k := range list.KeyMeta() {
+			if i >= len(seg.Key) {
+				break
+			}
+			v := seg.Key[i].String()
+			quote := "'"
+			if strings.ContainsRune(v, '\'') {
+				quote = `"`
+			}
+			id = append(id, fmt.Sprintf("[%s=%s%s%s]", k.Ident(), quote, v, quote)...)
+		}
+	}
+	if len(id) == 0 {
+		return "/"
+	}
+	return string(id)
+}
+
+var configChangeMeta struct {
+	once   sync.Once
+	module *meta.Module
+	err    error
+}
+
+// configChangeMod is ietf-netconf-notifications from IETF definitions that
+// come with this package
+func configChangeMod() (*meta.Module, error) {
+	configChangeMeta.once.Do(func() {
+		configChangeMeta.module, configChangeMeta.err = parser.LoadModule(InternalIetfRfcYPath, configChangeModule)
+	})
+	return configChangeMeta.module, configChangeMeta.err
+}
+
+// configChangeEmitter is what edits of d are told to, nil when no one is
+// interested
+func (srv *Server) configChangeEmitter(d device.Device) func(ConfigChange) {
+	notify := srv.ConfigChangeNotifications && srv.isMain(d)
+	if srv.OnConfigChange == nil && !notify {
+		return nil
+	}
+	return func(c ConfigChange) {
+		if srv.OnConfigChange != nil {
+			srv.OnConfigChange(c)
+		}
+		if notify {
+			if err := srv.publishConfigChange(c); err != nil {
+				fc.Err.Printf("could not send %s. %s", configChangeNotification, err)
+			}
+		}
+	}
+}
+
+// publishConfigChange sends netconf-config-change to event streams that
+// include ietf-netconf-notifications. Only running and startup are in
+// notification so edits to other datastores are not sent.
+func (srv *Server) publishConfigChange(c ConfigChange) error {
+	var datastore string
+	switch c.Datastore {
+	case DatastoreRunning, "":
+		datastore = "running"
+	case DatastoreStartup:
+		datastore = "startup"
+	default:
+		return nil
+	}
+	m, err := configChangeMod()
+	if err != nil {
+		return err
+	}
+	b := node.NewBrowser(m, &nodeutil.Basic{
+		OnNotify: func(r node.NotifyRequest) (node.NotifyCloser, error) {
+			r.SendWhen(configChangeNode(c, datastore), c.Time)
+			return func() error { return nil }, nil
+		},
+	})
+	sel, err := b.Root().Find(configChangeNotification)
+	if err != nil {
+		return err
+	}
+	closer, err := sel.Notifications(func(n node.Notification) {
+		for _, s := range srv.eventStreams() {
+			if s.includesModule(configChangeModule) {
+				s.publish(m, n)
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return closer()
+}
+
+// configChangeNode is content of netconf-config-change
+func configChangeNode(c ConfigChange, datastore string) node.Node {
+	return &nodeutil.Basic{
+		OnChild: func(r node.ChildRequest) (node.Node, error) {
+			switch r.Meta.Ident() {
+			case "changed-by":
+				if c.Principal != nil {
+					return changedByNode(c), nil
+				}
+			case "edit":
+				if len(c.Edits) > 0 {
+					return configEditsNode(c.Edits), nil
+				}
+			}
+			return nil, nil
+		},
+		OnField: func(r node.FieldRequest, hnd *node.ValueHandle) (err error) {
+			if r.Meta.Ident() == "datastore" {
+				hnd.Val, err = node.NewValue(r.Meta.Type(), datastore)
+			}
+			return
+		},
+	}
+}
+
+// changedByNode is user that made change, RESTCONF has no sessions so
+// session-id is 0. RFC8040 Sec. 1.4
+func changedByNode(c ConfigChange) node.Node {
+	return &nodeutil.Basic{
+		OnField: func(r node.FieldRequest, hnd *node.ValueHandle) (err error) {
+			switch r.Meta.Ident() {
+			case "username":
+				hnd.Val = val.String(fmt.Sprint(c.Principal))
+			case "session-id":
+				hnd.Val = val.UInt32(0)
+			case "source-host":
+				if c.RemoteHost != "" {
+					hnd.Val = val.String(c.RemoteHost)
+				}
+			}
+			return
+		},
+	}
+}
+
+func configEditsNode(edits []ConfigEdit) node.Node {
+	return &nodeutil.Basic{
+		OnNext: func(r node.ListRequest) (node.Node, []val.Value, error) {
+			if r.Row >= len(edits) {
+				return nil, nil, nil
+			}
+			return configEditNode(edits[r.Row]), nil, nil
+		},
+	}
+}
+
+func configEditNode(e ConfigEdit) node.Node {
+	return &nodeutil.Basic{
+		OnField: func(r node.FieldRequest, hnd *node.ValueHandle) (err error) {
+			switch r.Meta.Ident() {
+			case "target":
+				hnd.Val = InstanceIdentifier(e.Target)
+			case "operation":
+				hnd.Val, err = node.NewValue(r.Meta.Type(), e.Operation)
+			}
+			return
+		},
+	}
+}
+
+// configChanged tells about edit request made to target
+func (hndlr *browserHandler) configChanged(ctx context.Context, r *http.Request, target *node.Selection, creating bool) {
+	switch r.Method {
+	case "POST", "PUT", "PATCH", "DELETE":
+	default:
+		return
+	}
+	datastore := hndlr.datastore
+	if datastore == "" {
+		datastore = DatastoreRunning
+	}
+	host, _ := ctx.Value(RemoteIpAddressKey).(string)
+	hndlr.changed(ConfigChange{
+		Module:    hndlr.browser.Meta.Ident(),
+		Datastore: datastore,
+		Edits: []ConfigEdit{{
+			Target:    instanceIdentifierOf(target.Path),
+			Operation: editOperation(r.Method, creating),
+		}},
+		Principal:  PrincipalOf(ctx),
+		RemoteHost: host,
+		Time:       hndlr.now(),
+	})
+}
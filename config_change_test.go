@@ -0,0 +1,88 @@
+package restconf
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+)
+
+func TestConfigChange(t *testing.T) {
+	mstr := `module x {
+		namespace "x";
+		prefix "x";
+		revision 0;
+		container a {
+			leaf b {
+				type string;
+			}
+			list e {
+				key f;
+				leaf f {
+					type string;
+				}
+				leaf g {
+					type int32;
+				}
+			}
+		}
+	}`
+	s, ts := newTestServer(t, mstr, `{"a":{"b":"B","e":[{"f":"one","g":1}]}}`)
+	defer ts.Close()
+	var changes []ConfigChange
+	s.OnConfigChange = func(c ConfigChange) {
+		changes = append(changes, c)
+	}
+	s.ConfigChangeNotifications = true
+	json := string(YangDataJsonMimeType)
+	url := ts.URL + "/restconf/data/x:a"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest("GET", "/restconf/streams/NETCONF/json", nil).WithContext(ctx)
+	req.Header.Set("Accept", string(TextStreamMimeType))
+	w := newFlushRecorder()
+	done := make(chan bool)
+	go func() {
+		s.ServeHTTP(w, req)
+		done <- true
+	}()
+	<-w.flushed
+
+	resp, _ := testRequest(t, "PUT", url+"/e=one", `{"x:e":[{"f":"one","g":2}]}`, "Content-Type", json)
+	fc.AssertEqual(t, 204, resp.StatusCode)
+	body := <-w.flushed
+	cancel()
+	<-done
+	fc.AssertEqual(t, `data: {"ietf-restconf:notification":{"eventTime":"`+changes[0].Time.Format(EventTimeFormat)+`",`+
+		`"event":{"datastore":"running","edit":[{"target":"/x:a/e[f='one']","operation":"replace"}]}}}`+"\n\n", body)
+
+	fc.AssertEqual(t, 1, len(changes))
+	fc.AssertEqual(t, "x", changes[0].Module)
+	fc.AssertEqual(t, DatastoreRunning, changes[0].Datastore)
+	fc.AssertEqual(t, "/x:a/e[f='one']", changes[0].Edits[0].Target)
+
+	t.Run("operations", func(t *testing.T) {
+		changes = nil
+		resp, _ := testRequest(t, "PATCH", url, `{"x:a":{"b":"B2"}}`, "Content-Type", json)
+		fc.AssertEqual(t, 200, resp.StatusCode)
+		resp, _ = testRequest(t, "POST", url+"/e", `{"x:e":[{"f":"two"}]}`, "Content-Type", json)
+		fc.AssertEqual(t, 201, resp.StatusCode)
+		resp, _ = testRequest(t, "DELETE", url+"/b", "")
+		fc.AssertEqual(t, 200, resp.StatusCode)
+		fc.RequireEqual(t, 3, len(changes))
+		fc.AssertEqual(t, ConfigEdit{Target: "/x:a", Operation: "merge"}, changes[0].Edits[0])
+		fc.AssertEqual(t, ConfigEdit{Target: "/x:a/e", Operation: "create"}, changes[1].Edits[0])
+		fc.AssertEqual(t, ConfigEdit{Target: "/x:a/b", Operation: "delete"}, changes[2].Edits[0])
+	})
+
+	t.Run("not changed", func(t *testing.T) {
+		changes = nil
+		testRequest(t, "GET", url, "", "Accept", json)
+		resp, _ := testRequest(t, "PATCH", url, `{"x:a":{"e":[{"f":"one","g":"bad"}]}}`, "Content-Type", json)
+		fc.AssertEqual(t, 500, resp.StatusCode)
+		resp, _ = testRequest(t, "PATCH", url+"?dry-run", `{"x:a":{"b":"B3"}}`, "Content-Type", json)
+		fc.AssertEqual(t, 204, resp.StatusCode)
+		fc.AssertEqual(t, 0, len(changes))
+	})
+}
@@ -0,0 +1,110 @@
+package restconf
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORS lets web pages served from other origins call RESTCONF directly from
+// a browser. Preflight requests are answered before authentication as
+// browsers do not send credentials with them.
+type CORS struct {
+	// Origins pages may be served from, e.g. https://ui.example.com. "*" is
+	// any origin
+	AllowedOrigins []string
+
+	// Default is every method RESTCONF supports
+	AllowedMethods []string
+
+	// Request headers pages may send. Default is the headers RESTCONF reads
+	// like Content-Type, Authorization and If-Match
+	AllowedHeaders []string
+
+	// Let pages send cookies, HTTP authentication or client certificates.
+	// Origin is sent back instead of "*" as browsers require
+	AllowCredentials bool
+
+	// Optional: How long browsers may keep preflight results. Default is the
+	// browser's own default
+	MaxAge time.Duration
+}
+
+var corsDefaultMethods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+
+// methods in Access-Control-Allow-Methods when there is no CORS
+var corsPermissiveMethods = []string{"GET", "HEAD", "POST", "PUT", "OPTIONS", "DELETE", "PATCH"}
+
+var corsDefaultHeaders = []string{
+	"Accept",
+	"Content-Type",
+	"Authorization",
+	"If-Match",
+	"If-None-Match",
+	"If-Modified-Since",
+	"If-Unmodified-Since",
+	"Last-Event-ID",
+	"Prefer",
+}
+
+// response headers browsers hide from pages unless they are listed
+var corsExposedHeaders = []string{"ETag", "Location", "Allow", "Accept-Patch", "Preference-Applied"}
+
+// handle adds CORS headers for requests from an allowed origin and is true
+// when request was a preflight request and nothing else is left to do. A
+// RESTCONF OPTIONS request is not a preflight as it has no
+// Access-Control-Request-Method header.
+func (c *CORS) handle(w http.ResponseWriter, r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return false
+	}
+	h := w.Header()
+	h.Add("Vary", "Origin")
+	preflight := r.Method == "OPTIONS" && r.Header.Get("Access-Control-Request-Method") != ""
+	if preflight {
+		h.Add("Vary", "Access-Control-Request-Method")
+		h.Add("Vary", "Access-Control-Request-Headers")
+	}
+	if c.allowed(origin) {
+		if c.AllowCredentials || !c.allowed("*") {
+			h.Set("Access-Control-Allow-Origin", origin)
+		} else {
+			h.Set("Access-Control-Allow-Origin", "*")
+		}
+		if c.AllowCredentials {
+			h.Set("Access-Control-Allow-Credentials", "true")
+		}
+		if preflight {
+			h.Set("Access-Control-Allow-Methods", strings.Join(orDefault(c.AllowedMethods, corsDefaultMethods), ", "))
+			h.Set("Access-Control-Allow-Headers", strings.Join(orDefault(c.AllowedHeaders, corsDefaultHeaders), ", "))
+			if c.MaxAge > 0 {
+				h.Set("Access-Control-Max-Age", strconv.Itoa(int(c.MaxAge.Seconds())))
+			}
+		} else {
+			h.Set("Access-Control-Expose-Headers", strings.Join(corsExposedHeaders, ", "))
+		}
+	}
+	if preflight {
+		// w/o allow headers browser refuses the actual request
+		w.WriteHeader(http.StatusNoContent)
+	}
+	return preflight
+}
+
+func (c *CORS) allowed(origin string) bool {
+	for _, candidate := range c.AllowedOrigins {
+		if candidate == origin || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+func orDefault(vals []string, def []string) []string {
+	if len(vals) == 0 {
+		return def
+	}
+	return vals
+}
@@ -0,0 +1,87 @@
+package restconf
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/freeconf/yang/fc"
+)
+
+func TestCORS(t *testing.T) {
+	s, ts := newTestServer(t, nestedYang, nestedData)
+	defer ts.Close()
+	s.BasicAuth = testAuth{"joe": "secret"}
+	s.CORS = &CORS{
+		AllowedOrigins:   []string{"https://ui.example.com"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	}
+	url := ts.URL + "/restconf/data/x:a"
+	joe := "Basic " + base64.StdEncoding.EncodeToString([]byte("joe:secret"))
+
+	t.Run("preflight", func(t *testing.T) {
+		resp, _ := testRequest(t, "OPTIONS", url, "",
+			"Origin", "https://ui.example.com",
+			"Access-Control-Request-Method", "PATCH",
+			"Access-Control-Request-Headers", "content-type, authorization")
+		fc.AssertEqual(t, 204, resp.StatusCode)
+		fc.AssertEqual(t, "https://ui.example.com", resp.Header.Get("Access-Control-Allow-Origin"))
+		fc.AssertEqual(t, "true", resp.Header.Get("Access-Control-Allow-Credentials"))
+		fc.AssertEqual(t, "GET, HEAD, POST, PUT, PATCH, DELETE, OPTIONS", resp.Header.Get("Access-Control-Allow-Methods"))
+		fc.AssertEqual(t, "Accept, Content-Type, Authorization, If-Match, If-None-Match, If-Modified-Since, If-Unmodified-Since, Last-Event-ID, Prefer", resp.Header.Get("Access-Control-Allow-Headers"))
+		fc.AssertEqual(t, "600", resp.Header.Get("Access-Control-Max-Age"))
+		fc.AssertEqual(t, "", resp.Header.Get("Allow"))
+	})
+
+	t.Run("preflight other origin", func(t *testing.T) {
+		resp, _ := testRequest(t, "OPTIONS", url, "",
+			"Origin", "https://evil.example.com",
+			"Access-Control-Request-Method", "DELETE")
+		fc.AssertEqual(t, 204, resp.StatusCode)
+		fc.AssertEqual(t, "", resp.Header.Get("Access-Control-Allow-Origin"))
+		fc.AssertEqual(t, "", resp.Header.Get("Access-Control-Allow-Methods"))
+	})
+
+	t.Run("restconf options", func(t *testing.T) {
+		resp, _ := testRequest(t, "OPTIONS", url, "",
+			"Origin", "https://ui.example.com",
+			"Authorization", joe)
+		fc.AssertEqual(t, 200, resp.StatusCode)
+		fc.AssertEqual(t, "GET, HEAD, POST, PUT, PATCH, DELETE, OPTIONS", resp.Header.Get("Allow"))
+		fc.AssertEqual(t, "https://ui.example.com", resp.Header.Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("get", func(t *testing.T) {
+		resp, actual := testRequest(t, "GET", url+"/b", "",
+			"Origin", "https://ui.example.com",
+			"Authorization", joe)
+		fc.AssertEqual(t, 200, resp.StatusCode)
+		fc.AssertEqual(t, `{"b":"B"}`, actual)
+		fc.AssertEqual(t, "https://ui.example.com", resp.Header.Get("Access-Control-Allow-Origin"))
+		fc.AssertEqual(t, "true", resp.Header.Get("Access-Control-Allow-Credentials"))
+		fc.AssertEqual(t, "ETag, Location, Allow, Accept-Patch, Preference-Applied", resp.Header.Get("Access-Control-Expose-Headers"))
+		fc.AssertEqual(t, "Origin", resp.Header.Get("Vary"))
+		fc.AssertEqual(t, "", resp.Header.Get("Access-Control-Allow-Methods"))
+
+		// page can read failures too
+		resp, _ = testRequest(t, "GET", url+"/b", "", "Origin", "https://ui.example.com")
+		fc.AssertEqual(t, 401, resp.StatusCode)
+		fc.AssertEqual(t, "https://ui.example.com", resp.Header.Get("Access-Control-Allow-Origin"))
+
+		resp, _ = testRequest(t, "GET", url+"/b", "",
+			"Origin", "https://evil.example.com",
+			"Authorization", joe)
+		fc.AssertEqual(t, 200, resp.StatusCode)
+		fc.AssertEqual(t, "", resp.Header.Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("any origin", func(t *testing.T) {
+		s.BasicAuth = nil
+		s.CORS = &CORS{AllowedOrigins: []string{"*"}}
+		resp, _ := testRequest(t, "GET", url+"/b", "", "Origin", "https://other.example.com")
+		fc.AssertEqual(t, 200, resp.StatusCode)
+		fc.AssertEqual(t, "*", resp.Header.Get("Access-Control-Allow-Origin"))
+		fc.AssertEqual(t, "", resp.Header.Get("Access-Control-Allow-Credentials"))
+	})
+}
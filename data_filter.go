@@ -0,0 +1,102 @@
+package restconf
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/val"
+	"github.com/freeconf/yang/xpath"
+)
+
+// dataFilter keeps the entries of a list that match the filter parameter and
+// prunes everything in target that does not lead to that list. RFC8040 only
+// defines filter for event streams so this is a freeconf extension. Path is
+// relative to target, segments up to the first list select the list and the
+// rest is evaluated against each entry.
+//
+//	GET .../data/car:garage?filter=cars/engine/speed>10
+type dataFilter struct {
+	list  *meta.List
+	match *xpath.Path
+
+	// nil when target is the list itself
+	prune *fieldsSelector
+}
+
+// newDataFilter compiles filter against target schema so mistakes are
+// reported instead of silently returning nothing
+func newDataFilter(target *node.Selection, expr string) (*dataFilter, error) {
+	m := meta.RootModule(target.Meta())
+	lookup := func(prefix string) (*meta.Module, error) {
+		if prefix == m.Ident() || prefix == m.Prefix() {
+			return m, nil
+		}
+		return nil, fmt.Errorf("%w. unknown module '%s' in filter", fc.BadRequestError, prefix)
+	}
+	p, err := xpath.Parse2(lookup, strings.TrimPrefix(expr, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("%w. filter '%s'. %s", fc.BadRequestError, expr, err)
+	}
+	if err := checkFilterPath(target.Meta(), p); err != nil {
+		return nil, fmt.Errorf("%w. filter '%s'. %s", fc.BadRequestError, expr, err)
+	}
+	f := &dataFilter{}
+	var idents []string
+	if list, isList := target.Meta().(*meta.List); isList && !target.InsideList {
+		f.list = list
+		f.match = p
+	} else {
+		parent := target.Meta().(meta.HasDefinitions)
+		for seg := p; seg != nil && f.list == nil; seg = seg.Next {
+			def := meta.Find(parent, seg.Ident)
+			idents = append(idents, seg.Ident)
+			if list, isList := def.(*meta.List); isList {
+				f.list = list
+				f.match = seg.Next
+			} else if parent, isList = def.(meta.HasDefinitions); !isList {
+				break
+			}
+		}
+	}
+	if f.list == nil {
+		return nil, fmt.Errorf("%w. filter '%s' does not select entries of a list", fc.BadRequestError, expr)
+	}
+	last := f.match
+	for last != nil && last.Next != nil {
+		last = last.Next
+	}
+	if last == nil || last.Expr == nil {
+		return nil, fmt.Errorf("%w. filter '%s' must compare a leaf of %s to a value", fc.BadRequestError, expr, f.list.Ident())
+	}
+	if len(idents) > 0 {
+		if f.prune, err = parseFields(strings.Join(idents, "/")); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+func (f *dataFilter) CheckContainerPreConstraints(r *node.ChildRequest) (bool, error) {
+	if f.prune == nil {
+		return true, nil
+	}
+	return f.prune.CheckContainerPreConstraints(r)
+}
+
+func (f *dataFilter) CheckFieldPreConstraints(r *node.FieldRequest, hnd *node.ValueHandle) (bool, error) {
+	if f.prune == nil {
+		return true, nil
+	}
+	return f.prune.CheckFieldPreConstraints(r, hnd)
+}
+
+func (f *dataFilter) CheckListPostConstraints(r node.ListRequest, child *node.Selection, key []val.Value) (bool, bool, error) {
+	if r.IsNavigation() || r.Meta != f.list {
+		return true, true, nil
+	}
+	match, err := child.XPredicate(f.match)
+	return true, match, err
+}
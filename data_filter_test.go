@@ -0,0 +1,70 @@
+package restconf
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+)
+
+func TestDataFilter(t *testing.T) {
+	_, ts := newTestServer(t, nestedYang, nestedData)
+	defer ts.Close()
+	rfc := string(YangDataJsonMimeType1)
+	get := func(path string, filter string) (int, string) {
+		resp, actual := testRequest(t, "GET", ts.URL+"/restconf/data/"+path+"?filter="+url.QueryEscape(filter), "", "Accept", rfc)
+		return resp.StatusCode, actual
+	}
+
+	tests := []struct {
+		path     string
+		filter   string
+		expected string
+	}{
+		{
+			path:     "x:a",
+			filter:   "c/e/g/h>1",
+			expected: `{"c":{"e":[{"f":"two","g":{"h":2}}]}}`,
+		},
+		{
+			path:     "x:a",
+			filter:   "/x:c/e/f='one'",
+			expected: `{"c":{"e":[{"f":"one","g":{"h":1}}]}}`,
+		},
+		{
+			path:     "x:a/c",
+			filter:   "e/g/h<=2",
+			expected: `{"e":[{"f":"one","g":{"h":1}},{"f":"two","g":{"h":2}}]}`,
+		},
+		{
+			path:     "x:a/c/e",
+			filter:   "f!='one'",
+			expected: `{"e":[{"f":"two","g":{"h":2}}]}`,
+		},
+		{
+			path:     "x:a/c/e",
+			filter:   "g/h=3",
+			expected: `{"e":[]}`,
+		},
+	}
+	for _, test := range tests {
+		status, actual := get(test.path, test.filter)
+		fc.AssertEqual(t, 200, status, test.filter)
+		fc.AssertEqual(t, test.expected, actual, test.filter)
+	}
+
+	for _, invalid := range []string{
+		"c/nope='x'",
+		"c/d='D'",
+		"c/e",
+		"c/e/g",
+		"c/e/g/h",
+		"c/e/g/h>",
+		"y:c/e/f='one'",
+	} {
+		status, actual := get("x:a", invalid)
+		fc.AssertEqual(t, 400, status, invalid)
+		fc.AssertEqual(t, true, strings.Contains(actual, "invalid-value"), invalid)
+	}
+}
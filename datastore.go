@@ -0,0 +1,79 @@
+package restconf
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/freeconf/restconf/device"
+	"github.com/freeconf/yang/fc"
+)
+
+// NMDA datastores, identities in ietf-datastores. RFC8342 Sec. 5
+const (
+	DatastoreRunning     = "ietf-datastores:running"
+	DatastoreCandidate   = "ietf-datastores:candidate"
+	DatastoreStartup     = "ietf-datastores:startup"
+	DatastoreIntended    = "ietf-datastores:intended"
+	DatastoreOperational = "ietf-datastores:operational"
+)
+
+// ServeDatastore serves data of d at {+restconf}/ds/{datastore} so, for
+// example, config in running and state in operational can each come from
+// their own node. Datastore is an identity like DatastoreOperational. Writes
+// to operational are rejected as it is read-only. Running is the main device
+// unless served here. RFC8527 Sec. 3.1
+//
+//	/restconf/ds/ietf-datastores:operational/car:engine
+func (srv *Server) ServeDatastore(datastore string, d device.Device) error {
+	if !strings.ContainsRune(datastore, ':') {
+		return fmt.Errorf("datastore '%s' must be qualified like %s", datastore, DatastoreOperational)
+	}
+	if srv.datastores == nil {
+		srv.datastores = make(map[string]device.Device)
+	}
+	srv.datastores[datastore] = d
+	return nil
+}
+
+// findDatastore is device serving datastore on main device
+func (srv *Server) findDatastore(deviceId string, datastore string) (device.Device, error) {
+	if deviceId != "" {
+		return nil, fmt.Errorf("%w. datastores are not served for device %s", fc.NotFoundError, deviceId)
+	}
+	if d, found := srv.datastores[datastore]; found {
+		return d, nil
+	}
+	if datastore == DatastoreRunning {
+		return srv.mainDevice(), nil
+	}
+	return nil, fmt.Errorf("%w. datastore %s", fc.NotFoundError, datastore)
+}
+
+func (srv *Server) serveDatastore(compliance ComplianceOptions, ctx context.Context, deviceId string, w http.ResponseWriter, r *http.Request, accept MimeType) {
+	datastore, p := shift(r.URL, '/')
+	d, err := srv.findDatastore(deviceId, datastore)
+	if err != nil {
+		handleErr(compliance, err, r, w, accept)
+		return
+	}
+	r.URL = p
+	if hndlr, p := srv.shiftBrowserHandler(compliance, r, d, w, r.URL, accept); hndlr != nil {
+		r.URL = p
+		hndlr.datastore = datastore
+		if datastore == DatastoreOperational {
+			hndlr.readOnly = readOnlyPaths{{}}
+		}
+		hndlr.ServeHTTP(compliance, ctx, w, r, endpointData)
+	}
+}
+
+// checkWithOrigin rejects with-origin on datastores other than operational.
+// RFC8527 Sec. 3.2.2
+func checkWithOrigin(datastore string, p QueryParams) error {
+	if p.WithOrigin && datastore != "" && datastore != DatastoreOperational {
+		return fmt.Errorf("%w. '%s' is only allowed on %s", fc.BadRequestError, withOriginParam, DatastoreOperational)
+	}
+	return nil
+}
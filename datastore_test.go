@@ -0,0 +1,75 @@
+package restconf
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/freeconf/restconf/device"
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+	"github.com/freeconf/yang/source"
+)
+
+func TestDatastores(t *testing.T) {
+	mstr := `module x {
+		namespace "x";
+		prefix "x";
+		revision 0;
+		container a {
+			leaf speed {
+				type int32;
+			}
+		}
+	}`
+	s, ts := newTestServer(t, mstr, `{"a":{"speed":100}}`)
+	defer ts.Close()
+	m, err := parser.LoadModuleFromString(nil, mstr)
+	fc.RequireEqual(t, nil, err)
+	oper := device.New(source.Dir("./yang"))
+	state := map[string]interface{}{
+		"a": map[string]interface{}{"speed": 10},
+	}
+	oper.AddBrowser(node.NewBrowser(m, nodeutil.ReflectChild(state)))
+	fc.RequireEqual(t, nil, s.ServeDatastore(DatastoreOperational, oper))
+	fc.AssertEqual(t, true, s.ServeDatastore("operational", oper) != nil)
+	json := string(YangDataJsonMimeType)
+	ds := ts.URL + "/restconf/ds/"
+
+	resp, actual := testRequest(t, "GET", ds+"ietf-datastores:running/x:a/speed", "", "Accept", json)
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	fc.AssertEqual(t, `{"speed":100}`, actual)
+	resp, actual = testRequest(t, "GET", ds+"ietf-datastores:operational/x:a/speed", "", "Accept", json)
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	fc.AssertEqual(t, `{"speed":10}`, actual)
+	_, actual = testRequest(t, "GET", ts.URL+"/restconf/data/x:a/speed", "", "Accept", json)
+	fc.AssertEqual(t, `{"speed":100}`, actual)
+
+	t.Run("write", func(t *testing.T) {
+		resp, _ := testRequest(t, "PUT", ds+"ietf-datastores:running/x:a/speed", `{"x:speed":200}`, "Content-Type", json)
+		fc.AssertEqual(t, 204, resp.StatusCode)
+		_, actual := testRequest(t, "GET", ds+"ietf-datastores:running/x:a/speed", "", "Accept", json)
+		fc.AssertEqual(t, `{"speed":200}`, actual)
+
+		resp, _ = testRequest(t, "PUT", ds+"ietf-datastores:operational/x:a/speed", `{"x:speed":20}`, "Content-Type", json)
+		fc.AssertEqual(t, 405, resp.StatusCode)
+		_, actual = testRequest(t, "GET", ds+"ietf-datastores:operational/x:a/speed", "", "Accept", json)
+		fc.AssertEqual(t, `{"speed":10}`, actual)
+	})
+
+	t.Run("with-origin", func(t *testing.T) {
+		resp, _ := testRequest(t, "GET", ds+"ietf-datastores:operational/x:a?with-origin", "", "Accept", json)
+		fc.AssertEqual(t, 200, resp.StatusCode)
+		resp, actual := testRequest(t, "GET", ds+"ietf-datastores:running/x:a?with-origin", "", "Accept", json)
+		fc.AssertEqual(t, 400, resp.StatusCode)
+		fc.AssertEqual(t, true, strings.Contains(actual, `"error-path":"x:a"`), actual)
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		resp, _ := testRequest(t, "GET", ds+"ietf-datastores:startup/x:a", "", "Accept", json)
+		fc.AssertEqual(t, 404, resp.StatusCode)
+		resp, _ = testRequest(t, "GET", ds+"ietf-datastores:operational/y:a", "", "Accept", json)
+		fc.AssertEqual(t, 404, resp.StatusCode)
+	})
+}
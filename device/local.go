@@ -1,10 +1,12 @@
 package device
 
 import (
+	"container/list"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"sync"
 
 	"github.com/freeconf/yang/fc"
 	"github.com/freeconf/yang/meta"
@@ -18,12 +20,25 @@ type Local struct {
 	browsers     map[string]*node.Browser
 	schemaSource source.Opener
 	uiSource     source.Opener
+
+	listenersMu sync.Mutex
+	listeners   *list.List
+}
+
+// ModuleListener is called after modules are added to or removed from a device
+type ModuleListener func()
+
+// ModuleNotifier is implemented by devices whose modules can change while
+// they are being served
+type ModuleNotifier interface {
+	OnModuleChange(l ModuleListener) nodeutil.Subscription
 }
 
 func New(schemaSource source.Opener) *Local {
 	return &Local{
 		schemaSource: schemaSource,
 		browsers:     make(map[string]*node.Browser),
+		listeners:    list.New(),
 	}
 }
 
@@ -32,6 +47,39 @@ func NewWithUi(schemaSource source.Opener, uiSource source.Opener) *Local {
 		schemaSource: schemaSource,
 		uiSource:     uiSource,
 		browsers:     make(map[string]*node.Browser),
+		listeners:    list.New(),
+	}
+}
+
+// OnModuleChange calls l every time a module is added or removed
+func (self *Local) OnModuleChange(l ModuleListener) nodeutil.Subscription {
+	self.listenersMu.Lock()
+	defer self.listenersMu.Unlock()
+	return &moduleSubscription{d: self, e: self.listeners.PushBack(l)}
+}
+
+type moduleSubscription struct {
+	d *Local
+	e *list.Element
+}
+
+func (sub *moduleSubscription) Close() error {
+	sub.d.listenersMu.Lock()
+	defer sub.d.listenersMu.Unlock()
+	sub.d.listeners.Remove(sub.e)
+	return nil
+}
+
+func (self *Local) modulesChanged() {
+	self.listenersMu.Lock()
+	var listeners []ModuleListener
+	for p := self.listeners.Front(); p != nil; p = p.Next() {
+		listeners = append(listeners, p.Value.(ModuleListener))
+	}
+	self.listenersMu.Unlock()
+	// listeners may unsubscribe or add modules so call them w/o lock
+	for _, l := range listeners {
+		l()
 	}
 }
 
@@ -64,6 +112,7 @@ func (self *Local) Add(module string, n node.Node) error {
 		return err
 	}
 	self.browsers[module] = node.NewBrowser(m, n)
+	self.modulesChanged()
 	return nil
 }
 
@@ -73,11 +122,21 @@ func (self *Local) AddSource(module string, src func() node.Node) error {
 		return err
 	}
 	self.browsers[module] = node.NewBrowserSource(m, src)
+	self.modulesChanged()
 	return nil
 }
 
 func (self *Local) AddBrowser(b *node.Browser) {
 	self.browsers[b.Meta.Ident()] = b
+	self.modulesChanged()
+}
+
+// Remove stops serving module
+func (self *Local) Remove(module string) {
+	if _, found := self.browsers[module]; found {
+		delete(self.browsers, module)
+		self.modulesChanged()
+	}
 }
 
 func (self *Local) ApplyStartupConfig(config io.Reader) error {
@@ -1,9 +1,13 @@
 package device
 
 import (
+	"fmt"
+	"hash/fnv"
 	"reflect"
+	"sort"
 	"strings"
 
+	"github.com/freeconf/yang/fc"
 	"github.com/freeconf/yang/meta"
 	"github.com/freeconf/yang/node"
 	"github.com/freeconf/yang/nodeutil"
@@ -27,6 +31,33 @@ func LocalDeviceYangLibNode(addresser ModuleAddresser, d Device) node.Node {
 			}
 			return nil, nil
 		},
+		OnNotify: func(r node.NotifyRequest) (node.NotifyCloser, error) {
+			notifier, canNotify := d.(ModuleNotifier)
+			if !canNotify {
+				return nil, fmt.Errorf("%w. %s", fc.NotImplementedError, r.Meta.Ident())
+			}
+			var field string
+			switch r.Meta.Ident() {
+			case "yang-library-update":
+				field = "content-id"
+			case "yang-library-change":
+				field = "module-set-id"
+			default:
+				return nil, nil
+			}
+			sub := notifier.OnModuleChange(func() {
+				id := YangLibContentId(d.Modules())
+				r.Send(&nodeutil.Basic{
+					OnField: func(r node.FieldRequest, hnd *node.ValueHandle) error {
+						if r.Meta.Ident() == field {
+							hnd.Val = val.String(id)
+						}
+						return nil
+					},
+				})
+			})
+			return sub.Close, nil
+		},
 	}
 }
 
@@ -43,6 +74,10 @@ func localYangLibModuleState(addresser ModuleAddresser, d Device) node.Node {
 			return nil, nil
 		},
 		OnField: func(r node.FieldRequest, hnd *node.ValueHandle) error {
+			switch r.Meta.Ident() {
+			case "module-set-id":
+				hnd.Val = val.String(YangLibContentId(d.Modules()))
+			}
 			return nil
 		},
 	}
@@ -95,6 +130,9 @@ func yangLibModuleHandleNode(addresser ModuleAddresser, m *meta.Module) node.Nod
 			case "namespace":
 				hnd.Val = val.String(m.Namespace())
 			case "feature":
+				if features := EnabledFeatures(m); len(features) > 0 {
+					hnd.Val = val.StringList(features)
+				}
 			case "conformance-type":
 			}
 			return nil
@@ -116,6 +154,10 @@ func localYangLibYangLibrary(addresser ModuleAddresser, d Device) node.Node {
 			return nil, nil
 		},
 		OnField: func(r node.FieldRequest, hnd *node.ValueHandle) error {
+			switch r.Meta.Ident() {
+			case "content-id":
+				hnd.Val = val.String(YangLibContentId(mods))
+			}
 			return nil
 		},
 	}
@@ -221,12 +263,54 @@ func yangLibModuleSetModuleHandleNode(addresser ModuleAddresser, m *meta.Module)
 			case "location":
 				hnd.Val = val.String(addresser(m))
 			case "feature":
+				if features := EnabledFeatures(m); len(features) > 0 {
+					hnd.Val = val.StringList(features)
+				}
 			}
 			return nil
 		},
 	}
 }
 
+// EnabledFeatures are the names of features in module that are on, sorted
+func EnabledFeatures(m *meta.Module) []string {
+	var features []string
+	for ident := range m.Features() {
+		if m.FeatureSet() != nil {
+			// only way to ask feature set is w/an if-feature. builder complains
+			// there is no parent but still builds it
+			var b meta.Builder
+			on, err := m.FeatureSet().Resolve(b.IfFeature(nil, ident))
+			if err != nil || !on {
+				continue
+			}
+		}
+		features = append(features, ident)
+	}
+	sort.Strings(features)
+	return features
+}
+
+// YangLibContentId identifies the modules, revisions and features in a module
+// set and changes when any of them change. RFC8525 Sec. 3
+func YangLibContentId(mods map[string]*meta.Module) string {
+	names := make([]string, 0, len(mods))
+	for name := range mods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	h := fnv.New64a()
+	for _, name := range names {
+		m := mods[name]
+		rev := ""
+		if m.Revision() != nil {
+			rev = m.Revision().Ident()
+		}
+		fmt.Fprintf(h, "%s@%s:%s;", name, rev, strings.Join(EnabledFeatures(m), ","))
+	}
+	return fmt.Sprintf("%x", h.Sum64())
+}
+
 type moduleSet struct {
 	ident            string
 	module           map[string]*meta.Module
@@ -8,7 +8,9 @@ import (
 	"github.com/freeconf/restconf/testdata"
 	"github.com/freeconf/yang/fc"
 	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
 	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
 )
 
 var update = flag.Bool("update", false, "update golden test files")
@@ -40,3 +42,43 @@ func TestYangLibNode(t *testing.T) {
 	}
 	fc.Gold(t, *update, []byte(actual), "gold/yang_lib.json")
 }
+
+func TestYangLibFeatures(t *testing.T) {
+	mstr := `module x {
+		namespace "x";
+		prefix "x";
+		revision 2020-03-04;
+		feature a;
+		feature b;
+		feature c;
+	}`
+	opts := parser.Options{Features: meta.FeaturesOn([]string{"a", "c"})}
+	m, err := parser.LoadModuleFromStringWithOptions(nil, mstr, opts)
+	fc.RequireEqual(t, nil, err)
+	d := device.New(testdata.YangPath)
+	d.AddBrowser(node.NewBrowser(m, &nodeutil.Basic{}))
+	moduleNameAsAddress := func(m *meta.Module) string {
+		return m.Ident()
+	}
+	fc.RequireEqual(t, nil, d.Add("ietf-yang-library", device.LocalDeviceYangLibNode(moduleNameAsAddress, d)))
+	b, err := d.Browser("ietf-yang-library")
+	fc.RequireEqual(t, nil, err)
+
+	sel, err := b.Root().Find("yang-library/module-set=all/module=x")
+	fc.RequireEqual(t, nil, err)
+	fc.RequireEqual(t, true, sel != nil)
+	actual, err := nodeutil.WriteJSON(sel)
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, `{"name":"x","revision":"2020-03-04","namespace":"x","location":"x","feature":["a","c"]}`, actual)
+
+	contentId := func() string {
+		v, err := b.Root().GetValue("yang-library/content-id")
+		fc.RequireEqual(t, nil, err)
+		return v.String()
+	}
+	before := contentId()
+	fc.AssertEqual(t, before, contentId())
+	bird, _ := testdata.BirdBrowser("")
+	d.AddBrowser(bird)
+	fc.AssertEqual(t, false, before == contentId())
+}
@@ -0,0 +1,112 @@
+package restconf
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+)
+
+// dryRunParam asks for a write request to be checked but not made
+//
+//	PUT .../data/car:engine?dry-run
+const dryRunParam = "dry-run"
+
+func dryRunOf(u *url.URL) bool {
+	v, has := u.Query()[dryRunParam]
+	return has && v[0] != "false"
+}
+
+// serveDryRun makes the edit in a copy of module's data that is thrown away.
+// freeconf nodes cannot roll back an edit so the copy is a plain in-memory
+// node w/same schema. Request is decoded and checked against schema,
+// constraints, access rules and preconditions same as any other edit but
+// checks only application nodes make when data is written are not.
+func (hndlr *browserHandler) serveDryRun(compliance ComplianceOptions, ctx context.Context, w http.ResponseWriter, r *http.Request, endpointId int) {
+	acceptType := hndlr.codecs.accepted(r.Header.Get("Accept"))
+	var err error
+	switch {
+	case r.Method != "PUT" && r.Method != "POST" && r.Method != "PATCH" && r.Method != "DELETE":
+		err = fmt.Errorf("%w. parameter '%s' not allowed with %s", fc.BadRequestError, dryRunParam, r.Method)
+	case endpointId != endpointData:
+		err = fmt.Errorf("%w. parameter '%s' is only allowed on data resources", fc.BadRequestError, dryRunParam)
+	}
+	if err != nil {
+		handleErr(compliance, err, r, w, acceptType)
+		return
+	}
+	scratch, err := hndlr.scratch(ctx)
+	if err != nil {
+		handleErr(compliance, err, r, w, acceptType)
+		return
+	}
+	dry := &dryRunWriter{ResponseWriter: w}
+	defer dry.finish()
+	scratch.ServeHTTP(compliance, ctx, dry, r, endpointId)
+}
+
+// scratch is handler like this one but on a copy of module's data
+func (hndlr *browserHandler) scratch(ctx context.Context) (*browserHandler, error) {
+	if hndlr.locks != nil {
+		unlock, err := hndlr.locks.lock(ctx, hndlr.browser, false, 0)
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+	}
+	var buf bytes.Buffer
+	if err := hndlr.browser.RootWithContext(ctx).UpsertInto(jsonEncoder(&buf, snapshotCompliance)); err != nil {
+		return nil, err
+	}
+	var vals map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &vals); err != nil {
+		return nil, err
+	}
+	b := node.NewBrowser(hndlr.browser.Meta, nodeutil.ReflectChild(vals))
+	copy := *hndlr
+	copy.browser = b
+	copy.modified = hndlr.modified.copyFor(hndlr.browser, b, hndlr.now())
+	copy.dryRun = true
+	copy.changed = nil
+	return &copy, nil
+}
+
+// dryRunWriter reports an edit that succeeded as 204 w/o content as nothing
+// was edited. Errors are sent as they are.
+type dryRunWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *dryRunWriter) WriteHeader(status int) {
+	if w.status != 0 {
+		return
+	}
+	w.status = status
+	if status < 300 {
+		// describe a resource that was not made or changed
+		for _, name := range []string{"Location", "ETag", "Last-Modified", "Content-Type"} {
+			w.Header().Del(name)
+		}
+		status = http.StatusNoContent
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *dryRunWriter) Write(data []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	if w.status < 300 {
+		return len(data), nil
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *dryRunWriter) finish() {
+	w.WriteHeader(http.StatusOK)
+}
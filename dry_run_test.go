@@ -0,0 +1,59 @@
+package restconf
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+)
+
+func TestDryRun(t *testing.T) {
+	_, ts := newTestServer(t, nestedYang, nestedData)
+	defer ts.Close()
+	url := ts.URL + "/restconf/data/"
+	json := []string{"Content-Type", string(YangDataJsonMimeType1), "Accept", string(YangDataJsonMimeType1)}
+	_, expected := testRequest(t, "GET", url+"x:a", "")
+	assertUnchanged := func(t *testing.T) {
+		t.Helper()
+		_, actual := testRequest(t, "GET", url+"x:a", "")
+		fc.AssertEqual(t, expected, actual)
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		resp, body := testRequest(t, "PUT", url+"x:a/c?dry-run=true", `{"x:c":{"d":"D2","e":[{"f":"three"}]}}`, json...)
+		fc.AssertEqual(t, 204, resp.StatusCode)
+		fc.AssertEqual(t, "", body)
+		assertUnchanged(t)
+
+		resp, _ = testRequest(t, "POST", url+"x:a/c/e?dry-run", `{"x:e":[{"f":"three"}]}`, json...)
+		fc.AssertEqual(t, 204, resp.StatusCode)
+		fc.AssertEqual(t, "", resp.Header.Get("Location"))
+		resp, _ = testRequest(t, "DELETE", url+"x:a/c/e=one?dry-run", "")
+		fc.AssertEqual(t, 204, resp.StatusCode)
+		assertUnchanged(t)
+
+		// false is the same as not asking
+		resp, _ = testRequest(t, "PATCH", url+"x:a?dry-run=false", `{"b":"B2"}`, json...)
+		fc.AssertEqual(t, 200, resp.StatusCode)
+		_, actual := testRequest(t, "GET", url+"x:a/b", "")
+		fc.AssertEqual(t, `{"b":"B2"}`, actual)
+		testRequest(t, "PATCH", url+"x:a", `{"b":"B"}`, json...)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		resp, body := testRequest(t, "PUT", url+"x:a/c?dry-run=true", `{"x:c":{"d":"D2","e":[{"f":"three","g":{"h":"not-a-number"}}]}}`, json...)
+		// same error as w/o dry-run
+		fc.AssertEqual(t, 500, resp.StatusCode)
+		fc.AssertEqual(t, true, strings.Contains(body, `"error-tag":"operation-failed"`), body)
+		assertUnchanged(t)
+
+		resp, _ = testRequest(t, "POST", url+"x:a/c/e?dry-run", `{"x:e":[{"f":"one"}]}`, json...)
+		fc.AssertEqual(t, 409, resp.StatusCode)
+		resp, _ = testRequest(t, "DELETE", url+"x:a/c/e=nope?dry-run", "")
+		fc.AssertEqual(t, 404, resp.StatusCode)
+
+		resp, _ = testRequest(t, "GET", url+"x:a?dry-run", "")
+		fc.AssertEqual(t, 400, resp.StatusCode)
+		assertUnchanged(t)
+	})
+}
@@ -0,0 +1,268 @@
+package restconf
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/patch/xml"
+)
+
+// RestconfError is implemented by errors that know which error-tag they should
+// be reported with. HTTP status code is derived from the tag.
+type RestconfError interface {
+	error
+	ErrorTag() string
+}
+
+// ErrUnknownElement is reported with error-tag "unknown-element"
+var ErrUnknownElement = errors.New("unknown element")
+
+// ErrOperationNotSupported is reported with error-tag "operation-not-supported"
+var ErrOperationNotSupported = errors.New("operation not supported")
+
+// ErrUnsupportedMediaType is when request content is in a format server cannot
+// read
+var ErrUnsupportedMediaType = errors.New("unsupported media type")
+
+// ErrNotAcceptable is when response cannot be written in any format request
+// accepts
+var ErrNotAcceptable = errors.New("not acceptable")
+
+// ErrRequestTooLarge is when request content is more than server accepts
+var ErrRequestTooLarge = errors.New("request too large")
+
+// ErrUnavailable is for nodes that cannot serve a request for now, like when
+// a backend is busy or a transaction cannot begin. Reported with 503 and
+// Retry-After so client tries again later
+var ErrUnavailable = errors.New("temporarily unavailable")
+
+// ErrInUse is when data cannot be edited as another request is editing it.
+// Reported with error-tag "in-use"
+var ErrInUse = errors.New("in use")
+
+// Error is a single error in an error response. Return this, or Errors, from
+// a node to control exactly what is reported to the client otherwise the
+// error-tag is derived from the error. RFC8040 Sec. 7.1
+type Error struct {
+	// transport, rpc, protocol or application. Default is protocol
+	Type string `json:"error-type" xml:"error-type"`
+
+	// in-use, invalid-value, data-missing, ... HTTP status code is derived
+	// from this. Default is derived from error.
+	Tag string `json:"error-tag" xml:"error-tag"`
+
+	// Optional: Identifies error within a module or implementation
+	AppTag string `json:"error-app-tag,omitempty" xml:"error-app-tag,omitempty"`
+
+	// Default is request path
+	Path string `json:"error-path" xml:"error-path"`
+
+	Message string `json:"error-message" xml:"error-message"`
+
+	// Optional: Anydata with more about the error. Maps are written as
+	// elements keyed by map key in XML, other values as encoding/xml would.
+	Info interface{} `json:"error-info,omitempty" xml:"-"`
+}
+
+// NewError is an error reported with error-tag and message. Set other fields
+// on the result as needed.
+func NewError(tag string, msg string) Error {
+	return Error{Tag: tag, Message: msg}
+}
+
+func (e Error) Error() string {
+	return e.Message
+}
+
+func (e Error) ErrorTag() string {
+	return e.Tag
+}
+
+func (e Error) MarshalXML(enc *xml.Encoder, start xml.StartElement) error {
+	// plain keeps MarshalXML from recursing
+	type plain Error
+	v := struct {
+		plain
+		Info *xmlInfo `xml:"error-info,omitempty"`
+	}{plain: plain(e)}
+	if e.Info != nil {
+		v.Info = &xmlInfo{e.Info}
+	}
+	return enc.EncodeElement(v, start)
+}
+
+type xmlInfo struct {
+	v interface{}
+}
+
+func (i *xmlInfo) MarshalXML(enc *xml.Encoder, start xml.StartElement) error {
+	return encodeXmlAnydata(enc, start, i.v)
+}
+
+func encodeXmlAnydata(enc *xml.Encoder, start xml.StartElement, v interface{}) error {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		if err := enc.EncodeToken(start); err != nil {
+			return err
+		}
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if err := encodeXmlAnydata(enc, xml.StartElement{Name: xml.Name{Local: k}}, x[k]); err != nil {
+				return err
+			}
+		}
+		return enc.EncodeToken(start.End())
+	case []interface{}:
+		for _, item := range x {
+			if err := encodeXmlAnydata(enc, start, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	case nil:
+		// JSON null
+		if err := enc.EncodeToken(start); err != nil {
+			return err
+		}
+		return enc.EncodeToken(start.End())
+	}
+	return enc.EncodeElement(v, start)
+}
+
+// Errors reports more than one error in a single response. HTTP status code
+// is derived from the first error's tag.
+type Errors []Error
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e Errors) ErrorTag() string {
+	if len(e) == 0 {
+		return ""
+	}
+	return e[0].Tag
+}
+
+// errorTagStatus is HTTP status code for error-tag. RFC8040 Sec. 7
+var errorTagStatus = map[string]int{
+	"in-use":                  http.StatusConflict,
+	"invalid-value":           http.StatusBadRequest,
+	"too-big":                 http.StatusRequestEntityTooLarge,
+	"missing-attribute":       http.StatusBadRequest,
+	"bad-attribute":           http.StatusBadRequest,
+	"unknown-attribute":       http.StatusBadRequest,
+	"bad-element":             http.StatusBadRequest,
+	"unknown-element":         http.StatusBadRequest,
+	"unknown-namespace":       http.StatusBadRequest,
+	"access-denied":           http.StatusForbidden,
+	"lock-denied":             http.StatusConflict,
+	"resource-denied":         http.StatusConflict,
+	"rollback-failed":         http.StatusInternalServerError,
+	"data-exists":             http.StatusConflict,
+	"data-missing":            http.StatusConflict,
+	"operation-not-supported": http.StatusMethodNotAllowed,
+	"operation-failed":        http.StatusInternalServerError,
+	"partial-operation":       http.StatusInternalServerError,
+	"malformed-message":       http.StatusBadRequest,
+	"missing-element":         http.StatusBadRequest,
+}
+
+// errorTags picks error-tag for errors that do not implement RestconfError,
+// first match wins so more specific errors are listed first. Status code
+// overrides the status code for the tag.
+var errorTags = []struct {
+	err    error
+	tag    string
+	status int
+}{
+	{err: ErrDataExists, tag: "data-exists"},
+	{err: ErrDataMissing, tag: "data-missing"},
+	{err: ErrMissingElement, tag: "missing-element"},
+	{err: ErrUnknownElement, tag: "unknown-element"},
+	{err: ErrOperationNotSupported, tag: "operation-not-supported"},
+	{err: ErrUnsupportedMediaType, tag: "invalid-value", status: http.StatusUnsupportedMediaType},
+	{err: ErrNotAcceptable, tag: "invalid-value", status: http.StatusNotAcceptable},
+	{err: ErrRequestTooLarge, tag: "too-big"},
+	{err: ErrUnauthenticated, tag: "access-denied", status: http.StatusUnauthorized},
+	{err: ErrReadOnly, tag: "access-denied", status: http.StatusMethodNotAllowed},
+	{err: fc.NotFoundError, tag: "invalid-value", status: http.StatusNotFound},
+	{err: os.ErrNotExist, tag: "invalid-value", status: http.StatusNotFound},
+	{err: fc.NotImplementedError, tag: "operation-not-supported", status: http.StatusNotImplemented},
+	{err: fc.UnauthorizedError, tag: "access-denied"},
+	{err: ErrInUse, tag: "in-use"},
+	{err: fc.ConflictError, tag: "in-use"},
+	{err: fc.BadRequestError, tag: "invalid-value"},
+	{err: context.DeadlineExceeded, tag: "operation-failed", status: http.StatusServiceUnavailable},
+	{err: ErrShuttingDown, tag: "operation-failed", status: http.StatusServiceUnavailable},
+	{err: ErrUnavailable, tag: "operation-failed", status: http.StatusServiceUnavailable},
+}
+
+// decodeError is the error-tag and HTTP status code for err
+func decodeError(err error) (string, int) {
+	var rerr RestconfError
+	if errors.As(err, &rerr) && rerr.ErrorTag() != "" {
+		return rerr.ErrorTag(), tagStatus(rerr.ErrorTag())
+	}
+	for _, candidate := range errorTags {
+		if errors.Is(err, candidate.err) {
+			if candidate.status != 0 {
+				return candidate.tag, candidate.status
+			}
+			return candidate.tag, tagStatus(candidate.tag)
+		}
+	}
+	return "operation-failed", http.StatusInternalServerError
+}
+
+func tagStatus(tag string) int {
+	if status, known := errorTagStatus[tag]; known {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// errorResponse is the errors to report and HTTP status code for err
+func errorResponse(err error, path string) ([]Error, int) {
+	var errs Errors
+	var one Error
+	if errors.As(err, &errs) && len(errs) > 0 {
+		// copy so defaults are not filled into caller's errors
+		errs = append(Errors{}, errs...)
+	} else if errors.As(err, &one) {
+		errs = Errors{one}
+	} else {
+		tag, code := decodeError(err)
+		return []Error{{
+			Type:    "protocol",
+			Tag:     tag,
+			Path:    path,
+			Message: err.Error(),
+		}}, code
+	}
+	for i := range errs {
+		if errs[i].Type == "" {
+			errs[i].Type = "protocol"
+		}
+		if errs[i].Tag == "" {
+			errs[i].Tag = "operation-failed"
+		}
+		if errs[i].Path == "" {
+			errs[i].Path = path
+		}
+	}
+	return errs, tagStatus(errs[0].Tag)
+}
@@ -0,0 +1,77 @@
+package restconf
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strings"
+
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+)
+
+// resourceEtag is a strong entity tag derived from the contents of the data
+// resource so any change to the resource changes the tag. RFC8040 Sec. 3.4.1.2
+func resourceEtag(sel *node.Selection) (string, error) {
+	h := fnv.New64a()
+	wtr := &nodeutil.JSONWtr{Out: h}
+	if err := sel.UpsertIntoSetDefaults(wtr.Node()); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(`"%x"`, h.Sum64()), nil
+}
+
+// etagMatches checks if etag is in the list of etags in an If-Match or
+// If-None-Match header.  Weak tags are compared as if they were strong
+func etagMatches(hdr string, etag string) bool {
+	for _, candidate := range strings.Split(hdr, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPreconditions evaluates If-Match and If-None-Match headers against the
+// current etag of the resource and returns http status code to respond with if
+// the request should not proceed or 0 if it should. RFC7232 Sec. 6
+func checkPreconditions(r *http.Request, etag string) int {
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" {
+		if !etagMatches(ifMatch, etag) {
+			return http.StatusPreconditionFailed
+		}
+	}
+	// POST target is the parent so "*" is checked against the child it
+	// creates instead, see createOnly
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" && !(r.Method == "POST" && createOnly(r)) {
+		if etagMatches(ifNoneMatch, etag) {
+			if r.Method == "GET" || r.Method == "HEAD" {
+				return http.StatusNotModified
+			}
+			return http.StatusPreconditionFailed
+		}
+	}
+	return 0
+}
+
+// createOnly is true for "If-None-Match: *" which is only creating resource
+// if it does not exist yet. RFC7232 Sec. 3.2
+func createOnly(r *http.Request) bool {
+	return strings.TrimSpace(r.Header.Get("If-None-Match")) == "*"
+}
+
+// updateEtag sets the etag header of the resource at given path
+func updateEtag(root *node.Selection, path string, hdr http.Header) error {
+	sel, err := root.Find(path)
+	if err != nil || sel == nil {
+		return err
+	}
+	defer sel.Release()
+	etag, err := resourceEtag(sel)
+	if err != nil {
+		return err
+	}
+	hdr.Set("ETag", etag)
+	return nil
+}
@@ -0,0 +1,160 @@
+package restconf
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+)
+
+// fieldsSelector implements the "fields" query parameter from RFC8040 Sec. 4.8.3
+//
+//	fields-expr = path "(" fields-expr ")" / path ";" fields-expr / path
+//	path = api-identifier [ "/" path ]
+//
+// Example:
+//
+//	fields=a;b/c;d(e;f)
+//
+// A node is selected if it is a selected node, a descendant of a selected node or
+// an ancestor of a selected node. Ancestors only include children that lead to a
+// selected node.
+type fieldsSelector struct {
+	all      bool
+	children map[string]*fieldsSelector
+}
+
+func parseFields(expr string) (*fieldsSelector, error) {
+	root := &fieldsSelector{}
+	l := fieldsLex{expr: expr}
+	if err := root.parse(&l, 0); err != nil {
+		return nil, fmt.Errorf("%w. invalid fields expression '%s'. %s", fc.BadRequestError, expr, err)
+	}
+	return root, nil
+}
+
+type fieldsLex struct {
+	expr string
+	pos  int
+}
+
+func (l *fieldsLex) done() bool {
+	return l.pos >= len(l.expr)
+}
+
+func (l *fieldsLex) peek() byte {
+	return l.expr[l.pos]
+}
+
+func (l *fieldsLex) ident() string {
+	end := strings.IndexAny(l.expr[l.pos:], "(;)/")
+	if end < 0 {
+		end = len(l.expr) - l.pos
+	}
+	s := l.expr[l.pos : l.pos+end]
+	l.pos += end
+	return s
+}
+
+func (s *fieldsSelector) parse(l *fieldsLex, nesting int) error {
+	for {
+		// path
+		target := s
+		for {
+			ident := l.ident()
+			if ident == "" {
+				return fmt.Errorf("missing identifier at position %d", l.pos)
+			}
+			target = target.child(ident)
+			if l.done() || l.peek() != '/' {
+				break
+			}
+			l.pos++
+		}
+
+		// optional nested expression
+		if !l.done() && l.peek() == '(' {
+			l.pos++
+			if err := target.parse(l, nesting+1); err != nil {
+				return err
+			}
+			if l.done() || l.peek() != ')' {
+				return fmt.Errorf("missing ')' at position %d", l.pos)
+			}
+			l.pos++
+		} else {
+			target.all = true
+		}
+
+		if l.done() {
+			if nesting > 0 {
+				return fmt.Errorf("missing ')' at position %d", l.pos)
+			}
+			return nil
+		}
+		switch l.peek() {
+		case ';':
+			l.pos++
+		case ')':
+			if nesting == 0 {
+				return fmt.Errorf("unexpected ')' at position %d", l.pos)
+			}
+			return nil
+		default:
+			return fmt.Errorf("unexpected '%c' at position %d", l.peek(), l.pos)
+		}
+	}
+}
+
+func (s *fieldsSelector) child(ident string) *fieldsSelector {
+	// module name is optional and only required to disambiguate augments so
+	// we match on identifier alone
+	if colon := strings.IndexRune(ident, ':'); colon >= 0 {
+		ident = ident[colon+1:]
+	}
+	if s.children == nil {
+		s.children = make(map[string]*fieldsSelector)
+	}
+	c, found := s.children[ident]
+	if !found {
+		c = &fieldsSelector{}
+		s.children[ident] = c
+	}
+	return c
+}
+
+func (s *fieldsSelector) matches(base *node.Path, candidate *node.Path) bool {
+	var idents []string
+	for p := candidate; p != nil && p.Meta != base.Meta; p = p.Parent {
+		// lists have 2 entries in a path, list node and list item node
+		if p.Parent != nil && p.Parent.Meta == p.Meta {
+			continue
+		}
+		idents = append(idents, p.Meta.Ident())
+	}
+	sel := s
+	for i := len(idents) - 1; i >= 0; i-- {
+		if sel.all {
+			return true
+		}
+		if sel = sel.children[idents[i]]; sel == nil {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *fieldsSelector) CheckContainerPreConstraints(r *node.ChildRequest) (bool, error) {
+	if r.IsNavigation() {
+		return true, nil
+	}
+	return s.matches(r.Base, r.Path), nil
+}
+
+func (s *fieldsSelector) CheckFieldPreConstraints(r *node.FieldRequest, hnd *node.ValueHandle) (bool, error) {
+	if r.IsNavigation() {
+		return true, nil
+	}
+	return s.matches(r.Base, r.Path), nil
+}
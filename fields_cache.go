@@ -0,0 +1,83 @@
+package restconf
+
+import (
+	"container/list"
+	"sync"
+)
+
+// DefaultFieldsCacheSize is the FieldsCacheSize of servers from NewHttpServe
+const DefaultFieldsCacheSize = 128
+
+// fieldsCache keeps the most recently used fields expressions compiled so
+// clients that poll with the same fields parameter do not parse it on every
+// request. Selectors are only read once parsed so requests share them.
+type fieldsCache struct {
+	size int
+
+	mu      sync.Mutex
+	recent  *list.List // front is most recently used
+	entries map[string]*list.Element
+}
+
+type fieldsCacheEntry struct {
+	expr     string
+	selector *fieldsSelector
+}
+
+func newFieldsCache(size int) *fieldsCache {
+	return &fieldsCache{
+		size:    size,
+		recent:  list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// parse is parseFields for expressions not in cache. Invalid expressions are
+// not kept. Nil cache parses every time.
+func (c *fieldsCache) parse(expr string) (*fieldsSelector, error) {
+	if c == nil {
+		return parseFields(expr)
+	}
+	c.mu.Lock()
+	if e, found := c.entries[expr]; found {
+		c.recent.MoveToFront(e)
+		c.mu.Unlock()
+		return e.Value.(*fieldsCacheEntry).selector, nil
+	}
+	c.mu.Unlock()
+
+	// parse outside lock, two requests racing on same expression only cost
+	// a duplicate parse
+	selector, err := parseFields(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, found := c.entries[expr]; found {
+		c.recent.MoveToFront(e)
+		return e.Value.(*fieldsCacheEntry).selector, nil
+	}
+	c.entries[expr] = c.recent.PushFront(&fieldsCacheEntry{expr: expr, selector: selector})
+	for c.recent.Len() > c.size {
+		oldest := c.recent.Back()
+		c.recent.Remove(oldest)
+		delete(c.entries, oldest.Value.(*fieldsCacheEntry).expr)
+	}
+	return selector, nil
+}
+
+// fieldsCache is created on first use so FieldsCacheSize can be set after
+// NewHttpServe. Changing size drops what is cached.
+func (srv *Server) fieldsCache() *fieldsCache {
+	srv.fieldsMu.Lock()
+	defer srv.fieldsMu.Unlock()
+	if srv.FieldsCacheSize <= 0 {
+		return nil
+	}
+	if srv.fields == nil || srv.fields.size != srv.FieldsCacheSize {
+		srv.fields = newFieldsCache(srv.FieldsCacheSize)
+	}
+	return srv.fields
+}
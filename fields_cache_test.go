@@ -0,0 +1,76 @@
+package restconf
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+)
+
+func TestFieldsCache(t *testing.T) {
+	c := newFieldsCache(2)
+	a, err := c.parse("a")
+	fc.RequireEqual(t, nil, err)
+	_, err = c.parse("b")
+	fc.RequireEqual(t, nil, err)
+
+	again, _ := c.parse("a")
+	fc.AssertEqual(t, true, a == again)
+
+	// b is least recently used
+	_, err = c.parse("c")
+	fc.RequireEqual(t, nil, err)
+	fc.AssertEqual(t, 2, c.recent.Len())
+	_, found := c.entries["b"]
+	fc.AssertEqual(t, false, found)
+	again, _ = c.parse("a")
+	fc.AssertEqual(t, true, a == again)
+
+	_, err = c.parse("a(")
+	fc.AssertEqual(t, true, err != nil)
+	_, found = c.entries["a("]
+	fc.AssertEqual(t, false, found)
+
+	var nilCache *fieldsCache
+	s, err := nilCache.parse("a")
+	fc.RequireEqual(t, nil, err)
+	fc.AssertEqual(t, true, s != a)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if _, err := c.parse(fmt.Sprintf("x%d", (i+j)%4)); err != nil {
+					t.Error(err)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	fc.AssertEqual(t, 2, c.recent.Len())
+	fc.AssertEqual(t, 2, len(c.entries))
+}
+
+func BenchmarkFieldsCache(b *testing.B) {
+	expr := "a/b(c;d/e(f;g));h;i/j/k"
+	b.Run("parse", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := parseFields(expr); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("cached", func(b *testing.B) {
+		c := newFieldsCache(DefaultFieldsCacheSize)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := c.parse(expr); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,28 @@
+package restconf
+
+import (
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+)
+
+func TestParseFields(t *testing.T) {
+	tests := []struct {
+		expr   string
+		hasErr bool
+	}{
+		{expr: "a"},
+		{expr: "a;b/c;d(e;f)"},
+		{expr: "x:a/b(c/d;e(f))"},
+		{expr: "", hasErr: true},
+		{expr: "a;", hasErr: true},
+		{expr: "a(b", hasErr: true},
+		{expr: "a)", hasErr: true},
+		{expr: "a//b", hasErr: true},
+		{expr: "a(b)c", hasErr: true},
+	}
+	for _, test := range tests {
+		_, err := parseFields(test.expr)
+		fc.AssertEqual(t, test.hasErr, err != nil, test.expr)
+	}
+}
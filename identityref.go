@@ -0,0 +1,252 @@
+package restconf
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/patch/xml"
+	"github.com/freeconf/yang/val"
+)
+
+// Identityref values are prefixed with the module of the identity when it is
+// not the module of the leaf. RFC7951 Sec. 6.8, RFC7950 Sec. 9.10.3
+//
+//	JSON  "kind":"vehicles:truck"
+//	XML   <kind xmlns:vehicles="urn:vehicles">vehicles:truck</kind>
+//
+// freeconf writes the prefix but XML also needs the prefix declared, see
+// xmlLeaves. When reading, freeconf drops any prefix so it is checked here
+// first.
+
+// identitiesNode checks identityref values in content before any of it is
+// read. Check gets the selection content is first read into as that is the
+// first time schema is known.
+type identitiesNode struct {
+	*nodeutil.Extend
+	check   func(sel *node.Selection) error
+	checked bool
+}
+
+func identitiesChecked(n node.Node, check func(sel *node.Selection) error) *identitiesNode {
+	c := &identitiesNode{check: check}
+	c.Extend = &nodeutil.Extend{
+		Base: n,
+		OnChild: func(parent node.Node, r node.ChildRequest) (node.Node, error) {
+			if err := c.checkIdentities(r.Selection); err != nil {
+				return nil, err
+			}
+			return parent.Child(r)
+		},
+		OnNext: func(parent node.Node, r node.ListRequest) (node.Node, []val.Value, error) {
+			if err := c.checkIdentities(r.Selection); err != nil {
+				return nil, nil, err
+			}
+			return parent.Next(r)
+		},
+		OnField: func(parent node.Node, r node.FieldRequest, hnd *node.ValueHandle) error {
+			if err := c.checkIdentities(r.Selection); err != nil {
+				return err
+			}
+			return parent.Field(r, hnd)
+		},
+		OnChoose: func(parent node.Node, sel *node.Selection, choice *meta.Choice) (*meta.ChoiceCase, error) {
+			if err := c.checkIdentities(sel); err != nil {
+				return nil, err
+			}
+			return parent.Choose(sel, choice)
+		},
+	}
+	return c
+}
+
+func (c *identitiesNode) checkIdentities(sel *node.Selection) error {
+	if c.checked {
+		return nil
+	}
+	c.checked = true
+	return c.check(sel)
+}
+
+// checkIdentities checks identityref values in content read into sel ahead
+// of time for edits like PUT that delete data before content is read
+func checkIdentities(sel *node.Selection, content node.Node) error {
+	if c, valid := content.(*identitiesNode); valid && sel != nil {
+		return c.checkIdentities(sel)
+	}
+	return nil
+}
+
+// readJsonNode reads values w/identityref prefixes checked. Content is nil
+// when values are all in memory.
+func readJsonNode(c *requestContent, values map[string]interface{}) (node.Node, error) {
+	return identitiesChecked(jsonValuesNode(c, values), func(sel *node.Selection) error {
+		if list, isList := sel.Meta().(*meta.List); isList && !sel.InsideList {
+			return checkJsonIdentities(c, list, values, true)
+		}
+		return checkJsonIdentities(c, sel.Meta(), values, false)
+	}), nil
+}
+
+// checkJsonIdentities checks values of children of parent, or of parent
+// itself when values hold entries of list parent
+func checkJsonIdentities(c *requestContent, parent meta.Definition, values map[string]interface{}, self bool) error {
+	for key, v := range values {
+		var def meta.Definition
+		ident := key
+		if colon := strings.IndexRune(key, ':'); colon >= 0 {
+			ident = key[colon+1:]
+		}
+		if self {
+			if ident == parent.Ident() {
+				def = parent
+			}
+		} else if p, valid := parent.(meta.HasDataDefinitions); valid {
+			def = meta.Find(p, ident)
+		}
+		if def == nil {
+			continue
+		}
+		if err := checkJsonIdentity(c, def, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkJsonIdentity(c *requestContent, def meta.Definition, v interface{}) error {
+	switch x := def.(type) {
+	case meta.Leafable:
+		v, err := c.jsonValue(v)
+		if err != nil {
+			return err
+		}
+		var ids []interface{}
+		if l, isList := v.([]interface{}); isList {
+			ids = l
+		} else {
+			ids = []interface{}{v}
+		}
+		for _, id := range ids {
+			if s, valid := id.(string); valid {
+				if err := checkIdentityRef(x, s, func(prefix string, m *meta.Module) bool {
+					return prefix == m.Ident()
+				}); err != nil {
+					return err
+				}
+			}
+		}
+	case *meta.List:
+		// entries are read only after a PUT deleted the list they replace
+		entries, valid := c.jsonEntries(v)
+		if !valid {
+			if v != nil {
+				return fmt.Errorf("%w. expected JSON array for %s", fc.BadRequestError, x.Ident())
+			}
+			return nil
+		}
+		for row := 0; ; row++ {
+			entry, found, err := entries(row)
+			if err != nil || !found {
+				return err
+			}
+			if obj, valid := entry.(map[string]interface{}); valid {
+				if err := checkJsonIdentities(c, x, obj, false); err != nil {
+					return err
+				}
+			}
+		}
+	default:
+		obj, valid, err := c.jsonObject(v)
+		if err != nil || !valid {
+			return err
+		}
+		return checkJsonIdentities(c, def, obj, false)
+	}
+	return nil
+}
+
+// readXmlNode reads content w/identityref prefixes checked
+func readXmlNode(in io.Reader) (node.Node, error) {
+	x, err := readXmlContent(in)
+	if err != nil {
+		return nil, err
+	}
+	return identitiesChecked(xmlValuesNode(x), func(sel *node.Selection) error {
+		_, isList := sel.Meta().(*meta.List)
+		return checkXmlIdentities(sel.Meta(), x, isList && !sel.InsideList)
+	}), nil
+}
+
+// checkXmlIdentities checks elements under x against children of parent, or
+// parent itself when elements are entries of list parent
+func checkXmlIdentities(parent meta.Definition, x *xmlElement, self bool) error {
+	return x.each(func(child *xmlElement) error {
+		var def meta.Definition
+		if self {
+			if child.name.Local == parent.Ident() {
+				def = parent
+			}
+		} else if p, valid := parent.(meta.HasDataDefinitions); valid {
+			def = meta.Find(p, child.name.Local)
+		}
+		switch y := def.(type) {
+		case nil:
+		case meta.Leafable:
+			return checkIdentityRef(y, strings.TrimSpace(string(child.content)), func(prefix string, m *meta.Module) bool {
+				if ns, declared := child.scope.prefixes[prefix]; declared {
+					return ns == m.Namespace()
+				}
+				// not proper XML but what freeconf used to write
+				return prefix == m.Ident() || prefix == m.Prefix()
+			})
+		default:
+			return checkXmlIdentities(y, child, false)
+		}
+		return nil
+	})
+}
+
+func xmlPrefixes(parent map[string]string, attrs []xml.Attr) map[string]string {
+	scope := parent
+	for _, a := range attrs {
+		if a.Name.Space != "xmlns" {
+			continue
+		}
+		if len(scope) == len(parent) {
+			// copy on first declaration so siblings do not see it
+			scope = make(map[string]string, len(parent)+1)
+			for k, v := range parent {
+				scope[k] = v
+			}
+		}
+		scope[a.Name.Local] = a.Value
+	}
+	return scope
+}
+
+// checkIdentityRef checks the prefix on an identityref value names the module
+// the identity is defined in. Unprefixed values are left to freeconf.
+func checkIdentityRef(leaf meta.Leafable, value string, names func(prefix string, m *meta.Module) bool) error {
+	f := leaf.Type().Format()
+	if f != val.FmtIdentityRef && f != val.FmtIdentityRefList {
+		return nil
+	}
+	colon := strings.IndexRune(value, ':')
+	if colon < 0 {
+		return nil
+	}
+	prefix, ident := value[:colon], value[colon+1:]
+	idty := meta.FindIdentity(leaf.Type().Base(), ident)
+	if idty == nil {
+		return nil
+	}
+	if m := meta.RootModule(idty); !names(prefix, m) {
+		return fmt.Errorf("%w. identity '%s' of %s is defined in '%s' not '%s'", fc.BadRequestError, ident, leaf.Ident(), m.Ident(), prefix)
+	}
+	return nil
+}
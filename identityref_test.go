@@ -0,0 +1,110 @@
+package restconf
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+	"github.com/freeconf/yang/source"
+)
+
+const vehiclesYang = `module vehicles {
+	namespace "urn:vehicles";
+	prefix "v";
+	revision 0;
+	identity vehicle;
+	identity truck {
+		base vehicle;
+	}
+}`
+
+const identityYang = `module x {
+	namespace "urn:x";
+	prefix "x";
+	revision 0;
+	import vehicles {
+		prefix "v";
+	}
+	identity car {
+		base v:vehicle;
+	}
+	container a {
+		leaf kind {
+			type identityref {
+				base v:vehicle;
+			}
+		}
+		list garage {
+			key id;
+			leaf id {
+				type string;
+			}
+			leaf kind {
+				type identityref {
+					base v:vehicle;
+				}
+			}
+		}
+	}
+}`
+
+func TestIdentityRef(t *testing.T) {
+	ypath := source.Named("vehicles", strings.NewReader(vehiclesYang))
+	m, err := parser.LoadModuleFromString(ypath, identityYang)
+	fc.RequireEqual(t, nil, err)
+	_, ts := newTestServerWithNode(t, m, nodeutil.ReflectChild(map[string]interface{}{}))
+	defer ts.Close()
+	url := ts.URL + "/restconf/data/x:a"
+	json := "application/yang-data+json"
+	xml := "application/yang-data+xml"
+
+	expectedJson := `{"kind":"vehicles:truck","garage":[{"id":"1","kind":"car"}]}`
+	expectedXml := `<a xmlns="urn:x"><kind xmlns:vehicles="urn:vehicles">vehicles:truck</kind><garage><id>1</id><kind>car</kind></garage></a>`
+
+	t.Run("json", func(t *testing.T) {
+		for _, body := range []string{
+			`{"x:a":{"kind":"vehicles:truck","garage":[{"id":"1","kind":"car"}]}}`,
+			`{"x:a":{"kind":"truck","garage":[{"id":"1","kind":"x:car"}]}}`,
+		} {
+			resp, _ := testRequest(t, "PUT", url, body, "Content-Type", json)
+			fc.AssertEqual(t, true, resp.StatusCode < 300)
+			_, actual := testRequest(t, "GET", url, "", "Accept", json)
+			fc.AssertEqual(t, expectedJson, actual)
+		}
+	})
+
+	t.Run("xml", func(t *testing.T) {
+		for _, body := range []string{
+			expectedXml,
+			`<a xmlns="urn:x"><kind>truck</kind><garage><id>1</id><kind xmlns:y="urn:x">y:car</kind></garage></a>`,
+		} {
+			resp, _ := testRequest(t, "PUT", url, body, "Content-Type", xml)
+			fc.AssertEqual(t, true, resp.StatusCode < 300)
+			_, actual := testRequest(t, "GET", url, "", "Accept", xml)
+			fc.AssertEqual(t, expectedXml, actual)
+		}
+	})
+
+	t.Run("wrong module", func(t *testing.T) {
+		for _, c := range []struct {
+			contentType string
+			body        string
+		}{
+			{json, `{"x:a":{"kind":"x:truck"}}`},
+			{json, `{"x:a":{"garage":[{"id":"1","kind":"vehicles:car"}]}}`},
+			{xml, `<a xmlns="urn:x"><kind xmlns:y="urn:x">y:truck</kind></a>`},
+			{xml, `<a xmlns="urn:x"><garage><id>1</id><kind>bogus:car</kind></garage></a>`},
+		} {
+			resp, _ := testRequest(t, "PUT", url, c.body, "Content-Type", c.contentType)
+			fc.AssertEqual(t, 400, resp.StatusCode)
+		}
+		resp, _ := testRequest(t, "PATCH", url, `{"kind":"bogus:truck"}`, "Content-Type", json)
+		fc.AssertEqual(t, 400, resp.StatusCode)
+		resp, _ = testRequest(t, "PUT", url+"/garage=1", `{"x:garage":[{"id":"1","kind":"vehicles:car"}]}`, "Content-Type", json)
+		fc.AssertEqual(t, 400, resp.StatusCode)
+		_, actual := testRequest(t, "GET", url, "", "Accept", json)
+		fc.AssertEqual(t, expectedJson, actual)
+	})
+}
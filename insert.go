@@ -0,0 +1,401 @@
+package restconf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/val"
+)
+
+// ErrMissingElement is reported with error-tag "missing-element"
+var ErrMissingElement = errors.New("missing element")
+
+// RFC8040 Sec. 4.8.5 and Sec. 4.8.6
+const (
+	insertParam  = "insert"
+	pointParam   = "point"
+	insertFirst  = "first"
+	insertLast   = "last"
+	insertBefore = "before"
+	insertAfter  = "after"
+)
+
+// insertPoint is where new entries of "ordered-by user" lists and leaf-lists go.
+//
+// freeconf nodes have no notion of position so lists are reordered by removing
+// all the entries and adding them back in the requested order.
+type insertPoint struct {
+	insert string
+	point  []string
+
+	// schema path, w/o module and keys, of list point is an entry of. Path is
+	// from module when absolute otherwise it is relative to the edit. Nil when
+	// only key is known.
+	list     []string
+	absolute bool
+}
+
+// newInsertPoint returns nil when there is no insert parameter
+func newInsertPoint(insert string, point string) (*insertPoint, error) {
+	if insert == "" {
+		if point != "" {
+			return nil, fmt.Errorf("%w. point parameter requires insert parameter", fc.BadRequestError)
+		}
+		return nil, nil
+	}
+	p := &insertPoint{insert: insert}
+	switch insert {
+	case insertFirst, insertLast:
+	case insertBefore, insertAfter:
+		if point == "" {
+			return nil, fmt.Errorf("%w. %w. point parameter required when insert is '%s'", fc.BadRequestError, ErrMissingElement, insert)
+		}
+		var err error
+		if p.list, p.absolute, p.point, err = parsePoint(point); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w. insert must be first, last, before or after, got '%s'", fc.BadRequestError, insert)
+	}
+	return p, nil
+}
+
+// parsePoint picks the list and the key of the entry the point resource
+// identifier is for. Point is absolute when first segment has a module.
+//
+//	/example-jukebox:jukebox/library/artist=Foo%20Fighters/album=Wasting%20Light
+//	  => [jukebox library artist album], true, [Wasting Light]
+func parsePoint(point string) ([]string, bool, []string, error) {
+	segs := strings.Split(strings.TrimPrefix(point, "/"), "/")
+	last := segs[len(segs)-1]
+	eq := strings.IndexByte(last, '=')
+	if eq < 0 {
+		return nil, false, nil, fmt.Errorf("%w. point '%s' does not identify a list or leaf-list entry", fc.BadRequestError, point)
+	}
+	key, err := splitListKeys(last[eq+1:])
+	if err != nil {
+		return nil, false, nil, err
+	}
+	list := make([]string, len(segs))
+	absolute := false
+	for i, seg := range segs {
+		if eq := strings.IndexByte(seg, '='); eq >= 0 {
+			seg = seg[:eq]
+		}
+		seg = unescapePath(seg)
+		if colon := strings.IndexRune(seg, ':'); colon >= 0 {
+			absolute = absolute || i == 0
+			seg = seg[colon+1:]
+		}
+		list[i] = seg
+	}
+	return list, absolute, key, nil
+}
+
+// isEntryOf is true when point can be an entry of list, or leaf-list, m
+// under sel
+func (p *insertPoint) isEntryOf(sel *node.Selection, m meta.Definition) bool {
+	if p.list == nil {
+		return true
+	}
+	idents := append(pathIdents(sel.Path), m.Ident())
+	if p.absolute {
+		return equalKeys(idents, p.list)
+	}
+	return len(p.list) <= len(idents) && equalKeys(idents[len(idents)-len(p.list):], p.list)
+}
+
+// checkPoint fails when point is not an existing entry of list m under sel so
+// request fails before anything is added. When m is not known yet it is the
+// list point names. Entries are from before the edit.
+func (p *insertPoint) checkPoint(sel *node.Selection, m meta.Definition, before map[string]*orderedEntries) error {
+	if p.point == nil || p.list == nil {
+		return nil
+	}
+	ident := p.list[len(p.list)-1]
+	if m != nil {
+		ident = m.Ident()
+	}
+	e := before[ident]
+	if e == nil || !p.isEntryOf(sel, e.m) {
+		return fmt.Errorf("%w. point '%s' is not an entry of an \"ordered-by user\" list in %s", fc.BadRequestError, strings.Join(p.list, "/"), sel.Path)
+	}
+	if e.find(p.point) < 0 {
+		return fmt.Errorf("%w. point '%s' not found in %s", fc.BadRequestError, strings.Join(p.point, ","), e.m.Ident())
+	}
+	return nil
+}
+
+// checkMove is checkPoint for moving entry
+func (p *insertPoint) checkMove(entry *node.Selection) error {
+	parent := entry.Parent()
+	if parent == nil || parent.Parent() == nil {
+		return nil
+	}
+	e, err := readEntries(parent.Parent(), entry.Meta())
+	if err != nil {
+		return err
+	}
+	return p.checkPoint(parent.Parent(), e.m, map[string]*orderedEntries{e.m.Ident(): e})
+}
+
+// orderedEntries are the entries of an "ordered-by user" list or leaf-list. For
+// lists data is each entry serialized so it can be added back
+type orderedEntries struct {
+	m    meta.Definition
+	keys [][]string
+	data [][]byte
+}
+
+func (e *orderedEntries) find(key []string) int {
+	for i, candidate := range e.keys {
+		if equalKeys(candidate, key) {
+			return i
+		}
+	}
+	return -1
+}
+
+func equalKeys(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func isOrderedByUser(m meta.Definition) bool {
+	switch x := m.(type) {
+	case *meta.List:
+		return x.OrderedBy() == meta.OrderedByUser
+	case *meta.LeafList:
+		return x.OrderedBy() == meta.OrderedByUser
+	}
+	return false
+}
+
+// orderedParent is the selection holding the entries being edited. For
+// POSTs to a list that is the list's parent
+func orderedParent(sel *node.Selection) *node.Selection {
+	if meta.IsList(sel.Meta()) && !sel.InsideList {
+		return sel.Parent()
+	}
+	return sel
+}
+
+// readOrderedEntries reads all "ordered-by user" lists and leaf-lists directly
+// under sel
+func readOrderedEntries(sel *node.Selection) (map[string]*orderedEntries, error) {
+	entries := make(map[string]*orderedEntries)
+	parent, valid := sel.Meta().(meta.HasDataDefinitions)
+	if !valid {
+		return entries, nil
+	}
+	for _, m := range parent.DataDefinitions() {
+		if !isOrderedByUser(m) {
+			continue
+		}
+		e, err := readEntries(sel, m)
+		if err != nil {
+			return nil, err
+		}
+		entries[m.Ident()] = e
+	}
+	return entries, nil
+}
+
+func readEntries(sel *node.Selection, m meta.Definition) (*orderedEntries, error) {
+	e := &orderedEntries{m: m}
+	if ll, isLeafList := m.(*meta.LeafList); isLeafList {
+		v, err := sel.GetValue(ll.Ident())
+		if err != nil || v == nil {
+			return e, err
+		}
+		for _, item := range listValueStrings(v) {
+			e.keys = append(e.keys, []string{item})
+		}
+		return e, nil
+	}
+	listSel, err := sel.Find(m.Ident())
+	if err != nil || listSel == nil {
+		return e, err
+	}
+	defer listSel.Release()
+	item, err := listSel.First()
+	for item.Selection != nil && err == nil {
+		var key []string
+		for _, k := range item.Key {
+			key = append(key, k.String())
+		}
+		var buf bytes.Buffer
+		if err = item.Selection.UpsertInto(jsonEncoder(&buf, snapshotCompliance)); err != nil {
+			return nil, err
+		}
+		e.keys = append(e.keys, key)
+		e.data = append(e.data, buf.Bytes())
+		item, err = item.Next()
+	}
+	return e, err
+}
+
+func listValueStrings(v val.Value) []string {
+	if l, isList := v.(val.Listable); isList {
+		strs := make([]string, l.Len())
+		for i := range strs {
+			strs[i] = l.Item(i).String()
+		}
+		return strs
+	}
+	return []string{v.String()}
+}
+
+// place moves entries that are in after but not before to the insert point.
+func (p *insertPoint) place(sel *node.Selection, before map[string]*orderedEntries) error {
+	after, err := readOrderedEntries(sel)
+	if err != nil {
+		return err
+	}
+	for ident, now := range after {
+		was := before[ident]
+		var added []int
+		for i, key := range now.keys {
+			if was.find(key) < 0 {
+				added = append(added, i)
+			}
+		}
+		if len(added) == 0 {
+			continue
+		}
+		if err := p.reorder(sel, was, now, added); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// moveEntry moves an existing list entry, for PUT on a list entry
+func (p *insertPoint) moveEntry(entry *node.Selection) error {
+	parent := entry.Parent()
+	if parent == nil || parent.Parent() == nil {
+		return nil
+	}
+	sel := parent.Parent()
+	m := entry.Meta()
+	now, err := readEntries(sel, m)
+	if err != nil {
+		return err
+	}
+	var key []string
+	for _, k := range entry.Key() {
+		key = append(key, k.String())
+	}
+	moved := now.find(key)
+	if moved < 0 {
+		return nil
+	}
+	was := &orderedEntries{m: m}
+	was.keys = append(was.keys, now.keys[:moved]...)
+	was.keys = append(was.keys, now.keys[moved+1:]...)
+	return p.reorder(sel, was, now, []int{moved})
+}
+
+// reorder entries so added entries, indexes into now, are placed relative to
+// entries in was
+func (p *insertPoint) reorder(sel *node.Selection, was *orderedEntries, now *orderedEntries, added []int) error {
+	isAdded := make(map[int]bool)
+	for _, i := range added {
+		isAdded[i] = true
+	}
+	rest := &orderedEntries{m: now.m}
+	if _, isLeafList := now.m.(*meta.LeafList); isLeafList {
+		// leaf-list nodes may replace the values instead of adding to them
+		// so existing values come from before the edit
+		rest.keys = was.keys
+	} else {
+		for i := range now.keys {
+			if !isAdded[i] {
+				rest.keys = append(rest.keys, now.keys[i])
+				rest.data = append(rest.data, now.data[i])
+			}
+		}
+	}
+
+	pos := len(rest.keys)
+	switch p.insert {
+	case insertFirst:
+		pos = 0
+	case insertBefore, insertAfter:
+		if !p.isEntryOf(sel, now.m) {
+			return fmt.Errorf("%w. point '%s' is not an entry of %s", fc.BadRequestError, strings.Join(p.list, "/"), now.m.Ident())
+		}
+		if pos = rest.find(p.point); pos < 0 {
+			return fmt.Errorf("%w. point '%s' not found in %s", fc.BadRequestError, strings.Join(p.point, ","), now.m.Ident())
+		}
+		if p.insert == insertAfter {
+			pos++
+		}
+	}
+	ordered := &orderedEntries{m: now.m}
+	ordered.keys = append(ordered.keys, rest.keys[:pos]...)
+	for _, i := range added {
+		ordered.keys = append(ordered.keys, now.keys[i])
+	}
+	ordered.keys = append(ordered.keys, rest.keys[pos:]...)
+
+	if ll, isLeafList := now.m.(*meta.LeafList); isLeafList {
+		vals := make([]string, len(ordered.keys))
+		for i, key := range ordered.keys {
+			vals[i] = key[0]
+		}
+		v, err := node.NewValue(ll.Type(), vals)
+		if err != nil {
+			return err
+		}
+		leafSel, err := sel.Find(ll.Ident())
+		if err != nil {
+			return err
+		}
+		defer leafSel.Release()
+		return leafSel.Set(v)
+	}
+
+	inOrder := true
+	for i, key := range ordered.keys {
+		inOrder = inOrder && equalKeys(key, now.keys[i])
+	}
+	if inOrder {
+		return nil
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `{"%s":[`, now.m.Ident())
+	for i, key := range ordered.keys {
+		if i > 0 {
+			buf.WriteRune(',')
+		}
+		buf.Write(now.data[now.find(key)])
+	}
+	buf.WriteString("]}")
+	listSel, err := sel.Find(now.m.Ident())
+	if err != nil {
+		return err
+	}
+	err = listSel.Delete()
+	listSel.Release()
+	if err != nil {
+		return err
+	}
+	n, err := jsonDecoder(&buf)
+	if err != nil {
+		return err
+	}
+	return sel.UpsertFrom(n)
+}
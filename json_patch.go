@@ -0,0 +1,391 @@
+package restconf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+)
+
+// RFC6902 Sec. 4
+const (
+	jsonPatchAdd     = "add"
+	jsonPatchRemove  = "remove"
+	jsonPatchReplace = "replace"
+	jsonPatchMove    = "move"
+	jsonPatchCopy    = "copy"
+	jsonPatchTest    = "test"
+)
+
+// jsonPatchOp is a single operation of an RFC6902 JSON Patch. Paths are JSON
+// pointers into the JSON encoding of the target resource so list entries are
+// addressed by their position
+//
+//	{"op":"replace", "path":"/e/0/f", "value":"two"}
+type jsonPatchOp struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+func readJsonPatch(in io.Reader) ([]*jsonPatchOp, error) {
+	var ops []*jsonPatchOp
+	if err := json.NewDecoder(in).Decode(&ops); err != nil {
+		return nil, fmt.Errorf("%w. invalid json-patch. %s", fc.BadRequestError, err)
+	}
+	return ops, nil
+}
+
+// applyJsonPatch applies operations in order stopping at first operation that
+// fails. Operations that were applied are undone so data is left as it was.
+func applyJsonPatch(sel *node.Selection, ops []*jsonPatchOp) error {
+	var undos []func() error
+	for i, op := range ops {
+		undo, err := op.apply(sel)
+		if err != nil {
+			for j := len(undos) - 1; j >= 0; j-- {
+				if uerr := undos[j](); uerr != nil {
+					fc.Err.Printf("could not undo json-patch operation. %s", uerr)
+				}
+			}
+			return fmt.Errorf("json-patch operation %d '%s' on %s. %w", i, op.Op, op.Path, err)
+		}
+		undos = append(undos, undo)
+	}
+	return nil
+}
+
+// apply operation and return function that will undo the operation
+func (op *jsonPatchOp) apply(sel *node.Selection) (func() error, error) {
+	value := op.Value
+	var from *jsonPointer
+	switch op.Op {
+	case jsonPatchAdd, jsonPatchReplace, jsonPatchTest:
+		if len(value) == 0 {
+			return nil, fmt.Errorf("%w. %w. value", fc.BadRequestError, ErrMissingElement)
+		}
+	case jsonPatchMove, jsonPatchCopy:
+		var err error
+		if from, err = resolveJsonPointer(sel, op.From, false); err != nil {
+			return nil, err
+		}
+		var found bool
+		if value, found, err = from.read(sel); err != nil {
+			return nil, err
+		} else if !found {
+			return nil, fmt.Errorf("%w. %w. %s", fc.ConflictError, ErrDataMissing, op.From)
+		}
+	case jsonPatchRemove:
+	default:
+		return nil, fmt.Errorf("%w. unknown json-patch operation '%s'", fc.BadRequestError, op.Op)
+	}
+	adding := op.Op == jsonPatchAdd || op.Op == jsonPatchMove || op.Op == jsonPatchCopy
+	to, err := resolveJsonPointer(sel, op.Path, adding)
+	if err != nil {
+		return nil, err
+	}
+	if to.list != nil {
+		// new entry, it is identified by key in value
+		if to.ident, err = newEntryIdent(to.list, value); err != nil {
+			return nil, err
+		}
+	}
+	if op.Op == jsonPatchTest {
+		actual, found, err := to.read(sel)
+		if err != nil {
+			return nil, err
+		}
+		if !found || !jsonEqual(actual, value) {
+			return nil, fmt.Errorf("%w. test failed on %s", fc.ConflictError, op.Path)
+		}
+		return func() error { return nil }, nil
+	}
+	parent, err := to.parentSelection(sel)
+	if err != nil {
+		return nil, err
+	}
+	undo, existed, err := snapshotResource(parent, to.ident)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case to.list != nil && existed:
+		return nil, fmt.Errorf("%w. %w. %s", fc.ConflictError, ErrDataExists, op.Path)
+	case !existed && (op.Op == jsonPatchRemove || op.Op == jsonPatchReplace):
+		return nil, fmt.Errorf("%w. %w. %s", fc.ConflictError, ErrDataMissing, op.Path)
+	}
+	if op.Op == jsonPatchMove {
+		fromParent, err := from.parentSelection(sel)
+		if err != nil {
+			return nil, err
+		}
+		undoFrom, _, err := snapshotResource(fromParent, from.ident)
+		if err != nil {
+			return nil, err
+		}
+		undoTo := undo
+		undo = func() error {
+			if err := undoTo(); err != nil {
+				return err
+			}
+			return undoFrom()
+		}
+		err = deleteResource(fromParent, from.ident)
+	}
+	if err == nil && existed {
+		err = deleteResource(parent, to.ident)
+	}
+	if err == nil && op.Op != jsonPatchRemove {
+		err = to.write(parent, value)
+	}
+	if err != nil {
+		// operation may be partially applied
+		if uerr := undo(); uerr != nil {
+			fc.Err.Printf("could not undo json-patch operation. %s", uerr)
+		}
+		return nil, err
+	}
+	return undo, nil
+}
+
+// jsonPointer is a JSON pointer resolved to a resource relative to the target
+// of the request
+type jsonPointer struct {
+	// path to parent of resource, empty when parent is target itself
+	parent string
+
+	// identifier of resource in parent including key for list entries
+	//
+	//	e=one
+	ident string
+
+	// list a new entry is added to and, optionally, key of entry new entry
+	// goes in front of
+	list   *meta.List
+	before []string
+}
+
+// resolveJsonPointer translates each position of a list entry in pointer to
+// the entry's key. When adding, pointer may be the position after the last
+// entry or "-" to add a new entry to the end of a list.
+//
+//	/e/1/f => e=two/f
+func resolveJsonPointer(sel *node.Selection, pointer string, adding bool) (*jsonPointer, error) {
+	if pointer == "" {
+		return nil, fmt.Errorf("%w. json-patch cannot edit whole target, use PUT instead", fc.BadRequestError)
+	}
+	if pointer[0] != '/' {
+		return nil, fmt.Errorf("%w. invalid json pointer '%s'", fc.BadRequestError, pointer)
+	}
+	parentMeta, valid := sel.Meta().(meta.HasDataDefinitions)
+	if !valid || (meta.IsList(sel.Meta()) && !sel.InsideList) {
+		return nil, fmt.Errorf("%w. json-patch target must be a container or list entry", fc.BadRequestError)
+	}
+	segs := strings.Split(pointer[1:], "/")
+	var path []string
+	for i := 0; i < len(segs); i++ {
+		ident := unescapeJsonPointer(segs[i])
+		if colon := strings.IndexByte(ident, ':'); colon >= 0 {
+			ident = ident[colon+1:]
+		}
+		if parentMeta == nil {
+			return nil, fmt.Errorf("%w. %w. %s", fc.BadRequestError, ErrUnknownElement, pointer)
+		}
+		def := meta.Find(parentMeta, ident)
+		if def == nil {
+			return nil, fmt.Errorf("%w. %w. %s", fc.BadRequestError, ErrUnknownElement, pointer)
+		}
+		seg := def.Ident()
+		if _, isLeafList := def.(*meta.LeafList); isLeafList && i+1 < len(segs) {
+			return nil, fmt.Errorf("%w. leaf-list entries cannot be edited individually, edit the whole leaf-list instead. %s", fc.BadRequestError, pointer)
+		}
+		if list, isList := def.(*meta.List); isList && i+1 < len(segs) {
+			i++
+			last := i == len(segs)-1
+			parentPath := strings.Join(path, "/")
+			parent, err := (&jsonPointer{parent: parentPath}).parentSelection(sel)
+			if err != nil {
+				return nil, err
+			}
+			entries, err := readEntries(parent, list)
+			if err != nil {
+				return nil, err
+			}
+			index := unescapeJsonPointer(segs[i])
+			if index == "-" {
+				if !adding || !last {
+					return nil, fmt.Errorf("%w. '-' is only allowed when adding an entry. %s", fc.BadRequestError, pointer)
+				}
+				return &jsonPointer{parent: parentPath, list: list}, nil
+			}
+			n, err := strconv.Atoi(index)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("%w. '%s' is not a position in list %s", fc.BadRequestError, index, list.Ident())
+			}
+			if adding && last && n <= len(entries.keys) {
+				p := &jsonPointer{parent: parentPath, list: list}
+				if n < len(entries.keys) {
+					p.before = entries.keys[n]
+				}
+				return p, nil
+			}
+			if n >= len(entries.keys) {
+				return nil, fmt.Errorf("%w. %w. %s", fc.ConflictError, ErrDataMissing, pointer)
+			}
+			seg += "=" + joinListKeys(entries.keys[n])
+		}
+		path = append(path, seg)
+		parentMeta, _ = def.(meta.HasDataDefinitions)
+	}
+	return &jsonPointer{
+		parent: strings.Join(path[:len(path)-1], "/"),
+		ident:  path[len(path)-1],
+	}, nil
+}
+
+// unescapeJsonPointer undoes escaping of '/' and '~' in a segment of a JSON
+// pointer. RFC6901 Sec. 4
+func unescapeJsonPointer(seg string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(seg, "~1", "/"), "~0", "~")
+}
+
+func (p *jsonPointer) parentSelection(sel *node.Selection) (*node.Selection, error) {
+	if p.parent == "" {
+		return sel, nil
+	}
+	parent, err := sel.Find(p.parent)
+	if err != nil {
+		return nil, err
+	}
+	if parent == nil {
+		return nil, fmt.Errorf("%w. %w. /%s", fc.ConflictError, ErrDataMissing, p.parent)
+	}
+	return parent, nil
+}
+
+// read resource as it would appear in JSON encoding of the target
+func (p *jsonPointer) read(sel *node.Selection) (json.RawMessage, bool, error) {
+	parent, err := p.parentSelection(sel)
+	if err != nil {
+		return nil, false, err
+	}
+	if strings.IndexByte(p.ident, '=') >= 0 {
+		entry, err := parent.Find(p.ident)
+		if err != nil || entry == nil {
+			return nil, false, err
+		}
+		defer entry.Release()
+		var buf bytes.Buffer
+		if err = entry.UpsertInto(jsonEncoder(&buf, snapshotCompliance)); err != nil {
+			return nil, false, err
+		}
+		return buf.Bytes(), true, nil
+	}
+	var buf bytes.Buffer
+	if err = parent.UpsertInto(jsonEncoder(&buf, snapshotCompliance)); err != nil {
+		return nil, false, err
+	}
+	var members map[string]json.RawMessage
+	if err = json.Unmarshal(buf.Bytes(), &members); err != nil {
+		return nil, false, err
+	}
+	for name, v := range members {
+		if name == p.ident || strings.HasSuffix(name, ":"+p.ident) {
+			return v, true, nil
+		}
+	}
+	return nil, false, nil
+}
+
+// write value into parent where value is resource as it would appear in JSON
+// encoding of the target
+func (p *jsonPointer) write(parent *node.Selection, value json.RawMessage) error {
+	var before map[string]*orderedEntries
+	if p.before != nil && isOrderedByUser(p.list) {
+		var err error
+		if before, err = readOrderedEntries(parent); err != nil {
+			return err
+		}
+	}
+	var data string
+	if eq := strings.IndexByte(p.ident, '='); eq >= 0 {
+		data = fmt.Sprintf(`{"%s":[%s]}`, p.ident[:eq], value)
+	} else {
+		data = fmt.Sprintf(`{"%s":%s}`, p.ident, value)
+	}
+	n, err := jsonDecoder(strings.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("%w. invalid value. %s", fc.BadRequestError, err)
+	}
+	if err = parent.UpsertFrom(n); err != nil {
+		return err
+	}
+	if before != nil {
+		ins := &insertPoint{insert: insertBefore, point: p.before}
+		return ins.place(parent, before)
+	}
+	return nil
+}
+
+// newEntryIdent is the identifier of the list entry in value
+//
+//	{"f":"one"} => e=one
+func newEntryIdent(list *meta.List, value json.RawMessage) (string, error) {
+	d := json.NewDecoder(bytes.NewReader(value))
+	d.UseNumber()
+	var entry map[string]interface{}
+	if err := d.Decode(&entry); err != nil {
+		return "", fmt.Errorf("%w. list entry must be an object. %s", fc.BadRequestError, err)
+	}
+	var key []string
+	for _, k := range list.KeyMeta() {
+		v, found := entry[k.Ident()]
+		if !found {
+			return "", fmt.Errorf("%w. %w. key %s of new %s entry", fc.BadRequestError, ErrMissingElement, k.Ident(), list.Ident())
+		}
+		key = append(key, fmt.Sprint(v))
+	}
+	return list.Ident() + "=" + joinListKeys(key), nil
+}
+
+// jsonEqual compares JSON values ignoring formatting and member order. Numbers
+// and strings with the same text are equal because 64 bit numbers are encoded
+// as strings. RFC7951 Sec. 6.1
+func jsonEqual(a json.RawMessage, b json.RawMessage) bool {
+	decode := func(data json.RawMessage) (interface{}, bool) {
+		d := json.NewDecoder(bytes.NewReader(data))
+		d.UseNumber()
+		var v interface{}
+		if err := d.Decode(&v); err != nil {
+			return nil, false
+		}
+		return normalizeJsonNumbers(v), true
+	}
+	av, aok := decode(a)
+	bv, bok := decode(b)
+	return aok && bok && reflect.DeepEqual(av, bv)
+}
+
+func normalizeJsonNumbers(v interface{}) interface{} {
+	switch x := v.(type) {
+	case json.Number:
+		return x.String()
+	case map[string]interface{}:
+		for k, item := range x {
+			x[k] = normalizeJsonNumbers(item)
+		}
+	case []interface{}:
+		for i, item := range x {
+			x[i] = normalizeJsonNumbers(item)
+		}
+	}
+	return v
+}
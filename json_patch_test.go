@@ -0,0 +1,98 @@
+package restconf
+
+import (
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+)
+
+func TestJsonPatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		patch    string
+		status   int
+		expected string
+	}{
+		{
+			name: "add",
+			patch: `[
+				{"op":"add", "path":"/c/e/-", "value":{"f":"three","g":{"h":3}}},
+				{"op":"add", "path":"/c/e/0/g/h", "value":10},
+				{"op":"add", "path":"/b", "value":"B2"}
+			]`,
+			status:   200,
+			expected: `{"b":"B2","c":{"d":"D","e":[{"f":"one","g":{"h":10}},{"f":"two","g":{"h":2}},{"f":"three","g":{"h":3}}]}}`,
+		},
+		{
+			name: "remove",
+			patch: `[
+				{"op":"remove", "path":"/c/e/1"},
+				{"op":"remove", "path":"/c/d"}
+			]`,
+			status:   200,
+			expected: `{"b":"B","c":{"e":[{"f":"one","g":{"h":1}}]}}`,
+		},
+		{
+			name: "replace",
+			patch: `[
+				{"op":"replace", "path":"/c/e/1/g", "value":{"h":22}},
+				{"op":"replace", "path":"/x:b", "value":"B2"}
+			]`,
+			status:   200,
+			expected: `{"b":"B2","c":{"d":"D","e":[{"f":"one","g":{"h":1}},{"f":"two","g":{"h":22}}]}}`,
+		},
+		{
+			name: "move-copy",
+			patch: `[
+				{"op":"copy", "from":"/b", "path":"/c/d"},
+				{"op":"move", "from":"/c/e/0/g", "path":"/c/e/1/g"}
+			]`,
+			status:   200,
+			expected: `{"b":"B","c":{"d":"B","e":[{"f":"one"},{"f":"two","g":{"h":1}}]}}`,
+		},
+		{
+			name: "test",
+			patch: `[
+				{"op":"test", "path":"/c/e/1", "value":{"g":{"h":2},"f":"two"}},
+				{"op":"replace", "path":"/b", "value":"B2"}
+			]`,
+			status:   200,
+			expected: `{"b":"B2","c":{"d":"D","e":[{"f":"one","g":{"h":1}},{"f":"two","g":{"h":2}}]}}`,
+		},
+		{
+			name: "failed-test",
+			patch: `[
+				{"op":"replace", "path":"/b", "value":"B2"},
+				{"op":"remove", "path":"/c/e/0"},
+				{"op":"test", "path":"/c/d", "value":"not D"}
+			]`,
+			status: 409,
+			// restored entry goes to end of list that is not ordered-by user
+			expected: `{"b":"B","c":{"d":"D","e":[{"f":"two","g":{"h":2}},{"f":"one","g":{"h":1}}]}}`,
+		},
+		{
+			name:     "missing",
+			patch:    `[{"op":"remove", "path":"/c/e/5"}]`,
+			status:   409,
+			expected: `{"b":"B","c":{"d":"D","e":[{"f":"one","g":{"h":1}},{"f":"two","g":{"h":2}}]}}`,
+		},
+		{
+			name:     "unknown",
+			patch:    `[{"op":"add", "path":"/nope", "value":1}]`,
+			status:   400,
+			expected: `{"b":"B","c":{"d":"D","e":[{"f":"one","g":{"h":1}},{"f":"two","g":{"h":2}}]}}`,
+		},
+	}
+	rfc := string(YangDataJsonMimeType1)
+	for _, test := range tests {
+		_, ts := newTestServer(t, nestedYang, nestedData)
+		resp, actual := testRequest(t, "PATCH", ts.URL+"/restconf/data/x:a", test.patch,
+			"Content-Type", string(JsonPatchMimeType), "Accept", rfc)
+		fc.AssertEqual(t, test.status, resp.StatusCode, test.name, actual)
+
+		// failed patches leave data unchanged
+		_, actual = testRequest(t, "GET", ts.URL+"/restconf/data/x:a", "", "Accept", rfc)
+		fc.AssertEqual(t, test.expected, actual, test.name)
+		ts.Close()
+	}
+}
@@ -0,0 +1,292 @@
+package restconf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+)
+
+// contentMemoryBytes is how much request content is kept in memory, larger
+// content is kept in a temporary file
+var contentMemoryBytes int64 = 1 << 20
+
+// requestContent is request content that decoders read back from by offset
+// so only the part of it the editor is reading is decoded. Content is read
+// once before anything is decoded so it can be checked before edits like PUT
+// delete data.
+type requestContent struct {
+	io.ReaderAt
+	size int64
+}
+
+// readContent keeps content in memory up to contentMemoryBytes and the rest
+// in a temporary file so memory does not grow w/size of content. Readers that
+// can be read by offset already are read in place.
+func readContent(in io.Reader) (*requestContent, error) {
+	if r, valid := in.(interface {
+		io.ReaderAt
+		Len() int
+		Size() int64
+	}); valid && int64(r.Len()) == r.Size() {
+		return &requestContent{ReaderAt: r, size: r.Size()}, nil
+	}
+	var buf bytes.Buffer
+	n, err := io.CopyN(&buf, in, contentMemoryBytes+1)
+	if err != nil && err != io.EOF {
+		// reader errors like content being too large are kept as is
+		return nil, err
+	}
+	if n <= contentMemoryBytes {
+		return &requestContent{ReaderAt: bytes.NewReader(buf.Bytes()), size: n}, nil
+	}
+	f, err := os.CreateTemp("", "restconf-content-")
+	if err != nil {
+		return nil, err
+	}
+	// nothing is left behind where an open file can be removed
+	removed := os.Remove(f.Name()) == nil
+	c := &requestContent{ReaderAt: f}
+	runtime.SetFinalizer(c, func(*requestContent) {
+		f.Close()
+		if !removed {
+			os.Remove(f.Name())
+		}
+	})
+	if _, err = buf.WriteTo(f); err != nil {
+		return nil, err
+	}
+	if n, err = io.Copy(f, in); err != nil {
+		return nil, err
+	}
+	c.size = contentMemoryBytes + 1 + n
+	return c, nil
+}
+
+func (c *requestContent) reader(off int64) io.Reader {
+	return io.NewSectionReader(c, off, c.size-off)
+}
+
+// jsonDecoder reads JSON request content as the editor asks for it. Only the
+// members of the objects being read are held in memory, objects and arrays
+// in them are read from content when they are read themselves.
+func jsonDecoder(in io.Reader) (node.Node, error) {
+	c, values, err := readJsonContent(in)
+	if err != nil {
+		return nil, err
+	}
+	return readJsonNode(c, values)
+}
+
+// readJsonContent is the members of the JSON object in content. All of
+// content is checked to be JSON.
+func readJsonContent(in io.Reader) (*requestContent, map[string]interface{}, error) {
+	c, err := readContent(in)
+	if err != nil {
+		return nil, nil, err
+	}
+	d := json.NewDecoder(c.reader(0))
+	t, err := d.Token()
+	if err != nil {
+		return nil, nil, err
+	}
+	var values map[string]interface{}
+	if t == json.Delim('{') {
+		values, err = readJsonMembers(d, 0)
+	} else {
+		_, err = jsonMemberOf(d, 0, t)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if values == nil {
+		return nil, nil, fmt.Errorf("%w. expected JSON object", fc.BadRequestError)
+	}
+	if _, err := d.Token(); err == nil {
+		return nil, nil, fmt.Errorf("%w. content after JSON object", fc.BadRequestError)
+	} else if err != io.EOF {
+		return nil, nil, err
+	}
+	return c, values, nil
+}
+
+// jsonRef is an object or array in content that is read when it is used
+type jsonRef struct {
+	off   int64
+	delim json.Delim
+}
+
+// readJsonMembers reads members of the object d is in w/objects and arrays
+// left in content. Offsets in d are from base.
+func readJsonMembers(d *json.Decoder, base int64) (map[string]interface{}, error) {
+	obj := make(map[string]interface{})
+	for d.More() {
+		k, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		if obj[k.(string)], err = readJsonMember(d, base); err != nil {
+			return nil, err
+		}
+	}
+	_, err := d.Token()
+	return obj, err
+}
+
+func readJsonMember(d *json.Decoder, base int64) (interface{}, error) {
+	t, err := d.Token()
+	if err != nil {
+		return nil, err
+	}
+	return jsonMemberOf(d, base, t)
+}
+
+func jsonMemberOf(d *json.Decoder, base int64, t json.Token) (interface{}, error) {
+	delim, composite := t.(json.Delim)
+	if !composite {
+		return t, nil
+	}
+	// delimiter was the last byte read
+	ref := jsonRef{off: base + d.InputOffset() - 1, delim: delim}
+	for depth := 1; depth > 0; {
+		t, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+	}
+	return ref, nil
+}
+
+// jsonValue is v w/any object or array read from content. Content is nil
+// when values are all in memory.
+func (c *requestContent) jsonValue(v interface{}) (interface{}, error) {
+	ref, isRef := v.(jsonRef)
+	if !isRef {
+		return v, nil
+	}
+	return readJsonValue(json.NewDecoder(c.reader(ref.off)))
+}
+
+// jsonObject is the members of object v
+func (c *requestContent) jsonObject(v interface{}) (map[string]interface{}, bool, error) {
+	if ref, isRef := v.(jsonRef); isRef {
+		if ref.delim != '{' {
+			return nil, false, nil
+		}
+		d := json.NewDecoder(c.reader(ref.off))
+		if _, err := d.Token(); err != nil {
+			return nil, false, err
+		}
+		obj, err := readJsonMembers(d, ref.off)
+		return obj, err == nil, err
+	}
+	obj, valid := v.(map[string]interface{})
+	return obj, valid, nil
+}
+
+// jsonEntries is the entries of array v by row, found is false past the last
+// entry. Entries in content are read one at a time.
+type jsonEntries func(row int) (entry interface{}, found bool, err error)
+
+func (c *requestContent) jsonEntries(v interface{}) (jsonEntries, bool) {
+	if ref, isRef := v.(jsonRef); isRef {
+		if ref.delim != '[' {
+			return nil, false
+		}
+		return c.jsonArray(ref), true
+	}
+	l, valid := v.([]interface{})
+	if !valid {
+		return nil, false
+	}
+	return func(row int) (interface{}, bool, error) {
+		if row >= len(l) {
+			return nil, false, nil
+		}
+		return l[row], true, nil
+	}, true
+}
+
+// jsonArray reads entries in order and keeps the last entry read as it is
+// usually asked for again. Entries before that are read again from the
+// start of the array.
+func (c *requestContent) jsonArray(ref jsonRef) jsonEntries {
+	var d *json.Decoder
+	var entry interface{}
+	last := -1
+	return func(row int) (interface{}, bool, error) {
+		if d == nil || row < last {
+			d = json.NewDecoder(c.reader(ref.off))
+			if _, err := d.Token(); err != nil {
+				return nil, false, err
+			}
+			last, entry = -1, nil
+		}
+		for last < row {
+			if !d.More() {
+				return nil, false, nil
+			}
+			t, err := d.Token()
+			if err == nil {
+				if t == json.Delim('{') {
+					entry, err = readJsonMembers(d, ref.off)
+				} else {
+					entry, err = jsonMemberOf(d, ref.off, t)
+				}
+			}
+			if err != nil {
+				return nil, false, err
+			}
+			last++
+		}
+		return entry, true, nil
+	}
+}
+
+// readJsonValue is the next value in d w/the same types encoding/json would
+// decode into
+func readJsonValue(d *json.Decoder) (interface{}, error) {
+	t, err := d.Token()
+	if err != nil {
+		// reader errors like content being too large are kept as is
+		return nil, err
+	}
+	switch t {
+	case json.Delim('{'):
+		obj := make(map[string]interface{})
+		for d.More() {
+			k, err := d.Token()
+			if err != nil {
+				return nil, err
+			}
+			if obj[k.(string)], err = readJsonValue(d); err != nil {
+				return nil, err
+			}
+		}
+		_, err = d.Token()
+		return obj, err
+	case json.Delim('['):
+		list := []interface{}{}
+		for d.More() {
+			v, err := readJsonValue(d)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		_, err = d.Token()
+		return list, err
+	}
+	return t, nil
+}
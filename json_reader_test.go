@@ -0,0 +1,182 @@
+package restconf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+)
+
+// largeNestedData is nestedData with many list entries
+func largeNestedData(entries int) string {
+	var e []string
+	for i := 0; i < entries; i++ {
+		e = append(e, fmt.Sprintf(`{"f":"entry-%d","g":{"h":%d}}`, i, i))
+	}
+	return fmt.Sprintf(`{"b":"B","c":{"d":"D","e":[%s]}}`, strings.Join(e, ","))
+}
+
+// readNested is content as nestedYang has it written as JSON
+func readNested(t testing.TB, content node.Node) map[string]interface{} {
+	t.Helper()
+	m, err := parser.LoadModuleFromString(nil, nestedYang)
+	fc.RequireEqual(t, nil, err)
+	actual, err := nodeutil.WriteJSON(node.NewBrowser(m, content).Root())
+	fc.RequireEqual(t, nil, err)
+	var values map[string]interface{}
+	fc.RequireEqual(t, nil, json.Unmarshal([]byte(actual), &values))
+	return values
+}
+
+func TestReadJsonContent(t *testing.T) {
+	data := largeNestedData(5000)
+	var expected map[string]interface{}
+	fc.RequireEqual(t, nil, json.Unmarshal([]byte(`{"a":`+data+`}`), &expected))
+	defer func(memory int64) { contentMemoryBytes = memory }(contentMemoryBytes)
+	// in memory and in a file
+	for _, memory := range []int64{contentMemoryBytes, 1024} {
+		contentMemoryBytes = memory
+		content, err := jsonDecoder(io.MultiReader(strings.NewReader(`{"a":` + data + `}`)))
+		fc.RequireEqual(t, nil, err)
+		fc.AssertEqual(t, true, reflect.DeepEqual(expected, readNested(t, content)), fmt.Sprint(memory))
+
+		for _, invalid := range []string{`[]`, `"x"`, `{"a":1} {}`, `{"a":`, `{"a" 1}`, `{"a":{"b":[}}`} {
+			_, err := jsonDecoder(io.MultiReader(strings.NewReader(invalid)))
+			fc.AssertEqual(t, true, err != nil, invalid)
+		}
+
+		_, ts := newTestServer(t, nestedYang, nestedData)
+		resp, _ := testRequest(t, "PUT", ts.URL+"/restconf/data/x:a", `{"x:a":`+data+`}`, "Content-Type", string(YangDataJsonMimeType1))
+		fc.AssertEqual(t, 204, resp.StatusCode)
+		resp, actualData := testRequest(t, "GET", ts.URL+"/restconf/data/x:a", "")
+		fc.AssertEqual(t, 200, resp.StatusCode)
+		var stored struct {
+			C struct {
+				E []struct {
+					F string
+					G struct{ H int }
+				}
+			}
+		}
+		fc.RequireEqual(t, nil, json.Unmarshal([]byte(actualData), &stored))
+		fc.RequireEqual(t, 5000, len(stored.C.E))
+		for _, e := range stored.C.E {
+			fc.AssertEqual(t, fmt.Sprintf("entry-%d", e.G.H), e.F)
+		}
+		ts.Close()
+	}
+}
+
+func TestReadJsonContentOrder(t *testing.T) {
+	// members in any order, read again and looked up by key
+	content, err := jsonDecoder(strings.NewReader(`{"a":{"c":{"e":[{"g":{"h":1},"f":"one"},{"f":"two"}],"d":"D"},"b":"B"}}`))
+	fc.RequireEqual(t, nil, err)
+	m, err := parser.LoadModuleFromString(nil, nestedYang)
+	fc.RequireEqual(t, nil, err)
+	b := node.NewBrowser(m, content)
+	for i := 0; i < 2; i++ {
+		sel, err := b.Root().Find("a/c/e=one/g")
+		fc.RequireEqual(t, nil, err)
+		fc.RequireEqual(t, true, sel != nil)
+		actual, err := nodeutil.WriteJSON(sel)
+		fc.RequireEqual(t, nil, err)
+		fc.AssertEqual(t, `{"h":1}`, actual)
+	}
+	actual, err := nodeutil.WriteJSON(b.Root())
+	fc.RequireEqual(t, nil, err)
+	fc.AssertEqual(t, `{"a":{"b":"B","c":{"d":"D","e":[{"f":"one","g":{"h":1}},{"f":"two"}]}}}`, actual)
+}
+
+// peakHeap is the most heap f has in use at once, sampled as f runs
+func peakHeap(f func()) uint64 {
+	var stats runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&stats)
+	base, peak := stats.HeapInuse, stats.HeapInuse
+	done := make(chan struct{})
+	var sampling sync.WaitGroup
+	sampling.Add(1)
+	go func() {
+		defer sampling.Done()
+		var sample runtime.MemStats
+		for {
+			runtime.ReadMemStats(&sample)
+			if sample.HeapInuse > peak {
+				peak = sample.HeapInuse
+			}
+			select {
+			case <-done:
+				return
+			case <-time.After(time.Millisecond):
+			}
+		}
+	}()
+	f()
+	close(done)
+	sampling.Wait()
+	if peak < base {
+		return 0
+	}
+	return peak - base
+}
+
+// compares decoding all of content before it is edited to reading content as
+// it is edited. Content is edited into a writer that keeps nothing so only
+// memory used for reading content is measured.
+//
+//	go test -bench ReadRequest -benchmem
+func BenchmarkReadRequest(b *testing.B) {
+	m, err := parser.LoadModuleFromString(nil, nestedYang)
+	if err != nil {
+		b.Fatal(err)
+	}
+	jsonData := []byte(`{"a":` + largeNestedData(50000) + `}`)
+	xmlData := []byte(largeNestedXml(50000))
+	readers := []struct {
+		name string
+		data []byte
+		read func(io.Reader) (node.Node, error)
+	}{
+		{"json/buffered", jsonData, nodeutil.ReadJSONIO},
+		{"json/stream", jsonData, jsonDecoder},
+		{"xml/buffered", xmlData, func(in io.Reader) (node.Node, error) {
+			return nodeutil.ReadXMLBlock(in)
+		}},
+		{"xml/stream", xmlData, xmlDecoder},
+	}
+	for _, r := range readers {
+		b.Run(r.name, func(b *testing.B) {
+			b.ReportAllocs()
+			var peak uint64
+			for i := 0; i < b.N; i++ {
+				// content is read from request and not from memory
+				in := io.MultiReader(bytes.NewReader(r.data))
+				opPeak := peakHeap(func() {
+					content, err := r.read(in)
+					if err == nil {
+						wtr := &nodeutil.JSONWtr{Out: io.Discard}
+						err = node.NewBrowser(m, content).Root().UpsertInto(wtr.Node())
+					}
+					if err != nil {
+						b.Fatal(err)
+					}
+				})
+				if opPeak > peak {
+					peak = opPeak
+				}
+			}
+			b.ReportMetric(float64(peak), "peak-heap-B")
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package restconf
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/freeconf/yang/node"
+)
+
+// modTracker records when data resources were edited so Last-Modified can be
+// reported. An edit to a resource modifies all its ancestors and all its
+// descendants. Resources that were never edited report the time the
+// tracker first saw the module. RFC8040 Sec. 3.4.1.1
+type modTracker struct {
+	mu      sync.Mutex
+	modules map[*node.Browser]*moduleMods
+}
+
+type moduleMods struct {
+	started time.Time
+	edits   map[string]time.Time
+}
+
+func newModTracker() *modTracker {
+	return &modTracker{
+		modules: make(map[*node.Browser]*moduleMods),
+	}
+}
+
+func (t *modTracker) module(b *node.Browser, now time.Time) *moduleMods {
+	mods, found := t.modules[b]
+	if !found {
+		mods = &moduleMods{started: now, edits: make(map[string]time.Time)}
+		t.modules[b] = mods
+	}
+	return mods
+}
+
+func (t *modTracker) edited(b *node.Browser, path string, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	mods := t.module(b, now)
+	for p := range mods.edits {
+		// no point in remembering edits that are now covered by this edit
+		if isPathWithin(path, p) {
+			delete(mods.edits, p)
+		}
+	}
+	mods.edits[path] = now
+}
+
+// copyFor is a tracker for scratch that starts w/edits made to b so far.
+// Edits to either are not seen by the other.
+func (t *modTracker) copyFor(b *node.Browser, scratch *node.Browser, now time.Time) *modTracker {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	mods := t.module(b, now)
+	copy := &moduleMods{started: mods.started, edits: make(map[string]time.Time, len(mods.edits))}
+	for p, when := range mods.edits {
+		copy.edits[p] = when
+	}
+	return &modTracker{modules: map[*node.Browser]*moduleMods{scratch: copy}}
+}
+
+func (t *modTracker) lastModified(b *node.Browser, path string, now time.Time) time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	mods := t.module(b, now)
+	modified := mods.started
+	for p, when := range mods.edits {
+		if (isPathWithin(p, path) || isPathWithin(path, p)) && when.After(modified) {
+			modified = when
+		}
+	}
+	return modified
+}
+
+// isPathWithin is true if child is same as parent or a descendant of parent
+func isPathWithin(parent string, child string) bool {
+	return child == parent || strings.HasPrefix(child, parent+"/")
+}
+
+// checkModifiedPreconditions evaluates If-Modified-Since and If-Unmodified-Since
+// headers and returns http status code to respond with if the request should not
+// proceed or 0 if it should.  Only consulted when there are no etag headers as
+// etags take precedence. RFC7232 Sec. 6
+func checkModifiedPreconditions(r *http.Request, modified time.Time) int {
+	// http dates only have a resolution of seconds
+	modified = modified.Truncate(time.Second)
+	if r.Header.Get("If-Match") == "" {
+		if since, err := http.ParseTime(r.Header.Get("If-Unmodified-Since")); err == nil {
+			if modified.After(since) {
+				return http.StatusPreconditionFailed
+			}
+		}
+	}
+	if r.Header.Get("If-None-Match") == "" && (r.Method == "GET" || r.Method == "HEAD") {
+		if since, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil {
+			if !modified.After(since) {
+				return http.StatusNotModified
+			}
+		}
+	}
+	return 0
+}
@@ -0,0 +1,46 @@
+package restconf
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// DefaultMaxRequestBytes is the MaxRequestBytes of servers from NewHttpServe
+const DefaultMaxRequestBytes = 8 << 20
+
+// limitRequest rejects request content that is known to be too large and
+// otherwise fails reading content once it goes over MaxRequestBytes. Limit is
+// on content once it is decompressed so small compressed content cannot
+// expand w/o limit. Chunked content has no Content-Length so it is only
+// limited as it is read.
+func (srv *Server) limitRequest(w http.ResponseWriter, r *http.Request) error {
+	if srv.MaxRequestBytes <= 0 || r.Body == nil || r.Body == http.NoBody {
+		return nil
+	}
+	if r.ContentLength > srv.MaxRequestBytes {
+		return fmt.Errorf("%w. %d bytes is more than %d", ErrRequestTooLarge, r.ContentLength, srv.MaxRequestBytes)
+	}
+	r.Body = &limitedBody{
+		ReadCloser: http.MaxBytesReader(w, r.Body, srv.MaxRequestBytes),
+		max:        srv.MaxRequestBytes,
+	}
+	return nil
+}
+
+// limitedBody reports content that is too large as ErrRequestTooLarge so
+// decoders that keep reader errors lead to a 413
+type limitedBody struct {
+	io.ReadCloser
+	max int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		err = fmt.Errorf("%w. more than %d bytes", ErrRequestTooLarge, b.max)
+	}
+	return n, err
+}
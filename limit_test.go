@@ -0,0 +1,96 @@
+package restconf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+)
+
+func TestMaxRequestBytes(t *testing.T) {
+	s, ts := newTestServer(t, nestedYang, nestedData)
+	defer ts.Close()
+	fc.AssertEqual(t, int64(DefaultMaxRequestBytes), s.MaxRequestBytes)
+	s.MaxRequestBytes = 64
+
+	// json allows any amount of whitespace so size is easy to control
+	content := func(size int) string {
+		c := `{"b":"x"}`
+		return c + strings.Repeat(" ", size-len(c))
+	}
+	patch := func(body io.Reader, hdrs ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PATCH", "/restconf/data/x:a", body)
+		req.Header.Set("Content-Type", string(YangDataJsonMimeType1))
+		for i := 0; i < len(hdrs); i += 2 {
+			req.Header.Set(hdrs[i], hdrs[i+1])
+		}
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		return w
+	}
+
+	fc.AssertEqual(t, 200, patch(strings.NewReader(content(64))).Code)
+	over := patch(strings.NewReader(content(65)))
+	fc.AssertEqual(t, 413, over.Code)
+	fc.AssertEqual(t, true, strings.Contains(over.Body.String(), `"error-tag":"too-big"`), over.Body.String())
+
+	// size is not known up front
+	fc.AssertEqual(t, 200, patch(io.MultiReader(strings.NewReader(content(64)))).Code)
+	fc.AssertEqual(t, 413, patch(io.MultiReader(strings.NewReader(content(65)))).Code)
+
+	// limit is on content once decompressed
+	var zipped bytes.Buffer
+	zw := gzip.NewWriter(&zipped)
+	zw.Write([]byte(content(1000)))
+	zw.Close()
+	fc.AssertEqual(t, true, zipped.Len() < 64)
+	fc.AssertEqual(t, 413, patch(&zipped, "Content-Encoding", "gzip").Code)
+
+	s.MaxRequestBytes = 0
+	fc.AssertEqual(t, 200, patch(strings.NewReader(content(1000))).Code)
+}
+
+func TestChunkedRequest(t *testing.T) {
+	s, ts := newTestServer(t, nestedYang, nestedData)
+	defer ts.Close()
+	s.MaxRequestBytes = 64
+	var encoding []string
+	s.Filters = append(s.Filters, func(ctx context.Context, w http.ResponseWriter, r *http.Request) (context.Context, error) {
+		encoding = r.TransferEncoding
+		return ctx, nil
+	})
+	url := ts.URL + "/restconf/data/x:a/b"
+	put := func(content string) *http.Response {
+		// reader of unknown length is sent chunked w/o Content-Length
+		req, err := http.NewRequest("PUT", url, io.MultiReader(strings.NewReader(content)))
+		fc.RequireEqual(t, nil, err)
+		fc.AssertEqual(t, int64(0), req.ContentLength)
+		req.Header.Set("Content-Type", string(YangDataJsonMimeType))
+		resp, err := http.DefaultClient.Do(req)
+		fc.RequireEqual(t, nil, err)
+		resp.Body.Close()
+		return resp
+	}
+	json := string(YangDataJsonMimeType)
+
+	resp := put(`{"x:b":"chunked"}`)
+	fc.AssertEqual(t, 204, resp.StatusCode)
+	fc.AssertEqual(t, "chunked", strings.Join(encoding, ","))
+	_, actual := testRequest(t, "GET", url, "", "Accept", json)
+	fc.AssertEqual(t, `{"b":"chunked"}`, actual)
+
+	resp = put(`{"x:b":"` + strings.Repeat("x", 64) + `"}`)
+	fc.AssertEqual(t, 413, resp.StatusCode)
+	_, actual = testRequest(t, "GET", url, "", "Accept", json)
+	fc.AssertEqual(t, `{"b":"chunked"}`, actual)
+
+	s.MaxRequestBytes = 0
+	resp = put(`{"x:b":"` + strings.Repeat("x", 64) + `"}`)
+	fc.AssertEqual(t, 204, resp.StatusCode)
+}
@@ -0,0 +1,64 @@
+package restconf
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+)
+
+// streamsList is true when GET of target can be written one list entry at a
+// time. Only lists written by the built-in JSON encoder qualify as that is
+// framing known ahead of time, XML writer does not frame entries on their
+// own. Responses that are held to be reworked, like pretty, paged or tagged
+// defaults, are written whole.
+func streamsList(target *node.Selection, c *codecs, m MimeType, w http.ResponseWriter) bool {
+	if !meta.IsList(target.Meta()) || target.InsideList {
+		return false
+	}
+	if _, canFlush := w.(http.Flusher); !canFlush {
+		return false
+	}
+	return c.streamsLists(m)
+}
+
+// streamList writes entries of list into a JSON array one entry at a time and
+// flushes w after each so clients get a large list as it is read and server
+// does not hold all of it. Output is the same as writing the list at once.
+// Once first entry is sent status cannot change so a later error cuts the
+// response short.
+func streamList(list *node.Selection, enc Encoder, compliance ComplianceOptions, w io.Writer, flusher http.Flusher) error {
+	if _, err := fmt.Fprintf(w, `{"%s":[`, jsonMemberName(list.Path, !compliance.QualifyNamespaceDisabled)); err != nil {
+		return err
+	}
+	item, err := list.First()
+	for i := 0; err == nil && item.Selection != nil; i++ {
+		if i > 0 {
+			if _, err = io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		if err = item.Selection.UpsertIntoSetDefaults(enc(w, compliance)); err != nil {
+			return err
+		}
+		flusher.Flush()
+		item, err = item.Next()
+	}
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "]}")
+	return err
+}
+
+// jsonMemberName is name of data at p as JSON writer has it, w/module at top
+// and where module changes. RFC7951 Sec. 4
+func jsonMemberName(p *node.Path, qualify bool) string {
+	mod := meta.OriginalModule(p.Meta)
+	if qualify && (p.Len() == 2 || meta.OriginalModule(p.Parent.Meta) != mod) {
+		return mod.Ident() + ":" + p.Meta.Ident()
+	}
+	return p.Meta.Ident()
+}
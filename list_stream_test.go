@@ -0,0 +1,66 @@
+package restconf
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+)
+
+// partialRecorder remembers how much of body was written at each flush
+type partialRecorder struct {
+	*httptest.ResponseRecorder
+	flushed []int
+}
+
+func (r *partialRecorder) Flush() {
+	r.flushed = append(r.flushed, r.Body.Len())
+	r.ResponseRecorder.Flush()
+}
+
+func TestStreamList(t *testing.T) {
+	entries := make([]string, 1000)
+	for i := range entries {
+		entries[i] = fmt.Sprintf(`{"f":"e%d","g":{"h":%d}}`, i, i)
+	}
+	data := `{"a":{"b":"B","c":{"d":"D","e":[` + strings.Join(entries, ",") + `]}}}`
+	s, ts := newTestServer(t, nestedYang, data)
+	ts.Close()
+	get := func(path string, hdrs ...string) *partialRecorder {
+		t.Helper()
+		r := httptest.NewRequest("GET", "/restconf/data/"+path, nil)
+		for i := 0; i+1 < len(hdrs); i += 2 {
+			r.Header.Set(hdrs[i], hdrs[i+1])
+		}
+		w := &partialRecorder{ResponseRecorder: httptest.NewRecorder()}
+		s.ServeHTTP(w, r)
+		fc.RequireEqual(t, 200, w.Code, path)
+		return w
+	}
+	paths := []string{"x:a/c/e", "x:a/c/e?depth=2", "x:a/c/e?fields=f", "x:a/c/e?with-defaults=trim"}
+	expected := make([]string, len(paths))
+	for i, p := range paths {
+		expected[i] = get(p).Body.String()
+	}
+
+	s.StreamLists = true
+	w := get("x:a/c/e")
+	fc.AssertEqual(t, len(entries), len(w.flushed))
+	// first entry went out long before last one was read
+	fc.AssertEqual(t, `{"e":[`+entries[0], w.Body.String()[:w.flushed[0]])
+	fc.AssertEqual(t, true, w.flushed[len(w.flushed)-1] < w.Body.Len())
+	var vals map[string]interface{}
+	fc.AssertEqual(t, nil, json.Unmarshal(w.Body.Bytes(), &vals))
+	for i, p := range paths {
+		fc.AssertEqual(t, expected[i], get(p).Body.String(), p)
+	}
+
+	// written whole
+	for _, p := range []string{"x:a/c", "x:a/c/e=e1", "x:a/c/e?limit=10", "x:a/c/e?pretty"} {
+		fc.AssertEqual(t, 0, len(get(p).flushed), p)
+	}
+	fc.AssertEqual(t, 0, len(get("x:a/c/e", "Accept", string(YangDataXmlMimeType1)).flushed))
+}
@@ -0,0 +1,112 @@
+package restconf
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/freeconf/yang/node"
+)
+
+// DefaultLockTimeout is the LockTimeout of servers from NewHttpServe
+const DefaultLockTimeout = 10 * time.Second
+
+// dataLocks serializes edits to the data of each module while reads of it
+// proceed together like with a sync.RWMutex. Data of a module is that of
+// its browser so each datastore and device has its own lock.
+type dataLocks struct {
+	mu    sync.Mutex
+	locks map[*node.Browser]*dataLock
+}
+
+type dataLock struct {
+	readers int
+	writing bool
+	// writers waiting keep new readers from starving them
+	waitingWriters int
+	// requests holding or waiting for lock, lock is dropped at none
+	users int
+	// closed whenever lock is released so waiting requests try again
+	released chan struct{}
+}
+
+// lock data of b for an edit or, when not write, for a read. Edits that
+// wait longer than timeout fail with in-use, 0 waits as long as request
+// does. Call unlock once done.
+func (l *dataLocks) lock(ctx context.Context, b *node.Browser, write bool, timeout time.Duration) (unlock func(), err error) {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = make(map[*node.Browser]*dataLock)
+	}
+	lk, found := l.locks[b]
+	if !found {
+		lk = &dataLock{released: make(chan struct{})}
+		l.locks[b] = lk
+	}
+	lk.users++
+	if write {
+		lk.waitingWriters++
+	}
+	l.mu.Unlock()
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	for {
+		l.mu.Lock()
+		if write && !lk.writing && lk.readers == 0 {
+			lk.waitingWriters--
+			lk.writing = true
+			l.mu.Unlock()
+			return func() { l.unlock(b, lk, true) }, nil
+		} else if !write && !lk.writing && lk.waitingWriters == 0 {
+			lk.readers++
+			l.mu.Unlock()
+			return func() { l.unlock(b, lk, false) }, nil
+		}
+		released := lk.released
+		l.mu.Unlock()
+		select {
+		case <-released:
+		case <-expired:
+			l.giveUp(b, lk, write)
+			return nil, fmt.Errorf("%w. data is being edited by another request", ErrInUse)
+		case <-ctx.Done():
+			l.giveUp(b, lk, write)
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (l *dataLocks) unlock(b *node.Browser, lk *dataLock, write bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if write {
+		lk.writing = false
+	} else {
+		lk.readers--
+	}
+	l.release(b, lk)
+}
+
+// giveUp waiting for lock
+func (l *dataLocks) giveUp(b *node.Browser, lk *dataLock, write bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if write {
+		lk.waitingWriters--
+	}
+	l.release(b, lk)
+}
+
+func (l *dataLocks) release(b *node.Browser, lk *dataLock) {
+	close(lk.released)
+	lk.released = make(chan struct{})
+	if lk.users--; lk.users == 0 {
+		delete(l.locks, b)
+	}
+}
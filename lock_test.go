@@ -0,0 +1,188 @@
+package restconf
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+)
+
+// slowWrites is n w/a pause before every value written so edits that are not
+// serialized interleave
+func slowWrites(n node.Node) node.Node {
+	return &nodeutil.Extend{
+		Base: n,
+		OnChild: func(parent node.Node, r node.ChildRequest) (node.Node, error) {
+			child, err := parent.Child(r)
+			if child == nil || err != nil {
+				return child, err
+			}
+			return slowWrites(child), nil
+		},
+		OnField: func(parent node.Node, r node.FieldRequest, hnd *node.ValueHandle) error {
+			if r.Write {
+				time.Sleep(time.Millisecond)
+			}
+			return parent.Field(r, hnd)
+		},
+	}
+}
+
+func TestLockConcurrentEdits(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, nestedYang)
+	fc.RequireEqual(t, nil, err)
+	data := map[string]interface{}{
+		"a": map[string]interface{}{"b": "0", "c": map[string]interface{}{"d": "0"}},
+	}
+	_, ts := newTestServerWithNode(t, m, slowWrites(nodeutil.ReflectChild(data)))
+	defer ts.Close()
+	url := ts.URL + "/restconf/data/x:a"
+	ctype := string(YangDataJsonMimeType)
+	send := func(method string, body string) (int, string, error) {
+		req, err := http.NewRequest(method, url, strings.NewReader(body))
+		if err != nil {
+			return 0, "", err
+		}
+		req.Header.Set("Content-Type", ctype)
+		req.Header.Set("Accept", ctype)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return 0, "", err
+		}
+		defer resp.Body.Close()
+		content, err := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(content), err
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 40)
+	for i := 1; i <= 20; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			// PUT replaces data while PATCH merges into it, either half done is seen
+			method, expected := "PUT", 204
+			if i%2 == 0 {
+				method, expected = "PATCH", 200
+			}
+			body := fmt.Sprintf(`{"x:a":{"b":"%d","c":{"d":"%d"}}}`, i, i)
+			if status, content, err := send(method, body); err != nil || status != expected {
+				errs <- fmt.Errorf("%s %d. %d %s %v", method, i, status, content, err)
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			// reads never see an edit half done
+			var got struct {
+				B string
+				C struct {
+					D string
+				}
+			}
+			status, content, err := send("GET", "")
+			if err == nil && status == 200 {
+				err = json.Unmarshal([]byte(content), &got)
+			}
+			if err != nil || status != 200 || got.B != got.C.D {
+				errs <- fmt.Errorf("GET. %d %s %v", status, content, err)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	var final struct {
+		B string
+		C struct {
+			D string
+		}
+	}
+	_, actual, err := send("GET", "")
+	fc.RequireEqual(t, nil, err)
+	fc.RequireEqual(t, nil, json.Unmarshal([]byte(actual), &final))
+	fc.AssertEqual(t, final.B, final.C.D, actual)
+}
+
+func TestLockTimeout(t *testing.T) {
+	s, ts := newTestServer(t, nestedYang, nestedData)
+	defer ts.Close()
+	b, err := s.mainDevice().Browser("x")
+	fc.RequireEqual(t, nil, err)
+	url := ts.URL + "/restconf/data/x:a/b"
+	s.LockTimeout = 20 * time.Millisecond
+
+	unlock, err := s.locks.lock(context.Background(), b, true, 0)
+	fc.RequireEqual(t, nil, err)
+	resp, actual := testRequest(t, "PUT", url, `{"x:b":"Q"}`, "Accept", string(YangDataJsonMimeType))
+	fc.AssertEqual(t, 409, resp.StatusCode)
+	fc.AssertEqual(t, true, strings.Contains(actual, `"error-tag":"in-use"`), actual)
+
+	// reads wait for edit to finish
+	read := make(chan string)
+	go func() {
+		resp, err := http.Get(url)
+		if err != nil {
+			read <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		content, _ := io.ReadAll(resp.Body)
+		read <- string(content)
+	}()
+	select {
+	case actual := <-read:
+		t.Errorf("read during edit. %s", actual)
+	case <-time.After(50 * time.Millisecond):
+	}
+	unlock()
+	fc.AssertEqual(t, `{"b":"B"}`, <-read)
+
+	resp, _ = testRequest(t, "PUT", url, `{"x:b":"Q"}`)
+	fc.AssertEqual(t, 204, resp.StatusCode)
+	fc.AssertEqual(t, 0, len(s.locks.locks))
+}
+
+func TestDataLocks(t *testing.T) {
+	var locks dataLocks
+	b := &node.Browser{}
+	ctx := context.Background()
+	read1, err := locks.lock(ctx, b, false, 0)
+	fc.RequireEqual(t, nil, err)
+	read2, err := locks.lock(ctx, b, false, 0)
+	fc.RequireEqual(t, nil, err)
+	_, err = locks.lock(ctx, b, true, 10*time.Millisecond)
+	fc.AssertEqual(t, true, err != nil)
+	// other data is not locked
+	other, err := locks.lock(ctx, &node.Browser{}, true, 10*time.Millisecond)
+	fc.RequireEqual(t, nil, err)
+	other()
+
+	written := make(chan func())
+	go func() {
+		unlock, _ := locks.lock(ctx, b, true, time.Second)
+		written <- unlock
+	}()
+	read1()
+	read2()
+	write := <-written
+	fc.AssertEqual(t, true, write != nil)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = locks.lock(cancelled, b, false, 0)
+	fc.AssertEqual(t, context.Canceled, err)
+	write()
+	fc.AssertEqual(t, 0, len(locks.locks))
+}
@@ -0,0 +1,105 @@
+package restconf
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Logger receives an entry for every request and, at debug level, details on
+// content server could not read or write. Fields are name, value pairs so they
+// can be passed on to a structured logger
+//
+//	Info("request", "request-id", "5f0c...", "method", "GET", "path", "/restconf/data/car:", "status", 200)
+type Logger interface {
+	Debug(msg string, fields ...any)
+	Info(msg string, fields ...any)
+	Warn(msg string, fields ...any)
+	Error(msg string, fields ...any)
+}
+
+type requestLogContextKeyType string
+
+var requestLogContextKey = requestLogContextKeyType("FC_REQUEST_LOG")
+
+// requestLog collects what is known about a request as it is served
+type requestLog struct {
+	logger Logger
+	id     string
+	method string
+	path   string
+	module string
+	tag    string
+	err    error
+}
+
+func requestLogOf(r *http.Request) *requestLog {
+	l, _ := r.Context().Value(requestLogContextKey).(*requestLog)
+	return l
+}
+
+// requestModule is module in request path if there is one
+func requestModule(uri string) string {
+	module, _, err := SplitUri(strings.SplitN(uri, "?", 2)[0])
+	if err != nil {
+		return ""
+	}
+	return module
+}
+
+// logRequest logs request when returned func is called. Request has the log in
+// its context so errors can be added to it.
+func logRequest(logger Logger, w http.ResponseWriter, r *http.Request) (http.ResponseWriter, *http.Request, func()) {
+	l := &requestLog{
+		logger: logger,
+		id:     RequestIdOf(r.Context()),
+		method: r.Method,
+		path:   r.URL.Path,
+		module: requestModule(r.RequestURI),
+	}
+	r = r.WithContext(context.WithValue(r.Context(), requestLogContextKey, l))
+	rec := &statusRecorder{ResponseWriter: w}
+	started := time.Now()
+	return rec, r, func() {
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		fields := []any{"request-id", l.id, "method", l.method, "path", l.path, "module", l.module, "status", rec.status, "duration", time.Since(started)}
+		if l.err != nil {
+			fields = append(fields, "error-tag", l.tag, "error", l.err.Error())
+		}
+		switch {
+		case rec.status >= 500:
+			logger.Error("request", fields...)
+		case rec.status >= 400:
+			logger.Warn("request", fields...)
+		default:
+			logger.Info("request", fields...)
+		}
+	}
+}
+
+func (l *requestLog) failed(err error, tag string) {
+	if l == nil {
+		return
+	}
+	l.err, l.tag = err, tag
+}
+
+// decodeFailed is when request content could not be read
+func decodeFailed(r *http.Request, err error) {
+	if l := requestLogOf(r); l != nil {
+		l.logger.Debug("could not decode request content", "request-id", l.id, "method", r.Method,
+			"path", DecodeErrorPath(r.RequestURI), "module", l.module,
+			"content-type", r.Header.Get("Content-Type"), "error", err.Error())
+	}
+}
+
+// encodeFailed is when response content could not be written
+func encodeFailed(r *http.Request, err error) {
+	if l := requestLogOf(r); l != nil {
+		l.logger.Debug("could not write response content", "request-id", l.id, "method", r.Method,
+			"path", DecodeErrorPath(r.RequestURI), "module", l.module, "error", err.Error())
+	}
+}
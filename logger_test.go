@@ -0,0 +1,69 @@
+package restconf
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+)
+
+type testLogEntry struct {
+	level  string
+	msg    string
+	fields map[string]any
+}
+
+// testLogger keeps every entry
+type testLogger struct {
+	mu      sync.Mutex
+	entries []testLogEntry
+}
+
+func (l *testLogger) log(level string, msg string, fields []any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	e := testLogEntry{level: level, msg: msg, fields: make(map[string]any)}
+	for i := 0; i+1 < len(fields); i += 2 {
+		e.fields[fmt.Sprint(fields[i])] = fields[i+1]
+	}
+	l.entries = append(l.entries, e)
+}
+
+func (l *testLogger) Debug(msg string, fields ...any) { l.log("debug", msg, fields) }
+func (l *testLogger) Info(msg string, fields ...any)  { l.log("info", msg, fields) }
+func (l *testLogger) Warn(msg string, fields ...any)  { l.log("warn", msg, fields) }
+func (l *testLogger) Error(msg string, fields ...any) { l.log("error", msg, fields) }
+
+func TestLogger(t *testing.T) {
+	s, ts := newTestServer(t, nestedYang, nestedData)
+	defer ts.Close()
+	logger := &testLogger{}
+	s.Logger = logger
+
+	resp, _ := testRequest(t, "GET", ts.URL+"/restconf/data/x:a", "")
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	fc.RequireEqual(t, 1, len(logger.entries))
+	e := logger.entries[0]
+	fc.AssertEqual(t, "info", e.level)
+	fc.AssertEqual(t, "request", e.msg)
+	fc.AssertEqual(t, "GET", e.fields["method"])
+	fc.AssertEqual(t, "/restconf/data/x:a", e.fields["path"])
+	fc.AssertEqual(t, "x", e.fields["module"])
+	fc.AssertEqual(t, 200, e.fields["status"])
+
+	logger.entries = nil
+	resp, _ = testRequest(t, "PATCH", ts.URL+"/restconf/data/x:a", `{"b":`,
+		"Content-Type", string(YangDataJsonMimeType1))
+	fc.RequireEqual(t, 2, len(logger.entries))
+	decode := logger.entries[0]
+	fc.AssertEqual(t, "debug", decode.level)
+	fc.AssertEqual(t, "could not decode request content", decode.msg)
+	fc.AssertEqual(t, "x", decode.fields["module"])
+	fc.AssertEqual(t, "x:a", decode.fields["path"])
+	req := logger.entries[1]
+	fc.AssertEqual(t, "request", req.msg)
+	fc.AssertEqual(t, resp.StatusCode, req.fields["status"])
+	fc.AssertEqual(t, true, req.fields["error-tag"] != "")
+	fc.AssertEqual(t, true, req.fields["error"] != nil)
+}
@@ -0,0 +1,166 @@
+package restconf
+
+import (
+	"fmt"
+	"mime"
+	"strconv"
+	"strings"
+)
+
+// MimeType is a media type w/o parameters like charset, see ParseMimeType
+type MimeType string
+
+const (
+	// RFC8040 Sec. 11.3
+	YangDataJsonMimeType = MimeType("application/yang-data+json")
+	YangDataXmlMimeType  = MimeType("application/yang-data+xml")
+
+	// Deprecated: same as YangDataJsonMimeType
+	YangDataJsonMimeType1 = YangDataJsonMimeType
+	// Deprecated: same as YangDataXmlMimeType
+	YangDataXmlMimeType1 = YangDataXmlMimeType
+
+	// spelling w/a dot from drafts of RFC8040 that some clients still send.
+	// Read and written same as yang-data types and echoed back in
+	// Content-Type when asked for
+	YangDataJsonMimeType2 = MimeType("application/yang.data+json")
+	YangDataXmlMimeType2  = MimeType("application/yang.data+xml")
+
+	// generic types some clients send instead of yang-data types
+	PlainJsonMimeType = MimeType("application/json")
+	PlainXmlMimeType  = MimeType("application/xml")
+
+	// RFC8072
+	YangPatchJsonMimeType = MimeType("application/yang-patch+json")
+	YangPatchXmlMimeType  = MimeType("application/yang-patch+xml")
+
+	// RFC6902
+	JsonPatchMimeType = MimeType("application/json-patch+json")
+
+	TextStreamMimeType = MimeType("text/event-stream")
+
+	// module source at {+restconf}/schema. RFC6020 Sec. 14
+	YangMimeType = MimeType("application/yang")
+	YinMimeType  = MimeType("application/yin+xml")
+)
+
+// ParseMimeType reads a Content-Type header, or one type in an Accept header,
+// as the type w/o parameters in lower case. Content is only read and written
+// as UTF-8 so any other charset is an error. Types that are not one of the
+// constants are returned as well as there may be a codec registered for them.
+//
+//	application/yang-data+json; charset=utf-8  =>  application/yang-data+json
+//	Application/JSON                           =>  application/json
+func ParseMimeType(s string) (MimeType, error) {
+	t, _, err := parseMimeType(s)
+	return t, err
+}
+
+// parseMimeType is ParseMimeType and parameters other than charset like q
+func parseMimeType(s string) (MimeType, map[string]string, error) {
+	t, params, err := mime.ParseMediaType(s)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w. '%s' %s", ErrUnsupportedMediaType, s, err)
+	}
+	if charset, has := params["charset"]; has {
+		if !strings.EqualFold(charset, "utf-8") {
+			return "", nil, fmt.Errorf("%w. charset '%s', only utf-8 is supported", ErrUnsupportedMediaType, charset)
+		}
+		delete(params, "charset")
+	}
+	return MimeType(t), params, nil
+}
+
+// mediaType is the type in a Content-Type header w/o parameters like charset.
+// Types that cannot be read are left for checkContentType to reject.
+func mediaType(contentType string) MimeType {
+	if strings.TrimSpace(contentType) == "" {
+		return ""
+	}
+	t, err := ParseMimeType(contentType)
+	if err != nil {
+		return MimeType(strings.TrimSpace(contentType))
+	}
+	return t
+}
+
+func (m MimeType) IsXml() bool {
+	return strings.HasSuffix(string(m), "xml")
+}
+
+func (m MimeType) IsJson() bool {
+	return strings.HasSuffix(string(m), "json")
+}
+
+func (m MimeType) IsYangPatch() bool {
+	return strings.HasPrefix(string(m), string(YangPatchJsonMimeType)) || strings.HasPrefix(string(m), string(YangPatchXmlMimeType))
+}
+
+// isYangDataFor is true when m is the yang-data type of generic type other
+func (m MimeType) isYangDataFor(other MimeType) bool {
+	switch other {
+	case PlainJsonMimeType:
+		return m == YangDataJsonMimeType || m == YangDataJsonMimeType2
+	case PlainXmlMimeType:
+		return m == YangDataXmlMimeType || m == YangDataXmlMimeType2
+	}
+	return false
+}
+
+func (m MimeType) IsRfc() bool {
+	return m == YangDataJsonMimeType || m == YangDataJsonMimeType2 || m == YangDataXmlMimeType || m == YangDataXmlMimeType2 || m.IsYangPatch()
+}
+
+// acceptedMimeType is the type in an Accept header with the highest quality
+// value, first one wins a tie unless a later one is the yang-data type for
+// a generic type like application/json.
+//
+//	application/yang-data+json, application/yang-data+xml;q=0.9
+func acceptedMimeType(accept string) MimeType {
+	return preferredMimeType(accept, nil)
+}
+
+// unacceptableMimeType is the accepted type when Accept refuses every type
+// it names with q=0
+const unacceptableMimeType = MimeType("*/*;q=0")
+
+// preferredMimeType is like acceptedMimeType but types that are known win
+// over those that are not regardless of quality value. Types that cannot be
+// read are skipped and so are types refused with q=0. RFC9110 Sec. 12.4.2
+func preferredMimeType(accept string, known func(MimeType) bool) MimeType {
+	if strings.TrimSpace(accept) == "" {
+		return ""
+	}
+	var best, bestKnown MimeType
+	bestQ, bestKnownQ := -1.0, -1.0
+	refused := false
+	for _, candidate := range strings.Split(accept, ",") {
+		t, params, err := parseMimeType(candidate)
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if qstr, hasQ := params["q"]; hasQ {
+			if q, err = strconv.ParseFloat(qstr, 64); err != nil {
+				continue
+			}
+		}
+		if q == 0 {
+			refused = true
+			continue
+		}
+		if q > bestQ || (q == bestQ && t.isYangDataFor(best)) {
+			best, bestQ = t, q
+		}
+		if (q > bestKnownQ || (q == bestKnownQ && t.isYangDataFor(bestKnown))) && known != nil && known(t) {
+			bestKnown, bestKnownQ = t, q
+		}
+	}
+	if bestKnown != "" {
+		return bestKnown
+	}
+	if best == "" && refused {
+		return unacceptableMimeType
+	}
+	return best
+}
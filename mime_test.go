@@ -0,0 +1,66 @@
+package restconf
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+)
+
+func TestParseMimeType(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected MimeType
+		err      bool
+	}{
+		{in: "application/yang-data+json", expected: YangDataJsonMimeType},
+		{in: "application/yang-data+json; charset=utf-8", expected: YangDataJsonMimeType},
+		{in: "application/yang-data+xml;charset=UTF-8", expected: YangDataXmlMimeType},
+		{in: " Application/Yang-Data+XML ", expected: YangDataXmlMimeType},
+		{in: "application/yang.data+json; charset=\"utf-8\"", expected: YangDataJsonMimeType2},
+		{in: "application/yang.data+xml", expected: YangDataXmlMimeType2},
+		{in: "application/json; charset=utf-8", expected: PlainJsonMimeType},
+		{in: "application/xml", expected: PlainXmlMimeType},
+		{in: "application/yang-patch+json; charset=utf-8", expected: YangPatchJsonMimeType},
+		{in: "text/event-stream", expected: TextStreamMimeType},
+		{in: "application/cbor", expected: MimeType("application/cbor")},
+		{in: "application/json; charset=iso-8859-1", err: true},
+		{in: "application/", err: true},
+		{in: "", err: true},
+	}
+	for _, test := range tests {
+		actual, err := ParseMimeType(test.in)
+		if test.err {
+			fc.AssertEqual(t, true, errors.Is(err, ErrUnsupportedMediaType), test.in)
+			continue
+		}
+		fc.AssertEqual(t, nil, err, test.in)
+		fc.AssertEqual(t, test.expected, actual, test.in)
+	}
+
+	// numbered types are the same as yang-data types
+	fc.AssertEqual(t, YangDataJsonMimeType, YangDataJsonMimeType1)
+	fc.AssertEqual(t, YangDataXmlMimeType, YangDataXmlMimeType1)
+}
+
+func TestMimeTypeParams(t *testing.T) {
+	_, ts := newTestServer(t, nestedYang, nestedData)
+	defer ts.Close()
+	url := ts.URL + "/restconf/data/x:a/b"
+
+	resp, actual := testRequest(t, "GET", url, "", "Accept", "Application/Yang-Data+XML; charset=utf-8")
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	fc.AssertEqual(t, string(YangDataXmlMimeType), resp.Header.Get("Content-Type"))
+	fc.AssertEqual(t, true, strings.HasPrefix(actual, "<b"), actual)
+
+	resp, _ = testRequest(t, "PUT", url, `{"x:b":"B2"}`, "Content-Type", "application/yang.data+json; charset=UTF-8")
+	fc.AssertEqual(t, 204, resp.StatusCode)
+	resp, _ = testRequest(t, "PUT", url, `<b xmlns="x">B3</b>`, "Content-Type", "application/xml; charset=utf-8")
+	fc.AssertEqual(t, 204, resp.StatusCode)
+	_, actual = testRequest(t, "GET", url, "")
+	fc.AssertEqual(t, `{"b":"B3"}`, actual)
+
+	resp, _ = testRequest(t, "PUT", url, `{"x:b":"B4"}`, "Content-Type", "application/json; charset=iso-8859-1")
+	fc.AssertEqual(t, 415, resp.StatusCode)
+}
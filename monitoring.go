@@ -0,0 +1,158 @@
+package restconf
+
+import (
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/val"
+)
+
+// Capabilities this server supports for ietf-restconf-monitoring. RFC8040 Sec. 9.1.1
+func (srv *Server) Capabilities() []string {
+	return []string{
+		withDefaultsCapabilityUri(srv.withDefaultsBasicMode()),
+		"urn:ietf:params:restconf:capability:depth:1.0",
+		"urn:ietf:params:restconf:capability:fields:1.0",
+		"urn:ietf:params:restconf:capability:with-defaults:1.0",
+		withOriginCapability,
+		transactionsCapability,
+	}
+}
+
+func (srv *Server) withDefaultsBasicMode() string {
+	if srv.WithDefaultsBasicMode == "" {
+		return WithDefaultsReportAll
+	}
+	return srv.WithDefaultsBasicMode
+}
+
+// MonitoringNode implements ietf-restconf-monitoring
+func MonitoringNode(srv *Server) node.Node {
+	return &nodeutil.Basic{
+		OnChild: func(r node.ChildRequest) (node.Node, error) {
+			switch r.Meta.Ident() {
+			case "restconf-state":
+				return monitoringStateNode(srv), nil
+			}
+			return nil, nil
+		},
+	}
+}
+
+func monitoringStateNode(srv *Server) node.Node {
+	return &nodeutil.Basic{
+		OnChild: func(r node.ChildRequest) (node.Node, error) {
+			switch r.Meta.Ident() {
+			case "capabilities":
+				return monitoringCapabilitiesNode(srv), nil
+			case "streams":
+				return monitoringStreamsNode(srv), nil
+			}
+			return nil, nil
+		},
+	}
+}
+
+func monitoringCapabilitiesNode(srv *Server) node.Node {
+	return &nodeutil.Basic{
+		OnField: func(r node.FieldRequest, hnd *node.ValueHandle) error {
+			switch r.Meta.Ident() {
+			case "capability":
+				hnd.Val = val.StringList(srv.Capabilities())
+			}
+			return nil
+		},
+	}
+}
+
+func monitoringStreamsNode(srv *Server) node.Node {
+	return &nodeutil.Basic{
+		OnChild: func(r node.ChildRequest) (node.Node, error) {
+			switch r.Meta.Ident() {
+			case "stream":
+				return monitoringStreamList(srv, srv.eventStreams()), nil
+			}
+			return nil, nil
+		},
+	}
+}
+
+func monitoringStreamList(srv *Server, streams []*eventStream) node.Node {
+	return &nodeutil.Basic{
+		OnNext: func(r node.ListRequest) (node.Node, []val.Value, error) {
+			var s *eventStream
+			if r.Key != nil {
+				name := r.Key[0].String()
+				for _, candidate := range streams {
+					if candidate.Name == name {
+						s = candidate
+					}
+				}
+			} else if r.Row < len(streams) {
+				s = streams[r.Row]
+			}
+			if s != nil {
+				return monitoringStreamNode(srv, s), []val.Value{val.String(s.Name)}, nil
+			}
+			return nil, nil, nil
+		},
+	}
+}
+
+func monitoringStreamNode(srv *Server, s *eventStream) node.Node {
+	return &nodeutil.Basic{
+		OnChild: func(r node.ChildRequest) (node.Node, error) {
+			switch r.Meta.Ident() {
+			case "access":
+				return monitoringStreamAccessList(srv, s), nil
+			}
+			return nil, nil
+		},
+		OnField: func(r node.FieldRequest, hnd *node.ValueHandle) error {
+			switch r.Meta.Ident() {
+			case "name":
+				hnd.Val = val.String(s.Name)
+			case "description":
+				if s.Description != "" {
+					hnd.Val = val.String(s.Description)
+				}
+			case "replay-support":
+				hnd.Val = val.Bool(s.ReplaySupport())
+			case "replay-log-creation-time":
+				if s.ReplaySupport() {
+					hnd.Val = val.String(s.created.Format(EventTimeFormat))
+				}
+			}
+			return nil
+		},
+	}
+}
+
+func monitoringStreamAccessList(srv *Server, s *eventStream) node.Node {
+	encodings := s.encodings()
+	return &nodeutil.Basic{
+		OnNext: func(r node.ListRequest) (node.Node, []val.Value, error) {
+			var encoding string
+			if r.Key != nil {
+				if s.hasEncoding(r.Key[0].String()) {
+					encoding = r.Key[0].String()
+				}
+			} else if r.Row < len(encodings) {
+				encoding = encodings[r.Row]
+			}
+			if encoding == "" {
+				return nil, nil, nil
+			}
+			return &nodeutil.Basic{
+				OnField: func(r node.FieldRequest, hnd *node.ValueHandle) error {
+					switch r.Meta.Ident() {
+					case "encoding":
+						hnd.Val = val.String(encoding)
+					case "location":
+						hnd.Val = val.String(srv.StreamAddress(s.Name, encoding))
+					}
+					return nil
+				},
+			}, []val.Value{val.String(encoding)}, nil
+		},
+	}
+}
@@ -0,0 +1,190 @@
+package restconf
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/freeconf/restconf/device"
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/source"
+)
+
+// Mount serves modules of d at {+restconf} next to those of main device as
+// if they were all one device. Requests are routed to the device with the
+// module named in the URL, main device first and then mounts in order of
+// name, so a module that is in more than one is served from the first.
+// yang-library, operations, schema and event streams of main device list
+// modules of every mount. Mounting under a name already used replaces that
+// mount.
+//
+//	srv.Mount("car", carDevice)
+//	srv.Mount("garage", garageDevice)
+func (srv *Server) Mount(name string, d device.Device) error {
+	if name == "" {
+		return errors.New("mount needs a name")
+	}
+	if d == nil {
+		return fmt.Errorf("no device to mount at %s", name)
+	}
+	srv.mountsMu.Lock()
+	if srv.mounts == nil {
+		srv.mounts = make(map[string]device.Device)
+	}
+	srv.mounts[name] = d
+	srv.mountsMu.Unlock()
+	srv.mountsChanged()
+	return nil
+}
+
+// Unmount stops serving modules of device mounted under name
+func (srv *Server) Unmount(name string) error {
+	srv.mountsMu.Lock()
+	_, found := srv.mounts[name]
+	delete(srv.mounts, name)
+	srv.mountsMu.Unlock()
+	if !found {
+		return fmt.Errorf("%w. mount %s", fc.NotFoundError, name)
+	}
+	srv.mountsChanged()
+	return nil
+}
+
+// served are main device followed by mounts in order of name
+func (srv *Server) served() []device.Device {
+	srv.mountsMu.Lock()
+	defer srv.mountsMu.Unlock()
+	names := make([]string, 0, len(srv.mounts))
+	for name := range srv.mounts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	devices := make([]device.Device, 0, len(names)+1)
+	if srv.main != nil {
+		devices = append(devices, srv.main)
+	}
+	for _, name := range names {
+		devices = append(devices, srv.mounts[name])
+	}
+	return devices
+}
+
+func (srv *Server) mountsChanged() {
+	srv.mountsMu.Lock()
+	var listeners []device.ModuleListener
+	if srv.mountListeners != nil {
+		for p := srv.mountListeners.Front(); p != nil; p = p.Next() {
+			listeners = append(listeners, p.Value.(device.ModuleListener))
+		}
+	}
+	srv.mountsMu.Unlock()
+	for _, l := range listeners {
+		l()
+	}
+}
+
+// mainDevice is main device together with its mounts
+func (srv *Server) mainDevice() device.Device {
+	return &mountedDevice{srv: srv}
+}
+
+// isMain is true for main device whether or not it has mounts
+func (srv *Server) isMain(d device.Device) bool {
+	if m, valid := d.(*mountedDevice); valid {
+		return m.srv == srv
+	}
+	return d == srv.main
+}
+
+// mountedDevice is main device and every mount of server as one device
+type mountedDevice struct {
+	srv *Server
+}
+
+func (d *mountedDevice) SchemaSource() source.Opener {
+	var sources []source.Opener
+	for _, served := range d.srv.served() {
+		if s := served.SchemaSource(); s != nil {
+			sources = append(sources, s)
+		}
+	}
+	if len(sources) == 1 {
+		return sources[0]
+	}
+	return source.Any(sources...)
+}
+
+// UiSource is that of main device, mounts only add modules
+func (d *mountedDevice) UiSource() source.Opener {
+	if d.srv.main == nil {
+		return nil
+	}
+	return d.srv.main.UiSource()
+}
+
+func (d *mountedDevice) Browser(module string) (*node.Browser, error) {
+	for _, served := range d.srv.served() {
+		b, err := served.Browser(module)
+		if err != nil || b != nil {
+			return b, err
+		}
+	}
+	return nil, nil
+}
+
+func (d *mountedDevice) Modules() map[string]*meta.Module {
+	mods := make(map[string]*meta.Module)
+	served := d.srv.served()
+	// earlier devices win so add them last
+	for i := len(served) - 1; i >= 0; i-- {
+		for name, m := range served[i].Modules() {
+			mods[name] = m
+		}
+	}
+	return mods
+}
+
+// Close does nothing, devices are closed by whoever made them
+func (d *mountedDevice) Close() {
+}
+
+// OnModuleChange calls l when devices are mounted or unmounted and when
+// modules change of main device or of devices mounted when l was added
+func (d *mountedDevice) OnModuleChange(l device.ModuleListener) nodeutil.Subscription {
+	subs := &mountSubscription{srv: d.srv}
+	for _, served := range d.srv.served() {
+		if notifier, valid := served.(device.ModuleNotifier); valid {
+			subs.devices = append(subs.devices, notifier.OnModuleChange(l))
+		}
+	}
+	d.srv.mountsMu.Lock()
+	defer d.srv.mountsMu.Unlock()
+	if d.srv.mountListeners == nil {
+		d.srv.mountListeners = list.New()
+	}
+	subs.e = d.srv.mountListeners.PushBack(l)
+	return subs
+}
+
+type mountSubscription struct {
+	srv     *Server
+	e       *list.Element
+	devices []nodeutil.Subscription
+}
+
+func (sub *mountSubscription) Close() error {
+	sub.srv.mountsMu.Lock()
+	sub.srv.mountListeners.Remove(sub.e)
+	sub.srv.mountsMu.Unlock()
+	var err error
+	for _, s := range sub.devices {
+		if serr := s.Close(); serr != nil && err == nil {
+			err = serr
+		}
+	}
+	return err
+}
@@ -0,0 +1,79 @@
+package restconf
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/freeconf/restconf/device"
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+	"github.com/freeconf/yang/source"
+)
+
+func TestMount(t *testing.T) {
+	s, ts := newTestServer(t, nestedYang, nestedData)
+	defer ts.Close()
+	mount := func(mstr string, data map[string]interface{}) *device.Local {
+		m, err := parser.LoadModuleFromString(nil, mstr)
+		fc.RequireEqual(t, nil, err)
+		d := device.New(source.Dir("./yang"))
+		d.AddBrowser(node.NewBrowser(m, nodeutil.ReflectChild(data)))
+		return d
+	}
+	y := mount(`module y {
+		namespace "y";
+		prefix "y";
+		revision 0;
+		leaf speed {
+			type int32;
+		}
+		rpc reset {}
+	}`, map[string]interface{}{"speed": 10})
+	z := mount(`module z {
+		namespace "z";
+		prefix "z";
+		revision 0;
+		leaf speed {
+			type int32;
+		}
+		rpc stop {}
+	}`, map[string]interface{}{"speed": 20})
+	fc.RequireEqual(t, nil, s.Mount("y", y))
+	fc.RequireEqual(t, nil, s.Mount("z", z))
+	json := string(YangDataJsonMimeType)
+	data := ts.URL + "/restconf/data/"
+
+	_, actual := testRequest(t, "GET", data+"y:speed", "", "Accept", json)
+	fc.AssertEqual(t, `{"speed":10}`, actual)
+	_, actual = testRequest(t, "GET", data+"z:speed", "", "Accept", json)
+	fc.AssertEqual(t, `{"speed":20}`, actual)
+	_, actual = testRequest(t, "GET", data+"x:a/b", "", "Accept", json)
+	fc.AssertEqual(t, `{"b":"B"}`, actual)
+	resp, _ := testRequest(t, "PUT", data+"z:speed", `{"z:speed":30}`, "Content-Type", json)
+	fc.AssertEqual(t, 204, resp.StatusCode)
+	_, actual = testRequest(t, "GET", data+"z:speed", "", "Accept", json)
+	fc.AssertEqual(t, `{"speed":30}`, actual)
+	_, actual = testRequest(t, "GET", data+"y:speed", "", "Accept", json)
+	fc.AssertEqual(t, `{"speed":10}`, actual)
+
+	t.Run("listings", func(t *testing.T) {
+		_, actual := testRequest(t, "GET", ts.URL+"/restconf/operations", "", "Accept", json)
+		fc.AssertEqual(t, true, strings.Contains(actual, `"y:reset":[null],"z:stop":[null]`), actual)
+		_, actual = testRequest(t, "GET", data+"ietf-yang-library:modules-state", "", "Accept", json)
+		for _, name := range []string{"x", "y", "z"} {
+			fc.AssertEqual(t, true, strings.Contains(actual, `"name":"`+name+`"`), name, actual)
+		}
+	})
+
+	t.Run("unmount", func(t *testing.T) {
+		fc.RequireEqual(t, nil, s.Unmount("y"))
+		resp, _ := testRequest(t, "GET", data+"y:speed", "", "Accept", json)
+		fc.AssertEqual(t, 404, resp.StatusCode)
+		_, actual := testRequest(t, "GET", data+"z:speed", "", "Accept", json)
+		fc.AssertEqual(t, `{"speed":30}`, actual)
+		fc.AssertEqual(t, true, s.Unmount("y") != nil)
+		fc.AssertEqual(t, true, s.Mount("", y) != nil)
+	})
+}
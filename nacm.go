@@ -0,0 +1,247 @@
+package restconf
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+)
+
+// AccessOperation is what a request does to a resource. RFC8341 Sec. 3.2.2
+type AccessOperation string
+
+const (
+	AccessCreate AccessOperation = "create"
+	AccessRead   AccessOperation = "read"
+	AccessUpdate AccessOperation = "update"
+	AccessDelete AccessOperation = "delete"
+	AccessExec   AccessOperation = "exec"
+)
+
+// Authorizer decides if principal, from BasicAuth, TokenValidator or a
+// RequestFilter, may perform operation on the resource at path. Requests that
+// are not allowed are reported with error-tag "access-denied"
+type Authorizer interface {
+	Authorize(principal any, path *node.Path, op AccessOperation) (bool, error)
+}
+
+// accessOperation of an HTTP method on target. PUT is an update because
+// target has to exist already to be found
+func accessOperation(method string, target meta.Definition) AccessOperation {
+	switch method {
+	case "POST":
+		if meta.IsAction(target) {
+			return AccessExec
+		}
+		return AccessCreate
+	case "PUT", "PATCH":
+		return AccessUpdate
+	case "DELETE":
+		return AccessDelete
+	}
+	return AccessRead
+}
+
+func authorize(ctx context.Context, auth Authorizer, method string, target *node.Selection) error {
+	op := accessOperation(method, target.Meta())
+	allowed, err := auth.Authorize(PrincipalOf(ctx), target.Path, op)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return fmt.Errorf("%w. %s not allowed on %s", fc.UnauthorizedError, op, target.Path)
+	}
+	return nil
+}
+
+// accessConstraint authorizes data under target as it is read or edited.
+// Data that may not be read is left out, edits of data that may not be
+// edited fail with access-denied. Finding target is not checked, authorize
+// does that.
+type accessConstraint struct {
+	auth      Authorizer
+	principal any
+}
+
+func (c accessConstraint) check(sel *node.Selection, p *node.Path, op AccessOperation) (bool, error) {
+	if sel.Context != nil && sel.Context.Value(uncheckedAccessKey) != nil {
+		return true, nil
+	}
+	allowed, err := c.auth.Authorize(c.principal, p, op)
+	if err != nil || allowed {
+		return allowed, err
+	}
+	if op == AccessRead {
+		return false, nil
+	}
+	return false, fmt.Errorf("%w. %s not allowed on %s", fc.UnauthorizedError, op, p)
+}
+
+func (c accessConstraint) CheckContainerPreConstraints(r *node.ChildRequest) (bool, error) {
+	if r.IsNavigation() {
+		return true, nil
+	}
+	op := AccessRead
+	if r.New {
+		op = AccessCreate
+	} else if r.Delete {
+		op = AccessDelete
+	}
+	return c.check(r.Selection, &node.Path{Parent: r.Selection.Path, Meta: r.Meta}, op)
+}
+
+func (c accessConstraint) CheckListPreConstraints(r *node.ListRequest) (bool, error) {
+	// reading entries was checked when list was selected
+	if r.IsNavigation() || (!r.New && !r.Delete) {
+		return true, nil
+	}
+	op := AccessCreate
+	if r.Delete {
+		op = AccessDelete
+	}
+	// path of list entries' selection is the list
+	return c.check(r.Selection, r.Selection.Path, op)
+}
+
+func (c accessConstraint) CheckFieldPreConstraints(r *node.FieldRequest, hnd *node.ValueHandle) (bool, error) {
+	op := AccessRead
+	if r.Write {
+		op = AccessUpdate
+		if r.Clear {
+			op = AccessDelete
+		}
+	}
+	p := r.Selection.Path
+	if !meta.IsLeaf(p.Meta) {
+		p = &node.Path{Parent: p, Meta: r.Meta}
+	}
+	return c.check(r.Selection, p, op)
+}
+
+func addAccessConstraint(sel *node.Selection, auth Authorizer, principal any) {
+	if auth == nil {
+		return
+	}
+	sel.Constraints = node.NewConstraints(sel.Constraints)
+	sel.Constraints.AddConstraint("access", 0, 0, accessConstraint{auth: auth, principal: principal})
+}
+
+type accessContextKeyType string
+
+// uncheckedAccessKey marks selections from withoutAccessChecks
+var uncheckedAccessKey = accessContextKeyType("RESTCONF_UNCHECKED_ACCESS")
+
+// withoutAccessChecks is sel w/o accessConstraint so data can be recorded and
+// restored as it is and not as principal may read or edit it
+func withoutAccessChecks(sel *node.Selection) *node.Selection {
+	unchecked := *sel
+	unchecked.Context = context.WithValue(sel.Context, uncheckedAccessKey, true)
+	return &unchecked
+}
+
+// AccessRule permits or denies operations on a schema path and everything
+// under it. RFC8341 Sec. 3.4.5
+type AccessRule struct {
+	// principals rule applies to, "*" for everyone
+	Users []string
+
+	// module name or "*" for any module
+	Module string
+
+	// schema path under module w/o keys, empty for whole module
+	//
+	//	garage/car
+	Path string
+
+	// empty for all operations
+	Operations []AccessOperation
+
+	Permit bool
+}
+
+// AccessRules is an in-memory Authorizer. Rules are checked in order and the
+// first match decides. When nothing matches the operation's default decides.
+// Principals are compared as strings.
+type AccessRules struct {
+	Rules []AccessRule
+
+	// RFC8341 Sec. 3.4.1. NewAccessRules sets these to NACM defaults
+	ReadDefault  bool
+	WriteDefault bool
+	ExecDefault  bool
+}
+
+// NewAccessRules has NACM defaults of permitting reads and execs but not
+// writes
+func NewAccessRules() *AccessRules {
+	return &AccessRules{
+		ReadDefault: true,
+		ExecDefault: true,
+	}
+}
+
+func (rules *AccessRules) Authorize(principal any, path *node.Path, op AccessOperation) (bool, error) {
+	user := ""
+	if principal != nil {
+		user = fmt.Sprint(principal)
+	}
+	segs := path.Segments()
+	module := segs[0].Meta.Ident()
+	idents := make([]string, len(segs)-1)
+	for i, seg := range segs[1:] {
+		idents[i] = seg.Meta.Ident()
+	}
+	for _, rule := range rules.Rules {
+		if rule.matches(user, module, idents, op) {
+			return rule.Permit, nil
+		}
+	}
+	switch op {
+	case AccessRead:
+		return rules.ReadDefault, nil
+	case AccessExec:
+		return rules.ExecDefault, nil
+	}
+	return rules.WriteDefault, nil
+}
+
+func (rule AccessRule) matches(user string, module string, idents []string, op AccessOperation) bool {
+	if !matchesAny(rule.Users, user) {
+		return false
+	}
+	if rule.Module != "*" && rule.Module != module {
+		return false
+	}
+	if rule.Path != "" {
+		rulePath := strings.Split(strings.Trim(rule.Path, "/"), "/")
+		if len(rulePath) > len(idents) {
+			return false
+		}
+		for i, ident := range rulePath {
+			if idents[i] != ident {
+				return false
+			}
+		}
+	}
+	if len(rule.Operations) == 0 {
+		return true
+	}
+	for _, candidate := range rule.Operations {
+		if candidate == op {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesAny(users []string, user string) bool {
+	for _, candidate := range users {
+		if candidate == "*" || candidate == user {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package restconf
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+)
+
+func TestAccessRules(t *testing.T) {
+	s, ts := newTestServer(t, nestedYang, nestedData)
+	defer ts.Close()
+	s.BasicAuth = testAuth{"joe": "joe", "ann": "ann", "admin": "admin"}
+	rules := NewAccessRules()
+	rules.Rules = []AccessRule{
+		{Users: []string{"user:admin"}, Module: "*", Permit: true},
+		{Users: []string{"user:ann"}, Module: "x", Path: "a/c", Operations: []AccessOperation{AccessCreate, AccessUpdate, AccessDelete}},
+		{Users: []string{"user:ann"}, Module: "x", Operations: []AccessOperation{AccessUpdate}, Permit: true},
+		{Users: []string{"*"}, Module: "x", Path: "a/c", Operations: []AccessOperation{AccessRead}},
+	}
+	s.Authorizer = rules
+	ctype := string(YangDataJsonMimeType1)
+	tests := []struct {
+		user   string
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{user: "joe", method: "GET", path: "x:a/b", status: 200},
+		{user: "joe", method: "PATCH", path: "x:a", body: `{"b":"B2"}`, status: 403},
+		{user: "joe", method: "GET", path: "x:a/c", status: 403},
+		{user: "joe", method: "GET", path: "x:a/c/e=one", status: 403},
+		{user: "joe", method: "DELETE", path: "x:a/c/e=one", status: 403},
+		{user: "admin", method: "GET", path: "x:a/c", status: 200},
+		{user: "ann", method: "PATCH", path: "x:a", body: `{"b":"B3"}`, status: 200},
+		{user: "ann", method: "PATCH", path: "x:a", body: `{"c":{"d":"D2"}}`, status: 403},
+		{user: "admin", method: "PATCH", path: "x:a", body: `{"b":"B2"}`, status: 200},
+	}
+	for _, test := range tests {
+		desc := test.user + " " + test.method + " " + test.path
+		creds := base64.StdEncoding.EncodeToString([]byte(test.user + ":" + test.user))
+		resp, _ := testRequest(t, test.method, ts.URL+"/restconf/data/"+test.path, test.body,
+			"Accept", ctype, "Content-Type", ctype, "Authorization", "Basic "+creds)
+		fc.AssertEqual(t, test.status, resp.StatusCode, desc)
+	}
+
+	creds := base64.StdEncoding.EncodeToString([]byte("joe:joe"))
+	_, actual := testRequest(t, "DELETE", ts.URL+"/restconf/data/x:a", "",
+		"Accept", ctype, "Authorization", "Basic "+creds)
+	fc.AssertEqual(t, true, strings.Contains(actual, `"error-tag":"access-denied"`), actual)
+
+	// data under target that may not be read is left out
+	resp, actual := testRequest(t, "GET", ts.URL+"/restconf/data/x:a", "",
+		"Accept", ctype, "Authorization", "Basic "+creds)
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	fc.AssertEqual(t, `{"b":"B2"}`, actual)
+
+	// editing data under target that may not be edited is denied
+	creds = base64.StdEncoding.EncodeToString([]byte("ann:ann"))
+	edits := []string{
+		`{"edit-id":"1","operation":"merge","target":"/c/d","value":{"x:d":"D2"}}`,
+		`{"edit-id":"1","operation":"delete","target":"/c"}`,
+		`{"edit-id":"1","operation":"delete","target":"/c/e=one"}`,
+	}
+	for _, edit := range edits {
+		patch := `{"ietf-yang-patch:yang-patch":{"patch-id":"deny","edit":[` + edit + `]}}`
+		resp, actual = testRequest(t, "PATCH", ts.URL+"/restconf/data/x:a", patch,
+			"Accept", ctype, "Content-Type", string(YangPatchJsonMimeType), "Authorization", "Basic "+creds)
+		fc.AssertEqual(t, 403, resp.StatusCode, edit)
+		fc.AssertEqual(t, true, strings.Contains(actual, `"error-tag":"access-denied"`), actual)
+	}
+	creds = base64.StdEncoding.EncodeToString([]byte("admin:admin"))
+	_, actual = testRequest(t, "GET", ts.URL+"/restconf/data/x:a/c", "",
+		"Accept", ctype, "Authorization", "Basic "+creds)
+	fc.AssertEqual(t, `{"d":"D","e":[{"f":"one","g":{"h":1}},{"f":"two","g":{"h":2}}]}`, actual)
+}
@@ -37,7 +37,7 @@ func Node(mgmt *Server, ypath source.Opener) node.Node {
 			case "streamCount":
 				hnd.Val = val.Int32(mgmt.notifiers.Len())
 			case "subscriptionCount":
-				hnd.Val = val.Int32(subscribeCount)
+				hnd.Val = val.Int32(subscribeCount.Load())
 			default:
 				return p.Field(r, hnd)
 			}
@@ -0,0 +1,99 @@
+package restconf
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/xpath"
+)
+
+// notifyFilter keeps the events in a stream that match the filter parameter.
+// First segment of the XPath names the notification and the rest is evaluated
+// against the notification content.
+//
+//	/car:update/speed>10
+//
+// RFC8040 Sec. 4.8.4 and Sec. 6.3
+type notifyFilter struct {
+	expr string
+	p    *xpath.Path
+}
+
+// newNotifyFilter compiles filter against the notifications in stream so
+// mistakes are reported at subscription time instead of silently dropping
+// every event
+func newNotifyFilter(expr string, s *eventStream) (*notifyFilter, error) {
+	modules := s.d.Modules()
+	lookup := func(prefix string) (*meta.Module, error) {
+		for name, m := range modules {
+			if name == prefix || m.Prefix() == prefix {
+				return m, nil
+			}
+		}
+		return nil, fmt.Errorf("%w. unknown module '%s' in filter", fc.BadRequestError, prefix)
+	}
+	// freeconf xpaths are always relative to the notification
+	p, err := xpath.Parse2(lookup, strings.TrimPrefix(expr, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("%w. filter '%s'. %s", fc.BadRequestError, expr, err)
+	}
+	f := &notifyFilter{expr: expr, p: p}
+	if p.Expr != nil {
+		return nil, fmt.Errorf("%w. filter '%s' compares notification %s to a value", fc.BadRequestError, expr, p.Ident)
+	}
+	matched := false
+	for _, notif := range s.notifications() {
+		if !f.isNotification(notif) {
+			continue
+		}
+		matched = true
+		if err := checkFilterPath(notif, p.Next); err != nil {
+			return nil, fmt.Errorf("%w. filter '%s'. %s", fc.BadRequestError, expr, err)
+		}
+	}
+	if !matched {
+		return nil, fmt.Errorf("%w. filter '%s' matches no notification in stream %s", fc.BadRequestError, expr, s.Name)
+	}
+	return f, nil
+}
+
+func (f *notifyFilter) isNotification(notif *meta.Notification) bool {
+	if f.p.Ident != notif.Ident() {
+		return false
+	}
+	return f.p.Module == "" || f.p.Module == meta.OriginalModule(notif).Ident()
+}
+
+// checkFilterPath verifies each segment is in the schema
+func checkFilterPath(parent meta.Definition, p *xpath.Path) error {
+	for ; p != nil; p = p.Next {
+		container, valid := parent.(meta.HasDefinitions)
+		if !valid {
+			return fmt.Errorf("'%s' is not a container or list", parent.Ident())
+		}
+		if parent = meta.Find(container, p.Ident); parent == nil {
+			return fmt.Errorf("'%s' not found in %s", p.Ident, container.Ident())
+		}
+		isLeaf := meta.IsLeaf(parent)
+		if p.Expr != nil && !isLeaf {
+			return fmt.Errorf("'%s' is not a leaf and cannot be compared", p.Ident)
+		}
+		if isLeaf && (p.Expr == nil || p.Next != nil) {
+			return fmt.Errorf("'%s' is a leaf and must be compared to a value", p.Ident)
+		}
+	}
+	return nil
+}
+
+func (f *notifyFilter) matches(e streamEvent) (bool, error) {
+	notif, valid := e.notif.Event.Meta().(*meta.Notification)
+	if !valid || !f.isNotification(notif) {
+		return false, nil
+	}
+	if f.p.Next == nil {
+		return true, nil
+	}
+	return e.notif.Event.XPredicate(f.p.Next)
+}
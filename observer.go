@@ -0,0 +1,181 @@
+package restconf
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Observer is told about every request and event stream so they can be
+// reported to a metrics system such as Prometheus. Calls are made from the
+// goroutines serving requests so implementations must be safe for concurrent
+// use. Paths are as requested so they include list keys.
+type Observer interface {
+	RequestStarted(method string, path string)
+
+	// Event streams finish when subscriber disconnects
+	RequestFinished(method string, path string, status int, duration time.Duration)
+
+	// stream is name of stream under {+restconf}/streams or module:path of
+	// a notification under {+restconf}/data
+	StreamOpened(stream string)
+	StreamClosed(stream string)
+}
+
+// NopObserver ignores everything and is used when Server.Observer is not set
+type NopObserver struct{}
+
+func (NopObserver) RequestStarted(string, string)                      {}
+func (NopObserver) RequestFinished(string, string, int, time.Duration) {}
+func (NopObserver) StreamOpened(string)                                {}
+func (NopObserver) StreamClosed(string)                                {}
+
+// RequestKey identifies requests CountingObserver counts separately
+type RequestKey struct {
+	Method string
+	Path   string
+	Status int
+}
+
+// CountingObserver keeps counts in memory for tests or for an application to
+// read and report
+type CountingObserver struct {
+	mu          sync.Mutex
+	inFlight    int
+	requests    map[RequestKey]int
+	latency     map[RequestKey]time.Duration
+	openStreams map[string]int
+}
+
+func NewCountingObserver() *CountingObserver {
+	return &CountingObserver{
+		requests:    make(map[RequestKey]int),
+		latency:     make(map[RequestKey]time.Duration),
+		openStreams: make(map[string]int),
+	}
+}
+
+func (c *CountingObserver) RequestStarted(method string, path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.inFlight++
+}
+
+func (c *CountingObserver) RequestFinished(method string, path string, status int, duration time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.inFlight--
+	key := RequestKey{Method: method, Path: path, Status: status}
+	c.requests[key]++
+	c.latency[key] += duration
+}
+
+func (c *CountingObserver) StreamOpened(stream string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.openStreams[stream]++
+}
+
+func (c *CountingObserver) StreamClosed(stream string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.openStreams[stream]--; c.openStreams[stream] <= 0 {
+		delete(c.openStreams, stream)
+	}
+}
+
+// InFlight is number of requests started but not finished
+func (c *CountingObserver) InFlight() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.inFlight
+}
+
+// Requests is number of finished requests by method, path and status
+func (c *CountingObserver) Requests() map[RequestKey]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	copy := make(map[RequestKey]int, len(c.requests))
+	for k, v := range c.requests {
+		copy[k] = v
+	}
+	return copy
+}
+
+// Latency is total time spent on finished requests
+func (c *CountingObserver) Latency(key RequestKey) time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.latency[key]
+}
+
+// OpenStreams is number of subscribers by stream
+func (c *CountingObserver) OpenStreams() map[string]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	copy := make(map[string]int, len(c.openStreams))
+	for k, v := range c.openStreams {
+		copy[k] = v
+	}
+	return copy
+}
+
+func (srv *Server) observer() Observer {
+	if srv.Observer == nil {
+		return NopObserver{}
+	}
+	return srv.Observer
+}
+
+// statusRecorder remembers response status for Observer
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	if s.status == 0 {
+		s.status = status
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusRecorder) Write(data []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	return s.ResponseWriter.Write(data)
+}
+
+func (s *statusRecorder) Flush() {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	if flusher, valid := s.ResponseWriter.(http.Flusher); valid {
+		flusher.Flush()
+	}
+}
+
+// observe wraps w to report request to observer when returned func is called
+func observe(o Observer, w http.ResponseWriter, r *http.Request) (http.ResponseWriter, func()) {
+	method, path := r.Method, r.URL.Path
+	id := RequestIdOf(r.Context())
+	withId, hasId := o.(RequestIdObserver)
+	started := time.Now()
+	if hasId {
+		withId.RequestStartedWithId(id, method, path)
+	} else {
+		o.RequestStarted(method, path)
+	}
+	rec := &statusRecorder{ResponseWriter: w}
+	return rec, func() {
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		if hasId {
+			withId.RequestFinishedWithId(id, method, path, rec.status, time.Since(started))
+		} else {
+			o.RequestFinished(method, path, rec.status, time.Since(started))
+		}
+	}
+}
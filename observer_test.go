@@ -0,0 +1,64 @@
+package restconf
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/parser"
+)
+
+func TestObserver(t *testing.T) {
+	s, ts := newTestServer(t, nestedYang, nestedData)
+	defer ts.Close()
+	o := NewCountingObserver()
+	s.Observer = o
+
+	resp, _ := testRequest(t, "GET", ts.URL+"/restconf/data/x:a", "")
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	resp, _ = testRequest(t, "POST", ts.URL+"/restconf/data/x:nope", `{}`)
+	fc.AssertEqual(t, 404, resp.StatusCode)
+	expected := map[RequestKey]int{
+		{Method: "GET", Path: "/restconf/data/x:a", Status: 200}:     1,
+		{Method: "POST", Path: "/restconf/data/x:nope", Status: 404}: 1,
+	}
+	fc.AssertEqual(t, expected, o.Requests())
+	fc.AssertEqual(t, 0, o.InFlight())
+	fc.AssertEqual(t, true, o.Latency(RequestKey{Method: "GET", Path: "/restconf/data/x:a", Status: 200}) > 0)
+}
+
+func TestObserverStream(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, streamYang)
+	fc.RequireEqual(t, nil, err)
+	tn := newStreamTestNode()
+	s, ts := newTestServerWithNode(t, m, tn.node())
+	defer ts.Close()
+	o := NewCountingObserver()
+	s.Observer = o
+	for _, test := range []struct {
+		url    string
+		stream string
+	}{
+		{url: "/restconf/streams/NETCONF", stream: "NETCONF"},
+		{url: "/restconf/data/x:y", stream: "x:y"},
+	} {
+		ctx, cancel := context.WithCancel(context.Background())
+		req := httptest.NewRequest("GET", test.url, nil).WithContext(ctx)
+		req.Header.Set("Accept", string(TextStreamMimeType))
+		w := newFlushRecorder()
+		done := make(chan bool)
+		go func() {
+			s.ServeHTTP(w, req)
+			done <- true
+		}()
+		<-w.flushed
+		fc.AssertEqual(t, map[string]int{test.stream: 1}, o.OpenStreams(), test.url)
+		fc.AssertEqual(t, 1, o.InFlight(), test.url)
+		cancel()
+		<-done
+		fc.AssertEqual(t, 0, len(o.OpenStreams()), test.url)
+		fc.AssertEqual(t, 1, o.Requests()[RequestKey{Method: "GET", Path: test.url, Status: http.StatusOK}], test.url)
+	}
+}
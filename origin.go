@@ -0,0 +1,75 @@
+package restconf
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+)
+
+// with-origin parameter asks for where each reported value came from.
+// RFC8527 Sec. 3.2.2. Allowed on the operational datastore and on
+// {+restconf}/data that reports config and state like operational would.
+//
+//	"speed":1000,
+//	"@speed":{"ietf-origin:origin":"ietf-origin:learned"}
+//	<speed or:origin="or:learned">1000</speed>
+const (
+	withOriginParam      = "with-origin"
+	withOriginCapability = "urn:ietf:params:restconf:capability:with-origin:1.0"
+	originModule         = "ietf-origin"
+	originNs             = "urn:ietf:params:xml:ns:yang:ietf-origin"
+)
+
+// Origins of data, identities in ietf-origin. RFC8342 Sec. 7.4
+const (
+	OriginIntended = "intended"
+	OriginDynamic  = "dynamic"
+	OriginSystem   = "system"
+	OriginLearned  = "learned"
+	OriginDefault  = "default"
+	OriginUnknown  = "unknown"
+)
+
+// OriginNode is a node that knows where values of its leaves came from.
+// Origin is one of the Origin constants, or an identity derived from origin
+// in another module written as module:identity, or empty when not known and
+// then leaf is not annotated.
+type OriginNode interface {
+	node.Node
+	Origin(r node.FieldRequest) string
+}
+
+// parseWithOrigin reads with-origin which has no value
+func parseWithOrigin(s string) (bool, error) {
+	if s != "" {
+		return false, fmt.Errorf("%w. '%s' does not take a value, got '%s'", fc.BadRequestError, withOriginParam, s)
+	}
+	return true, nil
+}
+
+// originOf leaf being read when node knows it
+func originOf(r node.FieldRequest) string {
+	if n, valid := r.Selection.Node.(OriginNode); valid {
+		return n.Origin(r)
+	}
+	return ""
+}
+
+// originJson is origin as identityref value in JSON
+func originJson(origin string) string {
+	if strings.Contains(origin, ":") {
+		return origin
+	}
+	return originModule + ":" + origin
+}
+
+// originXml is origin as identityref value in XML w/prefix "or" declared
+// for ietf-origin
+func originXml(origin string) string {
+	if strings.Contains(origin, ":") {
+		return origin
+	}
+	return "or:" + origin
+}
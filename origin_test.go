@@ -0,0 +1,122 @@
+package restconf
+
+import (
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+)
+
+// originTestNode reports origins of leaves by name except under stats which
+// knows nothing about origin
+type originTestNode struct {
+	node.Node
+	origins map[string]string
+}
+
+func (n originTestNode) Child(r node.ChildRequest) (node.Node, error) {
+	child, err := n.Node.Child(r)
+	if child == nil || err != nil || r.Meta.Ident() == "stats" {
+		return child, err
+	}
+	return originTestNode{Node: child, origins: n.origins}, nil
+}
+
+func (n originTestNode) Origin(r node.FieldRequest) string {
+	return n.origins[r.Meta.Ident()]
+}
+
+func TestWithOrigin(t *testing.T) {
+	mstr := `module x {
+		namespace "x";
+		prefix "x";
+		revision 0;
+		container a {
+			leaf name {
+				type string;
+			}
+			leaf speed {
+				type int32;
+			}
+			leaf mtu {
+				type int32;
+				default 1500;
+			}
+			leaf-list addr {
+				type string;
+			}
+			leaf descr {
+				type string;
+			}
+			container stats {
+				config false;
+				leaf in {
+					type int32;
+				}
+			}
+		}
+	}`
+	m, err := parser.LoadModuleFromString(nil, mstr)
+	fc.RequireEqual(t, nil, err)
+	data := map[string]interface{}{
+		"a": map[string]interface{}{
+			"name":  "eth0",
+			"speed": 1000,
+			"addr":  []interface{}{"10.0.0.1", "10.0.0.2"},
+			"descr": "uplink",
+			"stats": map[string]interface{}{
+				"in": 10,
+			},
+		},
+	}
+	n := originTestNode{
+		Node: nodeutil.ReflectChild(data),
+		origins: map[string]string{
+			"name":  OriginIntended,
+			"speed": OriginLearned,
+			"mtu":   OriginDefault,
+			"addr":  OriginSystem,
+			"in":    OriginLearned,
+		},
+	}
+	_, ts := newTestServerWithNode(t, m, n)
+	defer ts.Close()
+	url := ts.URL + "/restconf/data/x:a"
+	json := string(YangDataJsonMimeType)
+	xml := string(YangDataXmlMimeType)
+
+	resp, actual := testRequest(t, "GET", url+"?with-origin", "", "Accept", json)
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	fc.AssertEqual(t, `{"name":"eth0","@name":{"ietf-origin:origin":"ietf-origin:intended"},`+
+		`"speed":1000,"@speed":{"ietf-origin:origin":"ietf-origin:learned"},`+
+		`"mtu":1500,"@mtu":{"ietf-origin:origin":"ietf-origin:default"},`+
+		`"addr":["10.0.0.1","10.0.0.2"],"@addr":[{"ietf-origin:origin":"ietf-origin:system"},{"ietf-origin:origin":"ietf-origin:system"}],`+
+		`"descr":"uplink","stats":{"in":10}}`, actual)
+
+	resp, actual = testRequest(t, "GET", url+"?with-origin", "", "Accept", xml)
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	fc.AssertEqual(t, `<a xmlns="x" xmlns:or="urn:ietf:params:xml:ns:yang:ietf-origin">`+
+		`<name or:origin="or:intended">eth0</name><speed or:origin="or:learned">1000</speed>`+
+		`<mtu or:origin="or:default">1500</mtu>`+
+		`<addr or:origin="or:system">10.0.0.1</addr><addr or:origin="or:system">10.0.0.2</addr>`+
+		`<descr>uplink</descr><stats><in>10</in></stats></a>`, actual)
+
+	t.Run("with-defaults", func(t *testing.T) {
+		_, actual := testRequest(t, "GET", url+"/mtu?with-origin&with-defaults=report-all-tagged", "", "Accept", json)
+		fc.AssertEqual(t, `{"mtu":1500,"@mtu":{"ietf-netconf-with-defaults:default":true,"ietf-origin:origin":"ietf-origin:default"}}`, actual)
+	})
+
+	t.Run("without", func(t *testing.T) {
+		_, actual := testRequest(t, "GET", url+"/speed", "", "Accept", json)
+		fc.AssertEqual(t, `{"speed":1000}`, actual)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		resp, _ := testRequest(t, "GET", url+"?with-origin=yes", "", "Accept", json)
+		fc.AssertEqual(t, 400, resp.StatusCode)
+		resp, _ = testRequest(t, "PUT", url+"/speed?with-origin", `{"x:speed":10}`, "Content-Type", json)
+		fc.AssertEqual(t, 400, resp.StatusCode)
+	})
+}
@@ -0,0 +1,112 @@
+package restconf
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/val"
+)
+
+// vendor parameters to read a list one window at a time
+//
+//	GET .../data/car:cars?offset=20&limit=10
+const (
+	offsetParam = "offset"
+	limitParam  = "limit"
+)
+
+// listPage is a window of entries of the target list. Entries are counted
+// after other constraints like "where" have hidden theirs so windows are
+// stable regardless of whether list node honors the requested row.
+type listPage struct {
+	list   *meta.List
+	depth  int
+	offset int64
+	limit  int64
+	count  int64
+	more   bool
+}
+
+// newListPage is nil when request has no offset or limit or when target is
+// not a whole list, parameters are ignored on anything else
+func newListPage(target *node.Selection, p QueryParams) (*listPage, error) {
+	offset, hasOffset, err := pageParam(p, offsetParam, 0)
+	if err != nil {
+		return nil, err
+	}
+	limit, hasLimit, err := pageParam(p, limitParam, 1)
+	if err != nil {
+		return nil, err
+	}
+	if !hasOffset && !hasLimit {
+		return nil, nil
+	}
+	list, isList := target.Meta().(*meta.List)
+	if !isList || target.InsideList {
+		return nil, nil
+	}
+	page := &listPage{
+		list:   list,
+		depth:  target.Path.Len(),
+		offset: offset,
+		limit:  limit,
+	}
+	if !hasLimit {
+		page.limit = -1
+	}
+	target.Constraints = node.NewConstraints(target.Constraints)
+	target.Constraints.AddConstraint("page", 90, 90, page)
+	return page, nil
+}
+
+func pageParam(p QueryParams, name string, min int64) (int64, bool, error) {
+	v, has := p.other[name]
+	if !has {
+		return 0, false, nil
+	}
+	n, err := strconv.ParseInt(v[0], 10, 64)
+	if err != nil || n < min {
+		return 0, false, fmt.Errorf("%w. %s must be an integer of at least %d, got '%s'", fc.BadRequestError, name, min, v[0])
+	}
+	return n, true, nil
+}
+
+func (p *listPage) CheckListPostConstraints(r node.ListRequest, child *node.Selection, key []val.Value) (bool, bool, error) {
+	if r.IsNavigation() || r.Meta != p.list || r.Selection.Path.Len() != p.depth {
+		return true, true, nil
+	}
+	row := p.count
+	p.count++
+	if row < p.offset {
+		return true, false, nil
+	}
+	if p.limit >= 0 && row >= p.offset+p.limit {
+		p.more = true
+		return false, false, nil
+	}
+	return true, true, nil
+}
+
+// nextLink is the Link header to the window after this one or empty when
+// this is the last window
+//
+//	<.../data/car:cars?limit=10&offset=30>; rel="next"
+func (p *listPage) nextLink(r *http.Request) (string, error) {
+	if !p.more {
+		return "", nil
+	}
+	u, err := url.ParseRequestURI(r.RequestURI)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	q.Set(offsetParam, strconv.FormatInt(p.offset+p.limit, 10))
+	q.Set(limitParam, strconv.FormatInt(p.limit, 10))
+	u.RawQuery = q.Encode()
+	return fmt.Sprintf(`<%s>; rel="next"`, u.RequestURI()), nil
+}
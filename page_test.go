@@ -0,0 +1,89 @@
+package restconf
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+)
+
+func TestListPage(t *testing.T) {
+	mstr := `module x {
+		revision 0;
+		container c {
+			leaf y {
+				type string;
+			}
+		}
+		list l {
+			key id;
+			leaf id {
+				type int32;
+			}
+		}
+	}`
+	var entries []string
+	for i := 0; i < 100; i++ {
+		entries = append(entries, fmt.Sprintf(`{"id":%d}`, i))
+	}
+	data := fmt.Sprintf(`{"c":{"y":"hi"},"l":[%s]}`, strings.Join(entries, ","))
+	_, ts := newTestServer(t, mstr, data)
+	defer ts.Close()
+	rfc := string(YangDataJsonMimeType1)
+	next := regexp.MustCompile(`^<([^>]+)>; rel="next"$`)
+
+	var ids []int
+	pages := 0
+	url := "/restconf/data/x:l?limit=10"
+	for url != "" {
+		resp, actual := testRequest(t, "GET", ts.URL+url, "", "Accept", rfc)
+		fc.RequireEqual(t, 200, resp.StatusCode)
+		var page struct {
+			L []struct{ Id int } `json:"x:l"`
+		}
+		fc.RequireEqual(t, nil, json.Unmarshal([]byte(actual), &page))
+		fc.AssertEqual(t, 10, len(page.L))
+		for _, e := range page.L {
+			ids = append(ids, e.Id)
+		}
+		pages++
+		url = ""
+		if link := resp.Header.Get("Link"); link != "" {
+			m := next.FindStringSubmatch(link)
+			fc.RequireEqual(t, 2, len(m))
+			url = m[1]
+		}
+	}
+	fc.AssertEqual(t, 10, pages)
+	fc.RequireEqual(t, 100, len(ids))
+	for i, id := range ids {
+		fc.AssertEqual(t, i, id)
+	}
+
+	// last partial window has no next
+	resp, actual := testRequest(t, "GET", ts.URL+"/restconf/data/x:l?offset=95&limit=10", "", "Accept", rfc)
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	fc.AssertEqual(t, `{"x:l":[{"id":95},{"id":96},{"id":97},{"id":98},{"id":99}]}`, actual)
+	fc.AssertEqual(t, "", resp.Header.Get("Link"))
+
+	// other parameters are kept in next link
+	resp, _ = testRequest(t, "GET", ts.URL+"/restconf/data/x:l?limit=2&depth=1", "")
+	fc.AssertEqual(t, `</restconf/data/x:l?depth=1&limit=2&offset=2>; rel="next"`, resp.Header.Get("Link"))
+
+	// ignored when target is not a list
+	resp, actual = testRequest(t, "GET", ts.URL+"/restconf/data/x:c?limit=1", "", "Accept", rfc)
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	fc.AssertEqual(t, `{"y":"hi"}`, actual)
+	fc.AssertEqual(t, "", resp.Header.Get("Link"))
+	resp, actual = testRequest(t, "GET", ts.URL+"/restconf/data/x:l=3?limit=1", "", "Accept", rfc)
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	fc.AssertEqual(t, `{"id":3}`, actual)
+
+	resp, _ = testRequest(t, "GET", ts.URL+"/restconf/data/x:l?limit=0", "")
+	fc.AssertEqual(t, 400, resp.StatusCode)
+	resp, _ = testRequest(t, "GET", ts.URL+"/restconf/data/x:l?offset=x", "")
+	fc.AssertEqual(t, 400, resp.StatusCode)
+}
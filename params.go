@@ -0,0 +1,315 @@
+package restconf
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+)
+
+// RFC8040 Sec. 4.8
+const (
+	contentParam   = "content"
+	depthParam     = "depth"
+	fieldsParam    = "fields"
+	filterParam    = "filter"
+	startTimeParam = "start-time"
+	stopTimeParam  = "stop-time"
+)
+
+// RFC8040 Sec. 4.8.1
+const (
+	contentAll       = "all"
+	contentConfig    = "config"
+	contentNonConfig = "nonconfig"
+)
+
+// RFC8040 Sec. 4.8.2 - depth is 1..65535 or "unbounded"
+const (
+	depthUnbounded      = "unbounded"
+	depthMax            = 65535
+	depthUnboundedValue = depthMax
+)
+
+// QueryParams are the RESTCONF query parameters of a request, parsed and
+// validated.  Parameters that are not given are left as the zero value.
+// RFC8040 Sec. 4.8
+type QueryParams struct {
+	// 1..65535, "unbounded" is 65535
+	Depth        int
+	Fields       string
+	Content      string
+	WithDefaults string
+	Insert       string
+	Point        string
+	Filter       string
+	StartTime    time.Time
+	StopTime     time.Time
+
+	// annotate values w/origin, see OriginNode
+	WithOrigin bool
+
+	fields *fieldsSelector
+	insert *insertPoint
+
+	// parameters that are not RESTCONF parameters are left to freeconf
+	// (e.g. fc.range, where) or the application
+	other url.Values
+}
+
+// ParseQueryParams reads and validates the RESTCONF query parameters in the
+// url.  Errors are bad requests so they are reported as "invalid-value"
+func ParseQueryParams(u *url.URL) (QueryParams, error) {
+	return parseQueryParams(u, nil)
+}
+
+// parseQueryParams is ParseQueryParams with fields expressions from cache
+func parseQueryParams(u *url.URL, fields *fieldsCache) (QueryParams, error) {
+	var p QueryParams
+	vals := u.Query()
+	p.other = make(url.Values)
+	for name, v := range vals {
+		if len(v) > 1 {
+			if _, isRestconf := queryParamMethods[name]; isRestconf {
+				return p, fmt.Errorf("%w. parameter '%s' given more than once", fc.BadRequestError, name)
+			}
+		}
+		s := v[0]
+		var err error
+		switch name {
+		case depthParam:
+			p.Depth, err = parseDepth(s)
+		case fieldsParam:
+			p.Fields = s
+			p.fields, err = fields.parse(s)
+		case contentParam:
+			p.Content = s
+			switch s {
+			case contentAll, contentConfig, contentNonConfig:
+			default:
+				err = fmt.Errorf("%w. content must be %s, %s or %s, got '%s'", fc.BadRequestError, contentAll, contentConfig, contentNonConfig, s)
+			}
+		case withDefaultsParam:
+			p.WithDefaults = s
+			err = checkWithDefaults(s)
+		case withOriginParam:
+			p.WithOrigin, err = parseWithOrigin(s)
+		case insertParam:
+			p.Insert = s
+		case pointParam:
+			p.Point = s
+		case filterParam:
+			p.Filter = s
+		case startTimeParam:
+			p.StartTime, err = parseQueryTime(name, s)
+		case stopTimeParam:
+			p.StopTime, err = parseQueryTime(name, s)
+		default:
+			p.other[name] = v
+		}
+		if err != nil {
+			return p, err
+		}
+	}
+	var err error
+	if p.insert, err = newInsertPoint(p.Insert, p.Point); err != nil {
+		return p, err
+	}
+	if !p.StopTime.IsZero() {
+		if p.StartTime.IsZero() {
+			return p, fmt.Errorf("%w. %s requires %s", fc.BadRequestError, stopTimeParam, startTimeParam)
+		}
+		if p.StopTime.Before(p.StartTime) {
+			return p, fmt.Errorf("%w. %s is before %s", fc.BadRequestError, stopTimeParam, startTimeParam)
+		}
+	}
+	return p, nil
+}
+
+// ErrorMode is how forgiving server is of requests that are not quite right
+type ErrorMode int
+
+const (
+	// ErrorModeStrict rejects requests with unknown query parameters
+	ErrorModeStrict ErrorMode = iota
+
+	// ErrorModeLenient logs and ignores unknown query parameters
+	ErrorModeLenient
+)
+
+func (m ErrorMode) String() string {
+	if m == ErrorModeLenient {
+		return "lenient"
+	}
+	return "strict"
+}
+
+type ErrorModeContextKeyType string
+
+// ErrorModeContextKey overrides Server.ErrorMode for a request when set in
+// request context, for example from a RequestFilter
+var ErrorModeContextKey = ErrorModeContextKeyType("RESTCONF_ERROR_MODE")
+
+func errorModeOf(ctx context.Context) ErrorMode {
+	mode, _ := ctx.Value(ErrorModeContextKey).(ErrorMode)
+	return mode
+}
+
+// parameters freeconf supports in addition to RESTCONF parameters, anything
+// starting with "fc." is also reserved for freeconf
+var freeconfParams = map[string]bool{
+	"where":                   true,
+	SimplifiedComplianceParam: true,
+	prettyParam:               true,
+	dryRunParam:               true,
+	offsetParam:               true,
+	limitParam:                true,
+}
+
+// pretty is true when request asks for indented response with ?pretty
+func (p *QueryParams) pretty() bool {
+	v, has := p.other[prettyParam]
+	return has && v[0] != "false"
+}
+
+// CheckUnknown reports parameters that are neither RESTCONF nor freeconf
+// parameters. In lenient mode they are logged and dropped instead.
+func (p *QueryParams) CheckUnknown(mode ErrorMode) error {
+	for name := range p.other {
+		if freeconfParams[name] || strings.HasPrefix(name, "fc.") {
+			continue
+		}
+		if mode == ErrorModeLenient {
+			fc.Debug.Printf("ignoring unknown parameter '%s'", name)
+			delete(p.other, name)
+			continue
+		}
+		return fmt.Errorf("%w. unknown parameter '%s'", fc.BadRequestError, name)
+	}
+	return nil
+}
+
+// methods each parameter is allowed on. RFC8040 Sec. 4.8
+var queryParamMethods = map[string][]string{
+	contentParam:      {"GET", "HEAD"},
+	depthParam:        {"GET", "HEAD"},
+	fieldsParam:       {"GET", "HEAD"},
+	filterParam:       {"GET", "HEAD"},
+	insertParam:       {"POST", "PUT"},
+	pointParam:        {"POST", "PUT"},
+	startTimeParam:    {"GET", "HEAD"},
+	stopTimeParam:     {"GET", "HEAD"},
+	withDefaultsParam: {"GET", "HEAD"},
+	withOriginParam:   {"GET", "HEAD"},
+}
+
+// CheckMethod verifies parameters are allowed with the request method
+func (p QueryParams) CheckMethod(method string) error {
+	given := map[string]bool{
+		contentParam:      p.Content != "",
+		depthParam:        p.Depth != 0,
+		fieldsParam:       p.Fields != "",
+		filterParam:       p.Filter != "",
+		insertParam:       p.Insert != "",
+		pointParam:        p.Point != "",
+		startTimeParam:    !p.StartTime.IsZero(),
+		stopTimeParam:     !p.StopTime.IsZero(),
+		withDefaultsParam: p.WithDefaults != "",
+		withOriginParam:   p.WithOrigin,
+	}
+	for name, methods := range queryParamMethods {
+		if !given[name] {
+			continue
+		}
+		allowed := false
+		for _, candidate := range methods {
+			allowed = allowed || candidate == method
+		}
+		if !allowed {
+			return fmt.Errorf("%w. parameter '%s' not allowed with %s", fc.BadRequestError, name, method)
+		}
+	}
+	return nil
+}
+
+func parseDepth(s string) (int, error) {
+	if s == depthUnbounded {
+		return depthUnboundedValue, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 || n > depthMax {
+		return 0, fmt.Errorf("%w. depth must be 1..%d or '%s', got '%s'", fc.BadRequestError, depthMax, depthUnbounded, s)
+	}
+	return n, nil
+}
+
+func parseQueryTime(name string, s string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return t, fmt.Errorf("%w. %s must be a date-and-time, got '%s'", fc.BadRequestError, name, s)
+	}
+	return t, nil
+}
+
+// buildConstraints applies query parameters to selection.  Parameters freeconf
+// does not support, or does not support completely, are handled here and the
+// rest are left to freeconf.
+func buildConstraints(sel *node.Selection, endpointId int, p QueryParams) error {
+	if err := checkContentParam(sel, endpointId, p); err != nil {
+		return err
+	}
+	params := make(url.Values)
+	for name, v := range p.other {
+		params[name] = v
+	}
+	if p.Depth != 0 {
+		params.Set(depthParam, strconv.Itoa(p.Depth))
+	}
+	if p.Content != "" {
+		params.Set(contentParam, p.Content)
+	}
+	if p.Filter != "" && endpointId != endpointData {
+		params.Set(filterParam, p.Filter)
+	}
+	switch p.WithDefaults {
+	case WithDefaultsTrim, WithDefaultsReportAll:
+		params.Set(withDefaultsParam, p.WithDefaults)
+	case WithDefaultsExplicit:
+		sel.Constraints = node.NewConstraints(sel.Constraints)
+		sel.Constraints.AddConstraint(withDefaultsParam, 50, 50, withDefaultsExplicit{})
+	}
+	if err := node.BuildConstraints(sel, params); err != nil {
+		return err
+	}
+	if p.fields != nil {
+		sel.Constraints = node.NewConstraints(sel.Constraints)
+		sel.Constraints.AddConstraint(fieldsParam, 10, 50, p.fields)
+	}
+	if p.Filter != "" && endpointId == endpointData {
+		f, err := newDataFilter(sel, p.Filter)
+		if err != nil {
+			return err
+		}
+		sel.Constraints = node.NewConstraints(sel.Constraints)
+		sel.Constraints.AddConstraint(filterParam, 10, 50, f)
+	}
+	return nil
+}
+
+// RFC8040 Sec. 4.8.1 - content only makes sense on data resources.
+func checkContentParam(sel *node.Selection, endpointId int, p QueryParams) error {
+	if p.Content == "" {
+		return nil
+	}
+	isData := endpointId == endpointData && !meta.IsAction(sel.Meta()) && !meta.IsNotification(sel.Meta())
+	if !isData {
+		return fmt.Errorf("%w. content parameter is only allowed on data resources", fc.BadRequestError)
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package restconf
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+	"github.com/freeconf/yang/val"
+)
+
+func TestParseQueryParams(t *testing.T) {
+	start := time.Date(2020, time.March, 4, 5, 6, 7, 0, time.UTC)
+	tests := []struct {
+		query    string
+		method   string
+		expected QueryParams
+		err      string
+	}{
+		{
+			query:    "depth=unbounded&content=config&with-defaults=trim&fields=a(b)",
+			method:   "GET",
+			expected: QueryParams{Depth: depthMax, Content: "config", WithDefaults: "trim", Fields: "a(b)"},
+		},
+		{
+			query:    "insert=before&point=/x:a/e=one",
+			method:   "POST",
+			expected: QueryParams{Insert: "before", Point: "/x:a/e=one"},
+		},
+		{
+			query:    "start-time=2020-03-04T05:06:07Z&stop-time=2020-03-04T05:06:07Z",
+			method:   "GET",
+			expected: QueryParams{StartTime: start, StopTime: start},
+		},
+		{
+			query:    "fc.range=e!1-2",
+			method:   "GET",
+			expected: QueryParams{},
+		},
+		{
+			query: "depth=1&depth=2",
+			err:   "bad request. parameter 'depth' given more than once",
+		},
+		{
+			query: "content=some",
+			err:   "bad request. content must be all, config or nonconfig, got 'some'",
+		},
+		{
+			query: "point=/x:a/e=one",
+			err:   "bad request. point parameter requires insert parameter",
+		},
+		{
+			query: "insert=after",
+			err:   "bad request. missing element. point parameter required when insert is 'after'",
+		},
+		{
+			query: "stop-time=2020-03-04T05:06:07Z",
+			err:   "bad request. stop-time requires start-time",
+		},
+		{
+			query: "start-time=2020-03-04T05:06:07Z&stop-time=2020-03-04T05:06:06Z",
+			err:   "bad request. stop-time is before start-time",
+		},
+		{
+			query: "start-time=yesterday",
+			err:   "bad request. start-time must be a date-and-time, got 'yesterday'",
+		},
+		{
+			query:  "depth=1",
+			method: "POST",
+			err:    "bad request. parameter 'depth' not allowed with POST",
+		},
+		{
+			query:  "insert=first",
+			method: "GET",
+			err:    "bad request. parameter 'insert' not allowed with GET",
+		},
+		{
+			query:  "with-defaults=trim",
+			method: "PUT",
+			err:    "bad request. parameter 'with-defaults' not allowed with PUT",
+		},
+	}
+	for _, test := range tests {
+		u, err := url.Parse("http://server/restconf/data/x:a?" + test.query)
+		fc.RequireEqual(t, nil, err)
+		actual, err := ParseQueryParams(u)
+		if err == nil {
+			err = actual.CheckMethod(test.method)
+		}
+		if test.err != "" {
+			fc.AssertEqual(t, test.err, err.Error(), test.query)
+			continue
+		}
+		fc.RequireEqual(t, nil, err, test.query)
+		fc.AssertEqual(t, test.expected.Depth, actual.Depth, test.query)
+		fc.AssertEqual(t, test.expected.Content, actual.Content, test.query)
+		fc.AssertEqual(t, test.expected.WithDefaults, actual.WithDefaults, test.query)
+		fc.AssertEqual(t, test.expected.Fields, actual.Fields, test.query)
+		fc.AssertEqual(t, test.expected.Insert, actual.Insert, test.query)
+		fc.AssertEqual(t, test.expected.Point, actual.Point, test.query)
+		fc.AssertEqual(t, test.expected.StartTime, actual.StartTime, test.query)
+		fc.AssertEqual(t, test.expected.StopTime, actual.StopTime, test.query)
+	}
+}
+
+func TestErrorMode(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module x {namespace ""; prefix ""; revision 0;
+		leaf a {
+			type string;
+		}
+	}`)
+	fc.RequireEqual(t, nil, err)
+	s, ts := newTestServerWithNode(t, m, &nodeutil.Basic{
+		OnField: func(r node.FieldRequest, hnd *node.ValueHandle) error {
+			hnd.Val = val.String("hi")
+			return nil
+		},
+	})
+	defer ts.Close()
+	get := func(url string) int {
+		resp, _ := testRequest(t, "GET", ts.URL+url, "")
+		return resp.StatusCode
+	}
+
+	fc.AssertEqual(t, ErrorModeStrict, s.ErrorMode)
+	fc.AssertEqual(t, 400, get("/restconf/data/x:?foo=bar"))
+	fc.AssertEqual(t, 400, get("/restconf/streams/NETCONF?foo=bar"))
+	fc.AssertEqual(t, 200, get("/restconf/data/x:?fc.range=a!1-2&depth=1"))
+
+	s.ErrorMode = ErrorModeLenient
+	fc.AssertEqual(t, 200, get("/restconf/data/x:?foo=bar"))
+
+	// per-request override
+	s.ErrorMode = ErrorModeStrict
+	s.Filters = append(s.Filters, func(ctx context.Context, w http.ResponseWriter, r *http.Request) (context.Context, error) {
+		if r.Header.Get("X-Lenient") != "" {
+			return context.WithValue(ctx, ErrorModeContextKey, ErrorModeLenient), nil
+		}
+		return ctx, nil
+	})
+	resp, _ := testRequest(t, "GET", ts.URL+"/restconf/data/x:?foo=bar", "", "X-Lenient", "true")
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	fc.AssertEqual(t, 400, get("/restconf/data/x:?foo=bar"))
+}
@@ -0,0 +1,65 @@
+package restconf
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+
+	"github.com/freeconf/yang/node"
+)
+
+// returnRepresentation is true when client prefers the resource it created
+// or replaced in the response over an empty one. RFC7240 Sec. 4.2
+//
+//	Prefer: return=representation
+func returnRepresentation(r *http.Request) bool {
+	for _, hdr := range r.Header.Values("Prefer") {
+		for _, pref := range strings.Split(hdr, ",") {
+			token, _, _ := strings.Cut(pref, ";")
+			name, value, _ := strings.Cut(token, "=")
+			if strings.EqualFold(strings.TrimSpace(name), "return") {
+				value = strings.Trim(strings.TrimSpace(value), `"`)
+				return strings.EqualFold(value, "representation")
+			}
+		}
+	}
+	return false
+}
+
+// representationPath is path of the resource a POST or PUT edited relative
+// to module, the created resource when there is a location
+func representationPath(r *http.Request, location string) (string, bool) {
+	p := r.URL.EscapedPath()
+	if location == "" {
+		return p, r.Method == "PUT"
+	}
+	reqPath := strings.SplitN(r.RequestURI, "?", 2)[0]
+	if !strings.HasSuffix(reqPath, p) {
+		return "", false
+	}
+	base := reqPath[:len(reqPath)-len(p)]
+	if !strings.HasPrefix(location, base) {
+		return "", false
+	}
+	return location[len(base):], true
+}
+
+// writeRepresentation responds with resource at path like a GET would
+func (hndlr *browserHandler) writeRepresentation(compliance ComplianceOptions, root *node.Selection, path string, w http.ResponseWriter, accept MimeType) error {
+	sel, err := root.Find(path)
+	if err != nil || sel == nil {
+		return err
+	}
+	defer sel.Release()
+	// written whole first so failing to write is an error response
+	var buf bytes.Buffer
+	if err = sel.UpsertIntoSetDefaults(hndlr.codecs.encoder(accept)(&buf, compliance)); err != nil {
+		return err
+	}
+	hdr := w.Header()
+	hdr.Set("Preference-Applied", "return=representation")
+	hndlr.codecs.setContentType(compliance, hdr, accept)
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,47 @@
+package restconf
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+)
+
+func TestPrefer(t *testing.T) {
+	_, ts := newTestServer(t, nestedYang, nestedData)
+	defer ts.Close()
+	json := string(YangDataJsonMimeType)
+	url := ts.URL + "/restconf/data/x:a/c"
+
+	t.Run("minimal", func(t *testing.T) {
+		resp, actual := testRequest(t, "PUT", url, `{"x:c":{"d":"M"}}`, "Prefer", "return=minimal")
+		fc.AssertEqual(t, 204, resp.StatusCode)
+		fc.AssertEqual(t, "", actual)
+		fc.AssertEqual(t, "", resp.Header.Get("Preference-Applied"))
+	})
+
+	t.Run("representation", func(t *testing.T) {
+		resp, actual := testRequest(t, "PUT", url, `{"x:c":{"d":"R"}}`, "Prefer", "respond-async, return=representation", "Accept", json)
+		fc.AssertEqual(t, 200, resp.StatusCode)
+		fc.AssertEqual(t, `{"d":"R"}`, actual)
+		fc.AssertEqual(t, "return=representation", resp.Header.Get("Preference-Applied"))
+		fc.AssertEqual(t, json, resp.Header.Get("Content-Type"))
+		fc.AssertEqual(t, true, resp.Header.Get("ETag") != "")
+
+		resp, actual = testRequest(t, "PUT", url+"/e=three", `{"x:e":[{"f":"three"}]}`, "Prefer", "return=representation", "Accept", json)
+		fc.AssertEqual(t, 200, resp.StatusCode)
+		fc.AssertEqual(t, `{"f":"three"}`, actual)
+		fc.AssertEqual(t, "/restconf/data/x:a/c/e=three", resp.Header.Get("Location"))
+
+		resp, actual = testRequest(t, "POST", url+"/e", `{"x:e":[{"f":"four"}]}`, "Prefer", "return=representation", "Accept", json)
+		fc.AssertEqual(t, 200, resp.StatusCode)
+		fc.AssertEqual(t, `{"f":"four"}`, actual)
+		fc.AssertEqual(t, "/restconf/data/x:a/c/e=four", resp.Header.Get("Location"))
+	})
+
+	t.Run("default", func(t *testing.T) {
+		resp, actual := testRequest(t, "PUT", url, `{"x:c":{"d":"D"}}`)
+		fc.AssertEqual(t, http.StatusNoContent, resp.StatusCode)
+		fc.AssertEqual(t, "", actual)
+	})
+}
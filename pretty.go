@@ -0,0 +1,117 @@
+package restconf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/freeconf/yang/patch/xml"
+)
+
+// prettyParam asks for indented responses for a single request
+const prettyParam = "pretty"
+
+const prettyIndent = "  "
+
+// prettyWriter holds the response body until it is complete so it can be
+// indented according to the response Content-Type. Content that is neither
+// JSON nor XML, or cannot be parsed, is sent as is.
+type prettyWriter struct {
+	http.ResponseWriter
+	buf bytes.Buffer
+}
+
+func (p *prettyWriter) Write(data []byte) (int, error) {
+	return p.buf.Write(data)
+}
+
+func (p *prettyWriter) flush() error {
+	if p.buf.Len() == 0 {
+		return nil
+	}
+	var pretty bytes.Buffer
+	var err error
+	ctype := MimeType(p.Header().Get("Content-Type"))
+	if ctype.IsXml() {
+		err = indentXML(&pretty, p.buf.Bytes(), prettyIndent)
+	} else if strings.Contains(string(ctype), "json") {
+		err = json.Indent(&pretty, p.buf.Bytes(), "", prettyIndent)
+	} else {
+		_, err = p.ResponseWriter.Write(p.buf.Bytes())
+		return err
+	}
+	if err != nil {
+		// send what we have rather than nothing
+		_, err = p.ResponseWriter.Write(p.buf.Bytes())
+		return err
+	}
+	pretty.WriteByte('\n')
+	_, err = p.ResponseWriter.Write(pretty.Bytes())
+	return err
+}
+
+// indentXML puts each element on its own line. Elements with only text stay on
+// one line
+//
+//	<a><b>x</b></a>  =>  <a>
+//	                       <b>x</b>
+//	                     </a>
+func indentXML(dst *bytes.Buffer, src []byte, indent string) error {
+	d := xml.NewDecoder(bytes.NewReader(src))
+	depth := 0
+	// close tag goes on same line as start tag or text
+	inline := false
+	newline := func() {
+		if dst.Len() > 0 {
+			dst.WriteByte('\n')
+		}
+		dst.WriteString(strings.Repeat(indent, depth))
+	}
+	for {
+		t, err := d.RawToken()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		switch x := t.(type) {
+		case xml.StartElement:
+			newline()
+			fmt.Fprintf(dst, "<%s", xmlName(x.Name))
+			for _, a := range x.Attr {
+				fmt.Fprintf(dst, ` %s="`, xmlName(a.Name))
+				if err = xml.EscapeText(dst, []byte(a.Value)); err != nil {
+					return err
+				}
+				dst.WriteByte('"')
+			}
+			dst.WriteByte('>')
+			depth++
+			inline = true
+		case xml.EndElement:
+			depth--
+			if !inline {
+				newline()
+			}
+			fmt.Fprintf(dst, "</%s>", xmlName(x.Name))
+			inline = false
+		case xml.CharData:
+			if len(bytes.TrimSpace(x)) == 0 {
+				continue
+			}
+			if err = xml.EscapeText(dst, x); err != nil {
+				return err
+			}
+		case xml.ProcInst:
+			newline()
+			fmt.Fprintf(dst, "<?%s %s?>", x.Target, x.Inst)
+		case xml.Comment:
+			newline()
+			fmt.Fprintf(dst, "<!--%s-->", x)
+			inline = false
+		}
+	}
+}
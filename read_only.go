@@ -0,0 +1,152 @@
+package restconf
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+)
+
+// ErrReadOnly is when a request writes to data in Server.ReadOnly, reported
+// with error-tag "access-denied" and 405
+var ErrReadOnly = errors.New("read-only")
+
+// readOnlyPaths are the schema paths, w/o module, of Server.ReadOnly entries
+// for one module. An empty path is the whole module.
+type readOnlyPaths [][]string
+
+// readOnlyPathsOf picks entries of Server.ReadOnly for module
+//
+//	car            =>  []
+//	car:engine/oil =>  [engine oil]
+func readOnlyPathsOf(entries []string, module string) readOnlyPaths {
+	var paths readOnlyPaths
+	for _, entry := range entries {
+		mod, p := entry, ""
+		if colon := strings.IndexRune(entry, ':'); colon >= 0 {
+			mod, p = entry[:colon], strings.Trim(entry[colon+1:], "/")
+		}
+		if mod != module {
+			continue
+		}
+		var idents []string
+		if p != "" {
+			idents = strings.Split(p, "/")
+		}
+		paths = append(paths, idents)
+	}
+	return paths
+}
+
+// pathIdents are identifiers of data in path w/o module
+func pathIdents(p *node.Path) []string {
+	segs := p.Segments()
+	idents := make([]string, len(segs)-1)
+	for i, seg := range segs[1:] {
+		idents[i] = seg.Meta.Ident()
+	}
+	return idents
+}
+
+// covers is true when idents are at or under a read-only path
+func (paths readOnlyPaths) covers(idents []string) bool {
+	for _, p := range paths {
+		if hasIdentPrefix(idents, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// contains is true when a read-only path is under idents, so deleting idents
+// would delete read-only data
+func (paths readOnlyPaths) contains(idents []string) bool {
+	for _, p := range paths {
+		if hasIdentPrefix(p, idents) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasIdentPrefix(idents []string, prefix []string) bool {
+	if len(prefix) > len(idents) {
+		return false
+	}
+	for i, ident := range prefix {
+		if idents[i] != ident {
+			return false
+		}
+	}
+	return true
+}
+
+// checkReadOnly rejects a request that writes to target when it is read-only
+// or, for requests that delete target first, has read-only data under it.
+// Writes further down are caught by readOnlyConstraint.
+func checkReadOnly(paths readOnlyPaths, w http.ResponseWriter, method string, target *node.Selection, replacing bool) error {
+	if len(paths) == 0 || method == "GET" || method == "HEAD" || method == "OPTIONS" {
+		return nil
+	}
+	if method == "POST" && meta.IsAction(target.Meta()) {
+		return nil
+	}
+	idents := pathIdents(target.Path)
+	if paths.covers(idents) {
+		w.Header().Set("Allow", "GET, HEAD, OPTIONS")
+		return fmt.Errorf("%w. %s not allowed on %s", ErrReadOnly, method, target.Path)
+	}
+	if (method == "DELETE" || replacing) && paths.contains(idents) {
+		return fmt.Errorf("%w. %s would change read-only data under %s", ErrReadOnly, method, target.Path)
+	}
+	return nil
+}
+
+// readOnlyConstraint fails edits that create, change or delete read-only data
+type readOnlyConstraint struct {
+	paths readOnlyPaths
+}
+
+func (c readOnlyConstraint) check(parent *node.Path, m meta.Definition) (bool, error) {
+	idents := append(pathIdents(parent), m.Ident())
+	if c.paths.covers(idents) {
+		return false, fmt.Errorf("%w. %s/%s", ErrReadOnly, parent, m.Ident())
+	}
+	return true, nil
+}
+
+func (c readOnlyConstraint) CheckContainerPreConstraints(r *node.ChildRequest) (bool, error) {
+	if !r.New && !r.Delete {
+		return true, nil
+	}
+	return c.check(r.Selection.Path, r.Meta)
+}
+
+func (c readOnlyConstraint) CheckListPreConstraints(r *node.ListRequest) (bool, error) {
+	if !r.New && !r.Delete {
+		return true, nil
+	}
+	// path of list entries' selection is the list
+	if c.paths.covers(pathIdents(r.Selection.Path)) {
+		return false, fmt.Errorf("%w. %s", ErrReadOnly, r.Selection.Path)
+	}
+	return true, nil
+}
+
+func (c readOnlyConstraint) CheckFieldPreConstraints(r *node.FieldRequest, hnd *node.ValueHandle) (bool, error) {
+	if !r.Write {
+		return true, nil
+	}
+	return c.check(r.Selection.Path, r.Meta)
+}
+
+func addReadOnlyConstraint(sel *node.Selection, paths readOnlyPaths) {
+	if len(paths) == 0 {
+		return
+	}
+	sel.Constraints = node.NewConstraints(sel.Constraints)
+	sel.Constraints.AddConstraint("read-only", 0, 0, readOnlyConstraint{paths: paths})
+}
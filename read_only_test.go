@@ -0,0 +1,73 @@
+package restconf
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/freeconf/restconf/device"
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+	"github.com/freeconf/yang/source"
+)
+
+func TestReadOnly(t *testing.T) {
+	d := device.New(source.Dir("./yang"))
+	for _, mod := range []struct {
+		yang string
+		data string
+	}{
+		{yang: nestedYang, data: nestedData},
+		{yang: `module y { namespace "y"; prefix "y"; revision 0; container z { leaf w { type string; } } }`, data: `{"z":{"w":"W"}}`},
+	} {
+		m, err := parser.LoadModuleFromString(nil, mod.yang)
+		fc.RequireEqual(t, nil, err)
+		var vals map[string]interface{}
+		fc.RequireEqual(t, nil, json.Unmarshal([]byte(mod.data), &vals))
+		d.AddBrowser(node.NewBrowser(m, nodeutil.ReflectChild(vals)))
+	}
+	s := NewHttpServe(d)
+	s.ReadOnly = []string{"y", "x:a/c"}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{method: "GET", path: "y:z", status: 200},
+		{method: "PATCH", path: "y:z", body: `{"w":"W2"}`, status: 405},
+		{method: "PUT", path: "y:z/w", body: `{"y:w":"W2"}`, status: 405},
+		{method: "DELETE", path: "y:z", status: 405},
+		{method: "GET", path: "x:a/c", status: 200},
+		{method: "PATCH", path: "x:a/c", body: `{"d":"D2"}`, status: 405},
+		{method: "PATCH", path: "x:a", body: `{"c":{"d":"D2"}}`, status: 405},
+		{method: "POST", path: "x:a/c", body: `{"x:e":[{"f":"three"}]}`, status: 405},
+		{method: "DELETE", path: "x:a/c/e=one", status: 405},
+		{method: "DELETE", path: "x:a", status: 405},
+		{method: "PUT", path: "x:a", body: `{"x:a":{"b":"B2"}}`, status: 405},
+		{method: "PATCH", path: "x:a", body: `{"b":"B2"}`, status: 200},
+		{method: "PUT", path: "x:a/b", body: `{"x:b":"B3"}`, status: 204},
+	}
+	for _, test := range tests {
+		desc := test.method + " " + test.path
+		resp, actual := testRequest(t, test.method, ts.URL+"/restconf/data/"+test.path, test.body,
+			"Content-Type", string(YangDataJsonMimeType1), "Accept", string(YangDataJsonMimeType1))
+		fc.AssertEqual(t, test.status, resp.StatusCode, desc)
+		if test.status == 405 {
+			fc.AssertEqual(t, true, strings.Contains(actual, `"error-tag":"access-denied"`), desc)
+		}
+	}
+
+	resp, _ := testRequest(t, "PATCH", ts.URL+"/restconf/data/y:z", `{"w":"W2"}`)
+	fc.AssertEqual(t, "GET, HEAD, OPTIONS", resp.Header.Get("Allow"))
+
+	_, actual := testRequest(t, "GET", ts.URL+"/restconf/data/x:a", "")
+	fc.AssertEqual(t, `{"b":"B3","c":{"d":"D","e":[{"f":"one","g":{"h":1}},{"f":"two","g":{"h":2}}]}}`, actual)
+	_, actual = testRequest(t, "GET", ts.URL+"/restconf/data/y:z", "")
+	fc.AssertEqual(t, `{"w":"W"}`, actual)
+}
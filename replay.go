@@ -0,0 +1,61 @@
+package restconf
+
+import (
+	"time"
+
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+)
+
+// ReplayEvent is a notification kept for replay along with the module that
+// defines it so it can be encoded again
+type ReplayEvent struct {
+	Module       *meta.Module
+	Notification node.Notification
+}
+
+// ReplayStore keeps past events of a stream so subscribers can ask for them
+// with start-time and stop-time. Stream serializes calls so implementations
+// do not need their own locking. RFC8040 Sec. 6.3
+type ReplayStore interface {
+	// Add is called with every event in stream.  Event data is a copy that
+	// stays valid after call.
+	Add(e ReplayEvent) error
+
+	// Replay returns events with event time from start to stop, oldest first.
+	// Zero stop means every event since start.
+	Replay(start time.Time, stop time.Time) ([]ReplayEvent, error)
+}
+
+// ReplayLog is a ReplayStore that keeps the most recent events in memory
+type ReplayLog struct {
+	size   int
+	events []ReplayEvent
+}
+
+func NewReplayLog(size int) *ReplayLog {
+	return &ReplayLog{size: size}
+}
+
+func (l *ReplayLog) Add(e ReplayEvent) error {
+	l.events = append(l.events, e)
+	if len(l.events) > l.size {
+		l.events = l.events[len(l.events)-l.size:]
+	}
+	return nil
+}
+
+func (l *ReplayLog) Replay(start time.Time, stop time.Time) ([]ReplayEvent, error) {
+	var events []ReplayEvent
+	for _, e := range l.events {
+		t := e.Notification.EventTime
+		if t.Before(start) {
+			continue
+		}
+		if !stop.IsZero() && t.After(stop) {
+			break
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
@@ -0,0 +1,75 @@
+package restconf
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"time"
+)
+
+// DefaultRequestIdHeader is where request ids are read from and echoed to
+// when Server.RequestIdHeader is not set
+const DefaultRequestIdHeader = "X-Request-ID"
+
+type RequestIdContextKeyType string
+
+// RequestIdContextKey is the id correlating request with server logs
+var RequestIdContextKey = RequestIdContextKeyType("RESTCONF_REQUEST_ID")
+
+// RequestIdOf is the id of the request from client or made by server, empty
+// outside of a request
+func RequestIdOf(ctx context.Context) string {
+	id, _ := ctx.Value(RequestIdContextKey).(string)
+	return id
+}
+
+// RequestIdObserver is an Observer that also wants the id of each request so
+// it can tell concurrent requests apart. These are called instead of
+// RequestStarted and RequestFinished.
+type RequestIdObserver interface {
+	RequestStartedWithId(id string, method string, path string)
+	RequestFinishedWithId(id string, method string, path string, status int, duration time.Duration)
+}
+
+// ids from clients that are longer are replaced so they cannot flood logs
+const maxRequestIdLen = 128
+
+// requestId is the header request id is in and the id from client or a new
+// one when client did not send one that is fit for logging
+func (srv *Server) requestId(r *http.Request) (string, string) {
+	hdr := srv.RequestIdHeader
+	if hdr == "" {
+		hdr = DefaultRequestIdHeader
+	}
+	if id := r.Header.Get(hdr); validRequestId(id) {
+		return hdr, id
+	}
+	if srv.RequestIdGenerator != nil {
+		return hdr, srv.RequestIdGenerator()
+	}
+	return hdr, newRequestId()
+}
+
+// validRequestId is printable ASCII so it cannot garble logs
+func validRequestId(id string) bool {
+	if id == "" || len(id) > maxRequestIdLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestId is 16 random bytes in hex
+func newRequestId() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		// only when system has no source of randomness
+		return time.Now().UTC().Format("20060102150405.000000000")
+	}
+	return hex.EncodeToString(b[:])
+}
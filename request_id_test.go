@@ -0,0 +1,103 @@
+package restconf
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/freeconf/yang/fc"
+)
+
+// testIdObserver remembers ids requests started and finished with
+type testIdObserver struct {
+	NopObserver
+	started  []string
+	finished []string
+}
+
+func (o *testIdObserver) RequestStartedWithId(id string, method string, path string) {
+	o.started = append(o.started, id)
+}
+
+func (o *testIdObserver) RequestFinishedWithId(id string, method string, path string, status int, duration time.Duration) {
+	o.finished = append(o.finished, id)
+}
+
+func TestRequestId(t *testing.T) {
+	s, ts := newTestServer(t, nestedYang, nestedData)
+	defer ts.Close()
+	logger := &testLogger{}
+	s.Logger = logger
+	observer := &testIdObserver{}
+	s.Observer = observer
+	var seen string
+	s.Filters = []RequestFilter{func(ctx context.Context, w http.ResponseWriter, r *http.Request) (context.Context, error) {
+		seen = RequestIdOf(ctx)
+		return ctx, nil
+	}}
+	url := ts.URL + "/restconf/data/x:a/b"
+	reset := func() {
+		logger.entries, observer.started, observer.finished, seen = nil, nil, nil, ""
+	}
+	assertId := func(t *testing.T, expected string) {
+		t.Helper()
+		fc.RequireEqual(t, 1, len(logger.entries))
+		fc.AssertEqual(t, expected, logger.entries[0].fields["request-id"])
+		fc.AssertEqual(t, expected, seen)
+		fc.AssertEqual(t, expected, strings.Join(observer.started, ","))
+		fc.AssertEqual(t, expected, strings.Join(observer.finished, ","))
+	}
+
+	t.Run("echoed", func(t *testing.T) {
+		reset()
+		resp, _ := testRequest(t, "GET", url, "", "X-Request-ID", "abc-123")
+		fc.AssertEqual(t, 200, resp.StatusCode)
+		fc.AssertEqual(t, "abc-123", resp.Header.Get("X-Request-ID"))
+		assertId(t, "abc-123")
+
+		// also on failures
+		reset()
+		resp, _ = testRequest(t, "GET", ts.URL+"/restconf/data/x:nope", "", "X-Request-ID", "abc-124")
+		fc.AssertEqual(t, "abc-124", resp.Header.Get("X-Request-ID"))
+	})
+
+	t.Run("generated", func(t *testing.T) {
+		reset()
+		resp, _ := testRequest(t, "GET", url, "")
+		id := resp.Header.Get("X-Request-ID")
+		fc.AssertEqual(t, 32, len(id))
+		assertId(t, id)
+
+		resp, _ = testRequest(t, "GET", url, "")
+		fc.AssertEqual(t, false, id == resp.Header.Get("X-Request-ID"))
+
+		// not fit for logs
+		reset()
+		resp, _ = testRequest(t, "GET", url, "", "X-Request-ID", "a b")
+		fc.AssertEqual(t, 32, len(resp.Header.Get("X-Request-ID")))
+		resp, _ = testRequest(t, "GET", url, "", "X-Request-ID", strings.Repeat("a", 200))
+		fc.AssertEqual(t, 32, len(resp.Header.Get("X-Request-ID")))
+	})
+
+	t.Run("custom", func(t *testing.T) {
+		s.RequestIdHeader = "X-Correlation-ID"
+		n := 0
+		s.RequestIdGenerator = func() string {
+			n++
+			return "gen-" + strings.Repeat("x", n)
+		}
+		reset()
+		resp, _ := testRequest(t, "GET", url, "")
+		fc.AssertEqual(t, "gen-x", resp.Header.Get("X-Correlation-ID"))
+		fc.AssertEqual(t, "", resp.Header.Get("X-Request-ID"))
+		assertId(t, "gen-x")
+
+		reset()
+		resp, _ = testRequest(t, "GET", url, "", "X-Correlation-ID", "from-client")
+		fc.AssertEqual(t, "from-client", resp.Header.Get("X-Correlation-ID"))
+		assertId(t, "from-client")
+		fc.AssertEqual(t, 1, n)
+	})
+}
@@ -0,0 +1,48 @@
+// Package restconftest serves a freeconf node over RESTCONF on a local port
+// so tests can exercise it through the RESTCONF client, the same way a
+// remote application would.
+package restconftest
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/freeconf/restconf"
+	"github.com/freeconf/restconf/client"
+	"github.com/freeconf/restconf/device"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/source"
+)
+
+// NewTestServer serves n as module on an httptest server and connects a
+// RESTCONF client to it. Operations on the returned browser go over HTTP to
+// the server and then to n. Module is found in ypath, RESTCONF's own modules
+// are included already. Call cleanup when test is done.
+//
+//	ts, b, cleanup := restconftest.NewTestServer(t, ypath, "car", carNode)
+//	defer cleanup()
+func NewTestServer(t testing.TB, ypath source.Opener, module string, n node.Node) (ts *httptest.Server, b *node.Browser, cleanup func()) {
+	t.Helper()
+	ypath = source.Any(ypath, restconf.InternalYPath)
+	local := device.New(ypath)
+	if err := local.Add(module, n); err != nil {
+		t.Fatalf("could not add %s. %s", module, err)
+	}
+	s := restconf.NewHttpServe(local)
+	ts = httptest.NewServer(s)
+	cleanup = func() {
+		ts.Close()
+		s.Close()
+	}
+	c := client.Client{YangPath: restconf.InternalYPath}
+	remote, err := c.NewDevice(ts.URL + "/restconf")
+	if err != nil {
+		cleanup()
+		t.Fatalf("could not connect to test server. %s", err)
+	}
+	if b, err = remote.Browser(module); err != nil || b == nil {
+		cleanup()
+		t.Fatalf("could not find %s on test server. %v", module, err)
+	}
+	return ts, b, cleanup
+}
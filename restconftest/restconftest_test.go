@@ -0,0 +1,55 @@
+package restconftest
+
+import (
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/source"
+)
+
+type garden struct {
+	Shed *shed
+}
+
+type shed struct {
+	Door    string
+	Shovels int
+}
+
+func TestNewTestServer(t *testing.T) {
+	var g garden
+	_, b, cleanup := NewTestServer(t, source.Dir("./testdata"), "garden", &nodeutil.Node{Object: &g})
+	defer cleanup()
+	root := b.Root()
+
+	// create
+	fc.RequireEqual(t, nil, root.InsertFrom(readJson(t, `{"shed":{"door":"red","shovels":1}}`)))
+	fc.RequireEqual(t, true, g.Shed != nil)
+	fc.AssertEqual(t, "red", g.Shed.Door)
+
+	// read
+	s, err := root.Find("shed")
+	fc.RequireEqual(t, nil, err)
+	fc.RequireEqual(t, true, s != nil)
+	actual, err := nodeutil.WriteJSON(s)
+	fc.RequireEqual(t, nil, err)
+	fc.AssertEqual(t, `{"door":"red","shovels":1}`, actual)
+
+	// update
+	fc.RequireEqual(t, nil, s.UpsertFrom(readJson(t, `{"shovels":2}`)))
+	fc.AssertEqual(t, 2, g.Shed.Shovels)
+	fc.AssertEqual(t, "red", g.Shed.Door)
+
+	// delete
+	fc.RequireEqual(t, nil, s.Delete())
+	fc.AssertEqual(t, true, g.Shed == nil)
+}
+
+func readJson(t *testing.T, s string) node.Node {
+	t.Helper()
+	n, err := nodeutil.ReadJSON(s)
+	fc.RequireEqual(t, nil, err)
+	return n
+}
@@ -0,0 +1,25 @@
+package restconf
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// DefaultRetryAfter is the RetryAfter of servers from NewHttpServe
+const DefaultRetryAfter = 5 * time.Second
+
+type retryAfterContextKeyType string
+
+var retryAfterContextKey = retryAfterContextKeyType("RESTCONF_RETRY_AFTER")
+
+// setRetryAfter asks client to wait Server.RetryAfter, in whole seconds,
+// before trying request again. RFC9110 Sec. 10.2.3
+func setRetryAfter(w http.ResponseWriter, r *http.Request) {
+	d, _ := r.Context().Value(retryAfterContextKey).(time.Duration)
+	if d <= 0 {
+		return
+	}
+	secs := int64((d + time.Second - 1) / time.Second)
+	w.Header().Set("Retry-After", strconv.FormatInt(secs, 10))
+}
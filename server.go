@@ -13,7 +13,10 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/freeconf/restconf/device"
 	"github.com/freeconf/restconf/secure"
@@ -39,6 +42,67 @@ type Server struct {
 	// Optional: Anything not handled by RESTCONF protocol can call this handler otherwise
 	UnhandledRequestHandler http.HandlerFunc
 
+	// Optional: Require HTTP Basic authentication on every request. Principal
+	// is available to filters and nodes with PrincipalOf
+	BasicAuth Auth
+
+	// Optional: Require "Authorization: Bearer <token>". When BasicAuth is
+	// also set either is accepted
+	TokenValidator TokenValidator
+
+	// Optional: Use name in verified client certificate as principal, see
+	// MutualTLS. Requests w/o a certificate can still use BasicAuth or
+	// TokenValidator when set
+	ClientCertAuth bool
+
+	// Optional: Decides which data, rpcs and notifications each principal can
+	// use. See AccessRules for NACM style rules
+	Authorizer Authorizer
+
+	// Optional: Modules, or schema paths in them w/o keys, that cannot be
+	// edited whatever their config statements say. Writes are rejected with
+	// 405 and error-tag access-denied for every principal, reads and rpcs are
+	// not affected. Coarser than Authorizer but easier to set up.
+	//
+	//	car              whole module
+	//	car:engine/oil   oil and everything under it
+	ReadOnly []string
+
+	// Optional: Only methods served, like GET, HEAD and OPTIONS for a
+	// read-only server. Others are rejected with 405 whatever the resource,
+	// and left out of Allow headers. Default is all methods
+	AllowedMethods []string
+
+	// Optional: Told about every request and event stream, for metrics. See
+	// CountingObserver
+	Observer Observer
+
+	// Optional: Logs every request and content that could not be read or
+	// written. Default is silent
+	Logger Logger
+
+	// Optional: Header with id correlating a request with server logs, default
+	// is X-Request-ID. Id client sends is kept, otherwise one is made with
+	// RequestIdGenerator. Id is echoed in response, added to Logger entries,
+	// given to a RequestIdObserver and available to filters and nodes with
+	// RequestIdOf.
+	RequestIdHeader string
+
+	// Optional: Makes ids for requests w/o one, default is 32 random hex
+	// digits
+	RequestIdGenerator func() string
+
+	// Optional: Headers added to every response, like Cache-Control or
+	// security headers, given the request so they can differ by path. Added
+	// before request is served so they are in event streams and errors too.
+	// Headers server sets itself, like Content-Type, replace these.
+	HeaderDecorator func(r *http.Request) http.Header
+
+	// Optional: Which web pages from other origins may call RESTCONF from a
+	// browser. Default allows any origin w/o credentials and does not answer
+	// preflight requests
+	CORS *CORS
+
 	// Give app change to read custom header data and stuff into context so info can get
 	// to app layer
 	Filters []RequestFilter
@@ -46,6 +110,105 @@ type Server struct {
 	// allow rpc to serve under /restconf/data/{module:}/{rpc} which while intuative and
 	// original design, it is not in compliance w/RESTCONF spec
 	OnlyStrictCompliance bool
+
+	// How default values are reported when request does not include the
+	// with-defaults parameter. One of report-all (default), trim, explicit
+	// or report-all-tagged. RFC6243 Sec. 3
+	WithDefaultsBasicMode string
+
+	// Optional: Path clients reach RESTCONF at, for example /my-api when
+	// behind a proxy. Requests are served under this path and it is reported in
+	// /.well-known/host-meta, device addresses and stream locations. Requests to
+	// /restconf are still served so http.StripPrefix keeps working. Default is
+	// /restconf
+	RootPath string
+
+	// Optional: Rewrites URL of each request before it is routed, for example
+	// to remove a prefix a gateway adds. Given a copy of request URL so it can
+	// be changed and returned, nil keeps URL as is. Links in responses like
+	// Location are made from the rewritten URL.
+	PathRewriter func(*url.URL) *url.URL
+
+	// Compress responses with gzip or deflate when client sends
+	// Accept-Encoding. Default is no compression
+	Compression bool
+
+	// Indent JSON and XML responses, otherwise requests can ask for this with
+	// ?pretty. Default is compact
+	Pretty bool
+
+	// Write JSON responses to GET of a list one entry at a time, flushing
+	// each, so large lists reach clients sooner and are not held in memory.
+	// Response is the same, but an error part way through cuts it short as
+	// status is already sent. XML, pretty and paged responses are written
+	// whole. Default is off
+	StreamLists bool
+
+	// Optional: Told about every edit to config made through RESTCONF once it
+	// is applied and, for transactional nodes, committed. Not told about
+	// dry-runs
+	OnConfigChange func(ConfigChange)
+
+	// Send a netconf-config-change notification from
+	// ietf-netconf-notifications for every edit to config of main device to
+	// event streams that include that module like NETCONF. RFC6470 Sec. 2.2.
+	// Default is off
+	ConfigChangeNotifications bool
+
+	// Optional: Longest a request may take before its context is cancelled
+	// and 503 is returned. Nodes see this as Selection.Context and should
+	// stop work when it is done. Event streams are not limited. Default is
+	// no limit
+	Timeout time.Duration
+
+	// How long clients are asked to wait with Retry-After in 503 responses,
+	// like when shutting down, a request times out or a node returns
+	// ErrUnavailable. Sent in whole seconds, 0 leaves header out.
+	// NewHttpServe sets DefaultRetryAfter
+	RetryAfter time.Duration
+
+	// Write an SSE comment on event streams when no event was sent in this
+	// interval so proxies do not close idle connections. Default is off
+	Heartbeat time.Duration
+
+	// How unknown query parameters are handled, default is to reject them.
+	// Override for a request with ErrorModeContextKey
+	ErrorMode ErrorMode
+
+	// Optional: Source of time for Last-Modified headers, default is time.Now
+	Now func() time.Time
+
+	// Largest request content in bytes, request fails with 413 once more
+	// is read. 0 is no limit. NewHttpServe sets DefaultMaxRequestBytes
+	MaxRequestBytes int64
+
+	// Number of compiled fields parameters kept for requests that repeat
+	// them, least recently used are dropped first. 0 parses every request.
+	// NewHttpServe sets DefaultFieldsCacheSize
+	FieldsCacheSize int
+
+	// How long an edit waits for other edits of the same data to finish
+	// before it fails with 409 in-use. Edits of data of a module are done one
+	// at a time while reads of it are done together. 0 waits as long as the
+	// request does. NewHttpServe sets DefaultLockTimeout
+	LockTimeout time.Duration
+
+	modified   *modTracker
+	codecs     *codecs
+	datastores map[string]device.Device
+
+	fieldsMu sync.Mutex
+	fields   *fieldsCache
+
+	streamsMu sync.Mutex
+	streams   map[string]*eventStream
+
+	mountsMu       sync.Mutex
+	mounts         map[string]device.Device
+	mountListeners *list.List
+
+	activity activity
+	locks    dataLocks
 }
 
 var ErrBadAddress = errors.New("expected format: http://server/restconf[=device]/operation/module:path")
@@ -64,17 +227,32 @@ func NewHttpServe(d *device.Local) *Server {
 	m := &Server{
 		notifiers: list.New(),
 		ypath:     d.SchemaSource(),
+		modified:  newModTracker(),
+		streams:   make(map[string]*eventStream),
+		codecs:    newCodecs(),
+
+		MaxRequestBytes: DefaultMaxRequestBytes,
+		FieldsCacheSize: DefaultFieldsCacheSize,
+		RetryAfter:      DefaultRetryAfter,
+		LockTimeout:     DefaultLockTimeout,
 	}
 	m.ServeDevice(d)
+	if err := m.AddStream(Stream{Name: NetconfStream, Description: "default NETCONF event stream"}); err != nil {
+		panic(err)
+	}
 
 	// Required by all devices according to RFC
-	if err := d.Add("ietf-yang-library", device.LocalDeviceYangLibNode(m.ModuleAddress, d)); err != nil {
+	if err := d.Add("ietf-yang-library", device.LocalDeviceYangLibNode(m.ModuleAddress, m.mainDevice())); err != nil {
+		panic(err)
+	}
+	if err := d.Add("ietf-restconf-monitoring", MonitoringNode(m)); err != nil {
 		panic(err)
 	}
 	return m
 }
 
 func (srv *Server) Close() error {
+	srv.closeStreams()
 	if srv.Web == nil {
 		return nil
 	}
@@ -83,12 +261,53 @@ func (srv *Server) Close() error {
 	return err
 }
 
+func (srv *Server) closeStreams() {
+	srv.streamsMu.Lock()
+	defer srv.streamsMu.Unlock()
+	for _, s := range srv.streams {
+		s.subMu.Lock()
+		s.close()
+		s.subMu.Unlock()
+	}
+}
+
+func (srv *Server) now() time.Time {
+	if srv.Now == nil {
+		return time.Now()
+	}
+	return srv.Now()
+}
+
+func (srv *Server) rootPath() string {
+	if root := strings.TrimSuffix(srv.RootPath, "/"); root != "" {
+		return root
+	}
+	return "/restconf"
+}
+
+// mountedUrl maps requests under RootPath onto /restconf
+func (srv *Server) mountedUrl(u *url.URL) *url.URL {
+	root := srv.rootPath()
+	if root == "/restconf" {
+		return u
+	}
+	escaped := u.EscapedPath()
+	if !strings.HasPrefix(escaped, root) {
+		return u
+	}
+	rest := escaped[len(root):]
+	if rest != "" && rest[0] != '/' && rest[0] != '=' {
+		return u
+	}
+	return withEscapedPath(u, "/restconf"+rest)
+}
+
 func (srv *Server) ModuleAddress(m *meta.Module) string {
 	return fmt.Sprint("schema/", m.Ident(), ".yang")
 }
 
 func (srv *Server) DeviceAddress(id string, d device.Device) string {
-	return fmt.Sprint("/restconf=", id)
+	return fmt.Sprint(srv.rootPath(), "=", id)
 }
 
 func (srv *Server) ServeDevices(m device.Map) error {
@@ -118,11 +337,60 @@ func (srv *Server) determineCompliance(r *http.Request, contentType MimeType, ac
 }
 
 func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	contentType := MimeType(r.Header.Get("Content-Type"))
-	acceptType := MimeType(r.Header.Get("Accept"))
+	if srv.PathRewriter != nil {
+		u := *r.URL
+		if rewritten := srv.PathRewriter(&u); rewritten != nil {
+			r.URL = rewritten
+			r.RequestURI = rewritten.RequestURI()
+		}
+	}
+	if srv.HeaderDecorator != nil {
+		h := w.Header()
+		for name, values := range srv.HeaderDecorator(r) {
+			h[http.CanonicalHeaderKey(name)] = values
+		}
+	}
+	idHeader, id := srv.requestId(r)
+	w.Header().Set(idHeader, id)
+	r = r.WithContext(context.WithValue(r.Context(), RequestIdContextKey, id))
+	if srv.RetryAfter > 0 {
+		r = r.WithContext(context.WithValue(r.Context(), retryAfterContextKey, srv.RetryAfter))
+	}
+	if srv.Observer != nil {
+		var finished func()
+		w, finished = observe(srv.Observer, w, r)
+		defer finished()
+	}
+	if srv.Logger != nil {
+		var logged func()
+		w, r, logged = logRequest(srv.Logger, w, r)
+		defer logged()
+	}
+	if srv.CORS != nil && srv.CORS.handle(w, r) {
+		return
+	}
+	contentType := mediaType(r.Header.Get("Content-Type"))
+	acceptType := srv.codecs.accepted(r.Header.Get("Accept"))
 	compliance := srv.determineCompliance(r, contentType, acceptType)
 	fc.Debug.Printf("compliance %s", compliance)
 	ctx := context.WithValue(r.Context(), ComplianceContextKey, compliance)
+	if err := srv.checkMethodAllowed(w, r.Method); err != nil {
+		handleErr(compliance, err, r, w, acceptType)
+		return
+	}
+	if !srv.activity.begin() {
+		handleErr(compliance, ErrShuttingDown, r, w, acceptType)
+		return
+	}
+	defer srv.activity.end()
+	if err := decompressRequest(r); err != nil {
+		handleErr(compliance, err, r, w, acceptType)
+		return
+	}
+	if err := srv.limitRequest(w, r); err != nil {
+		handleErr(compliance, err, r, w, acceptType)
+		return
+	}
 	if fc.DebugLogEnabled() {
 		fc.Debug.Printf("%s %s", r.Method, r.URL)
 		if r.Body != nil {
@@ -138,6 +406,14 @@ func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 	}
+	checkUtf8(r)
+	if srv.BasicAuth != nil || srv.TokenValidator != nil || srv.ClientCertAuth {
+		var err error
+		if ctx, err = srv.authenticate(ctx, r, w); err != nil {
+			handleErr(compliance, err, r, w, acceptType)
+			return
+		}
+	}
 	for _, f := range srv.Filters {
 		var err error
 		if ctx, err = f(ctx, w, r); err != nil {
@@ -145,13 +421,31 @@ func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
+	if srv.Timeout > 0 {
+		// event streams drop timeout once they are found, see untimed
+		var cancel context.CancelFunc
+		ctx, cancel = withTimeout(ctx, srv.Timeout)
+		defer cancel()
+	}
+	if _, override := ctx.Value(ErrorModeContextKey).(ErrorMode); !override {
+		ctx = context.WithValue(ctx, ErrorModeContextKey, srv.ErrorMode)
+	}
 
 	h := w.Header()
 
-	// CORS
-	h.Set("Access-Control-Allow-Headers", "origin, content-type, accept")
-	h.Set("Access-Control-Allow-Methods", "GET, POST, PUT, OPTIONS, DELETE, PATCH")
-	h.Set("Access-Control-Allow-Origin", "*")
+	// permissive CORS unless configured
+	if srv.CORS == nil {
+		h.Set("Access-Control-Allow-Headers", "origin, content-type, accept")
+		h.Set("Access-Control-Allow-Methods", strings.Join(restrictMethods(corsPermissiveMethods, srv.AllowedMethods), ", "))
+		h.Set("Access-Control-Allow-Origin", "*")
+	}
+	if srv.Compression && r.Method != "HEAD" {
+		if encoding := acceptedEncoding(r.Header.Get("Accept-Encoding")); encoding != "" {
+			cw := newCompressWriter(w, encoding)
+			defer cw.Close()
+			w = cw
+		}
+	}
 	if r.URL.Path == "/" {
 		switch r.Method {
 		case "OPTIONS":
@@ -164,7 +458,7 @@ func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	op1, deviceId, p := shiftOptionalParamWithinSegment(r.URL, '=', '/')
+	op1, deviceId, p := shiftOptionalParamWithinSegment(srv.mountedUrl(r.URL), '=', '/')
 	device, err := srv.findDevice(deviceId)
 	if err != nil {
 		handleErr(compliance, err, r, w, acceptType)
@@ -178,11 +472,19 @@ func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		srv.serveStaticRoute(w, r)
 		return
 	case "restconf":
+		// trailing slash is ignored, x:a/ is the same resource as x:a
+		p = trimTrailingSlash(r, p)
 		op2, p := shift(p, '/')
 		r.URL = p
 		switch op2 {
+		case "":
+			srv.serveRoot(compliance, device, w, r, acceptType)
+		case "yang-library-version":
+			srv.serveYangLibraryVersion(compliance, device, w, r, acceptType)
 		case "data":
 			srv.serve(compliance, ctx, device, w, r, endpointData, acceptType)
+		case "ds":
+			srv.serveDatastore(compliance, ctx, deviceId, w, r, acceptType)
 		case "streams":
 			srv.serve(compliance, ctx, device, w, r, endpointStreams, acceptType)
 		case "operations":
@@ -196,7 +498,7 @@ func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			if strings.Contains(accept, "/json") {
 				srv.serveSchema(compliance, ctx, w, r, device.SchemaSource(), acceptType)
 			} else {
-				srv.serveStreamSource(compliance, r, w, device.SchemaSource(), r.URL.Path, acceptType)
+				srv.serveYangSource(compliance, r, w, device.SchemaSource(), acceptType)
 			}
 		default:
 			handleErr(compliance, ErrBadAddress, r, w, acceptType)
@@ -233,11 +535,24 @@ func (srv *Server) serveSchema(compliance ComplianceOptions, ctx context.Context
 		return
 	}
 	b := nodeutil.SchemaBrowser(ylib, m)
-	hndlr := &browserHandler{browser: b}
+	hndlr := &browserHandler{browser: b, pretty: srv.Pretty, observer: srv.observer(), activity: &srv.activity}
 	hndlr.ServeHTTP(compliance, ctx, w, r, endpointSchema)
 }
 
 func (srv *Server) serve(compliance ComplianceOptions, ctx context.Context, d device.Device, w http.ResponseWriter, r *http.Request, endpointId int, accept MimeType) {
+	if endpointId == endpointStreams {
+		// named streams such as NETCONF, otherwise it's a single notification
+		// addressed by module:path
+		if name, p := shift(r.URL, '/'); name != "" && !strings.ContainsRune(name, ':') {
+			r.URL = p
+			srv.serveEventStream(compliance, untimed(ctx), w, r, d, name, accept)
+			return
+		}
+	}
+	if endpointId == endpointOperations && r.URL.Path == "" {
+		srv.serveOperations(compliance, d, w, r, accept)
+		return
+	}
 	if hndlr, p := srv.shiftBrowserHandler(compliance, r, d, w, r.URL, accept); hndlr != nil {
 		r.URL = p
 		hndlr.ServeHTTP(compliance, ctx, w, r, endpointId)
@@ -327,6 +642,68 @@ func (srv *Server) serveWebApp(w http.ResponseWriter, r *http.Request, wap webAp
 	}
 }
 
+// formatParam picks the way module source is written
+//
+//	GET {+restconf}/schema/car?format=yin
+const formatParam = "format"
+
+// serveYangSource sends source of a module as YANG or, when asked for w/a
+// .yin extension or ?format=yin, as YIN. Other files in schema source are
+// sent as they are.
+func (srv *Server) serveYangSource(compliance ComplianceOptions, r *http.Request, w http.ResponseWriter, ypath source.Opener, accept MimeType) {
+	name := r.URL.Path
+	format := r.URL.Query().Get(formatParam)
+	switch filepath.Ext(name) {
+	case ".yin":
+		if format == "" {
+			format = "yin"
+		}
+		name = strings.TrimSuffix(name, ".yin")
+	case ".yang":
+		name = strings.TrimSuffix(name, ".yang")
+	case "":
+	default:
+		srv.serveStreamSource(compliance, r, w, ypath, name, accept)
+		return
+	}
+	if format != "" && format != "yang" && format != "yin" {
+		err := fmt.Errorf("%w. %s must be yang or yin, got '%s'", fc.BadRequestError, formatParam, format)
+		handleErr(compliance, err, r, w, accept)
+		return
+	}
+	rdr, err := ypath(name, ".yang")
+	if err != nil {
+		handleErr(compliance, err, r, w, accept)
+		return
+	} else if rdr == nil {
+		handleErr(compliance, fc.NotFoundError, r, w, accept)
+		return
+	}
+	if closer, canClose := rdr.(io.Closer); canClose {
+		defer closer.Close()
+	}
+	src, err := io.ReadAll(rdr)
+	if err != nil {
+		handleErr(compliance, err, r, w, accept)
+		return
+	}
+	if format != "yin" {
+		w.Header().Set("Content-Type", string(YangMimeType))
+		w.Write(src)
+		return
+	}
+	// only for namespaces and extensions so submodules, that cannot be
+	// loaded alone, are still written
+	m, _ := parser.LoadModule(ypath, name)
+	var yin bytes.Buffer
+	if err = writeYin(&yin, src, m); err != nil {
+		handleErr(compliance, err, r, w, accept)
+		return
+	}
+	w.Header().Set("Content-Type", string(YinMimeType))
+	w.Write(yin.Bytes())
+}
+
 func (srv *Server) serveStreamSource(compliance ComplianceOptions, r *http.Request, w http.ResponseWriter, s source.Opener, path string, accept MimeType) {
 	rdr, err := s(path, "")
 	if err != nil {
@@ -346,7 +723,7 @@ func (srv *Server) serveStreamSource(compliance ComplianceOptions, r *http.Reque
 
 func (srv *Server) findDevice(deviceId string) (device.Device, error) {
 	if deviceId == "" {
-		return srv.main, nil
+		return srv.mainDevice(), nil
 	}
 	device, err := srv.devices.Device(deviceId)
 	if err != nil {
@@ -362,7 +739,23 @@ func (srv *Server) shiftBrowserHandler(compliance ComplianceOptions, r *http.Req
 	if module, p := shift(orig, ':'); module != "" {
 		if browser, err := d.Browser(module); browser != nil {
 			return &browserHandler{
-				browser: browser,
+				browser:        browser,
+				withDefaults:   srv.withDefaultsBasicMode(),
+				pretty:         srv.Pretty,
+				streamLists:    srv.StreamLists,
+				heartbeat:      srv.Heartbeat,
+				authorizer:     srv.Authorizer,
+				readOnly:       readOnlyPathsOf(srv.ReadOnly, browser.Meta.Ident()),
+				observer:       srv.observer(),
+				modified:       srv.modified,
+				now:            srv.now,
+				codecs:         srv.codecs,
+				fieldsCache:    srv.fieldsCache(),
+				activity:       &srv.activity,
+				locks:          &srv.locks,
+				lockTimeout:    srv.LockTimeout,
+				changed:        srv.configChangeEmitter(d),
+				allowedMethods: srv.AllowedMethods,
 			}, p
 		} else if err != nil {
 			handleErr(compliance, err, r, w, accept)
@@ -374,13 +767,142 @@ func (srv *Server) shiftBrowserHandler(compliance ComplianceOptions, r *http.Req
 	return nil, orig
 }
 
+const hostMetaXml = `<XRD xmlns="http://docs.oasis-open.org/ns/xri/xrd-1.0">
+  <Link rel="restconf" href="%s"/>
+</XRD>
+`
+
+const restconfNs = "urn:ietf:params:xml:ns:yang:ietf-restconf"
+
+const rootXml = `<restconf xmlns="%s"><data/><operations/><yang-library-version>%s</yang-library-version></restconf>`
+
+// serveRoot is the API root resource. RFC8040 Sec. 3.3
+func (srv *Server) serveRoot(compliance ComplianceOptions, d device.Device, w http.ResponseWriter, r *http.Request, accept MimeType) {
+	if err := readOnly(w, r); err != nil {
+		handleErr(compliance, err, r, w, accept)
+		return
+	}
+	ver, err := yangLibraryVersion(d)
+	if err != nil {
+		handleErr(compliance, err, r, w, accept)
+		return
+	}
+	if accept.IsXml() {
+		w.Header().Set("Content-Type", string(YangDataXmlMimeType))
+		fmt.Fprintf(w, rootXml, restconfNs, ver)
+		return
+	}
+	w.Header().Set("Content-Type", string(YangDataJsonMimeType))
+	fmt.Fprintf(w, `{"ietf-restconf:restconf":{"data":{},"operations":{},"yang-library-version":"%s"}}`, ver)
+}
+
+// serveYangLibraryVersion is the leaf of the API root on its own. RFC8040 Sec. 3.3.3
+func (srv *Server) serveYangLibraryVersion(compliance ComplianceOptions, d device.Device, w http.ResponseWriter, r *http.Request, accept MimeType) {
+	if err := readOnly(w, r); err != nil {
+		handleErr(compliance, err, r, w, accept)
+		return
+	}
+	ver, err := yangLibraryVersion(d)
+	if err != nil {
+		handleErr(compliance, err, r, w, accept)
+		return
+	}
+	if accept.IsXml() {
+		w.Header().Set("Content-Type", string(YangDataXmlMimeType))
+		fmt.Fprintf(w, `<yang-library-version xmlns="%s">%s</yang-library-version>`, restconfNs, ver)
+		return
+	}
+	w.Header().Set("Content-Type", string(YangDataJsonMimeType))
+	fmt.Fprintf(w, `{"ietf-restconf:yang-library-version":"%s"}`, ver)
+}
+
+// readOnly checks method is allowed on resources of the API root that are
+// only listings
+func readOnly(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		w.Header().Set("Allow", "GET, HEAD")
+		return fmt.Errorf("%w. %s not allowed on %s", ErrOperationNotSupported, r.Method, r.RequestURI)
+	}
+	return nil
+}
+
+// yangLibraryVersion is revision of ietf-yang-library that device supports
+func yangLibraryVersion(d device.Device) (string, error) {
+	b, err := d.Browser("ietf-yang-library")
+	if err != nil {
+		return "", err
+	}
+	if b == nil || b.Meta.Revision() == nil {
+		return "", fmt.Errorf("%w. ietf-yang-library", fc.NotFoundError)
+	}
+	return b.Meta.Revision().Ident(), nil
+}
+
+// serveOperations lists every rpc of every module device has as an empty
+// leaf. RFC8040 Sec. 3.3.2
+func (srv *Server) serveOperations(compliance ComplianceOptions, d device.Device, w http.ResponseWriter, r *http.Request, accept MimeType) {
+	if err := readOnly(w, r); err != nil {
+		handleErr(compliance, err, r, w, accept)
+		return
+	}
+	mods := d.Modules()
+	modNames := make([]string, 0, len(mods))
+	for name := range mods {
+		modNames = append(modNames, name)
+	}
+	sort.Strings(modNames)
+	var buf bytes.Buffer
+	xml := accept.IsXml()
+	if xml {
+		fmt.Fprintf(&buf, `<operations xmlns="%s">`, restconfNs)
+	} else {
+		buf.WriteString(`{"ietf-restconf:operations":{`)
+	}
+	first := true
+	for _, modName := range modNames {
+		m := mods[modName]
+		rpcs := m.Actions()
+		rpcNames := make([]string, 0, len(rpcs))
+		for name := range rpcs {
+			rpcNames = append(rpcNames, name)
+		}
+		sort.Strings(rpcNames)
+		for _, name := range rpcNames {
+			if xml {
+				fmt.Fprintf(&buf, `<%s xmlns="%s"/>`, name, m.Namespace())
+				continue
+			}
+			if !first {
+				buf.WriteByte(',')
+			}
+			first = false
+			fmt.Fprintf(&buf, `"%s:%s":[null]`, m.Ident(), name)
+		}
+	}
+	if xml {
+		buf.WriteString(`</operations>`)
+		w.Header().Set("Content-Type", string(YangDataXmlMimeType))
+	} else {
+		buf.WriteString(`}}`)
+		w.Header().Set("Content-Type", string(YangDataJsonMimeType))
+	}
+	w.Write(buf.Bytes())
+}
+
 func (srv *Server) serveStaticRoute(w http.ResponseWriter, r *http.Request) bool {
 	_, p := shift(r.URL, '/')
 	op, _ := shift(p, '/')
 	switch op {
 	case "host-meta":
 		// RESTCONF Sec. 3.1
-		fmt.Fprintf(w, `{ "xrd" : { "link" : { "@rel" : "restconf", "@href" : "/restconf" } } }`)
+		if strings.Contains(r.Header.Get("Accept"), "json") {
+			// RFC6415 Sec. 3.2
+			w.Header().Set("Content-Type", string(PlainJsonMimeType))
+			fmt.Fprintf(w, `{"links":[{"rel":"restconf","href":"%s"}]}`, srv.rootPath())
+		} else {
+			w.Header().Set("Content-Type", "application/xrd+xml")
+			fmt.Fprintf(w, hostMetaXml, srv.rootPath())
+		}
 		return true
 	}
 	return false
@@ -5,6 +5,7 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"strings"
 	"testing"
 	"time"
@@ -98,7 +99,7 @@ func TestServer(t *testing.T) {
 			{
 				format: YangDataJsonMimeType1,
 				input:  `{"car:input":{"source":"tripa"}}`,
-				output: `{"car:output":{"miles":0}}`,
+				output: `{"car:output":{"miles":"0"}}`,
 			},
 			{
 				format: YangDataXmlMimeType1,
@@ -140,3 +141,239 @@ func goldResponse(t *testing.T, goldFile string, r *http.Response, err error) {
 		t.Errorf("gave status code %d", r.StatusCode)
 	}
 }
+
+func TestHostMeta(t *testing.T) {
+	s, ts := newTestServer(t, nestedYang, nestedData)
+	defer ts.Close()
+
+	r, err := http.Get(ts.URL + "/.well-known/host-meta")
+	goldResponse(t, "testdata/gold/host-meta.xml", r, err)
+	fc.AssertEqual(t, "application/xrd+xml", r.Header.Get("Content-Type"))
+
+	s.RootPath = "/api/restconf"
+	resp, actual := testRequest(t, "GET", ts.URL+"/.well-known/host-meta", "", "Accept", "application/json")
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	fc.AssertEqual(t, `{"links":[{"rel":"restconf","href":"/api/restconf"}]}`, actual)
+}
+
+func TestRootResource(t *testing.T) {
+	_, ts := newTestServer(t, nestedYang, nestedData)
+	defer ts.Close()
+
+	tests := []struct {
+		accept string
+		gold   string
+	}{
+		{accept: string(YangDataJsonMimeType1), gold: "testdata/gold/root.json"},
+		{accept: string(YangDataXmlMimeType1), gold: "testdata/gold/root.xml"},
+	}
+	for _, test := range tests {
+		req, err := http.NewRequest("GET", ts.URL+"/restconf", nil)
+		fc.RequireEqual(t, nil, err)
+		req.Header.Set("Accept", test.accept)
+		r, err := http.DefaultClient.Do(req)
+		goldResponse(t, test.gold, r, err)
+		fc.AssertEqual(t, test.accept, r.Header.Get("Content-Type"))
+	}
+
+	resp, actual := testRequest(t, "GET", ts.URL+"/restconf/yang-library-version", "", "Accept", string(YangDataJsonMimeType1))
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	fc.AssertEqual(t, `{"ietf-restconf:yang-library-version":"2019-01-04"}`, actual)
+
+	resp, _ = testRequest(t, "DELETE", ts.URL+"/restconf", "")
+	fc.AssertEqual(t, 405, resp.StatusCode)
+}
+
+func TestOperationsResource(t *testing.T) {
+	mstr := `module x {
+		namespace "x";
+		prefix "x";
+		revision 0;
+		rpc stop {}
+		rpc start {
+			input {
+				leaf delay {
+					type int32;
+				}
+			}
+		}
+	}`
+	_, ts := newTestServer(t, mstr, "{}")
+	defer ts.Close()
+
+	resp, actual := testRequest(t, "GET", ts.URL+"/restconf/operations", "", "Accept", string(YangDataJsonMimeType1))
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	fc.AssertEqual(t, `{"ietf-restconf:operations":{"x:start":[null],"x:stop":[null]}}`, actual)
+
+	resp, actual = testRequest(t, "GET", ts.URL+"/restconf/operations", "", "Accept", string(YangDataXmlMimeType1))
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	fc.AssertEqual(t, string(YangDataXmlMimeType1), resp.Header.Get("Content-Type"))
+	fc.AssertEqual(t, `<operations xmlns="urn:ietf:params:xml:ns:yang:ietf-restconf"><start xmlns="x"/><stop xmlns="x"/></operations>`, actual)
+
+	resp, _ = testRequest(t, "POST", ts.URL+"/restconf/operations", "")
+	fc.AssertEqual(t, 405, resp.StatusCode)
+}
+
+func TestRootPath(t *testing.T) {
+	s, ts := newTestServer(t, nestedYang, nestedData)
+	defer ts.Close()
+	s.RootPath = "/my-api"
+	accept := string(YangDataJsonMimeType1)
+
+	resp, actual := testRequest(t, "GET", ts.URL+"/my-api/data/x:a/b", "", "Accept", accept)
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	fc.AssertEqual(t, `{"b":"B"}`, actual)
+
+	// still served when proxy strips the root path
+	resp, _ = testRequest(t, "GET", ts.URL+"/restconf/data/x:a/b", "", "Accept", accept)
+	fc.AssertEqual(t, 200, resp.StatusCode)
+
+	resp, actual = testRequest(t, "GET", ts.URL+"/.well-known/host-meta", "", "Accept", "application/json")
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	fc.AssertEqual(t, `{"links":[{"rel":"restconf","href":"/my-api"}]}`, actual)
+
+	resp, actual = testRequest(t, "GET", ts.URL+"/my-api/data/ietf-restconf-monitoring:restconf-state/streams", "", "Accept", accept)
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	fc.AssertEqual(t, true, strings.Contains(actual, `"location":"/my-api/streams/NETCONF/json"`), actual)
+	fc.AssertEqual(t, false, strings.Contains(actual, "/restconf/"), actual)
+
+	fc.AssertEqual(t, "/my-api=dev", s.DeviceAddress("dev", nil))
+}
+
+func TestPretty(t *testing.T) {
+	s, ts := newTestServer(t, nestedYang, nestedData)
+	defer ts.Close()
+	addr := ts.URL + "/restconf/data/x:a"
+	fc.AssertEqual(t, false, s.Pretty)
+
+	_, compact := testRequest(t, "GET", addr, "", "Accept", string(YangDataJsonMimeType1))
+	fc.AssertEqual(t, false, strings.Contains(compact, "\n"), compact)
+
+	_, hinted := testRequest(t, "GET", addr+"?pretty", "", "Accept", string(YangDataJsonMimeType1))
+	fc.AssertEqual(t, true, strings.Contains(hinted, "\n  "), hinted)
+
+	s.Pretty = true
+	for _, accept := range []MimeType{YangDataJsonMimeType1, YangDataXmlMimeType1} {
+		resp, actual := testRequest(t, "GET", addr, "", "Accept", string(accept))
+		fc.AssertEqual(t, 200, resp.StatusCode)
+		ext := ".json"
+		if accept.IsXml() {
+			ext = ".xml"
+		}
+		fc.Gold(t, *updateFlag, []byte(actual), "testdata/gold/pretty"+ext)
+	}
+}
+
+func TestPathRewriter(t *testing.T) {
+	s, ts := newTestServer(t, nestedYang, nestedData)
+	defer ts.Close()
+	rewrites := 0
+	s.PathRewriter = func(u *url.URL) *url.URL {
+		rewrites++
+		if !strings.HasPrefix(u.Path, "/gateway/") {
+			return nil
+		}
+		u.Path = strings.TrimPrefix(u.Path, "/gateway")
+		u.RawPath = ""
+		return u
+	}
+	accept := string(YangDataJsonMimeType1)
+
+	resp, actual := testRequest(t, "GET", ts.URL+"/gateway/restconf/data/x:a/b", "", "Accept", accept)
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	fc.AssertEqual(t, `{"b":"B"}`, actual)
+	fc.AssertEqual(t, 1, rewrites)
+
+	resp, _ = testRequest(t, "GET", ts.URL+"/restconf/data/x:a/b", "", "Accept", accept)
+	fc.AssertEqual(t, 200, resp.StatusCode)
+
+	resp, _ = testRequest(t, "GET", ts.URL+"/gateway/restconf/operations", "", "Accept", accept)
+	fc.AssertEqual(t, 200, resp.StatusCode)
+
+	resp, _ = testRequest(t, "PUT", ts.URL+"/gateway/restconf/data/x:a/c/e=three", `{"x:e":[{"f":"three"}]}`, "Content-Type", accept)
+	fc.AssertEqual(t, 201, resp.StatusCode)
+	fc.AssertEqual(t, "/restconf/data/x:a/c/e=three", resp.Header.Get("Location"))
+
+	req, err := http.NewRequest("GET", ts.URL+"/gateway/restconf/streams/NETCONF", nil)
+	fc.RequireEqual(t, nil, err)
+	req.Header.Set("Accept", string(TextStreamMimeType))
+	stream, err := http.DefaultClient.Do(req)
+	fc.RequireEqual(t, nil, err)
+	stream.Body.Close()
+	fc.AssertEqual(t, 200, stream.StatusCode)
+	fc.AssertEqual(t, true, strings.HasPrefix(stream.Header.Get("Content-Type"), string(TextStreamMimeType)))
+}
+
+func TestTrailingSlash(t *testing.T) {
+	_, ts := newTestServer(t, nestedYang, nestedData)
+	defer ts.Close()
+	json := string(YangDataJsonMimeType)
+	url := ts.URL + "/restconf/data/x:a/c/e"
+	for _, slash := range []string{"", "/", "//"} {
+		entry := url + "=one" + slash
+		_, actual := testRequest(t, "GET", entry, "", "Accept", json)
+		fc.AssertEqual(t, `{"f":"one","g":{"h":1}}`, actual, slash)
+
+		resp, _ := testRequest(t, "PUT", entry, `{"x:e":[{"f":"one","g":{"h":2}}]}`, "Content-Type", json)
+		fc.AssertEqual(t, 204, resp.StatusCode, slash)
+		_, actual = testRequest(t, "GET", url+"=one", "", "Accept", json)
+		fc.AssertEqual(t, `{"f":"one","g":{"h":2}}`, actual, slash)
+
+		resp, _ = testRequest(t, "DELETE", entry, "")
+		fc.AssertEqual(t, 200, resp.StatusCode, slash)
+		resp, _ = testRequest(t, "GET", url+"=one", "", "Accept", json)
+		fc.AssertEqual(t, 404, resp.StatusCode, slash)
+
+		// put it back and its location has no slash
+		resp, _ = testRequest(t, "PUT", entry, `{"x:e":[{"f":"one","g":{"h":1}}]}`, "Content-Type", json)
+		fc.AssertEqual(t, 201, resp.StatusCode, slash)
+		fc.AssertEqual(t, "/restconf/data/x:a/c/e=one", resp.Header.Get("Location"), slash)
+	}
+
+	resp, _ := testRequest(t, "POST", url+"/", `{"x:e":[{"f":"three"}]}`, "Content-Type", json)
+	fc.AssertEqual(t, 201, resp.StatusCode)
+	fc.AssertEqual(t, "/restconf/data/x:a/c/e=three", resp.Header.Get("Location"))
+	_, actual := testRequest(t, "GET", ts.URL+"/restconf/", "", "Accept", json)
+	fc.AssertEqual(t, true, strings.Contains(actual, `"ietf-restconf:restconf"`), actual)
+}
+
+func TestHeaderDecorator(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, streamYang)
+	fc.RequireEqual(t, nil, err)
+	tn := newStreamTestNode()
+	s, ts := newTestServerWithNode(t, m, tn.node())
+	defer ts.Close()
+	s.HeaderDecorator = func(r *http.Request) http.Header {
+		h := http.Header{"Cache-Control": {"no-store"}, "X-Frame-Options": {"DENY"}}
+		if strings.Contains(r.URL.Path, "/streams/") {
+			h.Set("x-stream", "yes")
+		}
+		// server decides content type
+		h.Set("Content-Type", "text/plain")
+		return h
+	}
+
+	resp, _ := testRequest(t, "GET", ts.URL+"/restconf/data/ietf-restconf-monitoring:restconf-state", "",
+		"Accept", string(YangDataJsonMimeType))
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	fc.AssertEqual(t, "no-store", resp.Header.Get("Cache-Control"))
+	fc.AssertEqual(t, "", resp.Header.Get("X-Stream"))
+	fc.AssertEqual(t, string(YangDataJsonMimeType), resp.Header.Get("Content-Type"))
+
+	resp, _ = testRequest(t, "GET", ts.URL+"/restconf/data/x:bogus", "")
+	fc.AssertEqual(t, 404, resp.StatusCode)
+	fc.AssertEqual(t, "no-store", resp.Header.Get("Cache-Control"))
+
+	// headers come before any event is sent
+	req, err := http.NewRequest("GET", ts.URL+"/restconf/streams/NETCONF", nil)
+	fc.RequireEqual(t, nil, err)
+	req.Header.Set("Accept", string(TextStreamMimeType))
+	resp, err = http.DefaultClient.Do(req)
+	fc.RequireEqual(t, nil, err)
+	defer resp.Body.Close()
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	fc.AssertEqual(t, "DENY", resp.Header.Get("X-Frame-Options"))
+	fc.AssertEqual(t, "yes", resp.Header.Get("X-Stream"))
+	// event streams are never cached whatever decorator says
+	fc.AssertEqual(t, "no-cache", resp.Header.Get("Cache-Control"))
+}
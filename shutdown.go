@@ -0,0 +1,104 @@
+package restconf
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrShuttingDown is when a request arrives after Shutdown was called
+var ErrShuttingDown = errors.New("server shutting down")
+
+// activity is the requests being served and the event streams among them so
+// Shutdown can tell streams to close and wait for everything else to finish
+type activity struct {
+	mu       sync.Mutex
+	requests int
+	streams  map[chan struct{}]struct{}
+	draining bool
+
+	// closed once draining and no requests are left
+	idle chan struct{}
+}
+
+// begin request, false when shutting down
+func (a *activity) begin() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.draining {
+		return false
+	}
+	a.requests++
+	return true
+}
+
+func (a *activity) end() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.requests--
+	if a.draining && a.requests == 0 {
+		close(a.idle)
+	}
+}
+
+// openStream registers a long running response. Closing is closed when
+// stream should stop and done is called when it did.
+func (a *activity) openStream() (closing <-chan struct{}, done func()) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	c := make(chan struct{})
+	if a.draining {
+		close(c)
+		return c, func() {}
+	}
+	if a.streams == nil {
+		a.streams = make(map[chan struct{}]struct{})
+	}
+	a.streams[c] = struct{}{}
+	return c, func() {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		delete(a.streams, c)
+	}
+}
+
+// drain refuses new requests, tells open streams to close and is closed once
+// requests being served are done
+func (a *activity) drain() <-chan struct{} {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.draining {
+		return a.idle
+	}
+	a.draining = true
+	a.idle = make(chan struct{})
+	for c := range a.streams {
+		close(c)
+	}
+	a.streams = nil
+	if a.requests == 0 {
+		close(a.idle)
+	}
+	return a.idle
+}
+
+// Shutdown stops accepting new connections and requests, closes event streams
+// and waits for requests being served to finish or ctx to be done, whichever
+// is first. Unlike Close, requests are not cut off.
+func (srv *Server) Shutdown(ctx context.Context) error {
+	idle := srv.activity.drain()
+	var err error
+	if srv.Web != nil {
+		err = srv.Web.Server.Shutdown(ctx)
+	}
+	if err == nil {
+		select {
+		case <-idle:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+	}
+	srv.closeStreams()
+	srv.Web = nil
+	return err
+}
@@ -0,0 +1,84 @@
+package restconf
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/parser"
+)
+
+func TestShutdown(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, streamYang)
+	fc.RequireEqual(t, nil, err)
+	tn := newStreamTestNode()
+	s, ts := newTestServerWithNode(t, m, tn.node())
+	defer ts.Close()
+
+	var bodies []io.ReadCloser
+	for _, url := range []string{"/restconf/streams/NETCONF", "/restconf/data/x:y"} {
+		req, err := http.NewRequest("GET", ts.URL+url, nil)
+		fc.RequireEqual(t, nil, err)
+		req.Header.Set("Accept", string(TextStreamMimeType))
+		resp, err := http.DefaultClient.Do(req)
+		fc.RequireEqual(t, nil, err)
+		fc.AssertEqual(t, 200, resp.StatusCode)
+		bodies = append(bodies, resp.Body)
+	}
+	tn.waitSubscribed(t, true)
+	fc.AssertEqual(t, true, <-tn.subscribed)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	fc.AssertEqual(t, nil, s.Shutdown(ctx))
+
+	// streams end w/o client closing them
+	for _, body := range bodies {
+		_, err := io.ReadAll(body)
+		fc.AssertEqual(t, nil, err)
+		body.Close()
+	}
+	for i := 0; i < 3; i++ {
+		fc.AssertEqual(t, false, <-tn.subscribed)
+	}
+
+	resp, _ := testRequest(t, "GET", ts.URL+"/restconf/data/x:y", "", "Accept", string(TextStreamMimeType))
+	fc.AssertEqual(t, 503, resp.StatusCode)
+	fc.AssertEqual(t, "5", resp.Header.Get("Retry-After"))
+
+	s.RetryAfter = 1500 * time.Millisecond
+	resp, _ = testRequest(t, "GET", ts.URL+"/restconf/data/x:y", "")
+	fc.AssertEqual(t, "2", resp.Header.Get("Retry-After"))
+	s.RetryAfter = 0
+	resp, _ = testRequest(t, "GET", ts.URL+"/restconf/data/x:y", "")
+	fc.AssertEqual(t, 503, resp.StatusCode)
+	fc.AssertEqual(t, "", resp.Header.Get("Retry-After"))
+}
+
+func TestShutdownWaitsForRequests(t *testing.T) {
+	s := &Server{}
+	fc.AssertEqual(t, true, s.activity.begin())
+	closing, closed := s.activity.openStream()
+	defer closed()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	fc.AssertEqual(t, context.DeadlineExceeded, s.Shutdown(ctx))
+	select {
+	case <-closing:
+	default:
+		t.Error("stream not told to close")
+	}
+	fc.AssertEqual(t, false, s.activity.begin())
+
+	idle := s.activity.drain()
+	s.activity.end()
+	select {
+	case <-idle:
+	case <-time.After(time.Second):
+		t.Error("not idle after last request ended")
+	}
+}
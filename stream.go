@@ -0,0 +1,577 @@
+package restconf
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/freeconf/restconf/device"
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+)
+
+// NetconfStream is the default event stream with all the notifications from
+// all the modules of a device. RFC8040 Sec. 6.2
+const NetconfStream = "NETCONF"
+
+// Stream is an event stream clients subscribe to at {+restconf}/streams/{name}
+// to receive notifications from many modules over a single connection.
+// RFC8040 Sec. 6
+type Stream struct {
+	Name        string
+	Description string
+
+	// Optional: Modules whose notifications are in stream, default is all
+	// modules in device
+	Modules []string
+
+	// Optional: Number of events to keep so subscribers can ask for past
+	// events with start-time parameter.  Zero means stream does not support
+	// replay unless there is a ReplayStore
+	ReplayLogSize int
+
+	// Optional: Where events are kept for replay, for example to keep events
+	// across restarts. Default is a ReplayLog of ReplayLogSize events
+	ReplayStore ReplayStore
+
+	// Optional: Encodings of events, "json" and/or "xml", default is both
+	Encodings []string
+
+	// Optional: Overrides Server.Heartbeat for this stream
+	Heartbeat time.Duration
+}
+
+// stream encodings. RFC8040 Sec. 9.1.3
+const (
+	streamEncodingJson = "json"
+	streamEncodingXml  = "xml"
+)
+
+// ReplaySupport is true when subscribers can ask for past events
+func (s Stream) ReplaySupport() bool {
+	return s.ReplayLogSize > 0 || s.ReplayStore != nil
+}
+
+func (s Stream) replayStore() ReplayStore {
+	if s.ReplayStore != nil {
+		return s.ReplayStore
+	}
+	if s.ReplayLogSize > 0 {
+		return NewReplayLog(s.ReplayLogSize)
+	}
+	return nil
+}
+
+func (s Stream) encodings() []string {
+	if len(s.Encodings) == 0 {
+		return []string{streamEncodingJson, streamEncodingXml}
+	}
+	return s.Encodings
+}
+
+func (s Stream) hasEncoding(encoding string) bool {
+	for _, candidate := range s.encodings() {
+		if candidate == encoding {
+			return true
+		}
+	}
+	return false
+}
+
+// how many events can wait for slow subscriber before subscriber is dropped
+const streamEventBacklog = 100
+
+type streamEvent struct {
+	module *meta.Module
+	notif  node.Notification
+}
+
+// eventStream merges the notifications of a device into a single stream.
+// Notifications are only subscribed to while there are subscribers unless
+// stream keeps a replay log in which case stream listens until closed.
+type eventStream struct {
+	Stream
+	d       device.Device
+	created time.Time
+
+	// guards opening and closing notification subscriptions
+	subMu   sync.Mutex
+	closers []node.NotifyCloser
+	opened  bool
+
+	// guards listeners and store
+	mu        sync.Mutex
+	listeners *list.List
+	store     ReplayStore
+}
+
+func newEventStream(d device.Device, s Stream, now time.Time) *eventStream {
+	return &eventStream{
+		Stream:    s,
+		d:         d,
+		created:   now,
+		listeners: list.New(),
+		store:     s.replayStore(),
+	}
+}
+
+func (s *eventStream) includesModule(module string) bool {
+	if len(s.Modules) == 0 {
+		return true
+	}
+	for _, candidate := range s.Modules {
+		if candidate == module {
+			return true
+		}
+	}
+	return false
+}
+
+// notifications are the definitions of all the notifications in stream
+func (s *eventStream) notifications() []*meta.Notification {
+	var notifs []*meta.Notification
+	for name, m := range s.d.Modules() {
+		if !s.includesModule(name) {
+			continue
+		}
+		for _, notif := range m.Notifications() {
+			notifs = append(notifs, notif)
+		}
+	}
+	return notifs
+}
+
+// open subscribes to every notification in stream. Caller must hold subMu
+func (s *eventStream) open() error {
+	if s.opened {
+		return nil
+	}
+	for name, m := range s.d.Modules() {
+		if !s.includesModule(name) {
+			continue
+		}
+		for _, notif := range m.Notifications() {
+			if err := s.subscribe(name, notif); err != nil {
+				s.close()
+				return err
+			}
+		}
+	}
+	s.opened = true
+	return nil
+}
+
+func (s *eventStream) subscribe(module string, notif *meta.Notification) error {
+	b, err := s.d.Browser(module)
+	if err != nil || b == nil {
+		return err
+	}
+	sel, err := b.Root().Find(notif.Ident())
+	if err != nil || sel == nil {
+		return err
+	}
+	origMod := meta.OriginalModule(notif)
+	closer, err := sel.Notifications(func(n node.Notification) {
+		s.publish(origMod, n)
+	})
+	if err != nil {
+		// not every module that defines notifications sends them
+		fc.Debug.Printf("stream %s skipping %s:%s. %s", s.Name, module, notif.Ident(), err)
+		return nil
+	}
+	if closer != nil {
+		s.closers = append(s.closers, closer)
+	}
+	return nil
+}
+
+// close unsubscribes to all notifications. Caller must hold subMu
+func (s *eventStream) close() {
+	for _, closer := range s.closers {
+		if err := closer(); err != nil {
+			fc.Err.Printf("stream %s closing subscription. %s", s.Name, err)
+		}
+	}
+	s.closers = nil
+	s.opened = false
+}
+
+func (s *eventStream) publish(module *meta.Module, n node.Notification) {
+	// event selection is only valid during callback but listeners send it
+	// later and replay keeps it so keep a copy
+	copy, err := copyEvent(n.Event)
+	if err != nil {
+		fc.Err.Printf("stream %s could not keep event. %s", s.Name, err)
+		return
+	}
+	e := streamEvent{module: module, notif: n}
+	e.notif.Event = copy
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.store != nil {
+		if err := s.store.Add(ReplayEvent{Module: e.module, Notification: e.notif}); err != nil {
+			fc.Err.Printf("stream %s could not keep event for replay. %s", s.Name, err)
+		}
+	}
+	for p := s.listeners.Front(); p != nil; p = p.Next() {
+		p.Value.(func(streamEvent))(e)
+	}
+}
+
+func copyEvent(event *node.Selection) (*node.Selection, error) {
+	var buf bytes.Buffer
+	if err := event.InsertInto(jsonEncoder(&buf, snapshotCompliance)); err != nil {
+		return nil, err
+	}
+	n, err := jsonDecoder(&buf)
+	if err != nil {
+		return nil, err
+	}
+	return event.Split(n), nil
+}
+
+// listen calls l with every new event and returns the events kept for replay
+// from start to stop, unless start is zero.  Replayed events and new events
+// are gathered under the same lock so no event is missed or sent twice. Call
+// returned func to stop listening.
+func (s *eventStream) listen(start time.Time, stop time.Time, l func(streamEvent)) ([]streamEvent, func(), error) {
+	if !start.IsZero() && !s.ReplaySupport() {
+		return nil, nil, fmt.Errorf("%w. stream %s does not support replay", fc.BadRequestError, s.Name)
+	}
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+	if err := s.open(); err != nil {
+		return nil, nil, err
+	}
+	s.mu.Lock()
+	var replay []streamEvent
+	if !start.IsZero() {
+		kept, err := s.store.Replay(start, stop)
+		if err != nil {
+			s.mu.Unlock()
+			return nil, nil, err
+		}
+		for _, e := range kept {
+			replay = append(replay, streamEvent{module: e.Module, notif: e.Notification})
+		}
+	}
+	elem := s.listeners.PushBack(l)
+	s.mu.Unlock()
+	return replay, func() {
+		s.subMu.Lock()
+		defer s.subMu.Unlock()
+		s.mu.Lock()
+		s.listeners.Remove(elem)
+		empty := s.listeners.Len() == 0
+		s.mu.Unlock()
+		if empty && !s.ReplaySupport() {
+			s.close()
+		}
+	}, nil
+}
+
+// AddStream makes stream available at {+restconf}/streams/{name} for the main
+// device replacing any stream with the same name.  Streams with a replay log
+// start listening to notifications right away so add them after all the
+// modules are added to the device.
+func (srv *Server) AddStream(s Stream) error {
+	es := newEventStream(srv.mainDevice(), s, srv.now())
+	if s.ReplaySupport() {
+		es.subMu.Lock()
+		err := es.open()
+		es.subMu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+	srv.streamsMu.Lock()
+	defer srv.streamsMu.Unlock()
+	if existing, found := srv.streams[s.Name]; found {
+		existing.subMu.Lock()
+		existing.close()
+		existing.subMu.Unlock()
+	}
+	srv.streams[s.Name] = es
+	return nil
+}
+
+// StreamAddress is where clients subscribe to stream in given encoding
+func (srv *Server) StreamAddress(name string, encoding string) string {
+	return fmt.Sprint(srv.rootPath(), "/streams/", name, "/", encoding)
+}
+
+// eventStreams of main device sorted by name
+func (srv *Server) eventStreams() []*eventStream {
+	srv.streamsMu.Lock()
+	defer srv.streamsMu.Unlock()
+	streams := make([]*eventStream, 0, len(srv.streams))
+	for _, s := range srv.streams {
+		streams = append(streams, s)
+	}
+	sort.Slice(streams, func(i, j int) bool {
+		return streams[i].Name < streams[j].Name
+	})
+	return streams
+}
+
+func (srv *Server) findStream(d device.Device, name string) (*eventStream, error) {
+	if srv.isMain(d) {
+		srv.streamsMu.Lock()
+		defer srv.streamsMu.Unlock()
+		if s, found := srv.streams[name]; found {
+			return s, nil
+		}
+	} else if name == NetconfStream {
+		// streams are registered for main device only, other devices get
+		// default stream w/o replay
+		return newEventStream(d, Stream{Name: NetconfStream}, srv.now()), nil
+	}
+	return nil, fmt.Errorf("%w. stream %s", fc.NotFoundError, name)
+}
+
+// serveEventStream keeps connection open and sends each notification in stream
+// as a Server-Sent Event until client disconnects or stop-time is reached.
+// Path may end with "/json" or "/xml" to pick the encoding regardless of the
+// Accept header.
+func (srv *Server) serveEventStream(compliance ComplianceOptions, ctx context.Context, w http.ResponseWriter, r *http.Request, d device.Device, name string, accept MimeType) {
+	if r.Method != "GET" {
+		handleErr(compliance, fmt.Errorf("%w. %s", ErrOperationNotSupported, r.Method), r, w, accept)
+		return
+	}
+	s, err := srv.findStream(d, name)
+	if err != nil {
+		handleErr(compliance, err, r, w, accept)
+		return
+	}
+	encoding, _ := shift(r.URL, '/')
+	if encoding != "" && !s.hasEncoding(encoding) {
+		handleErr(compliance, fmt.Errorf("%w. stream %s encoding %s", fc.NotFoundError, name, encoding), r, w, accept)
+		return
+	}
+	switch encoding {
+	case streamEncodingJson:
+		accept = YangDataJsonMimeType
+	case streamEncodingXml:
+		accept = YangDataXmlMimeType
+	}
+	params, err := parseQueryParams(r.URL, srv.fieldsCache())
+	if err == nil {
+		err = params.CheckMethod(r.Method)
+	}
+	if err == nil {
+		err = params.CheckUnknown(errorModeOf(ctx))
+	}
+	if err == nil && !params.StartTime.IsZero() && params.StartTime.After(srv.now()) {
+		err = fmt.Errorf("%w. %s is in the future", fc.BadRequestError, startTimeParam)
+	}
+	var filter *notifyFilter
+	if err == nil && params.Filter != "" {
+		filter, err = newNotifyFilter(params.Filter, s)
+	}
+	if err != nil {
+		handleErr(compliance, err, r, w, accept)
+		return
+	}
+	flusher, hasFlusher := w.(http.Flusher)
+	if !hasFlusher {
+		panic("invalid response writer")
+	}
+
+	// events are written from this goroutine so replayed events always come
+	// before new events and writes never overlap
+	events := make(chan streamEvent, streamEventBacklog)
+	overflow := make(chan struct{})
+	var overflowOnce sync.Once
+	replay, unsubscribe, err := s.listen(params.StartTime, params.StopTime, func(e streamEvent) {
+		select {
+		case events <- e:
+		default:
+			overflowOnce.Do(func() { close(overflow) })
+		}
+	})
+	if err != nil {
+		handleErr(compliance, err, r, w, accept)
+		return
+	}
+	defer unsubscribe()
+
+	heartbeat := srv.Heartbeat
+	if s.Heartbeat != 0 {
+		heartbeat = s.Heartbeat
+	}
+	sse := newSseWriter(w, flusher, heartbeat)
+	defer sse.stop()
+	srv.observer().StreamOpened(s.Name)
+	defer srv.observer().StreamClosed(s.Name)
+
+	setEventStreamHeaders(r, w.Header())
+	flusher.Flush()
+	subscribeCount.Add(1)
+	defer subscribeCount.Add(-1)
+
+	var stop <-chan time.Time
+	if !params.StopTime.IsZero() {
+		timer := time.NewTimer(params.StopTime.Sub(srv.now()))
+		defer timer.Stop()
+		stop = timer.C
+	}
+	send := func(e streamEvent) bool {
+		if !params.StopTime.IsZero() && e.notif.EventTime.After(params.StopTime) {
+			return false
+		}
+		if filter != nil {
+			if keep, err := filter.matches(e); err != nil {
+				fc.Err.Printf("stream %s filter %s. %s", name, filter.expr, err)
+				return true
+			} else if !keep {
+				return true
+			}
+		}
+		if err := sse.event(compliance, accept, e.module, e.notif); err != nil {
+			fc.Err.Printf("error writing notif. %s", err)
+			return false
+		}
+		return true
+	}
+	for _, e := range replay {
+		if !send(e) {
+			return
+		}
+	}
+	closing, closed := srv.activity.openStream()
+	defer closed()
+	for {
+		select {
+		case <-r.Context().Done():
+			// normal client closing subscription
+			return
+		case <-closing:
+			return
+		case <-stop:
+			return
+		case <-overflow:
+			fc.Err.Printf("stream %s dropping subscriber that cannot keep up", name)
+			return
+		case <-sse.heartbeat():
+			if err := sse.beat(); err != nil {
+				return
+			}
+		case e := <-events:
+			if !send(e) {
+				return
+			}
+		}
+	}
+}
+
+func setEventStreamHeaders(r *http.Request, hdr http.Header) {
+	hdr.Set("Content-Type", string(TextStreamMimeType)+"; charset=utf-8")
+	hdr.Set("Cache-Control", "no-cache")
+	hdr.Set("X-Accel-Buffering", "no")
+
+	// HTTP/2 frames each flush and does not allow connection specific
+	// headers. RFC7540 Sec. 8.1.2.2
+	if r.ProtoMajor < 2 {
+		hdr.Set("Connection", "keep-alive")
+
+		// default is chunked and web browsers don't know to read after each flush
+		hdr.Set("Transfer-Encoding", "identity")
+	}
+}
+
+// sseKeepalive is a Server-Sent Event comment which clients ignore
+const sseKeepalive = ": keepalive\n\n"
+
+// sseWriter writes one event at a time so events from different goroutines do
+// not mix. With a heartbeat, a comment is written when no event was sent in
+// the last interval.
+type sseWriter struct {
+	mu      sync.Mutex
+	w       io.Writer
+	flusher http.Flusher
+	sent    bool
+	ticker  *time.Ticker
+}
+
+func newSseWriter(w io.Writer, flusher http.Flusher, heartbeat time.Duration) *sseWriter {
+	s := &sseWriter{w: w, flusher: flusher}
+	if heartbeat > 0 {
+		s.ticker = time.NewTicker(heartbeat)
+	}
+	return s
+}
+
+// heartbeat is nil w/o a heartbeat so select never picks it
+func (s *sseWriter) heartbeat() <-chan time.Time {
+	if s.ticker == nil {
+		return nil
+	}
+	return s.ticker.C
+}
+
+func (s *sseWriter) stop() {
+	if s.ticker != nil {
+		s.ticker.Stop()
+	}
+}
+
+func (s *sseWriter) event(compliance ComplianceOptions, accept MimeType, module *meta.Module, n node.Notification) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := writeEvent(s.w, compliance, accept, module, n); err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	s.sent = true
+	return nil
+}
+
+// beat writes keepalive unless an event was sent since last beat
+func (s *sseWriter) beat() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sent {
+		s.sent = false
+		return nil
+	}
+	if _, err := io.WriteString(s.w, sseKeepalive); err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+// writeEvent writes notification as a single Server-Sent Event
+func writeEvent(w io.Writer, compliance ComplianceOptions, accept MimeType, module *meta.Module, n node.Notification) error {
+	// write into a buffer so we write data all at once to handle concurrent messages and
+	// ensure messages are not corrupted.  We could use a lock, but might cause deadlocks
+	var buf bytes.Buffer
+	wireFmt := getWireFormatter(accept)
+
+	// According to SSE Spec, each event needs following format:
+	// data: {payload}\n\n
+	fmt.Fprint(&buf, "data: ")
+	if !compliance.DisableNotificationWrapper {
+		etime := n.EventTime.Format(EventTimeFormat)
+		wireFmt.writeNotificationStart(&buf, module, etime)
+	}
+	if err := n.Event.InsertInto(nodeWtr(accept, compliance, &buf)); err != nil {
+		return err
+	}
+	if !compliance.DisableNotificationWrapper {
+		wireFmt.writeNotificationEnd(&buf)
+	}
+	fmt.Fprint(&buf, "\n\n")
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	fc.Debug.Printf("sent %d bytes in notif", buf.Len())
+	return nil
+}
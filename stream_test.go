@@ -0,0 +1,478 @@
+package restconf
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/freeconf/restconf/device"
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+)
+
+const streamYang = `module x {
+	namespace "x";
+	prefix "x";
+	revision 0;
+	notification y {
+		leaf z {
+			type string;
+		}
+	}
+	notification w {
+		leaf z {
+			type string;
+		}
+	}
+}`
+
+// flushRecorder is safe to read while handler is still writing and signals
+// every flush
+type flushRecorder struct {
+	*httptest.ResponseRecorder
+	mu      sync.Mutex
+	flushed chan string
+}
+
+func newFlushRecorder() *flushRecorder {
+	return &flushRecorder{
+		ResponseRecorder: httptest.NewRecorder(),
+		flushed:          make(chan string, 10),
+	}
+}
+
+func (r *flushRecorder) Write(data []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.ResponseRecorder.Write(data)
+}
+
+func (r *flushRecorder) Flush() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ResponseRecorder.Flush()
+	r.flushed <- r.Body.String()
+}
+
+// streamTestNode sends events to all subscribers of a notification
+type streamTestNode struct {
+	mu          sync.Mutex
+	subscribers map[int]node.NotifyRequest
+	counter     int
+	subscribed  chan bool
+}
+
+func newStreamTestNode() *streamTestNode {
+	return &streamTestNode{
+		subscribers: make(map[int]node.NotifyRequest),
+		subscribed:  make(chan bool, 10),
+	}
+}
+
+func (tn *streamTestNode) node() node.Node {
+	return &nodeutil.Basic{
+		OnNotify: func(r node.NotifyRequest) (node.NotifyCloser, error) {
+			tn.mu.Lock()
+			defer tn.mu.Unlock()
+			tn.counter++
+			id := tn.counter
+			tn.subscribers[id] = r
+			tn.subscribed <- true
+			return func() error {
+				tn.mu.Lock()
+				defer tn.mu.Unlock()
+				delete(tn.subscribers, id)
+				tn.subscribed <- false
+				return nil
+			}, nil
+		},
+	}
+}
+
+// waitSubscribed waits for stream to (un)subscribe to all notifications
+func (tn *streamTestNode) waitSubscribed(t *testing.T, expected bool) {
+	t.Helper()
+	for i := 0; i < 2; i++ {
+		fc.AssertEqual(t, expected, <-tn.subscribed)
+	}
+}
+
+func (tn *streamTestNode) send(notif string, z string, when time.Time) {
+	tn.mu.Lock()
+	defer tn.mu.Unlock()
+	for _, r := range tn.subscribers {
+		if r.Meta.Ident() != notif {
+			continue
+		}
+		r.SendWhen(nodeutil.ReflectChild(map[string]interface{}{"z": z}), when)
+	}
+}
+
+func TestEventStream(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, streamYang)
+	fc.RequireEqual(t, nil, err)
+	tn := newStreamTestNode()
+	s, ts := newTestServerWithNode(t, m, tn.node())
+	defer ts.Close()
+	t1 := time.Date(2020, time.March, 4, 5, 6, 7, 0, time.UTC)
+	s.Now = func() time.Time {
+		return t1.Add(time.Hour)
+	}
+
+	t.Run("live", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		req := httptest.NewRequest("GET", "/restconf/streams/NETCONF", nil).WithContext(ctx)
+		req.Header.Set("Accept", string(TextStreamMimeType))
+		w := newFlushRecorder()
+		done := make(chan bool)
+		go func() {
+			s.ServeHTTP(w, req)
+			done <- true
+		}()
+		tn.waitSubscribed(t, true)
+		<-w.flushed
+		tn.send("y", "hi", t1)
+		body := <-w.flushed
+		expected := `data: {"ietf-restconf:notification":{"eventTime":"2020-03-04T05:06:07+00:00","event":{"z":"hi"}}}` + "\n\n"
+		fc.AssertEqual(t, expected, body)
+		fc.AssertEqual(t, "text/event-stream; charset=utf-8", w.Header().Get("Content-Type"))
+
+		// client disconnects
+		cancel()
+		<-done
+		tn.waitSubscribed(t, false)
+	})
+
+	t.Run("replay", func(t *testing.T) {
+		fc.RequireEqual(t, nil, s.AddStream(Stream{Name: "replay", ReplayLogSize: 2}))
+		tn.waitSubscribed(t, true)
+		for i, z := range []string{"one", "two", "three", "four"} {
+			tn.send("y", z, t1.Add(time.Duration(i)*time.Minute))
+		}
+		// "one" and "two" aged out of replay log and "four" is after stop-time
+		req := httptest.NewRequest("GET", "/restconf/streams/replay/xml?start-time=2020-03-04T05:06:07Z&stop-time=2020-03-04T05:08:07Z", nil)
+		req.Header.Set("Accept", string(TextStreamMimeType))
+		w := newFlushRecorder()
+		s.ServeHTTP(w, req)
+		events := strings.Split(strings.TrimSpace(w.Body.String()), "\n\n")
+		fc.AssertEqual(t, 1, len(events))
+		fc.AssertEqual(t, true, strings.Contains(events[0], "<z>three</z>"), events[0])
+		fc.AssertEqual(t, true, strings.Contains(events[0], "<eventTime>2020-03-04T05:08:07+00:00</eventTime>"), events[0])
+	})
+
+	t.Run("filter", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		req := httptest.NewRequest("GET", "/restconf/streams/NETCONF?filter=/x:w", nil).WithContext(ctx)
+		req.Header.Set("Accept", string(TextStreamMimeType))
+		w := newFlushRecorder()
+		done := make(chan bool)
+		go func() {
+			s.ServeHTTP(w, req)
+			done <- true
+		}()
+		tn.waitSubscribed(t, true)
+		<-w.flushed
+		tn.send("y", "not me", t1)
+		tn.send("w", "me", t1)
+		body := <-w.flushed
+		fc.AssertEqual(t, false, strings.Contains(body, "not me"), body)
+		fc.AssertEqual(t, true, strings.Contains(body, `"z":"me"`), body)
+		cancel()
+		<-done
+		tn.waitSubscribed(t, false)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			url    string
+			status int
+		}{
+			{url: "/restconf/streams/NETCONF?start-time=2020-03-04T05:06:07Z", status: http.StatusBadRequest},
+			{url: "/restconf/streams/replay?start-time=2030-03-04T05:06:07Z", status: http.StatusBadRequest},
+			{url: "/restconf/streams/replay?start-time=2020-03-04T05:06:07Z&stop-time=2020-03-04T05:06:06Z", status: http.StatusBadRequest},
+			{url: "/restconf/streams/replay?stop-time=2020-03-04T05:06:07Z", status: http.StatusBadRequest},
+			{url: "/restconf/streams/nope", status: http.StatusNotFound},
+			{url: "/restconf/streams/NETCONF/yaml", status: http.StatusNotFound},
+			{url: "/restconf/streams/NETCONF?filter=nope", status: http.StatusBadRequest},
+			{url: "/restconf/streams/NETCONF?filter=y/nope='x'", status: http.StatusBadRequest},
+			{url: "/restconf/streams/NETCONF?filter=y/z", status: http.StatusBadRequest},
+			{url: "/restconf/streams/NETCONF?filter=q:y", status: http.StatusBadRequest},
+		}
+		for _, test := range tests {
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, httptest.NewRequest("GET", test.url, nil))
+			fc.AssertEqual(t, test.status, w.Code, test.url)
+		}
+	})
+}
+
+func TestEventStreamReusedEvent(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, streamYang)
+	fc.RequireEqual(t, nil, err)
+	tn := newStreamTestNode()
+	_, ts := newTestServerWithNode(t, m, tn.node())
+	defer ts.Close()
+	req, err := http.NewRequest("GET", ts.URL+"/restconf/streams/NETCONF", nil)
+	fc.RequireEqual(t, nil, err)
+	req.Header.Set("Accept", string(TextStreamMimeType))
+	resp, err := http.DefaultClient.Do(req)
+	fc.RequireEqual(t, nil, err)
+	defer resp.Body.Close()
+	tn.waitSubscribed(t, true)
+
+	// node reuses its event once event is sent, NETCONF has no replay
+	event := map[string]interface{}{}
+	n := nodeutil.ReflectChild(event)
+	tn.mu.Lock()
+	for i := 0; i < 5; i++ {
+		event["z"] = fmt.Sprint("event", i)
+		for _, r := range tn.subscribers {
+			if r.Meta.Ident() == "y" {
+				r.Send(n)
+			}
+		}
+	}
+	event["z"] = "reused"
+	tn.mu.Unlock()
+
+	rdr := bufio.NewReader(resp.Body)
+	for i := 0; i < 5; i++ {
+		line, err := rdr.ReadString('\n')
+		fc.RequireEqual(t, nil, err)
+		fc.AssertEqual(t, true, strings.Contains(line, fmt.Sprintf(`"z":"event%d"`, i)), line)
+		rdr.ReadString('\n')
+	}
+}
+
+func TestHeartbeat(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, streamYang)
+	fc.RequireEqual(t, nil, err)
+	tn := newStreamTestNode()
+	s, ts := newTestServerWithNode(t, m, tn.node())
+	defer ts.Close()
+	s.Heartbeat = 10 * time.Millisecond
+	for _, url := range []string{"/restconf/streams/NETCONF", "/restconf/data/x:y"} {
+		ctx, cancel := context.WithCancel(context.Background())
+		req := httptest.NewRequest("GET", url, nil).WithContext(ctx)
+		req.Header.Set("Accept", string(TextStreamMimeType))
+		w := newFlushRecorder()
+		done := make(chan bool)
+		go func() {
+			s.ServeHTTP(w, req)
+			done <- true
+		}()
+		<-w.flushed
+		fc.AssertEqual(t, ": keepalive\n\n", <-w.flushed, url)
+		fc.AssertEqual(t, ": keepalive\n\n: keepalive\n\n", <-w.flushed, url)
+		cancel()
+		<-done
+	}
+}
+
+// testReplayStore counts how often replay log is asked for events
+type testReplayStore struct {
+	*ReplayLog
+	replays int
+}
+
+func (s *testReplayStore) Replay(start time.Time, stop time.Time) ([]ReplayEvent, error) {
+	s.replays++
+	return s.ReplayLog.Replay(start, stop)
+}
+
+func TestReplayStore(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, streamYang)
+	fc.RequireEqual(t, nil, err)
+	tn := newStreamTestNode()
+	s, ts := newTestServerWithNode(t, m, tn.node())
+	defer ts.Close()
+	t1 := time.Date(2020, time.March, 4, 5, 6, 7, 0, time.UTC)
+	s.Now = func() time.Time {
+		return t1.Add(time.Hour)
+	}
+	store := &testReplayStore{ReplayLog: NewReplayLog(10)}
+	fc.RequireEqual(t, nil, s.AddStream(Stream{Name: "history", ReplayStore: store}))
+	tn.waitSubscribed(t, true)
+	for i, z := range []string{"one", "two", "three"} {
+		tn.send("y", z, t1.Add(time.Duration(i)*time.Minute))
+	}
+
+	t.Run("replay then live", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		req := httptest.NewRequest("GET", "/restconf/streams/history?start-time=2020-03-04T05:07:07Z", nil).WithContext(ctx)
+		req.Header.Set("Accept", string(TextStreamMimeType))
+		w := newFlushRecorder()
+		done := make(chan bool)
+		go func() {
+			s.ServeHTTP(w, req)
+			done <- true
+		}()
+		<-w.flushed
+		fc.AssertEqual(t, true, strings.Contains(<-w.flushed, `"z":"two"`))
+		fc.AssertEqual(t, true, strings.Contains(<-w.flushed, `"z":"three"`))
+		tn.send("y", "four", t1.Add(time.Hour))
+		fc.AssertEqual(t, true, strings.Contains(<-w.flushed, `"z":"four"`))
+		cancel()
+		<-done
+		fc.AssertEqual(t, 1, store.replays)
+	})
+
+	t.Run("stop-time", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/restconf/streams/history?start-time=2020-03-04T05:06:07Z&stop-time=2020-03-04T05:07:07Z", nil)
+		req.Header.Set("Accept", string(TextStreamMimeType))
+		w := newFlushRecorder()
+		// returns on its own because stop-time has passed
+		s.ServeHTTP(w, req)
+		events := strings.Split(strings.TrimSpace(w.Body.String()), "\n\n")
+		fc.AssertEqual(t, 2, len(events))
+		fc.AssertEqual(t, true, strings.Contains(events[0], `"z":"one"`), events[0])
+		fc.AssertEqual(t, true, strings.Contains(events[1], `"z":"two"`), events[1])
+	})
+}
+
+func TestReplayLog(t *testing.T) {
+	t1 := time.Date(2020, time.March, 4, 5, 6, 7, 0, time.UTC)
+	l := NewReplayLog(3)
+	for i := 0; i < 5; i++ {
+		l.Add(ReplayEvent{Notification: node.Notification{EventTime: t1.Add(time.Duration(i) * time.Minute)}})
+	}
+	minutes := func(events []ReplayEvent) []int {
+		var actual []int
+		for _, e := range events {
+			actual = append(actual, int(e.Notification.EventTime.Sub(t1).Minutes()))
+		}
+		return actual
+	}
+	events, err := l.Replay(t1, time.Time{})
+	fc.RequireEqual(t, nil, err)
+	fc.AssertEqual(t, []int{2, 3, 4}, minutes(events))
+	events, _ = l.Replay(t1.Add(3*time.Minute), time.Time{})
+	fc.AssertEqual(t, []int{3, 4}, minutes(events))
+	events, _ = l.Replay(t1, t1.Add(3*time.Minute))
+	fc.AssertEqual(t, []int{2, 3}, minutes(events))
+}
+
+func TestMonitoringStreams(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, streamYang)
+	fc.RequireEqual(t, nil, err)
+	tn := newStreamTestNode()
+	s, ts := newTestServerWithNode(t, m, tn.node())
+	defer ts.Close()
+	s.Now = func() time.Time {
+		return time.Date(2020, time.March, 4, 5, 6, 7, 0, time.UTC)
+	}
+	fc.RequireEqual(t, nil, s.AddStream(Stream{
+		Name:          "alerts",
+		Description:   "alarms",
+		ReplayLogSize: 10,
+		Encodings:     []string{"json"},
+	}))
+	fc.RequireEqual(t, nil, s.AddStream(Stream{
+		Name:      "audit",
+		Encodings: []string{"xml"},
+	}))
+	resp, actual := testRequest(t, "GET", ts.URL+"/restconf/data/ietf-restconf-monitoring:restconf-state/streams", "",
+		"Accept", string(YangDataJsonMimeType1))
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	expected := `{"stream":[` +
+		`{"name":"NETCONF","description":"default NETCONF event stream","replay-support":false,"access":[` +
+		`{"encoding":"json","location":"/restconf/streams/NETCONF/json"},` +
+		`{"encoding":"xml","location":"/restconf/streams/NETCONF/xml"}]},` +
+		`{"name":"alerts","description":"alarms","replay-support":true,"replay-log-creation-time":"2020-03-04T05:06:07+00:00","access":[` +
+		`{"encoding":"json","location":"/restconf/streams/alerts/json"}]},` +
+		`{"name":"audit","replay-support":false,"access":[` +
+		`{"encoding":"xml","location":"/restconf/streams/audit/xml"}]}]}`
+	fc.AssertEqual(t, expected, actual)
+
+	// encodings that are not configured are not served
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/restconf/streams/audit/json", nil))
+	fc.AssertEqual(t, http.StatusNotFound, w.Code)
+}
+
+func TestYangLibraryChangeEvent(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, streamYang)
+	fc.RequireEqual(t, nil, err)
+	tn := newStreamTestNode()
+	s, ts := newTestServerWithNode(t, m, tn.node())
+	defer ts.Close()
+	d := s.main.(*device.Local)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest("GET", "/restconf/streams/NETCONF?filter=ietf-yang-library:yang-library-update", nil).WithContext(ctx)
+	req.Header.Set("Accept", string(TextStreamMimeType))
+	w := newFlushRecorder()
+	done := make(chan bool)
+	go func() {
+		s.ServeHTTP(w, req)
+		done <- true
+	}()
+	tn.waitSubscribed(t, true)
+	<-w.flushed
+
+	added, err := parser.LoadModuleFromString(nil, `module added { namespace "added"; prefix "a"; revision 0; }`)
+	fc.RequireEqual(t, nil, err)
+	d.AddBrowser(node.NewBrowser(added, &nodeutil.Basic{}))
+	body := <-w.flushed
+	expected := fmt.Sprintf(`"content-id":"%s"`, device.YangLibContentId(d.Modules()))
+	fc.AssertEqual(t, true, strings.Contains(body, expected), body)
+	cancel()
+	<-done
+}
+
+func TestEventStreamHTTP2(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, streamYang)
+	fc.RequireEqual(t, nil, err)
+	tn := newStreamTestNode()
+	s, plain := newTestServerWithNode(t, m, tn.node())
+	plain.Close()
+	ts := httptest.NewUnstartedServer(s)
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	defer ts.Close()
+
+	for _, url := range []string{"/restconf/streams/NETCONF", "/restconf/data/x:y"} {
+		ctx, cancel := context.WithCancel(context.Background())
+		req, err := http.NewRequestWithContext(ctx, "GET", ts.URL+url, nil)
+		fc.RequireEqual(t, nil, err)
+		req.Header.Set("Accept", string(TextStreamMimeType))
+		resp, err := ts.Client().Do(req)
+		fc.RequireEqual(t, nil, err)
+		fc.AssertEqual(t, 2, resp.ProtoMajor)
+		fc.AssertEqual(t, "", resp.Header.Get("Connection"))
+		fc.AssertEqual(t, "", resp.Header.Get("Transfer-Encoding"))
+		fc.AssertEqual(t, int64(-1), resp.ContentLength)
+		if url == "/restconf/streams/NETCONF" {
+			tn.waitSubscribed(t, true)
+		} else {
+			fc.AssertEqual(t, true, <-tn.subscribed)
+		}
+
+		// each event arrives before the next one is sent
+		events := bufio.NewReader(resp.Body)
+		for _, z := range []string{"one", "two"} {
+			tn.send("y", z, time.Now())
+			line, err := events.ReadString('\n')
+			fc.RequireEqual(t, nil, err)
+			fc.AssertEqual(t, true, strings.Contains(line, fmt.Sprintf(`"z":"%s"`, z)), line)
+			blank, err := events.ReadString('\n')
+			fc.RequireEqual(t, nil, err)
+			fc.AssertEqual(t, "\n", blank)
+		}
+		cancel()
+		resp.Body.Close()
+		if url == "/restconf/streams/NETCONF" {
+			tn.waitSubscribed(t, false)
+		} else {
+			fc.AssertEqual(t, false, <-tn.subscribed)
+		}
+	}
+}
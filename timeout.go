@@ -0,0 +1,73 @@
+package restconf
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/freeconf/yang/node"
+)
+
+// cancelConstraint stops reading, editing or running actions once request
+// is cancelled or Server.Timeout passes so nodes that do not check context
+// themselves still stop between data items
+type cancelConstraint struct{}
+
+func checkCancelled(ctx context.Context, p *node.Path) (bool, error) {
+	if ctx == nil || ctx.Err() == nil {
+		return true, nil
+	}
+	return false, fmt.Errorf("%w. stopped at %s", ctx.Err(), p)
+}
+
+func (cancelConstraint) CheckContainerPreConstraints(r *node.ChildRequest) (bool, error) {
+	return checkCancelled(r.Selection.Context, r.Selection.Path)
+}
+
+func (cancelConstraint) CheckListPreConstraints(r *node.ListRequest) (bool, error) {
+	return checkCancelled(r.Selection.Context, r.Selection.Path)
+}
+
+func (cancelConstraint) CheckFieldPreConstraints(r *node.FieldRequest, hnd *node.ValueHandle) (bool, error) {
+	return checkCancelled(r.Selection.Context, r.Selection.Path)
+}
+
+func (cancelConstraint) CheckActionPreConstraints(r *node.ActionRequest) (bool, error) {
+	return checkCancelled(r.Selection.Context, r.Selection.Path)
+}
+
+type untimedContextKeyType string
+
+// untimedContextKey is the request context before Server.Timeout is applied
+var untimedContextKey = untimedContextKeyType("RESTCONF_UNTIMED")
+
+// withTimeout applies Server.Timeout to ctx but remembers ctx w/o it for
+// event streams, see untimed
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithValue(ctx, untimedContextKey, ctx), timeout)
+}
+
+// untimed is ctx w/o Server.Timeout, event streams last as long as client
+// wants them to once the request is known to be one. Values are still
+// those of ctx.
+func untimed(ctx context.Context) context.Context {
+	base, timed := ctx.Value(untimedContextKey).(context.Context)
+	if !timed {
+		return ctx
+	}
+	return untimedContext{Context: base, values: ctx}
+}
+
+type untimedContext struct {
+	context.Context
+	values context.Context
+}
+
+func (c untimedContext) Value(key interface{}) interface{} {
+	return c.values.Value(key)
+}
+
+func addCancelConstraint(sel *node.Selection) {
+	sel.Constraints = node.NewConstraints(sel.Constraints)
+	sel.Constraints.AddConstraint("cancel", 0, 0, cancelConstraint{})
+}
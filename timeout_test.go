@@ -0,0 +1,119 @@
+package restconf
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+	"github.com/freeconf/yang/val"
+)
+
+func TestTimeout(t *testing.T) {
+	mstr := `module x {
+		namespace "x";
+		prefix "x";
+		revision 0;
+		rpc slow {}
+		list l {
+			key "k";
+			config false;
+			leaf k {
+				type int32;
+			}
+		}
+	}`
+	m, err := parser.LoadModuleFromString(nil, mstr)
+	fc.RequireEqual(t, nil, err)
+	cancelled := make(chan error, 1)
+	rows := 0
+	var n node.Node
+	n = &nodeutil.Basic{
+		OnAction: func(r node.ActionRequest) (node.Node, error) {
+			select {
+			case <-r.Selection.Context.Done():
+				cancelled <- r.Selection.Context.Err()
+				return nil, r.Selection.Context.Err()
+			case <-time.After(10 * time.Second):
+				cancelled <- nil
+				return nil, nil
+			}
+		},
+		OnChild: func(r node.ChildRequest) (node.Node, error) {
+			return n, nil
+		},
+		OnNext: func(r node.ListRequest) (node.Node, []val.Value, error) {
+			// endless and slow list that does not check context itself
+			rows++
+			time.Sleep(time.Millisecond)
+			return &nodeutil.Basic{
+				OnField: func(fr node.FieldRequest, hnd *node.ValueHandle) error {
+					hnd.Val = val.Int32(r.Row)
+					return nil
+				},
+			}, []val.Value{val.Int32(r.Row)}, nil
+		},
+	}
+	s, ts := newTestServerWithNode(t, m, n)
+	defer ts.Close()
+	s.Timeout = 20 * time.Millisecond
+
+	resp, actual := testRequest(t, "POST", ts.URL+"/restconf/operations/x:slow", "",
+		"Accept", string(YangDataJsonMimeType1))
+	fc.AssertEqual(t, 503, resp.StatusCode)
+	fc.AssertEqual(t, "5", resp.Header.Get("Retry-After"))
+	fc.AssertEqual(t, context.DeadlineExceeded, <-cancelled)
+	fc.AssertEqual(t, true, strings.Contains(actual, "operation-failed"), actual)
+
+	// traversal stops even though node never checks
+	_, actual = testRequest(t, "GET", ts.URL+"/restconf/data/x:l", "")
+	fc.AssertEqual(t, true, strings.Contains(actual, "deadline exceeded"), actual)
+	fc.AssertEqual(t, true, rows < 1000)
+}
+
+func TestTimeoutEventStreams(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, streamYang)
+	fc.RequireEqual(t, nil, err)
+	tn := newStreamTestNode()
+	s, ts := newTestServerWithNode(t, m, tn.node())
+	defer ts.Close()
+	s.Timeout = 20 * time.Millisecond
+
+	// streams are told apart from other requests by what they are, not by
+	// what client accepts
+	for _, accept := range []string{"*/*", ""} {
+		for _, url := range []string{"/restconf/streams/NETCONF", "/restconf/data/x:y"} {
+			req, err := http.NewRequest("GET", ts.URL+url, nil)
+			fc.RequireEqual(t, nil, err)
+			if accept != "" {
+				req.Header.Set("Accept", accept)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			fc.RequireEqual(t, nil, err)
+			fc.AssertEqual(t, 200, resp.StatusCode, url)
+			// NETCONF stream subscribes to both notifications
+			subscriptions := 1
+			if url == "/restconf/streams/NETCONF" {
+				subscriptions = 2
+			}
+			for i := 0; i < subscriptions; i++ {
+				fc.AssertEqual(t, true, <-tn.subscribed)
+			}
+			time.Sleep(3 * s.Timeout)
+			tn.send("y", "late", time.Now())
+			line, err := bufio.NewReader(resp.Body).ReadString('\n')
+			fc.AssertEqual(t, nil, err, url, accept)
+			fc.AssertEqual(t, true, strings.Contains(line, `"z":"late"`), url, accept, line)
+			resp.Body.Close()
+			for i := 0; i < subscriptions; i++ {
+				fc.AssertEqual(t, false, <-tn.subscribed)
+			}
+		}
+	}
+}
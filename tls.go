@@ -0,0 +1,50 @@
+package restconf
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+
+	"github.com/freeconf/restconf/stock"
+)
+
+// MutualTLS is a copy of config that requires clients to send a certificate
+// signed by one of clientCAs. Set Server.ClientCertAuth to use certificate as
+// the principal. RFC8040 Sec. 2.5
+func MutualTLS(config *tls.Config, clientCAs *x509.CertPool) *tls.Config {
+	c := config.Clone()
+	c.ClientCAs = clientCAs
+	c.ClientAuth = tls.RequireAndVerifyClientCert
+	return c
+}
+
+// ListenAndServeTLS serves RESTCONF over HTTPS until Close is called. Server
+// certificates come from config.
+func (srv *Server) ListenAndServeTLS(addr string, config *tls.Config) error {
+	web := &http.Server{
+		Addr:      addr,
+		Handler:   srv,
+		TLSConfig: config,
+	}
+	srv.Web = &stock.HttpServer{Server: web}
+	if err := web.ListenAndServeTLS("", ""); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// certPrincipal identifies client by the common name of certificate or the
+// first subject alternative name when there is no common name
+func certPrincipal(cert *x509.Certificate) string {
+	switch {
+	case cert.Subject.CommonName != "":
+		return cert.Subject.CommonName
+	case len(cert.DNSNames) > 0:
+		return cert.DNSNames[0]
+	case len(cert.EmailAddresses) > 0:
+		return cert.EmailAddresses[0]
+	case len(cert.URIs) > 0:
+		return cert.URIs[0].String()
+	}
+	return ""
+}
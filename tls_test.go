@@ -0,0 +1,103 @@
+package restconf
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/freeconf/restconf/device"
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+	"github.com/freeconf/yang/source"
+	"github.com/freeconf/yang/val"
+)
+
+// testCert is signed by parent or self-signed when parent is nil
+func testCert(t *testing.T, name string, parent *tls.Certificate) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	fc.RequireEqual(t, nil, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  parent == nil,
+	}
+	signer, signerKey := template, any(key)
+	if parent != nil {
+		signer, signerKey = parent.Leaf, parent.PrivateKey
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	fc.RequireEqual(t, nil, err)
+	leaf, err := x509.ParseCertificate(raw)
+	fc.RequireEqual(t, nil, err)
+	return tls.Certificate{Certificate: [][]byte{raw}, PrivateKey: key, Leaf: leaf}
+}
+
+func x509CertPool(certs ...tls.Certificate) *x509.CertPool {
+	pool := x509.NewCertPool()
+	for _, c := range certs {
+		pool.AddCert(c.Leaf)
+	}
+	return pool
+}
+
+func TestClientCertAuth(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module x {namespace ""; prefix ""; revision 0;
+		leaf whoami {
+			type string;
+		}
+	}`)
+	fc.RequireEqual(t, nil, err)
+	d := device.New(source.Dir("./yang"))
+	d.AddBrowser(node.NewBrowser(m, &nodeutil.Basic{
+		OnField: func(r node.FieldRequest, hnd *node.ValueHandle) error {
+			principal, _ := PrincipalOf(r.Selection.Context).(string)
+			hnd.Val = val.String(principal)
+			return nil
+		},
+	}))
+	s := NewHttpServe(d)
+	s.ClientCertAuth = true
+
+	ca := testCert(t, "ca", nil)
+	pool := x509CertPool(ca)
+	ts := httptest.NewUnstartedServer(s)
+	ts.TLS = MutualTLS(&tls.Config{}, pool)
+	ts.StartTLS()
+	defer ts.Close()
+
+	c := ts.Client()
+	c.Transport.(*http.Transport).TLSClientConfig.Certificates = []tls.Certificate{testCert(t, "joe", &ca)}
+	req, err := http.NewRequest("GET", ts.URL+"/restconf/data/x:whoami", nil)
+	fc.RequireEqual(t, nil, err)
+	req.Header.Set("Accept", string(YangDataJsonMimeType1))
+	resp, err := c.Do(req)
+	fc.RequireEqual(t, nil, err)
+	defer resp.Body.Close()
+	actual, err := io.ReadAll(resp.Body)
+	fc.RequireEqual(t, nil, err)
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	fc.AssertEqual(t, `{"whoami":"joe"}`, string(actual))
+
+	// certificate from unknown authority fails handshake
+	c.Transport.(*http.Transport).TLSClientConfig.Certificates = []tls.Certificate{testCert(t, "eve", nil)}
+	c.CloseIdleConnections()
+	_, err = c.Get(ts.URL + "/restconf/data/x:whoami")
+	fc.AssertEqual(t, true, err != nil)
+}
@@ -0,0 +1,67 @@
+package restconf
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+)
+
+// transactions capability is listed in ietf-restconf-monitoring so clients
+// know edits to transactional nodes are all or nothing
+const transactionsCapability = "urn:freeconf:params:restconf:capability:transactions:1.0"
+
+// TransactionalNode is a root node, the one given to node.NewBrowser, whose
+// edits can be applied all or nothing. Each POST, PUT, PATCH or DELETE of
+// data begins a transaction before any edit is made and commits it once all
+// edits of the request succeed, otherwise it is aborted and data has to be
+// left as it was before the request. Edits to nodes that are not
+// transactional are applied one by one as they are read, so an edit that
+// fails part way through can leave earlier parts applied, yang-patch and
+// json-patch excepted as they undo what they applied.
+type TransactionalNode interface {
+	node.Node
+	BeginTransaction(ctx context.Context) (Transaction, error)
+}
+
+// Transaction of edits in a single request, see TransactionalNode
+type Transaction interface {
+	Commit() error
+	Abort() error
+}
+
+// beginTransaction on root node when it supports them, nil transaction
+// otherwise
+func beginTransaction(root *node.Selection) (Transaction, error) {
+	if n, valid := root.Node.(TransactionalNode); valid {
+		return n.BeginTransaction(root.Context)
+	}
+	return nil, nil
+}
+
+// endTransaction commits when edits succeed, aborts otherwise and returns
+// what went wrong
+func endTransaction(tx Transaction, editErr error) error {
+	if tx == nil {
+		return editErr
+	}
+	if editErr != nil {
+		abortTransaction(tx)
+		return editErr
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("could not commit. %w", err)
+	}
+	return nil
+}
+
+// abortTransaction that did not get to commit, nil transaction is ignored
+func abortTransaction(tx Transaction) {
+	if tx == nil {
+		return
+	}
+	if err := tx.Abort(); err != nil {
+		fc.Err.Printf("could not abort transaction. %s", err)
+	}
+}
@@ -0,0 +1,134 @@
+package restconf
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+)
+
+// mapTransactions keeps a copy of data when a transaction begins and puts it
+// back on abort
+type mapTransactions struct {
+	node.Node
+	data      map[string]interface{}
+	snapshot  map[string]interface{}
+	commits   int
+	aborts    int
+	commitErr error
+}
+
+func (n *mapTransactions) BeginTransaction(ctx context.Context) (Transaction, error) {
+	saved, err := json.Marshal(n.data)
+	if err != nil {
+		return nil, err
+	}
+	n.snapshot = nil
+	return n, json.Unmarshal(saved, &n.snapshot)
+}
+
+func (n *mapTransactions) Commit() error {
+	n.commits++
+	return n.commitErr
+}
+
+func (n *mapTransactions) Abort() error {
+	n.aborts++
+	for k := range n.data {
+		delete(n.data, k)
+	}
+	for k, v := range n.snapshot {
+		n.data[k] = v
+	}
+	return nil
+}
+
+func TestTransaction(t *testing.T) {
+	mstr := `module x {
+		namespace "x";
+		prefix "x";
+		revision 0;
+		container a {
+			leaf b {
+				type string;
+			}
+			leaf c {
+				type int32;
+			}
+		}
+	}`
+	m, err := parser.LoadModuleFromString(nil, mstr)
+	fc.RequireEqual(t, nil, err)
+	json := string(YangDataJsonMimeType)
+	// b is written before c fails
+	edit := `{"x:a":{"b":"B2","c":"bad"}}`
+
+	t.Run("none", func(t *testing.T) {
+		data := map[string]interface{}{"a": map[string]interface{}{"b": "B1", "c": 1}}
+		_, ts := newTestServerWithNode(t, m, nodeutil.ReflectChild(data))
+		defer ts.Close()
+		resp, _ := testRequest(t, "PATCH", ts.URL+"/restconf/data/x:a", edit, "Content-Type", json)
+		fc.AssertEqual(t, 500, resp.StatusCode)
+		_, actual := testRequest(t, "GET", ts.URL+"/restconf/data/x:a", "", "Accept", json)
+		fc.AssertEqual(t, `{"b":"B2","c":1}`, actual)
+	})
+
+	data := map[string]interface{}{"a": map[string]interface{}{"b": "B1", "c": 1}}
+	n := &mapTransactions{Node: nodeutil.ReflectChild(data), data: data}
+	_, ts := newTestServerWithNode(t, m, n)
+	defer ts.Close()
+	url := ts.URL + "/restconf/data/x:a"
+
+	for _, method := range []string{"PATCH", "PUT"} {
+		resp, _ := testRequest(t, method, url, edit, "Content-Type", json)
+		fc.AssertEqual(t, 500, resp.StatusCode, method)
+		_, actual := testRequest(t, "GET", url, "", "Accept", json)
+		fc.AssertEqual(t, `{"b":"B1","c":1}`, actual, method)
+	}
+	fc.AssertEqual(t, 0, n.commits)
+	fc.AssertEqual(t, 2, n.aborts)
+
+	resp, _ := testRequest(t, "PATCH", url, `{"x:a":{"b":"B2","c":2}}`, "Content-Type", json)
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	_, actual := testRequest(t, "GET", url, "", "Accept", json)
+	fc.AssertEqual(t, `{"b":"B2","c":2}`, actual)
+	fc.AssertEqual(t, 1, n.commits)
+
+	t.Run("yang-patch", func(t *testing.T) {
+		patch := `{"ietf-yang-patch:yang-patch":{"patch-id":"p","edit":[
+			{"edit-id":"1","operation":"merge","target":"/b","value":{"x:b":"B3"}},
+			{"edit-id":"2","operation":"create","target":"/b","value":{"x:b":"B4"}}]}}`
+		resp, _ := testRequest(t, "PATCH", url, patch, "Content-Type", string(YangPatchJsonMimeType))
+		fc.AssertEqual(t, 409, resp.StatusCode)
+		_, actual := testRequest(t, "GET", url, "", "Accept", json)
+		fc.AssertEqual(t, `{"b":"B2","c":2}`, actual)
+		fc.AssertEqual(t, 3, n.aborts)
+	})
+
+	t.Run("commit", func(t *testing.T) {
+		n.commitErr = errors.New("out of space")
+		defer func() { n.commitErr = nil }()
+		resp, actual := testRequest(t, "PATCH", url, `{"x:a":{"c":3}}`, "Content-Type", json)
+		fc.AssertEqual(t, 500, resp.StatusCode)
+		fc.AssertEqual(t, true, strings.Contains(actual, "out of space"), actual)
+
+		patch := `{"ietf-yang-patch:yang-patch":{"patch-id":"p","edit":[
+			{"edit-id":"1","operation":"merge","target":"/b","value":{"x:b":"B5"}}]}}`
+		resp, actual = testRequest(t, "PATCH", url, patch, "Content-Type", string(YangPatchJsonMimeType))
+		fc.AssertEqual(t, 500, resp.StatusCode)
+		fc.AssertEqual(t, `{"ietf-yang-patch:yang-patch-status":{"patch-id":"p",`+
+			`"global-errors":{"error":[{"error-type":"application","error-tag":"operation-failed","error-path":"/","error-message":"could not commit. out of space"}]},`+
+			`"edit-status":{"edit":[{"edit-id":"1","ok":[null]}]}}}`+"\n", actual)
+	})
+
+	t.Run("capability", func(t *testing.T) {
+		_, actual := testRequest(t, "GET", ts.URL+"/restconf/data/ietf-restconf-monitoring:restconf-state/capabilities", "", "Accept", json)
+		fc.AssertEqual(t, true, strings.Contains(actual, transactionsCapability), actual)
+	})
+}
@@ -0,0 +1,72 @@
+package restconf
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"unicode/utf8"
+
+	"github.com/freeconf/yang/fc"
+)
+
+var utf8Bom = []byte{0xEF, 0xBB, 0xBF}
+
+var errNotUtf8 = fmt.Errorf("%w. request content is not valid UTF-8", fc.BadRequestError)
+
+// checkUtf8 strips a leading byte order mark from JSON and XML request
+// content and fails reading it once it is not UTF-8 so decoders do not fail
+// with something cryptic. Other content, like files in forms, is left alone.
+func checkUtf8(r *http.Request) {
+	if r.Body == nil || r.Body == http.NoBody || isMultiPartForm(r.Header) {
+		return
+	}
+	contentType := mediaType(r.Header.Get("Content-Type"))
+	if contentType != "" && !contentType.IsJson() && !contentType.IsXml() {
+		return
+	}
+	buf := bufio.NewReader(r.Body)
+	if start, _ := buf.Peek(len(utf8Bom)); bytes.Equal(start, utf8Bom) {
+		buf.Discard(len(utf8Bom))
+		if r.ContentLength >= int64(len(utf8Bom)) {
+			r.ContentLength -= int64(len(utf8Bom))
+		}
+	}
+	r.Body = &utf8Body{Reader: buf, Closer: r.Body}
+}
+
+// utf8Body validates content as it is read. A character can be split over
+// reads so its start is kept until the rest of it is read.
+type utf8Body struct {
+	io.Reader
+	io.Closer
+	pending []byte
+}
+
+func (b *utf8Body) Read(p []byte) (int, error) {
+	n, err := b.Reader.Read(p)
+	s := p[:n]
+	for len(b.pending) > 0 && len(s) > 0 {
+		b.pending = append(b.pending, s[0])
+		s = s[1:]
+		if utf8.FullRune(b.pending) {
+			if !utf8.Valid(b.pending) {
+				return 0, errNotUtf8
+			}
+			b.pending = b.pending[:0]
+		}
+	}
+	start := len(s) - 1
+	for start > 0 && len(s)-start < utf8.UTFMax && !utf8.RuneStart(s[start]) {
+		start--
+	}
+	if start >= 0 && !utf8.FullRune(s[start:]) {
+		b.pending = append(b.pending, s[start:]...)
+		s = s[:start]
+	}
+	if !utf8.Valid(s) || (err == io.EOF && len(b.pending) > 0) {
+		return 0, errNotUtf8
+	}
+	return n, err
+}
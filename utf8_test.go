@@ -0,0 +1,59 @@
+package restconf
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/freeconf/yang/fc"
+)
+
+func TestUtf8Content(t *testing.T) {
+	_, ts := newTestServer(t, nestedYang, nestedData)
+	defer ts.Close()
+	json := string(YangDataJsonMimeType)
+	url := ts.URL + "/restconf/data/x:a/b"
+
+	resp, actual := testRequest(t, "PUT", url, "\xEF\xBB\xBF"+`{"x:b":"grüße"}`, "Content-Type", json)
+	fc.AssertEqual(t, 204, resp.StatusCode, actual)
+	_, actual = testRequest(t, "GET", url, "", "Accept", json)
+	fc.AssertEqual(t, `{"b":"grüße"}`, actual)
+
+	resp, actual = testRequest(t, "PUT", url, "{\"x:b\":\"gr\xFC\xDFe\"}", "Content-Type", json)
+	fc.AssertEqual(t, 400, resp.StatusCode)
+	fc.AssertEqual(t, true, strings.Contains(actual, `"error-tag":"invalid-value"`), actual)
+	fc.AssertEqual(t, true, strings.Contains(actual, "not valid UTF-8"), actual)
+	_, actual = testRequest(t, "GET", url, "", "Accept", json)
+	fc.AssertEqual(t, `{"b":"grüße"}`, actual)
+}
+
+func TestUtf8Body(t *testing.T) {
+	tests := []struct {
+		content string
+		read    string
+		valid   bool
+	}{
+		{content: "\xEF\xBB\xBFgrüße", read: "grüße", valid: true},
+		{content: "grüße\xEF\xBB\xBF", read: "grüße\xEF\xBB\xBF", valid: true},
+		{content: "€𝄞", read: "€𝄞", valid: true},
+		{content: "", read: "", valid: true},
+		{content: "gr\xFCe"},
+		{content: "gr\xE2\x82"},
+		{content: "\xC0\xAF"},
+	}
+	for _, test := range tests {
+		// one byte at a time splits every character over reads
+		r, err := http.NewRequest("PUT", "/", iotest.OneByteReader(strings.NewReader(test.content)))
+		fc.RequireEqual(t, nil, err)
+		checkUtf8(r)
+		actual, err := io.ReadAll(r.Body)
+		if !test.valid {
+			fc.AssertEqual(t, errNotUtf8, err, test.content)
+			continue
+		}
+		fc.AssertEqual(t, nil, err, test.content)
+		fc.AssertEqual(t, test.read, string(actual))
+	}
+}
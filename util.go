@@ -6,12 +6,15 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"strconv"
 
 	"strings"
 
 	"github.com/freeconf/yang/patch/xml"
 
 	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/val"
 )
 
 // SplitAddress takes a complete address and breaks it into pieces according
@@ -19,27 +22,15 @@ import (
 // Example:
 //
 //	http://server[:port]/restconf[=device]/module:path/here
+//
+// Path includes any query string, see ParseAddress to get the pieces, with
+// query separated from path, as a single structure.
 func SplitAddress(fullurl string) (address string, module string, path string, err error) {
-	eoSlashSlash := strings.Index(fullurl, "//") + 2
-	if eoSlashSlash < 2 {
-		err = ErrBadAddress
-		return
-	}
-	eoSlash := eoSlashSlash + strings.IndexRune(fullurl[eoSlashSlash:], '/') + 1
-	if eoSlash <= eoSlashSlash {
-		err = ErrBadAddress
-		return
-	}
-	colon := eoSlash + strings.IndexRune(fullurl[eoSlash:], ':')
-	if colon <= eoSlash {
-		err = ErrBadAddress
+	var a Address
+	if a, err = splitAddress(fullurl); err != nil {
 		return
 	}
-	moduleBegin := strings.LastIndex(fullurl[:colon], "/")
-	address = fullurl[:moduleBegin+1]
-	module = fullurl[moduleBegin+1 : colon]
-	path = fullurl[colon+1:]
-	return
+	return a.Base, a.Module, a.legacyPath(), nil
 }
 
 func SplitUri(uri string) (module string, path string, err error) {
@@ -76,21 +67,18 @@ func handleErr(compliance ComplianceOptions, err error, r *http.Request, w http.
 	}
 	fc.Debug.Printf("web request error [%s] %s %s", r.Method, r.URL, err.Error())
 	msg := err.Error()
-	code := fc.HttpStatusCode(err)
+	errs, code := errorResponse(err, DecodeErrorPath(r.RequestURI))
+	if len(errs) > 0 {
+		requestLogOf(r).failed(err, errs[0].Tag)
+	}
 	if !compliance.SimpleErrorResponse {
-		errResp := errResponse{
-			Type:    "protocol",
-			Tag:     decodeErrorTag(code, err),
-			Path:    decodeErrorPath(r.RequestURI),
-			Message: msg,
-		}
 		var buff bytes.Buffer
 		if mime.IsXml() {
 			emsg := struct {
-				XMLName xml.Name      `xml:"urn:ietf:params:xml:ns:yang:ietf-restconf errors"`
-				Errors  []errResponse `xml:"error"`
+				XMLName xml.Name `xml:"urn:ietf:params:xml:ns:yang:ietf-restconf errors"`
+				Errors  []Error  `xml:"error"`
 			}{
-				Errors: []errResponse{errResp},
+				Errors: errs,
 			}
 			if eerr := xml.NewEncoder(&buff).Encode(emsg); eerr != nil {
 				fc.Err.Printf("error encoding xml error response %s", eerr)
@@ -98,7 +86,7 @@ func handleErr(compliance ComplianceOptions, err error, r *http.Request, w http.
 		} else {
 			emsg := map[string]interface{}{
 				"ietf-restconf:errors": map[string]interface{}{
-					"error": []errResponse{errResp},
+					"error": errs,
 				},
 			}
 			if eerr := json.NewEncoder(&buff).Encode(emsg); eerr != nil {
@@ -109,48 +97,70 @@ func handleErr(compliance ComplianceOptions, err error, r *http.Request, w http.
 	}
 	w.Header().Set("Content-Type", string(mime))
 	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if code == http.StatusServiceUnavailable {
+		setRetryAfter(w, r)
+	}
 	w.WriteHeader(code)
 	fmt.Fprintln(w, msg)
 	return true
 }
 
-// https://datatracker.ietf.org/doc/html/rfc8040#section-7
-func decodeErrorTag(code int, _err error) string {
-	// This is bare minimum to return formatted error message response.
-	// but also all that can be done until more error types are defined
-	// beyond the few in github.com/freeconf/yang/fc/err.go or a more
-	// flexible error handling is implemented
-	switch code {
-	case 409:
-		return "in-use"
-	case 400:
-		return "invalid-value"
-	case 401:
-		return "access-denied"
-	}
-	return "operation-failed"
-}
-
-func decodeErrorPath(fullPath string) string {
-	module, path, err := SplitUri(fullPath)
-	if err != nil {
-		fc.Debug.Printf("unexpected path '%s', %s", fullPath, err)
-		return fullPath
+// DecodeErrorPath is the module:path of the resource a request path addresses
+// as used for error-path in error responses and for logging. Anything up to
+// the data, operations or streams segment is dropped whatever the root path
+// and device, as is the query string. So is the ds segment and datastore
+// after it. Path is percent-decoded.
+//
+//	/restconf/data/car:tire=a%2Fb?depth=1   => car:tire=a/b
+//	/api/v1=dev/operations/car:rotateTires => car:rotateTires
+//	/restconf/ds/ietf-datastores:running/car:engine => car:engine
+func DecodeErrorPath(requestPath string) string {
+	p := requestPath
+	if q := strings.IndexRune(p, '?'); q >= 0 {
+		p = p[:q]
 	}
-	return fmt.Sprint(module, ":", path)
-}
-
-type errResponse struct {
-	Type    string `json:"error-type" xml:"error-type"`
-	Tag     string `json:"error-tag"  xml:"error-tag"`
-	Path    string `json:"error-path"  xml:"error-path"`
-	Message string `json:"error-message"  xml:"error-message"`
+	segs := strings.Split(p, "/")
+	start := -1
+	for i := 1; i < len(segs) && start < 0; i++ {
+		switch segs[i-1] {
+		case "data", "operations", "streams":
+			if strings.ContainsRune(segs[i], ':') {
+				start = i
+			}
+		case "ds":
+			// datastore identity is not part of resource
+			if i+1 < len(segs) && strings.ContainsRune(segs[i+1], ':') {
+				start = i + 1
+			}
+		}
+	}
+	if start < 0 {
+		// not a known endpoint, take first segment naming a module
+		for i, seg := range segs {
+			if strings.ContainsRune(seg, ':') {
+				start = i
+				break
+			}
+		}
+	}
+	if start < 0 {
+		fc.Debug.Printf("unexpected path '%s', %s", requestPath, ErrBadAddress)
+		return requestPath
+	}
+	resource := strings.Join(segs[start:], "/")
+	if decoded, err := url.PathUnescape(resource); err == nil {
+		return decoded
+	}
+	return resource
 }
 
+// ipAddrSplitHostPort splits an IPv4 or IPv6 address w/optional port. IPv6
+// hosts keep their brackets and zone id (e.g. [fe80::1%eth0]) as given.
 func ipAddrSplitHostPort(addr string) (host string, port string) {
 	bracket := strings.IndexRune(addr, ']')
-	dblColon := strings.Index(addr, "::")
-	isIpv6 := (bracket >= 0 || dblColon >= 0)
+	// more than one colon is an IPv6 address w/o port, zone ids cannot have
+	// colons so this holds for zoned addresses too
+	isIpv6 := (bracket >= 0 || strings.Count(addr, ":") > 1)
 	if isIpv6 {
 		if bracket > 0 {
 			host = addr[:bracket+1]
@@ -172,30 +182,75 @@ func ipAddrSplitHostPort(addr string) (host string, port string) {
 	return
 }
 
+// appendUrlSegment joins a and b with exactly one "/" between them no matter
+// how many either side has at the join. An empty a or b is left as is.
 func appendUrlSegment(a string, b string) string {
 	if a == "" || b == "" {
 		return a + b
 	}
-	slashA := a[len(a)-1] == '/'
-	slashB := b[0] == '/'
-	if slashA != slashB {
-		return a + b
+	return strings.TrimRight(a, "/") + "/" + strings.TrimLeft(b, "/")
+}
+
+// trimTrailingSlash drops slashes at the end of path of request and of p, the
+// part of request path left to route, so a resource has the same URL with or
+// w/o them. RequestURI is trimmed too as links like Location are made from it.
+//
+//	/restconf/data/x:a/c/  =>  /restconf/data/x:a/c
+func trimTrailingSlash(r *http.Request, p *url.URL) *url.URL {
+	escaped := p.EscapedPath()
+	trimmed := strings.TrimRight(escaped, "/")
+	if trimmed == escaped {
+		return p
 	}
-	if slashA && slashB {
-		return a + b[1:]
+	reqPath, query, hasQuery := strings.Cut(r.RequestURI, "?")
+	r.RequestURI = strings.TrimRight(reqPath, "/")
+	if hasQuery {
+		r.RequestURI += "?" + query
 	}
-	return a + "/" + b
+	r.URL = withEscapedPath(r.URL, strings.TrimRight(r.URL.EscapedPath(), "/"))
+	return withEscapedPath(p, trimmed)
 }
 
+// shift splits off the first segment of the path. Path is split while still
+// escaped and segment is unescaped after so escaped delimiters like %2F in
+// list keys stay in the remaining path.
 func shift(orig *url.URL, delim rune) (string, *url.URL) {
 	if orig.Path == "" {
 		return "", orig
 	}
+	escaped := orig.EscapedPath()
+	segment, shifted := shiftInString(escaped, delim)
+	return unescapePath(segment), shiftedPath(orig, escaped, shifted)
+}
+
+// shiftedPath is like withEscapedPath for the rest of escaped path of orig
+// after a segment is shifted off. Unless segment was escaped, rest of Path is
+// already unescaped at the end of orig.Path and is not unescaped again.
+func shiftedPath(orig *url.URL, escaped string, rest string) *url.URL {
+	off := escaped[:len(escaped)-len(rest)]
+	if strings.IndexByte(off, '%') >= 0 || !strings.HasPrefix(orig.Path, off) {
+		return withEscapedPath(orig, rest)
+	}
 	copy := *orig
-	var segment string
-	segment, copy.Path = shiftInString(copy.Path, delim)
-	_, copy.RawPath = shiftInString(copy.RawPath, delim)
-	return segment, &copy
+	copy.RawPath = rest
+	copy.Path = orig.Path[len(off):]
+	return &copy
+}
+
+// withEscapedPath is copy of orig with path replaced. Both Path and RawPath
+// are set so EscapedPath() returns escaped exactly
+func withEscapedPath(orig *url.URL, escaped string) *url.URL {
+	copy := *orig
+	copy.RawPath = escaped
+	copy.Path = unescapePath(escaped)
+	return &copy
+}
+
+func unescapePath(escaped string) string {
+	if unescaped, err := url.PathUnescape(escaped); err == nil {
+		return unescaped
+	}
+	return escaped
 }
 
 func shiftInString(orig string, delim rune) (string, string) {
@@ -220,20 +275,230 @@ func shiftInString(orig string, delim rune) (string, string) {
 }
 
 func shiftOptionalParamWithinSegment(orig *url.URL, optionalDelim rune, segDelim rune) (string, string, *url.URL) {
-	copy := *orig
-	var segment, optional string
-	// trickery here - mutating a copy of the URL
-	segment, optional, copy.Path = shiftOptionalParamWithinSegmentInString(copy.Path, optionalDelim, segDelim)
+	// split while escaped so %2F and %3D are not mistaken for delimiters,
+	// segment and optional param are returned unescaped
+	escaped := orig.EscapedPath()
+	segment, optional, shifted := shiftOptionalParamWithinSegmentInString(escaped, optionalDelim, segDelim)
+	return unescapePath(segment), unescapePath(optional), shiftedPath(orig, escaped, shifted)
+}
+
+// shiftListKeys is like shiftOptionalParamWithinSegment but for list entries
+// with keys separated by commas. Keys are split before they are unescaped so
+// a key with an encoded comma stays whole.
+//
+//	interface=eth0,10/statistics  =>  "interface", ["eth0", "10"], "statistics"
+func shiftListKeys(orig *url.URL, segDelim rune) (string, []string, *url.URL, error) {
+	escaped := orig.EscapedPath()
+	segment, keys, shifted := shiftOptionalParamWithinSegmentInString(escaped, '=', segDelim)
+	var key []string
+	if keys != "" {
+		var err error
+		if key, err = splitListKeys(keys); err != nil {
+			return "", nil, orig, err
+		}
+	}
+	return unescapePath(segment), key, shiftedPath(orig, escaped, shifted), nil
+}
 
-	// NOTE: the segment and optional param are returned unescaped presumably because caller
-	// would want that.  If not, keep these results and not the ones from above
-	_, _, copy.RawPath = shiftOptionalParamWithinSegmentInString(copy.RawPath, optionalDelim, segDelim)
+// splitListKeys splits escaped keys of a list entry into unescaped keys
+//
+//	eth0,a%2Cb  =>  ["eth0", "a,b"]
+func splitListKeys(escaped string) ([]string, error) {
+	key := make([]string, 0, strings.Count(escaped, ",")+1)
+	rest := escaped
+	for {
+		k := rest
+		comma := strings.IndexByte(rest, ',')
+		if comma >= 0 {
+			k, rest = rest[:comma], rest[comma+1:]
+		}
+		unescaped, err := url.PathUnescape(k)
+		if err != nil {
+			return nil, fmt.Errorf("%w. key '%s'. %s", fc.BadRequestError, escaped, err)
+		}
+		key = append(key, unescaped)
+		if comma < 0 {
+			return key, nil
+		}
+	}
+}
+
+// joinListKeys is the inverse of splitListKeys
+//
+//	["eth0", "a,b"]  =>  eth0,a%2Cb
+func joinListKeys(key []string) string {
+	escaped := make([]string, len(key))
+	for i, k := range key {
+		escaped[i] = escapeListKey(k)
+	}
+	return strings.Join(escaped, ",")
+}
+
+// escapeListKey percent-encodes every byte of k that is not unreserved so
+// key survives splitting on "/", "=" and "," and unescaping by either
+// url.PathUnescape or url.QueryUnescape. RFC8040 Sec. 3.5.3
+//
+//	a/b c+ü  =>  a%2Fb%20c%2B%C3%BC
+func escapeListKey(k string) string {
+	var escaped strings.Builder
+	for i := 0; i < len(k); i++ {
+		c := k[i]
+		if isUnreserved(c) {
+			escaped.WriteByte(c)
+		} else {
+			fmt.Fprintf(&escaped, "%%%02X", c)
+		}
+	}
+	return escaped.String()
+}
+
+// isUnreserved is true for characters that never need escaping in a URL.
+// RFC3986 Sec. 2.3
+func isUnreserved(c byte) bool {
+	switch {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		return true
+	}
+	return c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+// buildResourceURL appends the segment of a resource to base, qualified with
+// module when given and with list keys when given. Keys are escaped so the
+// URL can be shifted apart again by shiftListKeys.
+//
+//	buildResourceURL("/restconf/data", "x", "e", "a/b", "1")  =>  /restconf/data/x:e=a%2Fb,1
+func buildResourceURL(base string, module string, path string, keys ...string) string {
+	segment := path
+	if module != "" {
+		segment = module + ":" + segment
+	}
+	if len(keys) > 0 {
+		segment += "=" + joinListKeys(keys)
+	}
+	return appendUrlSegment(base, segment)
+}
 
-	return segment, optional, &copy
+// checkListKeys checks keys of list entries, and values of leaf-list entries,
+// in escaped path p parse as the types of their key leaves. Otherwise a bad key fails however freeconf
+// happens to parse it. Error path is the entry with the bad key.
+//
+//	x:interface=eth0,abc/statistics  =>  x:interface=eth0,abc
+func checkListKeys(m *meta.Module, p string) error {
+	var parent meta.HasDataDefinitions = m
+	var walked []string
+	for p != "" {
+		seg, keys, rest := shiftOptionalParamWithinSegmentInString(p, '=', '/')
+		ident := unescapePath(seg)
+		if colon := strings.IndexRune(ident, ':'); colon >= 0 {
+			ident = ident[colon+1:]
+		}
+		def := meta.Find(parent, ident)
+		if def == nil {
+			// unknown segments are left to Find to report
+			return nil
+		}
+		if keys == "" {
+			walked = append(walked, seg)
+		} else {
+			walked = append(walked, seg+"="+keys)
+			if ll, isLeafList := def.(*meta.LeafList); isLeafList {
+				if rest != "" {
+					return fmt.Errorf("%w. leaf-list entry %s has no children", fc.BadRequestError, unescapePath(strings.Join(walked, "/")))
+				}
+				value := unescapePath(keys)
+				if err := checkKeyValue(ll.Type(), value); err != nil {
+					e := NewError("invalid-value", fmt.Sprintf("'%s' is not a valid entry of %s. %s", value, ll.Ident(), err))
+					e.Path = errorPathOf(m, walked)
+					return e
+				}
+			} else if list, isList := def.(*meta.List); isList {
+				key, err := splitListKeys(keys)
+				if err != nil {
+					return err
+				}
+				keyMeta := list.KeyMeta()
+				for i := 0; i < len(key) && i < len(keyMeta); i++ {
+					if err := checkKeyValue(keyMeta[i].Type(), key[i]); err != nil {
+						e := NewError("invalid-value", fmt.Sprintf("key '%s' of %s is not a valid %s. %s", key[i], keyMeta[i].Ident(), keyMeta[i].Type().Ident(), err))
+						e.Path = errorPathOf(m, walked)
+						return e
+					}
+				}
+			}
+		}
+		next, valid := def.(meta.HasDataDefinitions)
+		if !valid {
+			return nil
+		}
+		parent, p = next, rest
+	}
+	return nil
+}
+
+// errorPathOf is the module:path of the walked segments of an escaped path
+func errorPathOf(m *meta.Module, walked []string) string {
+	p := unescapePath(strings.Join(walked, "/"))
+	if !strings.HasPrefix(p, m.Ident()+":") {
+		p = m.Ident() + ":" + p
+	}
+	return p
+}
+
+// leafListEntry splits an escaped path to a leaf-list entry into the path to
+// the leaf-list and the unescaped value of the entry as freeconf only finds
+// whole leaf-lists
+//
+//	x:c/tags=a%20b  =>  x:c/tags, "a b"
+func leafListEntry(m *meta.Module, p string) (string, string, bool) {
+	last := strings.LastIndexByte(p, '/') + 1
+	eq := strings.IndexByte(p[last:], '=')
+	if eq < 0 {
+		return "", "", false
+	}
+	eq += last
+	segs := strings.Split(p[:eq], "/")
+	for i, seg := range segs {
+		if keys := strings.IndexByte(seg, '='); keys >= 0 {
+			seg = seg[:keys]
+		}
+		seg = unescapePath(seg)
+		if colon := strings.IndexRune(seg, ':'); colon >= 0 {
+			seg = seg[colon+1:]
+		}
+		segs[i] = seg
+	}
+	if _, isLeafList := meta.Find(m, strings.Join(segs, "/")).(*meta.LeafList); !isLeafList {
+		return "", "", false
+	}
+	return p[:eq], unescapePath(p[eq+1:]), true
+}
+
+// checkKeyValue checks key parses as typ including fitting in the bits of
+// integer types, freeconf does not check that
+func checkKeyValue(typ *meta.Type, key string) error {
+	if _, err := leafValue(typ, key, false); err != nil {
+		return err
+	}
+	var err error
+	switch typ.Format() {
+	case val.FmtInt8:
+		_, err = strconv.ParseInt(key, 10, 8)
+	case val.FmtInt16:
+		_, err = strconv.ParseInt(key, 10, 16)
+	case val.FmtInt32:
+		_, err = strconv.ParseInt(key, 10, 32)
+	case val.FmtUInt8:
+		_, err = strconv.ParseUint(key, 10, 8)
+	case val.FmtUInt16:
+		_, err = strconv.ParseUint(key, 10, 16)
+	case val.FmtUInt32:
+		_, err = strconv.ParseUint(key, 10, 32)
+	}
+	return err
 }
 
-// this will not work of unescaped paths that contain optionalDelim or segDelim in the part of the
-// url it's trying to shift.
+// orig is expected to be escaped otherwise escaped delimiters in the part of
+// the url it's trying to shift would be mistaken for actual delimiters.
 func shiftOptionalParamWithinSegmentInString(orig string, optionalDelim rune, segDelim rune) (string, string, string) {
 	termPos := strings.IndexRune(orig, segDelim)
 
@@ -3,10 +3,13 @@ package restconf
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"testing"
 
 	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"strings"
 
 	"github.com/freeconf/yang/fc"
 )
@@ -94,6 +97,24 @@ func Test_AppendUrlSegment(t *testing.T) {
 		{
 			"", "/b", "/b",
 		},
+		{
+			"a//", "//b", "a/b",
+		},
+		{
+			"a///", "b", "a/b",
+		},
+		{
+			"a", "//b", "a/b",
+		},
+		{
+			"a//", "", "a//",
+		},
+		{
+			"", "//b", "//b",
+		},
+		{
+			"/", "/b", "/b",
+		},
 	}
 	for _, test := range tests {
 		actual := appendUrlSegment(test[0], test[1])
@@ -108,6 +129,11 @@ func Test_ipAddrSplitHostPort(t *testing.T) {
 		{"[::1]:1000", "[::1]", "1000"},
 		{"::1", "::1", ""},
 		{"[0:0:0:0:0:0:0]:1000", "[0:0:0:0:0:0:0]", "1000"},
+		{"[fe80::1%eth0]:8080", "[fe80::1%eth0]", "8080"},
+		{"[fe80::1%25eth0]:8080", "[fe80::1%25eth0]", "8080"},
+		{"[fe80::1%eth0]", "[fe80::1%eth0]", ""},
+		{"fe80::1%eth0", "fe80::1%eth0", ""},
+		{"fe80:0:0:0:0:0:0:1%eth0", "fe80:0:0:0:0:0:0:1%eth0", ""},
 	}
 	for _, test := range tests {
 		host, port := ipAddrSplitHostPort(test[0])
@@ -117,8 +143,23 @@ func Test_ipAddrSplitHostPort(t *testing.T) {
 }
 
 func TestDecodeErrorPath(t *testing.T) {
-	fc.AssertEqual(t, "foo:some/path", decodeErrorPath("/restconf/data/foo:some/path"))
-	fc.AssertEqual(t, "bartend:", decodeErrorPath("/restconf/data/bartend:"))
+	tests := [][]string{
+		{"/restconf/data/foo:some/path", "foo:some/path"},
+		{"/restconf/data/bartend:", "bartend:"},
+		{"/restconf=dev/data/foo:some/path", "foo:some/path"},
+		{"/my-api/v1/data/foo:some/path", "foo:some/path"},
+		{"/api:v2/data/foo:some", "foo:some"},
+		{"/my-api/operations/foo:reset", "foo:reset"},
+		{"/restconf/data/foo:some/list=a%2Fb,c%20d/x", "foo:some/list=a/b,c d/x"},
+		{"/restconf/data/foo:some/path?depth=1&with-defaults=report-all", "foo:some/path"},
+		{"/restconf/ds/ietf-datastores:running/foo:some/path", "foo:some/path"},
+		{"/restconf=dev/ds/ietf-datastores:operational/foo:some?with-origin", "foo:some"},
+		{"foo:some/path", "foo:some/path"},
+		{"/restconf/data", "/restconf/data"},
+	}
+	for _, test := range tests {
+		fc.AssertEqual(t, test[1], DecodeErrorPath(test[0]), test[0])
+	}
 }
 
 func Test_shift(t *testing.T) {
@@ -149,6 +190,24 @@ func Test_shift(t *testing.T) {
 			expectedSegment: "some",
 			expectedPath:    "path/here",
 		},
+		{
+			in:              "http://server:999/data/x:list=a%2Fb/here",
+			expectedSegment: "data",
+			expectedPath:    "x:list=a/b/here",
+			expectedRaw:     "x:list=a%2Fb/here",
+		},
+		{
+			in:              "http://server:999/data%2Fdata/list=a%3Db",
+			expectedSegment: "data/data",
+			expectedPath:    "list=a=b",
+			expectedRaw:     "list=a%3Db",
+		},
+		{
+			in:              "http://server:999/data/list=a%3Ab",
+			expectedSegment: "data",
+			expectedPath:    "list=a:b",
+			expectedRaw:     "list=a%3Ab",
+		},
 		{
 			in:              "some",
 			expectedSegment: "some",
@@ -175,12 +234,63 @@ func Test_shift(t *testing.T) {
 	}
 }
 
+func BenchmarkShift(b *testing.B) {
+	for name, in := range map[string]string{
+		"escaped": "http://server:999/restconf/data/car:garage/car=beetle/tire=front%2Fleft,15/wear",
+		"plain":   "http://server:999/restconf/data/car:garage/car=beetle/tire",
+	} {
+		orig, err := url.Parse(in)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				p := orig
+				for p.Path != "" {
+					_, p = shift(p, '/')
+				}
+			}
+		})
+	}
+	b.Run("listKeys", func(b *testing.B) {
+		orig, _ := url.Parse("car=beetle/tire=front%2Fleft,15/wear")
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			p := orig
+			for p.Path != "" {
+				var err error
+				if _, _, p, err = shiftListKeys(p, '/'); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+	b.Run("device", func(b *testing.B) {
+		orig, _ := url.Parse("restconf=car/data/car:garage")
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			shiftOptionalParamWithinSegment(orig, '=', '/')
+		}
+	})
+}
+
+func BenchmarkSplitAddress(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, _, _, err := SplitAddress("http://server:999/restconf/data/car:garage/car=beetle?depth=1"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
 func Test_shiftOptionalParamWithinSegment(t *testing.T) {
 	tests := []struct {
 		in    string
 		seg   string
 		param string
 		path  string
+		raw   string
 	}{
 		{
 			in:   "http://server:999/some/path/here",
@@ -200,16 +310,26 @@ func Test_shiftOptionalParamWithinSegment(t *testing.T) {
 			param: "x",
 			seg:   "some",
 		},
-		// NOTE: you cannot use following url encoded values as they match delims and
-		// unescaped path parsing will not work.  details in code implementation
-		//   %2f   /
-		//   %3d   =
 		{
 			in:    "some=x%3ax/path",
 			param: "x:x",
 			seg:   "some",
 			path:  "path",
 		},
+		{
+			in:    "some=x%2fx/path=a%2Fb",
+			param: "x/x",
+			seg:   "some",
+			path:  "path=a/b",
+			raw:   "path=a%2Fb",
+		},
+		{
+			in:    "some=x%3dx/path=a%3Db",
+			param: "x=x",
+			seg:   "some",
+			path:  "path=a=b",
+			raw:   "path=a%3Db",
+		},
 		{
 			in:   "data/call-home-register:",
 			seg:  "data",
@@ -230,12 +350,15 @@ func Test_shiftOptionalParamWithinSegment(t *testing.T) {
 		fc.AssertEqual(t, test.seg, seg)
 		fc.AssertEqual(t, test.param, param)
 		fc.AssertEqual(t, test.path, path.Path)
+		if test.raw != "" {
+			fc.AssertEqual(t, test.raw, path.EscapedPath())
+		}
 	}
 }
 
 func TestHandleErr(t *testing.T) {
 	werr := errors.New("some error")
-	r := http.Request{}
+	r := http.Request{RequestURI: "/restconf/data/car:engine"}
 	w := dummyResponseWriter{}
 	handleErr(Strict, werr, &r, &w, YangDataXmlMimeType1)
 	fc.Gold(t, *updateFlag, w.buf.Bytes(), "testdata/gold/error.xml")
@@ -243,6 +366,88 @@ func TestHandleErr(t *testing.T) {
 	w.buf.Reset()
 	handleErr(Strict, werr, &r, &w, YangDataJsonMimeType1)
 	fc.Gold(t, *updateFlag, w.buf.Bytes(), "testdata/gold/error.json")
+
+	multi := Errors{
+		{Tag: "data-missing", AppTag: "car:no-engine", Message: "no engine"},
+		{Type: "application", Tag: "invalid-value", Path: "car:tire=1/size", Message: "bad size"},
+	}
+	w.buf.Reset()
+	handleErr(Strict, multi, &r, &w, YangDataXmlMimeType1)
+	fc.Gold(t, *updateFlag, w.buf.Bytes(), "testdata/gold/error-multi.xml")
+
+	w.buf.Reset()
+	handleErr(Strict, fmt.Errorf("wrapped. %w", multi), &r, &w, YangDataJsonMimeType1)
+	fc.Gold(t, *updateFlag, w.buf.Bytes(), "testdata/gold/error-multi.json")
+
+	info := NewError("invalid-value", "speed too high")
+	info.AppTag = "car:speed-limit"
+	info.Info = map[string]interface{}{
+		"limit": 100,
+		"speed": 120,
+		"tires": []interface{}{"front", "back"},
+		"state": map[string]interface{}{"running": true},
+	}
+	w.buf.Reset()
+	handleErr(Strict, info, &r, &w, YangDataXmlMimeType1)
+	fc.Gold(t, *updateFlag, w.buf.Bytes(), "testdata/gold/error-info.xml")
+
+	w.buf.Reset()
+	handleErr(Strict, fmt.Errorf("wrapped. %w", info), &r, &w, YangDataJsonMimeType1)
+	fc.Gold(t, *updateFlag, w.buf.Bytes(), "testdata/gold/error-info.json")
+}
+
+// tagError signals its own error-tag
+type tagError string
+
+func (e tagError) Error() string {
+	return "tagged"
+}
+
+func (e tagError) ErrorTag() string {
+	return string(e)
+}
+
+func TestHandleErrStatus(t *testing.T) {
+	tests := []struct {
+		err    error
+		tag    string
+		status int
+	}{
+		{err: errors.New("oops"), tag: "operation-failed", status: http.StatusInternalServerError},
+		{err: fc.NotFoundError, tag: "invalid-value", status: http.StatusNotFound},
+		{err: fc.BadRequestError, tag: "invalid-value", status: http.StatusBadRequest},
+		{err: fc.ConflictError, tag: "in-use", status: http.StatusConflict},
+		{err: fc.UnauthorizedError, tag: "access-denied", status: http.StatusForbidden},
+		{err: fc.NotImplementedError, tag: "operation-not-supported", status: http.StatusNotImplemented},
+		{err: fmt.Errorf("%w. x", ErrOperationNotSupported), tag: "operation-not-supported", status: http.StatusMethodNotAllowed},
+		{err: fmt.Errorf("%w. %w. x", fc.BadRequestError, ErrMissingElement), tag: "missing-element", status: http.StatusBadRequest},
+		{err: fmt.Errorf("%w. x", ErrUnknownElement), tag: "unknown-element", status: http.StatusBadRequest},
+		{err: fmt.Errorf("%w. %w. x", fc.ConflictError, ErrDataExists), tag: "data-exists", status: http.StatusConflict},
+		{err: fmt.Errorf("%w. %w. x", fc.ConflictError, ErrDataMissing), tag: "data-missing", status: http.StatusConflict},
+		{err: fmt.Errorf("x. %w", tagError("lock-denied")), tag: "lock-denied", status: http.StatusConflict},
+		{err: tagError("too-big"), tag: "too-big", status: http.StatusRequestEntityTooLarge},
+		{err: Error{Tag: "data-exists"}, tag: "data-exists", status: http.StatusConflict},
+		{err: Error{}, tag: "operation-failed", status: http.StatusInternalServerError},
+		{err: Errors{{Tag: "too-big"}, {Tag: "in-use"}}, tag: "too-big", status: http.StatusRequestEntityTooLarge},
+		{err: fmt.Errorf("x. %w", Error{Tag: "invalid-value"}), tag: "invalid-value", status: http.StatusBadRequest},
+		{err: fmt.Errorf("%w. backend busy", ErrUnavailable), tag: "operation-failed", status: http.StatusServiceUnavailable},
+	}
+	for _, test := range tests {
+		for _, mime := range []MimeType{YangDataJsonMimeType1, YangDataXmlMimeType1} {
+			w := httptest.NewRecorder()
+			handleErr(Strict, test.err, httptest.NewRequest("GET", "/restconf/data/x:y", nil), w, mime)
+			msg := fmt.Sprintf("%s %s", test.err, mime)
+			fc.AssertEqual(t, test.status, w.Code, msg)
+			body := w.Body.String()
+			if mime.IsXml() {
+				fc.AssertEqual(t, true, strings.Contains(body, "<error-tag>"+test.tag+"</error-tag>"), body)
+				fc.AssertEqual(t, true, strings.Contains(body, "<error-path>x:y</error-path>"), body)
+			} else {
+				fc.AssertEqual(t, true, strings.Contains(body, `"error-tag":"`+test.tag+`"`), body)
+				fc.AssertEqual(t, true, strings.Contains(body, `"error-path":"x:y"`), body)
+			}
+		}
+	}
 }
 
 type dummyResponseWriter struct {
@@ -261,3 +466,81 @@ func (dummyResponseWriter) WriteHeader(statusCode int) {
 func (d dummyResponseWriter) Header() http.Header {
 	return http.Header{}
 }
+
+func Test_shiftListKeys(t *testing.T) {
+	tests := []struct {
+		in   string
+		seg  string
+		key  []string
+		path string
+	}{
+		{
+			in:   "interface/statistics",
+			seg:  "interface",
+			path: "statistics",
+		},
+		{
+			in:   "interface=eth0/statistics",
+			seg:  "interface",
+			key:  []string{"eth0"},
+			path: "statistics",
+		},
+		{
+			in:   "interface=eth0,10/statistics",
+			seg:  "interface",
+			key:  []string{"eth0", "10"},
+			path: "statistics",
+		},
+		{
+			in:  "route=10.0.0.0,8,eth0",
+			seg: "route",
+			key: []string{"10.0.0.0", "8", "eth0"},
+		},
+		{
+			in:   "interface=a%2Cb,c%2Fd/statistics=x%2Cy",
+			seg:  "interface",
+			key:  []string{"a,b", "c/d"},
+			path: "statistics=x,y",
+		},
+	}
+	for _, test := range tests {
+		orig, err := url.Parse(test.in)
+		fc.RequireEqual(t, nil, err)
+		seg, key, path, err := shiftListKeys(orig, '/')
+		fc.RequireEqual(t, nil, err, test.in)
+		fc.AssertEqual(t, test.seg, seg, test.in)
+		fc.AssertEqual(t, strings.Join(test.key, "|"), strings.Join(key, "|"), test.in)
+		fc.AssertEqual(t, len(test.key), len(key), test.in)
+		fc.AssertEqual(t, test.path, path.Path, test.in)
+	}
+
+	_, err := splitListKeys("a,%zz")
+	fc.AssertEqual(t, true, errors.Is(err, fc.BadRequestError))
+}
+
+func Test_buildResourceURL(t *testing.T) {
+	tests := []struct {
+		module string
+		path   string
+		keys   []string
+		url    string
+	}{
+		{path: "a", url: "/restconf/data/a"},
+		{module: "x", path: "a", url: "/restconf/data/x:a"},
+		{path: "e", keys: []string{"eth0", "10"}, url: "/restconf/data/e=eth0,10"},
+		{path: "e", keys: []string{"a/b", "c=d", "e,f", "g h"}, url: "/restconf/data/e=a%2Fb,c%3Dd,e%2Cf,g%20h"},
+		{path: "e", keys: []string{"ü+~"}, url: "/restconf/data/e=%C3%BC%2B~"},
+	}
+	for _, test := range tests {
+		actual := buildResourceURL("/restconf/data", test.module, test.path, test.keys...)
+		fc.AssertEqual(t, test.url, actual)
+		if len(test.keys) == 0 {
+			continue
+		}
+		orig, err := url.Parse(strings.TrimPrefix(actual, "/restconf/data/"))
+		fc.RequireEqual(t, nil, err)
+		_, key, _, err := shiftListKeys(orig, '/')
+		fc.RequireEqual(t, nil, err)
+		fc.AssertEqual(t, strings.Join(test.keys, "|"), strings.Join(key, "|"), actual)
+	}
+}
@@ -42,8 +42,7 @@ func (jsonWireFormat) writeRpcOutputEnd(w io.Writer) (int, error) {
 type xmlWireFormat int
 
 func (xmlWireFormat) writeNotificationStart(w io.Writer, module *meta.Module, etime string) (int, error) {
-	return fmt.Fprintf(w, `<notification xmlns="urn:ietf:params:xml:ns:netconf:notif\
-	ication:1.0"><eventTime>%s</eventTime><event xmlns="%s">`, etime, module.Namespace())
+	return fmt.Fprintf(w, `<notification xmlns="urn:ietf:params:xml:ns:netconf:notification:1.0"><eventTime>%s</eventTime><event xmlns="%s">`, etime, module.Namespace())
 }
 
 func (xmlWireFormat) writeNotificationEnd(w io.Writer) (int, error) {
@@ -0,0 +1,449 @@
+package restconf
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/patch/xml"
+	"github.com/freeconf/yang/val"
+)
+
+// RFC6243 with-defaults basic modes and query parameter values. freeconf
+// supports report-all and trim directly, the rest are implemented here.
+const (
+	withDefaultsParam     = "with-defaults"
+	WithDefaultsReportAll = "report-all"
+	WithDefaultsTrim      = "trim"
+	WithDefaultsExplicit  = "explicit"
+	WithDefaultsTagged    = "report-all-tagged"
+)
+
+const (
+	withDefaultsNs         = "urn:ietf:params:xml:ns:netconf:default:1.0"
+	withDefaultsModule     = "ietf-netconf-with-defaults"
+	withDefaultsCapability = "urn:ietf:params:restconf:capability:defaults:1.0?basic-mode="
+)
+
+// checkWithDefaults validates with-defaults parameter. freeconf handles trim and
+// report-all, the explicit constraint is added in buildConstraints and tagging for
+// report-all-tagged happens when output is written, see writeTaggedDefaults.
+func checkWithDefaults(mode string) error {
+	switch mode {
+	case WithDefaultsReportAll, WithDefaultsTrim, WithDefaultsExplicit, WithDefaultsTagged:
+		return nil
+	}
+	return fmt.Errorf("%w. invalid 'with-defaults' parameter: %s", fc.BadRequestError, mode)
+}
+
+// withDefaultsExplicit only reports values the node has, never the schema
+// default values
+type withDefaultsExplicit struct{}
+
+func (withDefaultsExplicit) CheckFieldPreConstraints(r *node.FieldRequest, hnd *node.ValueHandle) (bool, error) {
+	if r.IsNavigation() || r.Write {
+		return true, nil
+	}
+	// reading value here w/o falling back to default value skips fields
+	// that would only be reported because of the default value.
+	if err := r.Selection.Node.Field(*r, hnd); err != nil {
+		return false, err
+	}
+	return hnd.Val != nil, nil
+}
+
+// leafTagger records, in traversal order, whether each reported leaf is
+// using its default value and where it came from so output can be annotated
+// after it is written
+type leafTagger struct {
+	defaults bool
+	origins  bool
+	reported []reportedLeaf
+}
+
+type reportedLeaf struct {
+	isDefault bool
+
+	// empty when not known or not asked for
+	origin string
+
+	// ident of anydata or anyxml whose content is written as is
+	anydata string
+}
+
+func (l reportedLeaf) tagged() bool {
+	return l.isDefault || l.origin != ""
+}
+
+func (t *leafTagger) CheckFieldPostConstraints(r node.FieldRequest, hnd *node.ValueHandle) (bool, error) {
+	if r.IsNavigation() || hnd.Val == nil {
+		return true, nil
+	}
+	if _, isAny := r.Meta.(*meta.Any); isAny {
+		t.reported = append(t.reported, reportedLeaf{anydata: r.Meta.Ident()})
+		return true, nil
+	}
+	var leaf reportedLeaf
+	if t.defaults && r.Meta.HasDefault() {
+		def, err := node.NewValue(r.Meta.Type(), r.Meta.DefaultValue())
+		if err != nil {
+			return false, err
+		}
+		leaf.isDefault = val.Equal(def, hnd.Val)
+	}
+	if t.origins {
+		leaf.origin = originOf(r)
+	}
+	t.reported = append(t.reported, leaf)
+	return true, nil
+}
+
+// writeTagged writes selection like usual but with default values tagged
+// according to RFC6243 Sec. 3.4 when defaults is set and values annotated
+// with their origin according to RFC8527 Sec. 3.2.2 when origins is set
+func writeTagged(sel *node.Selection, mime MimeType, compliance ComplianceOptions, defaults bool, origins bool, out io.Writer) error {
+	tagger := &leafTagger{defaults: defaults, origins: origins}
+
+	// should be last post constraint so only fields that are written are tagged
+	sel.Constraints = node.NewConstraints(sel.Constraints)
+	sel.Constraints.AddConstraint("tagged-leaves", 100, 100, tagger)
+	var buf bytes.Buffer
+	if err := sel.UpsertIntoSetDefaults(nodeWtr(mime, compliance, &buf)); err != nil {
+		return err
+	}
+	if mime.IsXml() {
+		return tagLeavesXml(&buf, tagger, out)
+	}
+	// json of a leaf or a whole list names it, otherwise members are the
+	// children of selection
+	var def meta.Meta = sel.Meta()
+	if meta.IsLeaf(def) || (meta.IsList(def) && !sel.InsideList) {
+		def = def.Parent()
+	}
+	return tagLeavesJson(&buf, def, tagger.reported, out)
+}
+
+type jsonTagFrame struct {
+	isObject bool
+	count    int
+	key      string
+
+	// schema of object or array, nil when not known
+	def meta.Meta
+}
+
+// tagLeavesJson re-emits json adding RFC7952 metadata annotations after each
+// leaf that is using default value or has an origin
+//
+//	"speed" : 1000,
+//	"@speed" : {"ietf-netconf-with-defaults:default" : true},
+//	"dns" : ["a", "b"],
+//	"@dns" : [{"ietf-netconf-with-defaults:default" : true}, {"ietf-netconf-with-defaults:default" : true}]
+func tagLeavesJson(in io.Reader, def meta.Meta, defaulted []reportedLeaf, out io.Writer) error {
+	dec := json.NewDecoder(in)
+	dec.UseNumber()
+	var stack []*jsonTagFrame
+	var buf bytes.Buffer
+	top := func() *jsonTagFrame {
+		if len(stack) == 0 {
+			return nil
+		}
+		return stack[len(stack)-1]
+	}
+	annotate := func(leaf reportedLeaf) {
+		buf.WriteRune('{')
+		if leaf.isDefault {
+			fmt.Fprintf(&buf, `"%s:default":true`, withDefaultsModule)
+			if leaf.origin != "" {
+				buf.WriteRune(',')
+			}
+		}
+		if leaf.origin != "" {
+			fmt.Fprintf(&buf, `"%s:origin":"%s"`, originModule, originJson(leaf.origin))
+		}
+		buf.WriteRune('}')
+	}
+	// leafDone annotates leaf just written or, when entries is not -1, the
+	// leaf-list with that many entries. Leaf-lists have an annotation per
+	// entry. RFC7952 Sec. 5.2.2
+	leafDone := func(entries int) error {
+		f := top()
+		if f == nil || !f.isObject {
+			return nil
+		}
+		if len(defaulted) == 0 {
+			return errors.New("more leaves written than recorded")
+		}
+		leaf := defaulted[0]
+		defaulted = defaulted[1:]
+		if !leaf.tagged() {
+			return nil
+		}
+		fmt.Fprintf(&buf, `,"@%s":`, f.key)
+		if entries < 0 {
+			annotate(leaf)
+			return nil
+		}
+		buf.WriteRune('[')
+		for i := 0; i < entries; i++ {
+			if i > 0 {
+				buf.WriteRune(',')
+			}
+			// entries of a leaf-list are read and so annotated together
+			annotate(leaf)
+		}
+		buf.WriteRune(']')
+		return nil
+	}
+	valueStart := func() {
+		if f := top(); f != nil && !f.isObject {
+			if f.count > 0 {
+				buf.WriteRune(',')
+			}
+			f.count++
+		}
+	}
+	expectKey := func() bool {
+		f := top()
+		return f != nil && f.isObject && f.key == ""
+	}
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		switch x := tok.(type) {
+		case json.Delim:
+			switch x {
+			case '{', '[':
+				var child meta.Meta
+				switch f := top(); {
+				case f == nil:
+					child = def
+				case !f.isObject:
+					// list entry
+					child = f.def
+				case f.def != nil:
+					child = meta.Find(f.def, f.key)
+				}
+				valueStart()
+				buf.WriteRune(rune(x))
+				stack = append(stack, &jsonTagFrame{isObject: x == '{', def: child})
+			case '}', ']':
+				buf.WriteRune(rune(x))
+				f := top()
+				stack = stack[:len(stack)-1]
+				if _, isLeafList := f.def.(*meta.LeafList); x == ']' && isLeafList {
+					// leaf-list
+					if err := leafDone(f.count); err != nil {
+						return err
+					}
+				}
+				if p := top(); p != nil && p.isObject {
+					p.key = ""
+				}
+			}
+		default:
+			data, err := json.Marshal(tok)
+			if err != nil {
+				return err
+			}
+			if expectKey() {
+				f := top()
+				if f.count > 0 {
+					buf.WriteRune(',')
+				}
+				f.count++
+				f.key = tok.(string)
+				buf.Write(data)
+				buf.WriteRune(':')
+				if len(defaulted) > 0 && defaulted[0].anydata != "" && jsonIdent(f.key) == defaulted[0].anydata {
+					var content json.RawMessage
+					if err := dec.Decode(&content); err != nil {
+						return err
+					}
+					buf.Write(content)
+					defaulted = defaulted[1:]
+					f.key = ""
+				}
+				continue
+			}
+			valueStart()
+			buf.Write(data)
+			if f := top(); f != nil && f.isObject {
+				if err := leafDone(-1); err != nil {
+					return err
+				}
+				f.key = ""
+			}
+		}
+	}
+	_, err := out.Write(buf.Bytes())
+	return err
+}
+
+// tagLeavesXml re-emits xml adding RFC6243 attribute to each leaf that
+// is using default value and RFC8527 attribute to each leaf with an origin
+//
+//	<speed wd:default="true">1000</speed>
+//	<speed or:origin="or:learned">1000</speed>
+//
+// Consecutive leaves with same name are leaf-list items and share tag.
+func tagLeavesXml(in io.Reader, tagger *leafTagger, out io.Writer) error {
+	defaulted := tagger.reported
+	dec := xml.NewDecoder(in)
+	var buf bytes.Buffer
+	var pending *xml.StartElement
+	var pendingText bytes.Buffer
+	isRoot := true
+	var prevLeaf string
+	var prev reportedLeaf
+	var prevAnydata string
+	writeStart := func(e *xml.StartElement, tag reportedLeaf) {
+		buf.WriteRune('<')
+		buf.WriteString(xmlName(e.Name))
+		for _, a := range e.Attr {
+			fmt.Fprintf(&buf, ` %s="`, xmlName(a.Name))
+			xml.EscapeText(&buf, []byte(a.Value))
+			buf.WriteRune('"')
+		}
+		if isRoot {
+			if tagger.defaults {
+				fmt.Fprintf(&buf, ` xmlns:wd="%s"`, withDefaultsNs)
+			}
+			if tagger.origins {
+				fmt.Fprintf(&buf, ` xmlns:or="%s"`, originNs)
+			}
+			isRoot = false
+		}
+		if tag.isDefault {
+			buf.WriteString(` wd:default="true"`)
+		}
+		if tag.origin != "" {
+			buf.WriteString(` or:origin="`)
+			xml.EscapeText(&buf, []byte(originXml(tag.origin)))
+			buf.WriteRune('"')
+		}
+		buf.WriteRune('>')
+	}
+	for {
+		tok, err := dec.RawToken()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		switch x := tok.(type) {
+		case xml.StartElement:
+			if pending != nil {
+				writeStart(pending, reportedLeaf{})
+				buf.Write(pendingText.Bytes())
+				prevLeaf = ""
+			}
+			e := x.Copy()
+			pending = nil
+			isAnydata := x.Name.Local == prevAnydata
+			if !isAnydata && len(defaulted) > 0 && defaulted[0].anydata == x.Name.Local {
+				// anydata w/list content repeats element
+				isAnydata = true
+				defaulted = defaulted[1:]
+			}
+			if isAnydata {
+				writeStart(&e, reportedLeaf{})
+				if err := copyXmlElement(dec, &buf); err != nil {
+					return err
+				}
+				prevLeaf, prevAnydata = "", x.Name.Local
+				continue
+			}
+			prevAnydata = ""
+			pending = &e
+			pendingText.Reset()
+		case xml.CharData:
+			if pending != nil {
+				xml.EscapeText(&pendingText, x)
+			} else {
+				xml.EscapeText(&buf, x)
+			}
+		case xml.EndElement:
+			if pending != nil {
+				// leaf
+				name := xmlName(pending.Name)
+				leaf := prev
+				if name != prevLeaf {
+					if len(defaulted) == 0 {
+						return errors.New("more leaves written than recorded")
+					}
+					leaf = defaulted[0]
+					defaulted = defaulted[1:]
+				}
+				writeStart(pending, leaf)
+				buf.Write(pendingText.Bytes())
+				prevLeaf, prev = name, leaf
+				pending = nil
+			} else {
+				prevLeaf = ""
+			}
+			prevAnydata = ""
+			fmt.Fprintf(&buf, "</%s>", xmlName(x.Name))
+		}
+	}
+	_, err := out.Write(buf.Bytes())
+	return err
+}
+
+// copyXmlElement writes rest of element whose start was just read
+func copyXmlElement(dec *xml.Decoder, buf *bytes.Buffer) error {
+	depth := 1
+	for depth > 0 {
+		tok, err := dec.RawToken()
+		if err != nil {
+			return err
+		}
+		switch x := tok.(type) {
+		case xml.StartElement:
+			depth++
+			buf.WriteRune('<')
+			buf.WriteString(xmlName(x.Name))
+			for _, a := range x.Attr {
+				fmt.Fprintf(buf, ` %s="`, xmlName(a.Name))
+				xml.EscapeText(buf, []byte(a.Value))
+				buf.WriteRune('"')
+			}
+			buf.WriteRune('>')
+		case xml.CharData:
+			xml.EscapeText(buf, x)
+		case xml.EndElement:
+			depth--
+			fmt.Fprintf(buf, "</%s>", xmlName(x.Name))
+		}
+	}
+	return nil
+}
+
+func jsonIdent(key string) string {
+	if colon := strings.IndexByte(key, ':'); colon >= 0 {
+		return key[colon+1:]
+	}
+	return key
+}
+
+func xmlName(n xml.Name) string {
+	if n.Space != "" {
+		return n.Space + ":" + n.Local
+	}
+	return n.Local
+}
+
+// withDefaultsCapabilityUri for ietf-restconf-monitoring capabilities. RFC8040 Sec. 9.1.2
+func withDefaultsCapabilityUri(basicMode string) string {
+	return withDefaultsCapability + basicMode
+}
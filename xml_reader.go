@@ -0,0 +1,246 @@
+package restconf
+
+import (
+	"bufio"
+	"io"
+
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/patch/xml"
+)
+
+// xmlElement is an element in request content w/the first of each of its
+// child elements. Elements in child elements are read from content when the
+// child is read itself.
+type xmlElement struct {
+	c    *requestContent
+	off  int64
+	name xml.Name
+	// namespaces inside element
+	scope xmlScope
+	// character data of elements w/o elements
+	content     []byte
+	hasElements bool
+	children    []*xmlElement
+	loaded      bool
+	// block around the document element, see nodeutil.ReadXMLBlock
+	block bool
+}
+
+// xmlScope is the namespaces declared where an element is
+type xmlScope struct {
+	prefixes map[string]string
+	space    string
+}
+
+func (s xmlScope) inside(attrs []xml.Attr) xmlScope {
+	s.prefixes = xmlPrefixes(s.prefixes, attrs)
+	for _, a := range attrs {
+		if a.Name.Space == "" && a.Name.Local == "xmlns" {
+			s.space = a.Value
+		}
+	}
+	return s
+}
+
+// translate is the name w/namespace like xml.Decoder.Token has it
+func (s xmlScope) translate(n xml.Name) xml.Name {
+	if n.Space == "" {
+		n.Space = s.space
+	} else if ns, declared := s.prefixes[n.Space]; declared {
+		n.Space = ns
+	}
+	return n
+}
+
+// readXmlContent is the block around the document element in content. All
+// of the document element is checked to be XML.
+func readXmlContent(in io.Reader) (*xmlElement, error) {
+	c, err := readContent(in)
+	if err != nil {
+		return nil, err
+	}
+	d := xml.NewDecoder(c.reader(0))
+	for {
+		off := d.InputOffset()
+		t, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		if _, isStart := t.(xml.StartElement); isStart {
+			if err = d.Skip(); err != nil {
+				return nil, err
+			}
+			doc, err := (&xmlElement{c: c}).readChild(xmlDecoderAt(c, off), off)
+			if err != nil {
+				return nil, err
+			}
+			return &xmlElement{c: c, children: []*xmlElement{doc}, loaded: true, block: true}, nil
+		}
+	}
+}
+
+// xmlDecoderAt reads content from off. Elements are read from where they
+// are often so decoder buffer is kept small.
+func xmlDecoderAt(c *requestContent, off int64) *xml.Decoder {
+	return xml.NewDecoder(bufio.NewReaderSize(c.reader(off), 512))
+}
+
+// readChild reads the child element that is next in d as far as its
+// content. Like the rest of reading content, d is only read by RawToken as
+// content was checked by readXmlContent already.
+func (x *xmlElement) readChild(d *xml.Decoder, base int64) (*xmlElement, error) {
+	for {
+		off := base + d.InputOffset()
+		t, err := d.RawToken()
+		if err != nil {
+			return nil, err
+		}
+		switch y := t.(type) {
+		case xml.StartElement:
+			child := &xmlElement{c: x.c, off: off, scope: x.scope.inside(y.Attr)}
+			child.name = child.scope.translate(y.Name)
+			for depth := 1; depth > 0; {
+				if t, err = d.RawToken(); err != nil {
+					return nil, err
+				}
+				switch z := t.(type) {
+				case xml.StartElement:
+					child.hasElements = true
+					depth++
+				case xml.EndElement:
+					depth--
+				case xml.CharData:
+					if depth == 1 && !child.hasElements {
+						child.content = append(child.content, z...)
+					}
+				}
+			}
+			return child, nil
+		case xml.EndElement:
+			return nil, nil
+		}
+	}
+}
+
+// xmlChildren reads child elements of an element in order
+type xmlChildren func() (*xmlElement, error)
+
+func (x *xmlElement) childElements() (xmlChildren, error) {
+	if x.block {
+		next := 0
+		return func() (*xmlElement, error) {
+			if next >= len(x.children) {
+				return nil, nil
+			}
+			next++
+			return x.children[next-1], nil
+		}, nil
+	}
+	d := xmlDecoderAt(x.c, x.off)
+	if _, err := d.RawToken(); err != nil {
+		return nil, err
+	}
+	done := false
+	return func() (*xmlElement, error) {
+		if done {
+			return nil, nil
+		}
+		child, err := x.readChild(d, x.off)
+		// d is past element once its end is read
+		done = child == nil || err != nil
+		return child, err
+	}, nil
+}
+
+// each child element in order
+func (x *xmlElement) each(f func(child *xmlElement) error) error {
+	next, err := x.childElements()
+	if err != nil {
+		return err
+	}
+	for {
+		child, err := next()
+		if err != nil || child == nil {
+			return err
+		}
+		if err = f(child); err != nil {
+			return err
+		}
+	}
+}
+
+// load reads the first of each of the child elements
+func (x *xmlElement) load() error {
+	if x.loaded {
+		return nil
+	}
+	seen := make(map[xml.Name]bool)
+	err := x.each(func(child *xmlElement) error {
+		if !seen[child.name] {
+			seen[child.name] = true
+			x.children = append(x.children, child)
+		}
+		return nil
+	})
+	x.loaded = err == nil
+	return err
+}
+
+// find is the first child element for m like nodeutil.XmlNode.Find
+func (x *xmlElement) find(m meta.Definition) (*xmlElement, error) {
+	if err := x.load(); err != nil {
+		return nil, err
+	}
+	for _, child := range x.children {
+		if xmlMatches(child.name, m) {
+			return child, nil
+		}
+	}
+	return nil, nil
+}
+
+func xmlMatches(n xml.Name, m meta.Definition) bool {
+	return n.Local == m.Ident() && (n.Space == "" || n.Space == meta.OriginalModule(m).Namespace())
+}
+
+// tree is all of element like nodeutil.ReadXMLDoc reads it
+func (x *xmlElement) tree() (*nodeutil.XmlNode, error) {
+	return nodeutil.ReadXMLDoc(x.c.reader(x.off))
+}
+
+// xmlEntries is the child elements of a list by row, found is false past
+// the last entry
+type xmlEntries func(row int) (entry *xmlElement, found bool, err error)
+
+// entries reads child elements for m, or all child elements when m is nil,
+// in order and keeps the last entry read as it is usually asked for again.
+// Entries before that are read again from the start of the element.
+func (x *xmlElement) entries(m meta.Definition) xmlEntries {
+	var next xmlChildren
+	var entry *xmlElement
+	last := -1
+	return func(row int) (*xmlElement, bool, error) {
+		if next == nil || row < last {
+			var err error
+			if next, err = x.childElements(); err != nil {
+				return nil, false, err
+			}
+			last, entry = -1, nil
+		}
+		for last < row {
+			child, err := next()
+			if err != nil || child == nil {
+				return nil, false, err
+			}
+			if m != nil && !xmlMatches(child.name, m) {
+				continue
+			}
+			last, entry = last+1, child
+		}
+		if err := entry.load(); err != nil {
+			return nil, false, err
+		}
+		return entry, true, nil
+	}
+}
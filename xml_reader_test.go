@@ -0,0 +1,78 @@
+package restconf
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+)
+
+// largeNestedXml is largeNestedData as XML
+func largeNestedXml(entries int) string {
+	var e strings.Builder
+	for i := 0; i < entries; i++ {
+		fmt.Fprintf(&e, `<e><f>entry-%d</f><g><h>%d</h></g></e>`, i, i)
+	}
+	return fmt.Sprintf(`<a xmlns="x"><b>B</b><c><d>D</d>%s</c></a>`, e.String())
+}
+
+func TestReadXmlContent(t *testing.T) {
+	var expected map[string]interface{}
+	fc.RequireEqual(t, nil, json.Unmarshal([]byte(`{"a":`+largeNestedData(5000)+`}`), &expected))
+	defer func(memory int64) { contentMemoryBytes = memory }(contentMemoryBytes)
+	// in memory and in a file
+	for _, memory := range []int64{contentMemoryBytes, 1024} {
+		contentMemoryBytes = memory
+		content, err := xmlDecoder(io.MultiReader(strings.NewReader(largeNestedXml(5000))))
+		fc.RequireEqual(t, nil, err)
+		fc.AssertEqual(t, true, reflect.DeepEqual(expected, readNested(t, content)), fmt.Sprint(memory))
+
+		for _, invalid := range []string{``, `<a>`, `<a></b>`, `<a><b>B</a>`, `<a x=></a>`} {
+			_, err := xmlDecoder(io.MultiReader(strings.NewReader(invalid)))
+			fc.AssertEqual(t, true, err != nil, invalid)
+		}
+	}
+
+	// entries interleaved w/other elements, prefixes and elements of other
+	// namespaces
+	content, err := xmlDecoder(strings.NewReader(`<?xml version="1.0"?>
+<x:a xmlns:x="x">
+	<x:c>
+		<e xmlns="x"><f>one</f><g><h>1</h></g></e>
+		<d xmlns="other">O</d>
+		<x:d>D</x:d>
+		<e xmlns="x"><f>two</f></e>
+	</x:c>
+	<b>B</b>
+</x:a>`))
+	fc.RequireEqual(t, nil, err)
+	actual, err := json.Marshal(readNested(t, content))
+	fc.RequireEqual(t, nil, err)
+	fc.AssertEqual(t, `{"a":{"b":"B","c":{"d":"D","e":[{"f":"one","g":{"h":1}},{"f":"two"}]}}}`, string(actual))
+
+	_, ts := newTestServer(t, nestedYang, nestedData)
+	defer ts.Close()
+	resp, _ := testRequest(t, "PUT", ts.URL+"/restconf/data/x:a", largeNestedXml(5000), "Content-Type", string(YangDataXmlMimeType1))
+	fc.AssertEqual(t, 204, resp.StatusCode)
+	resp, actualData := testRequest(t, "GET", ts.URL+"/restconf/data/x:a", "")
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	var stored struct {
+		B string
+		C struct {
+			E []struct {
+				F string
+				G struct{ H int }
+			}
+		}
+	}
+	fc.RequireEqual(t, nil, json.Unmarshal([]byte(actualData), &stored))
+	fc.AssertEqual(t, "B", stored.B)
+	fc.RequireEqual(t, 5000, len(stored.C.E))
+	for _, e := range stored.C.E {
+		fc.AssertEqual(t, fmt.Sprintf("entry-%d", e.G.H), e.F)
+	}
+}
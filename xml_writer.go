@@ -0,0 +1,175 @@
+package restconf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/patch/xml"
+	"github.com/freeconf/yang/val"
+)
+
+// xmlLeaves records, in the order leaf elements are written, what freeconf
+// does not write in XML so it can be fixed after output is written
+//
+//   - namespace of identities from other modules
+//   - anydata and anyxml content, written as text otherwise
+type xmlLeaves struct {
+	leaves []xmlLeaf
+	fix    bool
+	buf    bytes.Buffer
+	out    io.Writer
+}
+
+type xmlLeaf struct {
+	// module identity value is from when it is not the leaf's module
+	ns *meta.Module
+
+	isAny   bool
+	anydata interface{}
+}
+
+func (x *xmlLeaves) node(base node.Node) node.Node {
+	return &nodeutil.Extend{
+		Base: base,
+		OnField: func(parent node.Node, r node.FieldRequest, hnd *node.ValueHandle) error {
+			if r.Write && hnd.Val != nil {
+				x.record(r.Meta, hnd.Val)
+			}
+			return parent.Field(r, hnd)
+		},
+		OnExtend: func(e *nodeutil.Extend, sel *node.Selection, m meta.HasDefinitions, child node.Node) (node.Node, error) {
+			c := e.Extend(child).(*nodeutil.Extend)
+			c.OnEndEdit = nil
+			return c, nil
+		},
+		OnEndEdit: func(parent node.Node, r node.NodeRequest) error {
+			if err := parent.EndEdit(r); err != nil {
+				return err
+			}
+			// writer has written everything once root is done
+			return x.flush()
+		},
+	}
+}
+
+func (x *xmlLeaves) record(m meta.Leafable, v val.Value) {
+	if v.Format() == val.FmtAny {
+		x.leaves = append(x.leaves, xmlLeaf{isAny: true, anydata: v.Value()})
+		x.fix = true
+		return
+	}
+	var items []val.Value
+	if l, listable := v.(val.Listable); listable {
+		for i := 0; i < l.Len(); i++ {
+			items = append(items, l.Item(i))
+		}
+	} else {
+		items = []val.Value{v}
+	}
+	for _, item := range items {
+		var leaf xmlLeaf
+		if item.Format() == val.FmtIdentityRef {
+			if idty := meta.FindIdentity(m.Type().Base(), item.String()); idty != nil {
+				if idtyMod := meta.RootModule(idty); idtyMod != meta.OriginalModule(m) {
+					leaf.ns = idtyMod
+					x.fix = true
+				}
+			}
+		}
+		x.leaves = append(x.leaves, leaf)
+	}
+}
+
+func (x *xmlLeaves) flush() error {
+	defer func() {
+		x.buf.Reset()
+		x.leaves = nil
+		x.fix = false
+	}()
+	if !x.fix {
+		_, err := x.out.Write(x.buf.Bytes())
+		return err
+	}
+	return fixXmlLeaves(&x.buf, x.leaves, x.out)
+}
+
+// fixXmlLeaves re-emits xml adding a namespace declaration to each leaf that
+// references an identity from another module and anydata content in place of
+// its text
+func fixXmlLeaves(in io.Reader, leaves []xmlLeaf, out io.Writer) error {
+	dec := xml.NewDecoder(in)
+	var buf bytes.Buffer
+	var pending *xml.StartElement
+	var pendingText bytes.Buffer
+	writeStart := func(e *xml.StartElement, mod *meta.Module) {
+		buf.WriteRune('<')
+		buf.WriteString(xmlName(e.Name))
+		for _, a := range e.Attr {
+			fmt.Fprintf(&buf, ` %s="`, xmlName(a.Name))
+			xml.EscapeText(&buf, []byte(a.Value))
+			buf.WriteRune('"')
+		}
+		if mod != nil {
+			fmt.Fprintf(&buf, ` xmlns:%s="`, mod.Ident())
+			xml.EscapeText(&buf, []byte(mod.Namespace()))
+			buf.WriteRune('"')
+		}
+		buf.WriteRune('>')
+	}
+	for {
+		tok, err := dec.RawToken()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		switch x := tok.(type) {
+		case xml.StartElement:
+			if pending != nil {
+				writeStart(pending, nil)
+				buf.Write(pendingText.Bytes())
+			}
+			e := x.Copy()
+			pending = &e
+			pendingText.Reset()
+		case xml.CharData:
+			if pending != nil {
+				xml.EscapeText(&pendingText, x)
+			} else {
+				xml.EscapeText(&buf, x)
+			}
+		case xml.EndElement:
+			if pending == nil {
+				fmt.Fprintf(&buf, "</%s>", xmlName(x.Name))
+				continue
+			}
+			// leaf
+			if len(leaves) == 0 {
+				return errors.New("more leaves written than recorded")
+			}
+			leaf := leaves[0]
+			leaves = leaves[1:]
+			if leaf.isAny {
+				enc := xml.NewEncoder(&buf)
+				if err := encodeXmlAnydata(enc, *pending, leaf.anydata); err != nil {
+					return err
+				}
+				if err := enc.Flush(); err != nil {
+					return err
+				}
+			} else {
+				writeStart(pending, leaf.ns)
+				buf.Write(pendingText.Bytes())
+				fmt.Fprintf(&buf, "</%s>", xmlName(x.Name))
+			}
+			pending = nil
+		}
+	}
+	_, err := out.Write(buf.Bytes())
+	return err
+}
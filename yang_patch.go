@@ -0,0 +1,504 @@
+package restconf
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/patch/xml"
+)
+
+// ErrDataExists is reported with error-tag "data-exists"
+var ErrDataExists = errors.New("data exists")
+
+// ErrDataMissing is reported with error-tag "data-missing"
+var ErrDataMissing = errors.New("data missing")
+
+// RFC8072 Sec. 2.5
+const (
+	yangPatchCreate  = "create"
+	yangPatchDelete  = "delete"
+	yangPatchInsert  = "insert"
+	yangPatchMerge   = "merge"
+	yangPatchMove    = "move"
+	yangPatchReplace = "replace"
+	yangPatchRemove  = "remove"
+)
+
+// yangPatch is the parsed RFC8072 yang-patch request
+type yangPatch struct {
+	patchId string
+	edits   []*yangPatchEdit
+}
+
+type yangPatchEdit struct {
+	editId    string
+	operation string
+	target    string
+	point     string
+	where     string
+
+	// value is the data for the target wrapped in target's identifier just
+	// as it would be in the target's parent
+	value func() (node.Node, error)
+}
+
+// yangPatchStatus is the RFC8072 yang-patch-status response
+type yangPatchStatus struct {
+	patchId string
+
+	// edits that were tried in order, only last one can have failed
+	edits []*yangPatchEditResult
+
+	// errors not of any one edit like undoing edits or committing a
+	// transaction failing
+	globalErrs []error
+}
+
+type yangPatchEditResult struct {
+	editId string
+	target string
+	err    error
+}
+
+// failed is first error that failed patch, nil when all edits were applied
+func (s *yangPatchStatus) failed() error {
+	if len(s.globalErrs) > 0 {
+		return s.globalErrs[0]
+	}
+	if n := len(s.edits); n > 0 {
+		return s.edits[n-1].err
+	}
+	return nil
+}
+
+type yangPatchJson struct {
+	PatchId string `json:"patch-id"`
+	Edit    []struct {
+		EditId    string          `json:"edit-id"`
+		Operation string          `json:"operation"`
+		Target    string          `json:"target"`
+		Point     string          `json:"point"`
+		Where     string          `json:"where"`
+		Value     json.RawMessage `json:"value"`
+	} `json:"edit"`
+}
+
+type yangPatchXml struct {
+	XMLName xml.Name `xml:"yang-patch"`
+	PatchId string   `xml:"patch-id"`
+	Edit    []struct {
+		EditId    string `xml:"edit-id"`
+		Operation string `xml:"operation"`
+		Target    string `xml:"target"`
+		Point     string `xml:"point"`
+		Where     string `xml:"where"`
+		Value     struct {
+			Inner []byte `xml:",innerxml"`
+		} `xml:"value"`
+	} `xml:"edit"`
+}
+
+func readYangPatch(contentType MimeType, in io.Reader) (*yangPatch, error) {
+	if contentType.IsXml() {
+		return readYangPatchXml(in)
+	}
+	return readYangPatchJson(in)
+}
+
+func readYangPatchJson(in io.Reader) (*yangPatch, error) {
+	var envelope map[string]json.RawMessage
+	if err := json.NewDecoder(in).Decode(&envelope); err != nil {
+		return nil, fmt.Errorf("%w. invalid yang-patch. %s", fc.BadRequestError, err)
+	}
+	data, found := envelope["ietf-yang-patch:yang-patch"]
+	if !found {
+		if data, found = envelope["yang-patch"]; !found {
+			return nil, fmt.Errorf("%w. missing yang-patch", fc.BadRequestError)
+		}
+	}
+	var req yangPatchJson
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, fmt.Errorf("%w. invalid yang-patch. %s", fc.BadRequestError, err)
+	}
+	p := &yangPatch{patchId: req.PatchId}
+	for _, e := range req.Edit {
+		value := e.Value
+		p.edits = append(p.edits, &yangPatchEdit{
+			editId:    e.EditId,
+			operation: e.Operation,
+			target:    e.Target,
+			point:     e.Point,
+			where:     e.Where,
+			value: func() (node.Node, error) {
+				return jsonDecoder(bytes.NewReader(value))
+			},
+		})
+	}
+	return p, nil
+}
+
+func readYangPatchXml(in io.Reader) (*yangPatch, error) {
+	var req yangPatchXml
+	if err := xml.NewDecoder(in).Decode(&req); err != nil {
+		return nil, fmt.Errorf("%w. invalid yang-patch. %s", fc.BadRequestError, err)
+	}
+	p := &yangPatch{patchId: req.PatchId}
+	for _, e := range req.Edit {
+		var value bytes.Buffer
+		value.WriteString("<value>")
+		value.Write(e.Value.Inner)
+		value.WriteString("</value>")
+		p.edits = append(p.edits, &yangPatchEdit{
+			editId:    e.EditId,
+			operation: e.Operation,
+			target:    e.Target,
+			point:     e.Point,
+			where:     e.Where,
+			value: func() (node.Node, error) {
+				x, err := readXmlContent(bytes.NewReader(value.Bytes()))
+				if err != nil {
+					return nil, err
+				}
+				doc := x.children[0]
+				if err = doc.load(); err != nil {
+					return nil, err
+				}
+				return xmlValuesNode(doc), nil
+			},
+		})
+	}
+	return p, nil
+}
+
+// apply edits in order stopping at first edit that fails. Edits that were
+// applied are undone so data is left as it was, edits that cannot be undone
+// are reported as global errors.
+func (p *yangPatch) apply(sel *node.Selection) *yangPatchStatus {
+	status := &yangPatchStatus{patchId: p.patchId}
+	var undos []func() error
+	for _, e := range p.edits {
+		undo, err := e.apply(sel)
+		status.edits = append(status.edits, &yangPatchEditResult{editId: e.editId, target: e.target, err: err})
+		if err != nil {
+			for i := len(undos) - 1; i >= 0; i-- {
+				if uerr := undos[i](); uerr != nil {
+					fc.Err.Printf("could not undo yang-patch %s edit. %s", p.patchId, uerr)
+					uerr = fmt.Errorf("could not undo edit %s, it is still applied. %w", p.edits[i].editId, uerr)
+					status.globalErrs = append(status.globalErrs, uerr)
+				}
+			}
+			break
+		}
+		undos = append(undos, undo)
+	}
+	return status
+}
+
+// apply edit and return function that will undo the edit
+func (e *yangPatchEdit) apply(sel *node.Selection) (func() error, error) {
+	parentPath, ident := splitEditTarget(e.target)
+	parent := sel
+	if parentPath != "" {
+		var err error
+		if parent, err = sel.Find(parentPath); err != nil {
+			return nil, err
+		}
+		if parent == nil {
+			return nil, fmt.Errorf("%w. %w. %s", fc.ConflictError, ErrDataMissing, e.target)
+		}
+	} else if ident == "" {
+		// target is the resource itself
+		if parent = sel.Parent(); parent == nil {
+			return nil, fmt.Errorf("%w. edit target cannot be the datastore", fc.BadRequestError)
+		}
+		ident = sel.Path.StringNoModule()
+		ident = ident[strings.LastIndexByte(ident, '/')+1:]
+	}
+	// undo restores data as it was even if principal may not see all of it
+	undo, existed, err := snapshotResource(withoutAccessChecks(parent), ident)
+	if err != nil {
+		return nil, err
+	}
+	switch e.operation {
+	case yangPatchCreate, yangPatchInsert:
+		if existed {
+			return nil, fmt.Errorf("%w. %w. %s", fc.ConflictError, ErrDataExists, e.target)
+		}
+	case yangPatchDelete, yangPatchMove:
+		if !existed {
+			return nil, fmt.Errorf("%w. %w. %s", fc.ConflictError, ErrDataMissing, e.target)
+		}
+	case yangPatchMerge, yangPatchReplace, yangPatchRemove:
+	default:
+		return nil, fmt.Errorf("%w. unknown yang-patch operation '%s'", fc.BadRequestError, e.operation)
+	}
+
+	switch e.operation {
+	case yangPatchCreate, yangPatchMerge:
+		err = e.merge(parent)
+	case yangPatchReplace:
+		if existed {
+			err = deleteResource(parent, ident)
+		}
+		if err == nil {
+			err = e.merge(parent)
+		}
+	case yangPatchDelete, yangPatchRemove:
+		if existed {
+			err = deleteResource(parent, ident)
+		}
+	case yangPatchInsert, yangPatchMove:
+		var ins *insertPoint
+		if ins, err = e.insertPoint(); err != nil {
+			break
+		}
+		if e.operation == yangPatchMove {
+			var entry *node.Selection
+			if entry, err = parent.Find(ident); err == nil {
+				err = ins.moveEntry(entry)
+				entry.Release()
+			}
+			break
+		}
+		var before map[string]*orderedEntries
+		if before, err = readOrderedEntries(parent); err != nil {
+			break
+		}
+		if err = e.merge(parent); err == nil {
+			err = ins.place(parent, before)
+		}
+	}
+	if err != nil {
+		// edit may be partially applied
+		if uerr := undo(); uerr != nil {
+			fc.Err.Printf("could not undo yang-patch edit. %s", uerr)
+		}
+		return nil, err
+	}
+	return undo, nil
+}
+
+func (e *yangPatchEdit) merge(parent *node.Selection) error {
+	n, err := e.value()
+	if err != nil {
+		return fmt.Errorf("%w. invalid value in edit %s. %s", fc.BadRequestError, e.editId, err)
+	}
+	return parent.UpsertFrom(n)
+}
+
+func (e *yangPatchEdit) insertPoint() (*insertPoint, error) {
+	where := e.where
+	if where == "" {
+		where = insertLast
+	}
+	return newInsertPoint(where, e.point)
+}
+
+// splitEditTarget splits target into the path to the parent of the target
+// and the last segment
+//
+//	/a/e=one => a, e=one
+func splitEditTarget(target string) (string, string) {
+	target = strings.Trim(target, "/")
+	slash := strings.LastIndexByte(target, '/')
+	if slash < 0 {
+		return "", target
+	}
+	return target[:slash], target[slash+1:]
+}
+
+// snapshotResource records resource so it can be restored to what it is now
+// even if it does not exist now.
+func snapshotResource(parent *node.Selection, ident string) (func() error, bool, error) {
+	sel, err := parent.Find(ident)
+	if err != nil {
+		// most likely a bad path
+		return nil, false, fmt.Errorf("%w. %s", fc.BadRequestError, err)
+	}
+	if sel == nil {
+		restore := func() error {
+			return deleteResource(parent, ident)
+		}
+		return restore, false, nil
+	}
+	defer sel.Release()
+	if meta.IsLeaf(sel.Meta()) {
+		v, err := sel.Get()
+		if err != nil {
+			return nil, false, err
+		}
+		restore := func() error {
+			leaf, err := parent.Find(ident)
+			if err != nil {
+				return err
+			}
+			defer leaf.Release()
+			if v == nil {
+				return parent.ClearField(leaf.Meta().(meta.Leafable))
+			}
+			return leaf.Set(v)
+		}
+		return restore, v != nil, nil
+	}
+	var buf bytes.Buffer
+	if err = sel.UpsertInto(jsonEncoder(&buf, snapshotCompliance)); err != nil {
+		return nil, false, err
+	}
+	var data string
+	if sel.InsideList {
+		data = fmt.Sprintf(`{"%s":[%s]}`, sel.Meta().Ident(), buf.String())
+	} else {
+		data = fmt.Sprintf(`{"%s":%s}`, sel.Meta().Ident(), buf.String())
+	}
+	restore := func() error {
+		if err := deleteResource(parent, ident); err != nil {
+			return err
+		}
+		n, err := jsonDecoder(strings.NewReader(data))
+		if err != nil {
+			return err
+		}
+		return parent.UpsertFrom(n)
+	}
+	return restore, true, nil
+}
+
+// deleteResource if it exists
+func deleteResource(parent *node.Selection, ident string) error {
+	sel, err := parent.Find(ident)
+	if err != nil || sel == nil {
+		return err
+	}
+	defer sel.Release()
+	if meta.IsLeaf(sel.Meta()) {
+		return parent.ClearField(sel.Meta().(meta.Leafable))
+	}
+	// Delete does not check constraints so check them like edits do
+	var proceed bool
+	if sel.InsideList {
+		list := sel.Parent()
+		proceed, err = list.Constraints.CheckListPreConstraints(&node.ListRequest{
+			Request: node.Request{Selection: list},
+			Meta:    list.Meta().(*meta.List),
+			Delete:  true,
+			Key:     sel.Key(),
+		})
+	} else {
+		proceed, err = parent.Constraints.CheckContainerPreConstraints(&node.ChildRequest{
+			Request: node.Request{Selection: parent},
+			Meta:    sel.Meta().(meta.HasDataDefinitions),
+			Delete:  true,
+		})
+	}
+	if !proceed || err != nil {
+		return err
+	}
+	return sel.Delete()
+}
+
+// write status of every edit that was tried, edits that were applied are ok
+// even though they are undone when a later edit fails. RFC8072 Sec. 2.3
+func (s *yangPatchStatus) write(mime MimeType, out io.Writer) error {
+	var globalErrs *yangPatchErrors
+	if len(s.globalErrs) > 0 {
+		globalErrs = &yangPatchErrors{}
+		for _, err := range s.globalErrs {
+			// "/" is resource patch was sent to like edit targets
+			globalErrs.Error = append(globalErrs.Error, yangPatchError(err, "/"))
+		}
+	}
+	var edits *yangPatchEditStatusList
+	if s.failed() != nil {
+		edits = &yangPatchEditStatusList{}
+		for _, e := range s.edits {
+			status := &yangPatchEditStatus{EditId: e.editId}
+			if e.err != nil {
+				status.Errors = &yangPatchErrors{Error: []Error{yangPatchError(e.err, e.target)}}
+			} else {
+				status.Ok = &yangPatchOk{}
+			}
+			edits.Edit = append(edits.Edit, status)
+		}
+	}
+	if mime.IsXml() {
+		resp := struct {
+			XMLName      xml.Name                 `xml:"urn:ietf:params:xml:ns:yang:ietf-yang-patch yang-patch-status"`
+			PatchId      string                   `xml:"patch-id"`
+			GlobalErrors *yangPatchErrors         `xml:"global-errors"`
+			Ok           *struct{}                `xml:"ok"`
+			EditStatus   *yangPatchEditStatusList `xml:"edit-status"`
+		}{
+			PatchId:      s.patchId,
+			GlobalErrors: globalErrs,
+			EditStatus:   edits,
+		}
+		if edits == nil {
+			resp.Ok = &struct{}{}
+		}
+		return xml.NewEncoder(out).Encode(resp)
+	}
+	resp := struct {
+		Status struct {
+			PatchId      string                   `json:"patch-id"`
+			GlobalErrors *yangPatchErrors         `json:"global-errors,omitempty"`
+			Ok           []interface{}            `json:"ok,omitempty"`
+			EditStatus   *yangPatchEditStatusList `json:"edit-status,omitempty"`
+		} `json:"ietf-yang-patch:yang-patch-status"`
+	}{}
+	resp.Status.PatchId = s.patchId
+	resp.Status.GlobalErrors = globalErrs
+	resp.Status.EditStatus = edits
+	if edits == nil {
+		resp.Status.Ok = []interface{}{nil}
+	}
+	return json.NewEncoder(out).Encode(resp)
+}
+
+func yangPatchError(err error, path string) Error {
+	tag, _ := decodeError(err)
+	return Error{
+		Type:    "application",
+		Tag:     tag,
+		Path:    path,
+		Message: err.Error(),
+	}
+}
+
+// httpStatus is 200 when all edits were applied, otherwise status of the
+// error that failed patch
+func (s *yangPatchStatus) httpStatus() int {
+	err := s.failed()
+	if err == nil {
+		return 200
+	}
+	_, status := decodeError(err)
+	return status
+}
+
+type yangPatchEditStatus struct {
+	EditId string           `json:"edit-id" xml:"edit-id"`
+	Ok     *yangPatchOk     `json:"ok,omitempty" xml:"ok"`
+	Errors *yangPatchErrors `json:"errors,omitempty" xml:"errors"`
+}
+
+// yangPatchOk is the empty leaf ok, written as [null] in JSON. RFC7951 Sec. 6.9
+type yangPatchOk struct{}
+
+func (yangPatchOk) MarshalJSON() ([]byte, error) {
+	return []byte("[null]"), nil
+}
+
+type yangPatchErrors struct {
+	Error []Error `json:"error" xml:"error"`
+}
+
+type yangPatchEditStatusList struct {
+	Edit []*yangPatchEditStatus `json:"edit" xml:"edit"`
+}
@@ -0,0 +1,507 @@
+package restconf
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/val"
+)
+
+// YANG types freeconf does not read or write as RFC7951 has them
+//
+//	int64, uint64, decimal64  "-5"  not  -5. Sec. 6.1
+//	union                     type of JSON value picks member. Sec. 6.10
+//	instance-identifier       "/x:a/b". Sec. 6.11
+//
+// and uint64 values past the largest int64 cannot be read at all.
+
+// InstanceIdentifier is the value of an instance-identifier leaf, a path to
+// data in the form of the encoding it was read from. Nodes return this for
+// instance-identifier leaves as freeconf has no value for them.
+type InstanceIdentifier string
+
+func (InstanceIdentifier) Format() val.Format {
+	return val.FmtInstanceRef
+}
+
+func (x InstanceIdentifier) String() string {
+	return string(x)
+}
+
+func (x InstanceIdentifier) Value() interface{} {
+	return string(x)
+}
+
+// InstanceIdentifierList is the value of a leaf-list of instance-identifiers
+type InstanceIdentifierList []string
+
+func (InstanceIdentifierList) Format() val.Format {
+	return val.FmtInstanceRefList
+}
+
+func (x InstanceIdentifierList) String() string {
+	return strings.Join(x, ",")
+}
+
+func (x InstanceIdentifierList) Value() interface{} {
+	return []string(x)
+}
+
+func (x InstanceIdentifierList) Len() int {
+	return len(x)
+}
+
+func (x InstanceIdentifierList) Item(i int) val.Value {
+	return InstanceIdentifier(x[i])
+}
+
+// jsonStrings has values of types JSON has as strings written as strings.
+// Numbers are left as is unless quoted.
+func jsonStrings(base node.Node, quoteNumbers bool) node.Node {
+	return &nodeutil.Extend{
+		Base: base,
+		OnField: func(parent node.Node, r node.FieldRequest, hnd *node.ValueHandle) error {
+			if !r.Write || hnd.Val == nil {
+				return parent.Field(r, hnd)
+			}
+			str := node.ValueHandle{Val: jsonString(hnd.Val, quoteNumbers)}
+			return parent.Field(r, &str)
+		},
+		OnExtend: func(e *nodeutil.Extend, sel *node.Selection, m meta.HasDefinitions, child node.Node) (node.Node, error) {
+			return e.Extend(child), nil
+		},
+	}
+}
+
+func jsonString(v val.Value, quoteNumbers bool) val.Value {
+	switch v.Format() {
+	case val.FmtInstanceRef:
+		return val.String(v.String())
+	case val.FmtInt64, val.FmtUInt64:
+		if quoteNumbers {
+			return val.String(v.String())
+		}
+	case val.FmtDecimal64:
+		if quoteNumbers {
+			return val.String(strconv.FormatFloat(v.Value().(float64), 'f', -1, 64))
+		}
+	case val.FmtInstanceRefList:
+		return val.StringList(v.Value().([]string))
+	case val.FmtInt64List, val.FmtUInt64List, val.FmtDecimal64List:
+		if quoteNumbers {
+			l := v.(val.Listable)
+			strs := make([]string, l.Len())
+			for i := range strs {
+				strs[i] = jsonString(l.Item(i), true).String()
+			}
+			return val.StringList(strs)
+		}
+	}
+	return v
+}
+
+// jsonValuesNode reads values like nodeutil.ReadJSONValues w/leaves read
+// by leafValue. Objects and arrays in values may still be in content.
+func jsonValuesNode(c *requestContent, values map[string]interface{}) node.Node {
+	var entries jsonEntries
+	return &nodeutil.Extend{
+		Base: nodeutil.JsonContainerReader(values),
+		OnChild: func(parent node.Node, r node.ChildRequest) (node.Node, error) {
+			v, found := jsonGet(r.Meta, values)
+			if !found {
+				return nil, nil
+			}
+			if meta.IsList(r.Meta) {
+				l, valid := c.jsonEntries(v)
+				if !valid {
+					return nil, fmt.Errorf("%w. expected JSON array for %s", fc.BadRequestError, r.Meta.Ident())
+				}
+				return jsonListNode(c, l), nil
+			}
+			obj, valid, err := c.jsonObject(v)
+			if err != nil {
+				return nil, err
+			}
+			if !valid {
+				return nil, fmt.Errorf("%w. expected JSON object for %s", fc.BadRequestError, r.Meta.Ident())
+			}
+			return jsonValuesNode(c, obj), nil
+		},
+		OnNext: func(parent node.Node, r node.ListRequest) (node.Node, []val.Value, error) {
+			// values of just the list like {"x:b":[...]}
+			if entries == nil {
+				v, _ := jsonGet(r.Meta, values)
+				l, valid := c.jsonEntries(v)
+				if len(values) != 1 || !valid {
+					return nil, nil, fmt.Errorf("%w. expected { %s: [] }", fc.BadRequestError, r.Meta.Ident())
+				}
+				entries = l
+			}
+			return jsonNext(c, r, entries)
+		},
+		OnField: func(parent node.Node, r node.FieldRequest, hnd *node.ValueHandle) error {
+			v, found := jsonGet(r.Meta, values)
+			if !found {
+				return nil
+			}
+			v, err := c.jsonValue(v)
+			if err != nil {
+				return err
+			}
+			hnd.Val, err = leafValue(r.Meta.Type(), v, true)
+			return err
+		},
+	}
+}
+
+func jsonListNode(c *requestContent, entries jsonEntries) node.Node {
+	return &nodeutil.Basic{
+		OnNext: func(r node.ListRequest) (node.Node, []val.Value, error) {
+			return jsonNext(c, r, entries)
+		},
+	}
+}
+
+func jsonNext(c *requestContent, r node.ListRequest, entries jsonEntries) (node.Node, []val.Value, error) {
+	keyMeta := r.Meta.KeyMeta()
+	if len(r.Key) > 0 {
+		if !r.First {
+			return nil, nil, nil
+		}
+		for row := 0; ; row++ {
+			candidate, found, err := entries(row)
+			if err != nil || !found {
+				return nil, nil, err
+			}
+			entry, _ := candidate.(map[string]interface{})
+			key, err := jsonKey(c, keyMeta, entry)
+			if err != nil {
+				return nil, nil, err
+			}
+			if sameKey(key, r.Key) {
+				return jsonValuesNode(c, entry), r.Key, nil
+			}
+		}
+	}
+	candidate, found, err := entries(r.Row)
+	if err != nil || !found {
+		return nil, nil, err
+	}
+	entry, valid := candidate.(map[string]interface{})
+	if !valid {
+		return nil, nil, fmt.Errorf("%w. expected JSON object in %s", fc.BadRequestError, r.Meta.Ident())
+	}
+	key, err := jsonKey(c, keyMeta, entry)
+	if err != nil {
+		return nil, nil, err
+	}
+	return jsonValuesNode(c, entry), key, nil
+}
+
+// jsonKey is nil for keys that are missing as they may be when inserting
+func jsonKey(c *requestContent, keyMeta []meta.Leafable, entry map[string]interface{}) ([]val.Value, error) {
+	if len(keyMeta) == 0 {
+		return nil, nil
+	}
+	key := make([]val.Value, len(keyMeta))
+	for i, k := range keyMeta {
+		v, _ := jsonGet(k, entry)
+		v, err := c.jsonValue(v)
+		if err != nil {
+			return nil, err
+		}
+		if key[i], err = leafValue(k.Type(), v, true); err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+func sameKey(a []val.Value, b []val.Value) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] == nil || b[i] == nil || a[i].String() != b[i].String() {
+			return false
+		}
+	}
+	return true
+}
+
+func jsonGet(m meta.Definition, values map[string]interface{}) (interface{}, bool) {
+	if v, found := values[m.Ident()]; found {
+		return v, true
+	}
+	v, found := values[meta.OriginalModule(m).Ident()+":"+m.Ident()]
+	return v, found
+}
+
+// xmlValuesNode reads x w/leaves and keys read by leafValue
+func xmlValuesNode(x *xmlElement) node.Node {
+	var entries xmlEntries
+	return &nodeutil.Basic{
+		OnChoose: func(sel *node.Selection, choice *meta.Choice) (*meta.ChoiceCase, error) {
+			for _, kase := range choice.Cases() {
+				for _, m := range kase.DataDefinitions() {
+					if found, err := x.find(m); err != nil || found != nil {
+						return kase, err
+					}
+				}
+			}
+			return nil, nil
+		},
+		OnChild: func(r node.ChildRequest) (node.Node, error) {
+			child, err := x.find(r.Meta)
+			if err != nil || child == nil {
+				return nil, err
+			}
+			if meta.IsList(r.Meta) {
+				// RFC7950 Sec. 7.8.5 entries may be interleaved with other elements
+				l := x.entries(r.Meta)
+				return &nodeutil.Basic{
+					OnNext: func(r node.ListRequest) (node.Node, []val.Value, error) {
+						return xmlNext(r, l)
+					},
+				}, nil
+			}
+			if err = child.load(); err != nil {
+				return nil, err
+			}
+			return xmlValuesNode(child), nil
+		},
+		OnNext: func(r node.ListRequest) (node.Node, []val.Value, error) {
+			if entries == nil {
+				entries = x.entries(nil)
+			}
+			return xmlNext(r, entries)
+		},
+		OnField: func(r node.FieldRequest, hnd *node.ValueHandle) error {
+			v, found, err := xmlGet(r.Meta, x)
+			if err == nil && found {
+				hnd.Val, err = leafValue(r.Meta.Type(), v, false)
+			}
+			return err
+		},
+	}
+}
+
+func xmlNext(r node.ListRequest, entries xmlEntries) (node.Node, []val.Value, error) {
+	keyMeta := r.Meta.KeyMeta()
+	if len(r.Key) > 0 {
+		for row := 0; ; row++ {
+			entry, found, err := entries(row)
+			if err != nil || !found {
+				return nil, nil, err
+			}
+			key, err := xmlKey(keyMeta, entry)
+			if err != nil {
+				return nil, nil, err
+			}
+			if sameKey(key, r.Key) {
+				return xmlValuesNode(entry), r.Key, nil
+			}
+		}
+	}
+	entry, found, err := entries(r.Row)
+	if err != nil || !found {
+		return nil, nil, err
+	}
+	key, err := xmlKey(keyMeta, entry)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i, k := range key {
+		if k == nil {
+			return nil, nil, fmt.Errorf("%w. key '%s' missing from %s", fc.BadRequestError, keyMeta[i].Ident(), r.Path)
+		}
+	}
+	return xmlValuesNode(entry), key, nil
+}
+
+func xmlKey(keyMeta []meta.Leafable, entry *xmlElement) ([]val.Value, error) {
+	if len(keyMeta) == 0 {
+		return nil, nil
+	}
+	key := make([]val.Value, len(keyMeta))
+	for i, k := range keyMeta {
+		v, _, err := xmlGet(k, entry)
+		if err != nil {
+			return nil, err
+		}
+		if key[i], err = leafValue(k.Type(), v, false); err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+// xmlGet is the content of leaf m or of each of the elements of leaf-list m
+func xmlGet(m meta.Leafable, x *xmlElement) (interface{}, bool, error) {
+	first, err := x.find(m)
+	if err != nil || first == nil {
+		return nil, false, err
+	}
+	if _, isAny := m.(*meta.Any); isAny {
+		tree, err := first.tree()
+		if err != nil {
+			return nil, false, err
+		}
+		return xmlAnydata(tree), true, nil
+	}
+	if _, isList := m.(*meta.LeafList); !isList {
+		return strings.TrimSpace(string(first.content)), true, nil
+	}
+	// RFC7950 Sec. 7.7.8 entries may be interleaved with other elements
+	var found []string
+	err = x.each(func(child *xmlElement) error {
+		if xmlMatches(child.name, m) {
+			found = append(found, strings.TrimSpace(string(child.content)))
+		}
+		return nil
+	})
+	return found, true, err
+}
+
+// xmlAnydata is content of anydata or anyxml element in the form JSON content
+// is read into. Elements w/same name are a list and elements w/o elements
+// are strings as XML has no other types.
+func xmlAnydata(x *nodeutil.XmlNode) interface{} {
+	if len(x.Nodes) == 0 {
+		return x.ContentTrim()
+	}
+	obj := make(map[string]interface{}, len(x.Nodes))
+	for _, child := range x.Nodes {
+		name := child.XMLName.Local
+		v := xmlAnydata(child)
+		switch prev := obj[name].(type) {
+		case nil:
+			obj[name] = v
+		case []interface{}:
+			obj[name] = append(prev, v)
+		default:
+			obj[name] = []interface{}{prev, v}
+		}
+	}
+	return obj
+}
+
+// leafValue is content of a leaf or leaf-list as a value of its type. Types
+// freeconf reads as RFC7950 and RFC7951 have them are left to freeconf.
+func leafValue(typ *meta.Type, v interface{}, fromJson bool) (val.Value, error) {
+	if v == nil {
+		return nil, nil
+	}
+	switch typ.Format() {
+	case val.FmtLeafRef, val.FmtLeafRefList:
+		return leafValue(typ.Resolve(), v, fromJson)
+	case val.FmtUnion:
+		// RFC7950 Sec. 9.12 members are tried in order they are defined
+		for _, member := range typ.Union() {
+			if fromJson && !jsonTypeMatches(member, v) {
+				continue
+			}
+			if x, err := leafValue(member, v, fromJson); err == nil {
+				return x, nil
+			}
+		}
+		return nil, fmt.Errorf("%w. %v is none of the types in union", fc.BadRequestError, v)
+	case val.FmtUInt64:
+		if s, isStr := v.(string); isStr {
+			n, err := parseUint64(s)
+			return val.UInt64(n), err
+		}
+	case val.FmtUInt64List:
+		items := leafItems(v)
+		l := make([]uint64, len(items))
+		for i, item := range items {
+			s, isStr := item.(string)
+			if !isStr {
+				return node.NewValue(typ, v)
+			}
+			var err error
+			if l[i], err = parseUint64(s); err != nil {
+				return nil, err
+			}
+		}
+		return val.UInt64List(l), nil
+	case val.FmtInstanceRef:
+		s, err := instanceIdentifier(v)
+		return InstanceIdentifier(s), err
+	case val.FmtInstanceRefList:
+		items := leafItems(v)
+		l := make([]string, len(items))
+		for i, item := range items {
+			var err error
+			if l[i], err = instanceIdentifier(item); err != nil {
+				return nil, err
+			}
+		}
+		return InstanceIdentifierList(l), nil
+	}
+	return node.NewValue(typ, v)
+}
+
+func leafItems(v interface{}) []interface{} {
+	switch x := v.(type) {
+	case []interface{}:
+		return x
+	case []string:
+		items := make([]interface{}, len(x))
+		for i, s := range x {
+			items[i] = s
+		}
+		return items
+	}
+	return []interface{}{v}
+}
+
+func parseUint64(s string) (uint64, error) {
+	n, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w. '%s' is not a uint64", fc.BadRequestError, s)
+	}
+	return n, nil
+}
+
+func instanceIdentifier(v interface{}) (string, error) {
+	s, valid := v.(string)
+	if !valid || !strings.HasPrefix(s, "/") {
+		return "", fmt.Errorf("%w. '%v' is not an instance-identifier", fc.BadRequestError, v)
+	}
+	return s, nil
+}
+
+// jsonTypeMatches is when v is of the JSON type union member is written as.
+// Numbers are allowed for 64-bit types too as freeconf has always read them.
+func jsonTypeMatches(member *meta.Type, v interface{}) bool {
+	f := member.Format()
+	if f == val.FmtLeafRef {
+		f = member.Resolve().Format()
+	}
+	if f == val.FmtUnion {
+		return true
+	}
+	switch v.(type) {
+	case bool:
+		return f == val.FmtBool
+	case float64:
+		return isJsonNumber(f) || f == val.FmtInt64 || f == val.FmtUInt64 || f == val.FmtDecimal64
+	case string:
+		return !isJsonNumber(f) && f != val.FmtBool && f != val.FmtEmpty
+	}
+	return true
+}
+
+func isJsonNumber(f val.Format) bool {
+	switch f {
+	case val.FmtInt8, val.FmtInt16, val.FmtInt32, val.FmtUInt8, val.FmtUInt16, val.FmtUInt32:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,184 @@
+package restconf
+
+import (
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+	"github.com/freeconf/yang/val"
+)
+
+const yangTypesYang = `module x {
+	namespace "x";
+	prefix "x";
+	revision 0;
+	container a {
+		leaf i64 {
+			type int64;
+		}
+		leaf u64 {
+			type uint64;
+		}
+		leaf d {
+			type decimal64 {
+				fraction-digits 2;
+			}
+		}
+		leaf num {
+			type union {
+				type int32;
+				type string;
+			}
+		}
+		leaf flag {
+			type union {
+				type boolean;
+				type string;
+			}
+		}
+		leaf ref {
+			type instance-identifier;
+		}
+		leaf-list big {
+			type uint64;
+		}
+	}
+}`
+
+// valuesNode keeps values as they are written unlike reflection that would
+// convert them to the leaf's type when read again
+func valuesNode() node.Node {
+	var values map[string]val.Value
+	return &nodeutil.Basic{
+		OnChild: func(r node.ChildRequest) (node.Node, error) {
+			if r.New {
+				values = make(map[string]val.Value)
+			} else if r.Delete {
+				values = nil
+			}
+			if values == nil {
+				return nil, nil
+			}
+			return &nodeutil.Basic{
+				OnField: func(r node.FieldRequest, hnd *node.ValueHandle) error {
+					if r.Write {
+						values[r.Meta.Ident()] = hnd.Val
+					} else {
+						hnd.Val = values[r.Meta.Ident()]
+					}
+					return nil
+				},
+			}, nil
+		},
+	}
+}
+
+func TestYangTypes(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, yangTypesYang)
+	fc.RequireEqual(t, nil, err)
+	_, ts := newTestServerWithNode(t, m, valuesNode())
+	defer ts.Close()
+	url := ts.URL + "/restconf/data/x:a"
+	json := string(YangDataJsonMimeType1)
+	xml := string(YangDataXmlMimeType1)
+
+	put := func(t *testing.T, contentType string, body string) {
+		t.Helper()
+		resp, msg := testRequest(t, "PUT", url, body, "Content-Type", contentType)
+		fc.RequireEqual(t, true, resp.StatusCode < 300, msg)
+	}
+	get := func(t *testing.T, accept string) string {
+		t.Helper()
+		_, actual := testRequest(t, "GET", url, "", "Accept", accept)
+		return actual
+	}
+
+	t.Run("json", func(t *testing.T) {
+		put(t, json, `{"x:a":{"i64":"-9007199254740993","u64":"18446744073709551615","d":"-1.25","num":"12","flag":"true","ref":"/x:a/i64","big":["1","18446744073709551615"]}}`)
+		expected := `{"i64":"-9007199254740993","u64":"18446744073709551615","d":"-1.25","num":"12","flag":"true","ref":"/x:a/i64","big":["1","18446744073709551615"]}`
+		actual := get(t, json)
+		fc.AssertEqual(t, expected, actual)
+		put(t, json, `{"x:a":`+actual+`}`)
+		fc.AssertEqual(t, expected, get(t, json))
+
+		// JSON type decides union member
+		put(t, json, `{"x:a":{"num":12,"flag":true}}`)
+		fc.AssertEqual(t, `{"num":12,"flag":true}`, get(t, json))
+
+		// numbers are still read
+		put(t, json, `{"x:a":{"i64":-5,"u64":5,"d":1.5}}`)
+		fc.AssertEqual(t, `{"i64":"-5","u64":"5","d":"1.5"}`, get(t, json))
+		fc.AssertEqual(t, `{"i64":-5,"u64":5,"d":1.5}`, get(t, string(PlainJsonMimeType)))
+	})
+
+	t.Run("xml", func(t *testing.T) {
+		put(t, xml, `<a xmlns="x"><i64>-9007199254740993</i64><u64>18446744073709551615</u64><d>-1.25</d><num>12</num><flag>yes</flag><ref>/x:a/i64</ref><big>1</big><big>18446744073709551615</big></a>`)
+		expected := `<a xmlns="x"><i64>-9007199254740993</i64><u64>18446744073709551615</u64><d>-1.25</d><num>12</num><flag>true</flag><ref>/x:a/i64</ref><big>1</big><big>18446744073709551615</big></a>`
+		actual := get(t, xml)
+		fc.AssertEqual(t, expected, actual)
+		put(t, xml, actual)
+		fc.AssertEqual(t, expected, get(t, xml))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, body := range []string{
+			`{"x:a":{"u64":"18446744073709551616"}}`,
+			`{"x:a":{"u64":"-1"}}`,
+			`{"x:a":{"ref":"x:a"}}`,
+			`{"x:a":{"flag":12}}`,
+		} {
+			resp, _ := testRequest(t, "PUT", url, body, "Content-Type", json)
+			fc.AssertEqual(t, 400, resp.StatusCode, body)
+		}
+	})
+}
+
+func TestAnydata(t *testing.T) {
+	mstr := `module x {
+		namespace "x";
+		prefix "x";
+		revision 0;
+		container a {
+			leaf b {
+				type string;
+				default "B";
+			}
+			anydata extra;
+			anyxml raw;
+		}
+	}`
+	_, ts := newTestServer(t, mstr, `{}`)
+	defer ts.Close()
+	url := ts.URL + "/restconf/data/x:a"
+	json := string(YangDataJsonMimeType1)
+	xml := string(YangDataXmlMimeType1)
+
+	content := `{"b":"B","extra":{"p":{"q":[1,"two",{"r":null}]},"s":true},"raw":{"z":"1"}}`
+	resp, _ := testRequest(t, "PUT", url, `{"x:a":`+content+`}`, "Content-Type", json)
+	fc.AssertEqual(t, 201, resp.StatusCode)
+	_, actual := testRequest(t, "GET", url, "", "Accept", json)
+	fc.AssertEqual(t, content, actual)
+	_, actual = testRequest(t, "GET", url, "", "Accept", xml)
+	fc.AssertEqual(t, `<a xmlns="x"><b>B</b><extra><p><q>1</q><q>two</q><q><r></r></q></p><s>true</s></extra><raw><z>1</z></raw></a>`, actual)
+
+	t.Run("tagged", func(t *testing.T) {
+		_, actual := testRequest(t, "GET", url+"?with-defaults=report-all-tagged", "", "Accept", json)
+		fc.AssertEqual(t, `{"b":"B","@b":{"ietf-netconf-with-defaults:default":true},"extra":{"p":{"q":[1,"two",{"r":null}]},"s":true},"raw":{"z":"1"}}`, actual)
+		_, actual = testRequest(t, "GET", url+"?with-defaults=report-all-tagged", "", "Accept", xml)
+		fc.AssertEqual(t, `<a xmlns="x" xmlns:wd="urn:ietf:params:xml:ns:netconf:default:1.0"><b wd:default="true">B</b><extra><p><q>1</q><q>two</q><q><r></r></q></p><s>true</s></extra><raw><z>1</z></raw></a>`, actual)
+	})
+
+	t.Run("xml", func(t *testing.T) {
+		content := `<a xmlns="x"><b>B</b><extra><p><q>1</q><q>two</q></p><s>true</s></extra><raw><y><z>1</z></y></raw></a>`
+		resp, _ := testRequest(t, "PUT", url, content, "Content-Type", xml)
+		fc.AssertEqual(t, 204, resp.StatusCode)
+		_, actual := testRequest(t, "GET", url, "", "Accept", xml)
+		fc.AssertEqual(t, content, actual)
+
+		// XML has only text
+		_, actual = testRequest(t, "GET", url, "", "Accept", json)
+		fc.AssertEqual(t, `{"b":"B","extra":{"p":{"q":["1","two"]},"s":"true"},"raw":{"y":{"z":"1"}}}`, actual)
+	})
+}
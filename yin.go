@@ -0,0 +1,440 @@
+package restconf
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/freeconf/yang/meta"
+)
+
+// YIN is YANG written as XML. Statements are read from YANG source as they
+// are written rather than from schema so nothing is lost, like the order of
+// statements, and written as YIN elements. RFC7950 Sec. 13
+
+const yinNamespace = "urn:ietf:params:xml:ns:yang:yin:1"
+
+// yinArguments is how argument of each YANG statement is written in YIN, as
+// attribute w/name or, when element is true, as child element w/name.
+// Statements w/o an argument are not listed. RFC7950 Sec. 13.1
+var yinArguments = map[string]struct {
+	name    string
+	element bool
+}{
+	"action":           {name: "name"},
+	"anydata":          {name: "name"},
+	"anyxml":           {name: "name"},
+	"argument":         {name: "name"},
+	"augment":          {name: "target-node"},
+	"base":             {name: "name"},
+	"belongs-to":       {name: "module"},
+	"bit":              {name: "name"},
+	"case":             {name: "name"},
+	"choice":           {name: "name"},
+	"config":           {name: "value"},
+	"contact":          {name: "text", element: true},
+	"container":        {name: "name"},
+	"default":          {name: "value"},
+	"description":      {name: "text", element: true},
+	"deviate":          {name: "value"},
+	"deviation":        {name: "target-node"},
+	"enum":             {name: "name"},
+	"error-app-tag":    {name: "value"},
+	"error-message":    {name: "value", element: true},
+	"extension":        {name: "name"},
+	"feature":          {name: "name"},
+	"fraction-digits":  {name: "value"},
+	"grouping":         {name: "name"},
+	"identity":         {name: "name"},
+	"if-feature":       {name: "name"},
+	"import":           {name: "module"},
+	"include":          {name: "module"},
+	"key":              {name: "value"},
+	"leaf":             {name: "name"},
+	"leaf-list":        {name: "name"},
+	"length":           {name: "value"},
+	"list":             {name: "name"},
+	"mandatory":        {name: "value"},
+	"max-elements":     {name: "value"},
+	"min-elements":     {name: "value"},
+	"modifier":         {name: "value"},
+	"module":           {name: "name"},
+	"must":             {name: "condition"},
+	"namespace":        {name: "uri"},
+	"notification":     {name: "name"},
+	"ordered-by":       {name: "value"},
+	"organization":     {name: "text", element: true},
+	"path":             {name: "value"},
+	"pattern":          {name: "value"},
+	"position":         {name: "value"},
+	"prefix":           {name: "value"},
+	"presence":         {name: "value"},
+	"range":            {name: "value"},
+	"reference":        {name: "text", element: true},
+	"refine":           {name: "target-node"},
+	"require-instance": {name: "value"},
+	"revision":         {name: "date"},
+	"revision-date":    {name: "date"},
+	"rpc":              {name: "name"},
+	"status":           {name: "value"},
+	"submodule":        {name: "name"},
+	"type":             {name: "name"},
+	"typedef":          {name: "name"},
+	"unique":           {name: "tag"},
+	"units":            {name: "name"},
+	"uses":             {name: "name"},
+	"value":            {name: "value"},
+	"when":             {name: "condition"},
+	"yang-version":     {name: "value"},
+	"yin-element":      {name: "value"},
+}
+
+// yangStmt is a statement in YANG source
+type yangStmt struct {
+	keyword string
+	arg     string
+	hasArg  bool
+	subs    []*yangStmt
+}
+
+// writeYin writes YANG source of module m as YIN. m is used for namespaces
+// of imported modules and arguments of extensions and may be nil, for
+// example for submodules, then extensions have their argument as attribute
+// "value".
+func writeYin(out io.Writer, src []byte, m *meta.Module) error {
+	stmts, err := parseYangStmts(src)
+	if err != nil {
+		return err
+	}
+	if len(stmts) != 1 || (stmts[0].keyword != "module" && stmts[0].keyword != "submodule") {
+		return fmt.Errorf("expected a single module or submodule")
+	}
+	w := &yinWriter{module: m}
+	w.buf.WriteString(xml.Header)
+	w.stmt(stmts[0], 0)
+	_, err = out.Write(w.buf.Bytes())
+	return err
+}
+
+type yinWriter struct {
+	buf    bytes.Buffer
+	module *meta.Module
+}
+
+func (w *yinWriter) stmt(s *yangStmt, depth int) {
+	indent := strings.Repeat("  ", depth)
+	argName, argElement := w.argument(s.keyword)
+	fmt.Fprintf(&w.buf, "%s<%s", indent, s.keyword)
+	if s.hasArg && !argElement {
+		fmt.Fprintf(&w.buf, ` %s="%s"`, argName, escapeXmlAttr(s.arg))
+	}
+	if depth == 0 {
+		w.namespaces(s)
+	}
+	if len(s.subs) == 0 && !(s.hasArg && argElement) {
+		w.buf.WriteString("/>\n")
+		return
+	}
+	w.buf.WriteString(">\n")
+	if s.hasArg && argElement {
+		fmt.Fprintf(&w.buf, "%s  <%s>%s</%s>\n", indent, argName, yinTextEscaper.Replace(s.arg), argName)
+	}
+	for _, sub := range s.subs {
+		w.stmt(sub, depth+1)
+	}
+	fmt.Fprintf(&w.buf, "%s</%s>\n", indent, s.keyword)
+}
+
+// namespaces are declared on root element, YIN namespace and one for own
+// prefix and prefix of each import
+func (w *yinWriter) namespaces(root *yangStmt) {
+	fmt.Fprintf(&w.buf, ` xmlns="%s"`, yinNamespace)
+	for _, s := range root.subs {
+		switch s.keyword {
+		case "prefix":
+			w.declare(s.arg)
+		case "import":
+			for _, sub := range s.subs {
+				if sub.keyword == "prefix" {
+					w.declare(sub.arg)
+				}
+			}
+		}
+	}
+}
+
+// declare namespace of prefix when it is known. freeconf allows an empty
+// prefix which cannot be declared
+func (w *yinWriter) declare(prefix string) {
+	if prefix == "" || strings.ContainsAny(prefix, " :\"'<>&") {
+		return
+	}
+	if ns := w.namespace(prefix); ns != "" {
+		fmt.Fprintf(&w.buf, ` xmlns:%s="%s"`, prefix, escapeXmlAttr(ns))
+	}
+}
+
+// namespace of module w/prefix, empty when not known
+func (w *yinWriter) namespace(prefix string) string {
+	if m := w.prefixModule(prefix); m != nil {
+		return m.Namespace()
+	}
+	return ""
+}
+
+func (w *yinWriter) prefixModule(prefix string) *meta.Module {
+	if w.module == nil {
+		return nil
+	}
+	if prefix == w.module.Prefix() {
+		return w.module
+	}
+	if i, found := w.module.Imports()[prefix]; found {
+		return i.Module()
+	}
+	return nil
+}
+
+// argument of statement as YIN has it, for extensions like ext:foo it is
+// what extension's definition says
+func (w *yinWriter) argument(keyword string) (string, bool) {
+	if arg, found := yinArguments[keyword]; found {
+		return arg.name, arg.element
+	}
+	if colon := strings.IndexRune(keyword, ':'); colon > 0 {
+		if m := w.prefixModule(keyword[:colon]); m != nil {
+			if def, found := m.ExtensionDefs()[keyword[colon+1:]]; found && def.Argument() != nil {
+				return def.Argument().Ident(), def.Argument().YinElement()
+			}
+		}
+	}
+	return "value", false
+}
+
+func escapeXmlAttr(s string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
+
+// text like descriptions keeps its line breaks
+var yinTextEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// parseYangStmts reads statements of YANG source w/o checking what they are.
+// RFC7950 Sec. 6
+func parseYangStmts(src []byte) ([]*yangStmt, error) {
+	p := &yangStmtParser{src: src}
+	stmts, err := p.stmts()
+	if err != nil {
+		return nil, err
+	}
+	if tok, _ := p.next(); tok != "" {
+		return nil, p.errorf("unexpected '%s'", tok)
+	}
+	return stmts, nil
+}
+
+type yangStmtParser struct {
+	src []byte
+	pos int
+}
+
+func (p *yangStmtParser) errorf(format string, args ...interface{}) error {
+	line := bytes.Count(p.src[:p.pos], []byte("\n")) + 1
+	return fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+// stmts until '}' or end of source
+func (p *yangStmtParser) stmts() ([]*yangStmt, error) {
+	var stmts []*yangStmt
+	for {
+		p.skip()
+		if p.pos >= len(p.src) || p.src[p.pos] == '}' {
+			return stmts, nil
+		}
+		s, err := p.stmt()
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, s)
+	}
+}
+
+func (p *yangStmtParser) stmt() (*yangStmt, error) {
+	keyword, quoted := p.next()
+	if keyword == "" || quoted || strings.ContainsAny(keyword, ";{}") {
+		return nil, p.errorf("expected keyword, got '%s'", keyword)
+	}
+	s := &yangStmt{keyword: keyword}
+	p.skip()
+	if p.pos < len(p.src) && p.src[p.pos] != ';' && p.src[p.pos] != '{' {
+		arg, err := p.argument()
+		if err != nil {
+			return nil, err
+		}
+		s.arg, s.hasArg = arg, true
+		p.skip()
+	}
+	if p.pos >= len(p.src) {
+		return nil, p.errorf("unexpected end of %s", keyword)
+	}
+	switch p.src[p.pos] {
+	case ';':
+		p.pos++
+	case '{':
+		p.pos++
+		subs, err := p.stmts()
+		if err != nil {
+			return nil, err
+		}
+		if p.pos >= len(p.src) {
+			return nil, p.errorf("missing '}' of %s", keyword)
+		}
+		p.pos++
+		s.subs = subs
+	default:
+		return nil, p.errorf("expected ';' or '{' after %s", keyword)
+	}
+	return s, nil
+}
+
+// argument is an unquoted string or quoted strings joined w/'+'
+func (p *yangStmtParser) argument() (string, error) {
+	if c := p.src[p.pos]; c != '"' && c != '\'' {
+		arg, _ := p.next()
+		return arg, nil
+	}
+	var arg strings.Builder
+	for {
+		s, err := p.quoted()
+		if err != nil {
+			return "", err
+		}
+		arg.WriteString(s)
+		p.skip()
+		if p.pos >= len(p.src) || p.src[p.pos] != '+' {
+			return arg.String(), nil
+		}
+		p.pos++
+		p.skip()
+		if p.pos >= len(p.src) || (p.src[p.pos] != '"' && p.src[p.pos] != '\'') {
+			return "", p.errorf("expected string after '+'")
+		}
+	}
+}
+
+// next is an unquoted token or a quoted string
+func (p *yangStmtParser) next() (string, bool) {
+	p.skip()
+	if p.pos >= len(p.src) {
+		return "", false
+	}
+	if c := p.src[p.pos]; c == '"' || c == '\'' {
+		s, _ := p.quoted()
+		return s, true
+	}
+	start := p.pos
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if isYangSpace(c) || c == ';' || c == '{' || c == '}' || p.comment() {
+			break
+		}
+		p.pos++
+	}
+	if p.pos == start {
+		p.pos++
+	}
+	return string(p.src[start:p.pos]), false
+}
+
+// quoted string w/escapes and, for double quotes, indentation of lines
+// after the first removed up to and including column of opening quote. Tabs
+// are 8 columns. RFC7950 Sec. 6.1.3
+func (p *yangStmtParser) quoted() (string, error) {
+	quote := p.src[p.pos]
+	col := 1
+	for _, c := range p.src[bytes.LastIndexByte(p.src[:p.pos], '\n')+1 : p.pos] {
+		col += yangColumns(c)
+	}
+	p.pos++
+	var s strings.Builder
+	for {
+		if p.pos >= len(p.src) {
+			return "", p.errorf("missing closing quote")
+		}
+		c := p.src[p.pos]
+		p.pos++
+		switch {
+		case c == quote:
+			return s.String(), nil
+		case quote == '\'':
+			s.WriteByte(c)
+		case c == '\\' && p.pos < len(p.src):
+			esc := p.src[p.pos]
+			p.pos++
+			switch esc {
+			case 'n':
+				s.WriteByte('\n')
+			case 't':
+				s.WriteByte('\t')
+			default:
+				s.WriteByte(esc)
+			}
+		case c == '\n':
+			// trailing whitespace of line is dropped
+			trimmed := strings.TrimRight(s.String(), " \t")
+			s.Reset()
+			s.WriteString(trimmed)
+			s.WriteByte('\n')
+			for skipped := 0; skipped < col && p.pos < len(p.src); p.pos++ {
+				if c := p.src[p.pos]; c != ' ' && c != '\t' {
+					break
+				}
+				skipped += yangColumns(p.src[p.pos])
+			}
+		default:
+			s.WriteByte(c)
+		}
+	}
+}
+
+// skip whitespace and comments
+func (p *yangStmtParser) skip() {
+	for p.pos < len(p.src) {
+		if isYangSpace(p.src[p.pos]) {
+			p.pos++
+		} else if bytes.HasPrefix(p.src[p.pos:], []byte("//")) {
+			if nl := bytes.IndexByte(p.src[p.pos:], '\n'); nl >= 0 {
+				p.pos += nl + 1
+			} else {
+				p.pos = len(p.src)
+			}
+		} else if bytes.HasPrefix(p.src[p.pos:], []byte("/*")) {
+			if end := bytes.Index(p.src[p.pos+2:], []byte("*/")); end >= 0 {
+				p.pos += end + 4
+			} else {
+				p.pos = len(p.src)
+			}
+		} else {
+			return
+		}
+	}
+}
+
+func (p *yangStmtParser) comment() bool {
+	rest := p.src[p.pos:]
+	return bytes.HasPrefix(rest, []byte("//")) || bytes.HasPrefix(rest, []byte("/*"))
+}
+
+func yangColumns(c byte) int {
+	if c == '\t' {
+		return 8
+	}
+	return 1
+}
+
+func isYangSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
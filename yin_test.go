@@ -0,0 +1,114 @@
+package restconf
+
+import (
+	"bytes"
+	"io"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/freeconf/restconf/device"
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+	"github.com/freeconf/yang/source"
+)
+
+func TestWriteYin(t *testing.T) {
+	ypath := source.Any(source.Dir("./yang"), func(name string, ext string) (io.Reader, error) {
+		if name == "e" {
+			return strings.NewReader(`module e { namespace "urn:e"; prefix e; extension note { argument text { yin-element true; } } extension tag { argument name; } }`), nil
+		}
+		return nil, nil
+	})
+	src := `module y {
+	namespace "urn:y";
+	prefix y;
+	import e {
+		prefix ext;
+	}
+
+	/* comment */
+	description
+		"first line
+		 second  line" + ' & <third>';
+	container c {
+		ext:note "n";
+		ext:tag t; // comment
+		leaf l {
+			type string {
+				pattern '[a-z]+';
+			}
+			config false;
+		}
+	}
+}`
+	m, err := parser.LoadModuleFromString(ypath, src)
+	fc.RequireEqual(t, nil, err)
+	var actual bytes.Buffer
+	fc.RequireEqual(t, nil, writeYin(&actual, []byte(src), m))
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<module name="y" xmlns="urn:ietf:params:xml:ns:yang:yin:1" xmlns:y="urn:y" xmlns:ext="urn:e">
+  <namespace uri="urn:y"/>
+  <prefix value="y"/>
+  <import module="e">
+    <prefix value="ext"/>
+  </import>
+  <description>
+    <text>first line
+second  line &amp; &lt;third&gt;</text>
+  </description>
+  <container name="c">
+    <ext:note>
+      <text>n</text>
+    </ext:note>
+    <ext:tag name="t"/>
+    <leaf name="l">
+      <type name="string">
+        <pattern value="[a-z]+"/>
+      </type>
+      <config value="false"/>
+    </leaf>
+  </container>
+</module>
+`
+	fc.AssertEqual(t, expected, actual.String())
+
+	for _, bad := range []string{`module y {`, `module y { leaf "x }`, `module y { leaf x }`, `x; y;`} {
+		fc.AssertEqual(t, true, writeYin(&actual, []byte(bad), nil) != nil, bad)
+	}
+}
+
+func TestSchemaSource(t *testing.T) {
+	ypath := source.Any(source.Dir("./testdata"), source.Dir("./yang"))
+	m, err := parser.LoadModule(ypath, "car")
+	fc.RequireEqual(t, nil, err)
+	d := device.New(ypath)
+	d.AddBrowser(node.NewBrowser(m, &nodeutil.Basic{}))
+	ts := httptest.NewServer(NewHttpServe(d))
+	defer ts.Close()
+	url := ts.URL + "/restconf/schema/"
+	expected, err := os.ReadFile("testdata/car.yang")
+	fc.RequireEqual(t, nil, err)
+
+	for _, p := range []string{"car", "car.yang", "car?format=yang"} {
+		resp, actual := testRequest(t, "GET", url+p, "")
+		fc.AssertEqual(t, 200, resp.StatusCode, p)
+		fc.AssertEqual(t, "application/yang", resp.Header.Get("Content-Type"), p)
+		fc.AssertEqual(t, string(expected), actual, p)
+	}
+
+	resp, yin := testRequest(t, "GET", url+"car?format=yin", "")
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	fc.AssertEqual(t, "application/yin+xml", resp.Header.Get("Content-Type"))
+	fc.Gold(t, *updateFlag, []byte(yin), "testdata/gold/car.yin")
+	_, actual := testRequest(t, "GET", url+"car.yin", "")
+	fc.AssertEqual(t, yin, actual)
+
+	resp, _ = testRequest(t, "GET", url+"car?format=json", "")
+	fc.AssertEqual(t, 400, resp.StatusCode)
+	resp, _ = testRequest(t, "GET", url+"bogus?format=yin", "")
+	fc.AssertEqual(t, 404, resp.StatusCode)
+}